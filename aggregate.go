@@ -0,0 +1,142 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "reflect"
+
+// Aggregator computes a tag value of a merged feature, such as a cluster,
+// from the tag values of the features that it was merged from. Init is
+// called before each merged feature, then Accumulate for each value of the
+// source key, and finally Result. Result returns false when there is no
+// value, in which case the tag is left out. An Aggregator is reused for
+// every merged feature of a layer.
+type Aggregator interface {
+	Init()
+	Accumulate(value interface{})
+	Result() (value interface{}, ok bool)
+}
+
+// aggregate is an Aggregator of a source key that sets a tag
+type aggregate struct {
+	tag string
+	key string
+	agg Aggregator
+}
+
+// aggregateTags returns the tags of the aggregates over the features
+func aggregateTags(tags []Tag, aggs []aggregate, features []*Feature) []Tag {
+	for _, a := range aggs {
+		a.agg.Init()
+		for _, f := range features {
+			if v, ok := f.Tag(a.key); ok {
+				a.agg.Accumulate(v)
+			}
+		}
+		if v, ok := a.agg.Result(); ok {
+			tags = append(tags, Tag{a.tag, v})
+		}
+	}
+	return tags
+}
+
+type sumAggregator struct{ sum float64 }
+
+func (a *sumAggregator) Init()                       { a.sum = 0 }
+func (a *sumAggregator) Result() (interface{}, bool) { return a.sum, true }
+func (a *sumAggregator) Accumulate(value interface{}) {
+	if n, ok := toFloat(value); ok {
+		a.sum += n
+	}
+}
+
+// SumAggregator returns an Aggregator of the sum of numeric values, which
+// is zero when there are none.
+func SumAggregator() Aggregator {
+	return &sumAggregator{}
+}
+
+type meanAggregator struct {
+	sum   float64
+	count int
+}
+
+func (a *meanAggregator) Init() { *a = meanAggregator{} }
+func (a *meanAggregator) Accumulate(value interface{}) {
+	if n, ok := toFloat(value); ok {
+		a.sum += n
+		a.count++
+	}
+}
+func (a *meanAggregator) Result() (interface{}, bool) {
+	if a.count == 0 {
+		return nil, false
+	}
+	return a.sum / float64(a.count), true
+}
+
+// MeanAggregator returns an Aggregator of the mean of numeric values
+func MeanAggregator() Aggregator {
+	return &meanAggregator{}
+}
+
+type extremeAggregator struct {
+	max   bool
+	value interface{}
+	n     float64
+	ok    bool
+}
+
+func (a *extremeAggregator) Init() { *a = extremeAggregator{max: a.max} }
+func (a *extremeAggregator) Accumulate(value interface{}) {
+	if n, ok := toFloat(value); ok {
+		if !a.ok || (a.max && n > a.n) || (!a.max && n < a.n) {
+			a.value, a.n, a.ok = value, n, true
+		}
+	}
+}
+func (a *extremeAggregator) Result() (interface{}, bool) {
+	return a.value, a.ok
+}
+
+// MinAggregator returns an Aggregator of the smallest numeric value, which
+// keeps its original type.
+func MinAggregator() Aggregator {
+	return &extremeAggregator{}
+}
+
+// MaxAggregator returns an Aggregator of the largest numeric value, which
+// keeps its original type.
+func MaxAggregator() Aggregator {
+	return &extremeAggregator{max: true}
+}
+
+type modeAggregator struct {
+	counts map[interface{}]int
+	value  interface{}
+	best   int
+}
+
+func (a *modeAggregator) Init() {
+	a.counts = make(map[interface{}]int)
+	a.value, a.best = nil, 0
+}
+func (a *modeAggregator) Accumulate(value interface{}) {
+	if value == nil || !reflect.TypeOf(value).Comparable() {
+		return
+	}
+	a.counts[value]++
+	if n := a.counts[value]; n > a.best {
+		a.value, a.best = value, n
+	}
+}
+func (a *modeAggregator) Result() (interface{}, bool) {
+	return a.value, a.best > 0
+}
+
+// ModeAggregator returns an Aggregator of the most common value, which is
+// the first one seen for a tie. Lists and maps are not counted.
+func ModeAggregator() Aggregator {
+	return &modeAggregator{}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestAggregators(t *testing.T) {
+	values := []interface{}{3, "a", 1.5, int64(7), "b", "a", []int{1}, nil}
+	for _, tc := range []struct {
+		agg    Aggregator
+		expect interface{}
+	}{
+		{SumAggregator(), 11.5},
+		{MeanAggregator(), 11.5 / 3},
+		{MinAggregator(), 1.5},
+		{MaxAggregator(), int64(7)},
+		{ModeAggregator(), "a"},
+	} {
+		// twice to check that Init resets the aggregator
+		for i := 0; i < 2; i++ {
+			tc.agg.Init()
+			for _, v := range values {
+				tc.agg.Accumulate(v)
+			}
+			if v, ok := tc.agg.Result(); !ok || v != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, v)
+			}
+		}
+	}
+	for _, agg := range []Aggregator{MeanAggregator(), MinAggregator(),
+		MaxAggregator(), ModeAggregator()} {
+		agg.Init()
+		agg.Accumulate("a")
+		agg.Init()
+		if _, ok := agg.Result(); ok {
+			t.Fatalf("expected no result for %T", agg)
+		}
+	}
+}
+
+func TestClusterAggregate(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	for _, kind := range []string{"cafe", "bar", "cafe"} {
+		f := l.AddFeature(Point)
+		f.MoveTo(10, 10)
+		f.AddTag("kind", kind)
+	}
+	l.SetCluster(8, 2)
+	l.SetClusterAggregate("kind", "kind", ModeAggregator())
+	features := l.render()
+	if len(features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(features))
+	}
+	if v, _ := features[0].Tag("kind"); v != "cafe" {
+		t.Fatalf("expected cafe, got %v", v)
+	}
+}
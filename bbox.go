@@ -0,0 +1,66 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// BBoxMode is how the bounding boxes of lat/lon geometries are emitted,
+// which may be combined.
+type BBoxMode int
+
+const (
+	// BBoxTags adds the tags bbox_minx, bbox_miny, bbox_maxx, and
+	// bbox_maxy to features, which are the lon/lat bounds of the whole of
+	// their geometry, before it is clipped to the tile
+	BBoxTags BBoxMode = 1 << iota
+	// BBoxLayer adds a polygon of the bounds of each feature in the tile,
+	// after it is clipped, to a layer of the tile that is named like the
+	// layer with a "_bbox" suffix, for debugging
+	BBoxLayer
+)
+
+// SetBBoxes sets how the bounding boxes of the geometries that are added
+// to the layer with AddGeometry, AddGeoFeature, or AddFrom are emitted.
+// Default is zero, which emits none.
+func (l *Layer) SetBBoxes(mode BBoxMode) {
+	l.bboxes = mode
+}
+
+// addBBoxes emits the bounding box of the lat/lon geometry of the feature
+func (l *Layer) addBBoxes(f *Feature, g Geometry) {
+	minLat, minLon, maxLat, maxLon := g.Bounds()
+	if l.bboxes&BBoxTags != 0 {
+		f.AddTag("bbox_minx", minLon)
+		f.AddTag("bbox_miny", minLat)
+		f.AddTag("bbox_maxx", maxLon)
+		f.AddTag("bbox_maxy", maxLat)
+	}
+	if l.bboxes&BBoxLayer == 0 || l.tile == nil {
+		return
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := 0; i < len(f.geom.coords); i += 2 {
+		minX, maxX = math.Min(minX, f.geom.coords[i]), math.Max(maxX,
+			f.geom.coords[i])
+		minY, maxY = math.Min(minY, f.geom.coords[i+1]), math.Max(maxY,
+			f.geom.coords[i+1])
+	}
+	if !(maxX > minX && maxY > minY) {
+		// no area, such as a point
+		return
+	}
+	name := l.name + "_bbox"
+	bl := l.tile.GetLayer(name)
+	if bl == nil {
+		bl = l.tile.AddLayer(name)
+	}
+	bf := bl.AddFeature(Polygon)
+	bf.Rect(minX, minY, maxX-minX, maxY-minY)
+	bf.AddTag("bbox_minx", minLon)
+	bf.AddTag("bbox_miny", minLat)
+	bf.AddTag("bbox_maxx", maxLon)
+	bf.AddTag("bbox_maxy", maxLat)
+}
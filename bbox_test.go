@@ -0,0 +1,50 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBBoxes(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{Z: 1, X: 1, Y: 0})
+	l := tile.AddLayer("areas")
+	l.SetBBoxes(BBoxTags | BBoxLayer)
+	poly := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{-10, 10}, {20, 10}, {20, 30}, {-10, 30}, {-10, 10}},
+	}}
+	f := l.AddGeometry(poly)
+	var tags []string
+	for _, tag := range f.Tags() {
+		tags = append(tags, fmt.Sprint(tag.Key, "=", tag.Value))
+	}
+	if s := fmt.Sprint(tags); s !=
+		"[bbox_minx=-10 bbox_miny=10 bbox_maxx=20 bbox_maxy=30]" {
+		t.Fatalf("unexpected tags %s", s)
+	}
+	bl := tile.GetLayer("areas_bbox")
+	if bl == nil || len(bl.Features()) != 1 {
+		t.Fatal("expected a bbox feature")
+	}
+	// the box is of the feature as it was clipped to the tile
+	b := bl.Features()[0]
+	_, y0 := LatLonToPixel(30, 0, 1)
+	x1, y1 := LatLonToPixel(10, 20, 1)
+	expect := fmt.Sprint([]float64{-clipBuffer, y0, x1 - 512, y0, x1 - 512, y1,
+		-clipBuffer, y1})
+	if s := fmt.Sprint(b.geom.coords); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+	if err := b.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// points have tags but no box
+	l.AddGeometry(Geometry{Type: Point, Paths: [][][2]float64{{{10, 10}}}})
+	if len(bl.Features()) != 1 || len(l.Features()[1].Tags()) != 4 {
+		t.Fatal("expected bbox tags and no bbox feature")
+	}
+}
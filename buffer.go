@@ -0,0 +1,213 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// CapStyle is the shape of the ends of a buffered line
+type CapStyle int
+
+const (
+	// ButtCap ends the line square at its end points
+	ButtCap CapStyle = iota
+	// RoundCap ends the line with a half circle
+	RoundCap
+	// SquareCap ends the line square, past its end points by half of the
+	// width
+	SquareCap
+)
+
+// JoinStyle is the shape of the outer corners of a buffered line
+type JoinStyle int
+
+const (
+	// MiterJoin extends the sides of a corner to meet at a point, unless
+	// it is sharp enough that the point is further than twice the width
+	// from the corner, which is then beveled
+	MiterJoin JoinStyle = iota
+	// RoundJoin rounds a corner with an arc
+	RoundJoin
+	// BevelJoin cuts a corner straight across
+	BevelJoin
+)
+
+// miterLimit is the longest a miter may be, as a multiple of half of the
+// width of the line
+const miterLimit = 4
+
+// BufferLine adds a Polygon feature of the outline of the line of x/y
+// points, when it is stroked with the width in pixels, so that lines of a
+// fixed width on the ground may be drawn as polygons. It returns nil when
+// the line has fewer than two distinct points or the width is not
+// positive. The sharp corners of lines with short segments may make the
+// outline overlap itself on the inside of the corners.
+func (l *Layer) BufferLine(points [][2]float64, width float64,
+	capStyle CapStyle, joinStyle JoinStyle) *Feature {
+	ring := bufferLine(points, width/2, capStyle, joinStyle)
+	if ring == nil {
+		return nil
+	}
+	f := l.AddFeature(Polygon)
+	f.drawRing(ring)
+	return f
+}
+
+// bufferLine returns the outline of the line as a ring that is oriented
+// as an exterior ring.
+func bufferLine(points [][2]float64, hw float64, capStyle CapStyle,
+	joinStyle JoinStyle) [][2]float64 {
+	// the distinct points of the line
+	var line [][2]float64
+	for _, p := range points {
+		if len(line) == 0 || p != line[len(line)-1] {
+			line = append(line, p)
+		}
+	}
+	if len(line) < 2 || !(hw > 0) {
+		return nil
+	}
+	dirs := make([][2]float64, len(line)-1)
+	for i := range dirs {
+		dx, dy := line[i+1][0]-line[i][0], line[i+1][1]-line[i][1]
+		d := math.Hypot(dx, dy)
+		dirs[i] = [2]float64{dx / d, dy / d}
+	}
+	// offset returns the point at a side of a point of the line, where
+	// the side is 1 or -1
+	offset := func(p, d [2]float64, side float64) [2]float64 {
+		return [2]float64{p[0] - side*hw*d[1], p[1] + side*hw*d[0]}
+	}
+	sides := [2][][2]float64{}
+	for s, side := range [2]float64{1, -1} {
+		var pts [][2]float64
+		pts = append(pts, offset(line[0], dirs[0], side))
+		for i := 1; i < len(line)-1; i++ {
+			pts = append(pts, bufferJoin(line[i-1], line[i], line[i+1],
+				dirs[i-1], dirs[i], hw, side, joinStyle)...)
+		}
+		pts = append(pts, offset(line[len(line)-1], dirs[len(dirs)-1], side))
+		sides[s] = pts
+	}
+	ring := sides[0]
+	last, first := line[len(line)-1], line[0]
+	ring = append(ring, bufferCap(last, dirs[len(dirs)-1],
+		offset(last, dirs[len(dirs)-1], 1), hw, capStyle)...)
+	for i := len(sides[1]) - 1; i >= 0; i-- {
+		ring = append(ring, sides[1][i])
+	}
+	d := dirs[0]
+	ring = append(ring, bufferCap(first, [2]float64{-d[0], -d[1]},
+		offset(first, d, -1), hw, capStyle)...)
+	// remove repeated points, which the arcs add at their ends
+	out := ring[:1]
+	for _, p := range ring[1:] {
+		q := out[len(out)-1]
+		if math.Abs(p[0]-q[0]) > 1e-9 || math.Abs(p[1]-q[1]) > 1e-9 {
+			out = append(out, p)
+		}
+	}
+	if len(out) > 1 && math.Abs(out[0][0]-out[len(out)-1][0]) < 1e-9 &&
+		math.Abs(out[0][1]-out[len(out)-1][1]) < 1e-9 {
+		out = out[:len(out)-1]
+	}
+	var area float64
+	for i := range out {
+		a, b := out[i], out[(i+1)%len(out)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	if area < 0 {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
+
+// bufferJoin returns the points of the side of a corner of a buffered
+// line, at p between the segments from prev and to next, whose directions
+// are d1 and d2.
+func bufferJoin(prev, p, next, d1, d2 [2]float64, hw, side float64,
+	joinStyle JoinStyle) [][2]float64 {
+	a := [2]float64{p[0] - side*hw*d1[1], p[1] + side*hw*d1[0]}
+	b := [2]float64{p[0] - side*hw*d2[1], p[1] + side*hw*d2[0]}
+	cross := d1[0]*d2[1] - d1[1]*d2[0]
+	dot := d1[0]*d2[0] + d1[1]*d2[1]
+	if math.Abs(cross) < 1e-9 && dot > 0 {
+		// straight on
+		return [][2]float64{a}
+	}
+	if side*cross > 0 {
+		// the inside of the corner, where the sides of the segments meet
+		// if the segments are long enough
+		a0 := [2]float64{prev[0] - side*hw*d1[1], prev[1] + side*hw*d1[0]}
+		b1 := [2]float64{next[0] - side*hw*d2[1], next[1] + side*hw*d2[0]}
+		if x, ok := segmentIntersection(a0, a, b, b1); ok {
+			return [][2]float64{x}
+		}
+		return [][2]float64{a, p, b}
+	}
+	switch joinStyle {
+	case RoundJoin:
+		start := math.Atan2(a[1]-p[1], a[0]-p[0])
+		sweep := math.Atan2(cross, dot)
+		if math.Abs(cross) < 1e-9 {
+			// a full turn back, around the front of the line
+			sweep = math.Pi
+			if mid := start + sweep/2; math.Cos(mid)*d1[0]+
+				math.Sin(mid)*d1[1] < 0 {
+				sweep = -sweep
+			}
+		}
+		return arcPoints(p[0], p[1], hw, start, start+sweep)
+	case MiterJoin:
+		if dot > -1+1e-9 {
+			// the miter is along the bisector of the normals
+			nx, ny := (a[0]+b[0])/2-p[0], (a[1]+b[1])/2-p[1]
+			n := math.Hypot(nx, ny)
+			if length := hw * hw / n; length <= miterLimit*hw {
+				return [][2]float64{{p[0] + nx/n*length, p[1] + ny/n*length}}
+			}
+		}
+	}
+	return [][2]float64{a, b}
+}
+
+// bufferCap returns the points of the cap at the end p of a buffered line
+// that goes in the direction d, starting from the side at a.
+func bufferCap(p, d, a [2]float64, hw float64, capStyle CapStyle) [][2]float64 {
+	b := [2]float64{2*p[0] - a[0], 2*p[1] - a[1]}
+	switch capStyle {
+	case RoundCap:
+		start := math.Atan2(a[1]-p[1], a[0]-p[0])
+		sweep := math.Pi
+		if mid := start + sweep/2; math.Cos(mid)*d[0]+math.Sin(mid)*d[1] < 0 {
+			sweep = -sweep
+		}
+		return arcPoints(p[0], p[1], hw, start, start+sweep)
+	case SquareCap:
+		return [][2]float64{
+			{a[0] + d[0]*hw, a[1] + d[1]*hw}, {b[0] + d[0]*hw, b[1] + d[1]*hw},
+		}
+	}
+	return nil
+}
+
+// segmentIntersection returns the point where the segments a-b and c-d
+// cross, if they do.
+func segmentIntersection(a, b, c, d [2]float64) ([2]float64, bool) {
+	rx, ry := b[0]-a[0], b[1]-a[1]
+	sx, sy := d[0]-c[0], d[1]-c[1]
+	denom := rx*sy - ry*sx
+	if denom == 0 {
+		return [2]float64{}, false
+	}
+	qx, qy := c[0]-a[0], c[1]-a[1]
+	t := (qx*sy - qy*sx) / denom
+	u := (qx*ry - qy*rx) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return [2]float64{}, false
+	}
+	return [2]float64{a[0] + t*rx, a[1] + t*ry}, true
+}
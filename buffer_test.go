@@ -0,0 +1,80 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestBufferLine(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("strokes")
+	straight := [][2]float64{{0, 100}, {50, 100}, {100, 100}}
+	turn := [][2]float64{{0, 0}, {100, 0}, {100, 0}, {100, 100}}
+	// the deficit of a flattened arc of the radius and angle
+	arcDeficit := func(radius, angle float64) float64 {
+		return radius * angle * curveTolerance
+	}
+	for _, tc := range []struct {
+		points [][2]float64
+		cap    CapStyle
+		join   JoinStyle
+		area   float64
+		within float64
+	}{
+		{straight, ButtCap, MiterJoin, 2000, 0},
+		{straight, SquareCap, MiterJoin, 2400, 0},
+		{straight, RoundCap, MiterJoin, 2000 + math.Pi*100,
+			arcDeficit(10, 2*math.Pi)},
+		{turn, ButtCap, MiterJoin, 4000, 0},
+		{turn, ButtCap, BevelJoin, 4000 - 50, 1e-9},
+		{turn, ButtCap, RoundJoin, 4000 - 100 + math.Pi*100/4,
+			arcDeficit(10, math.Pi/2)},
+	} {
+		f := l.BufferLine(tc.points, 20, tc.cap, tc.join)
+		if f == nil {
+			t.Fatal("expected a feature")
+		}
+		if err := f.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if a := f.area(); math.Abs(a-tc.area) > tc.within+1e-9 {
+			t.Fatalf("cap %d join %d: expected an area of %v, got %v",
+				tc.cap, tc.join, tc.area, a)
+		}
+	}
+	f := l.BufferLine(turn, 20, ButtCap, MiterJoin)
+	coords := make([]float64, len(f.geom.coords))
+	for i, v := range f.geom.coords {
+		coords[i] = math.Round(v*1e6) / 1e6
+	}
+	if s := fmt.Sprint(coords); s !=
+		"[0 -10 110 -10 110 100 90 100 90 10 0 10]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+
+	// a sharp corner is beveled past the miter limit
+	sharp := [][2]float64{{0, 0}, {100, 0}, {0, 5}}
+	miter := l.BufferLine(sharp, 20, ButtCap, MiterJoin)
+	bevel := l.BufferLine(sharp, 20, ButtCap, BevelJoin)
+	if fmt.Sprint(miter.geom.coords) != fmt.Sprint(bevel.geom.coords) {
+		t.Fatal("expected the sharp corner to be beveled")
+	}
+	// a full turn back
+	back := l.BufferLine([][2]float64{{0, 0}, {100, 0}, {50, 0}}, 20,
+		RoundCap, RoundJoin)
+	if err := back.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if l.BufferLine([][2]float64{{1, 1}, {1, 1}}, 20, RoundCap,
+		RoundJoin) != nil {
+		t.Fatal("expected no feature for a single point")
+	}
+	if l.BufferLine(straight, 0, ButtCap, MiterJoin) != nil {
+		t.Fatal("expected no feature for no width")
+	}
+}
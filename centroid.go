@@ -0,0 +1,146 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// Placement is where the point of a line or polygon is placed
+type Placement int
+
+const (
+	// Centroid is the center of mass of the area of a polygon, or of the
+	// length of a line, which may be outside of a concave polygon or off of
+	// a curved line
+	Centroid Placement = iota
+	// RepresentativePoint is a point that is on the geometry, which is the
+	// pole of inaccessibility of a polygon, see Polylabel, or the point
+	// halfway along the longest line
+	RepresentativePoint
+)
+
+// centroidOptions are the options of centroid point features
+type centroidOptions struct {
+	layer     string
+	placement Placement
+}
+
+// SetCentroids sets the name of a layer of the tile to which a point
+// feature is added for each line and polygon that is added to this layer
+// with AddGeoFeature, or AddFrom, with the tags and id of the feature. The
+// points are placed in Web Mercator by the placement, and are only added
+// to the tile that they are in, so that they may be used for symbols at
+// low zooms. The layer may be this layer. An empty name, the default,
+// adds no points.
+func (l *Layer) SetCentroids(layer string, placement Placement) {
+	if layer == "" {
+		l.centroids = nil
+		return
+	}
+	l.centroids = &centroidOptions{layer, placement}
+}
+
+// addCentroid adds the point of the line or polygon to the centroid layer
+func (l *Layer) addCentroid(gf GeoFeature) {
+	g := gf.Geometry
+	if g.Type != LineString && g.Type != Polygon {
+		return
+	}
+	paths := make([][][2]float64, len(g.Paths))
+	for i, path := range g.Paths {
+		paths[i] = make([][2]float64, len(path))
+		for j, p := range path {
+			paths[i][j][0], paths[i][j][1] = lonLatMercator(p[0], p[1])
+		}
+	}
+	var p [2]float64
+	switch {
+	case l.centroids.placement == RepresentativePoint && g.Type == Polygon:
+		precision := originShift / float64(gMapSize(l.tileID().Z))
+		merc := Geometry{Type: Polygon, Paths: paths, Rings: g.Rings}
+		p = polylabel(largestPolygon(merc), precision)
+	case l.centroids.placement == RepresentativePoint:
+		p = lineMidpoint(paths)
+	case g.Type == Polygon:
+		merc := Geometry{Type: Polygon, Paths: paths, Rings: g.Rings}
+		p = areaCentroid(merc.orient().Paths)
+	default:
+		p = lengthCentroid(paths)
+	}
+	lon, lat := mercatorLonLat(p[0], p[1])
+	l.addPointTo(l.centroids.layer, lat, lon, gf)
+}
+
+// areaCentroid returns the centroid of the area of the rings, whose holes
+// wind the other way from their exteriors. Rings without area have the
+// centroid of their length.
+func areaCentroid(rings [][][2]float64) [2]float64 {
+	var x, y, area float64
+	for _, ring := range rings {
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			f := a[0]*b[1] - b[0]*a[1]
+			x += (a[0] + b[0]) * f
+			y += (a[1] + b[1]) * f
+			area += f * 3
+		}
+	}
+	if area == 0 {
+		return lengthCentroid(rings)
+	}
+	return [2]float64{x / area, y / area}
+}
+
+// lengthCentroid returns the centroid of the length of the lines, or of
+// their points when they have no length.
+func lengthCentroid(lines [][][2]float64) [2]float64 {
+	var x, y, length, px, py, n float64
+	for _, line := range lines {
+		for i, b := range line {
+			px, py, n = px+b[0], py+b[1], n+1
+			if i == 0 {
+				continue
+			}
+			a := line[i-1]
+			d := math.Hypot(b[0]-a[0], b[1]-a[1])
+			x += (a[0] + b[0]) / 2 * d
+			y += (a[1] + b[1]) / 2 * d
+			length += d
+		}
+	}
+	if length == 0 {
+		return [2]float64{px / n, py / n}
+	}
+	return [2]float64{x / length, y / length}
+}
+
+// lineMidpoint returns the point halfway along the longest line
+func lineMidpoint(lines [][][2]float64) [2]float64 {
+	var best [][2]float64
+	var bestLength float64
+	for _, line := range lines {
+		var length float64
+		for i := 1; i < len(line); i++ {
+			length += math.Hypot(line[i][0]-line[i-1][0],
+				line[i][1]-line[i-1][1])
+		}
+		if best == nil || length > bestLength {
+			best, bestLength = line, length
+		}
+	}
+	if len(best) == 0 {
+		return [2]float64{math.NaN(), math.NaN()}
+	}
+	half := bestLength / 2
+	for i := 1; i < len(best); i++ {
+		a, b := best[i-1], best[i]
+		d := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if d > 0 && d >= half {
+			return [2]float64{a[0] + (b[0]-a[0])*half/d,
+				a[1] + (b[1]-a[1])*half/d}
+		}
+		half -= d
+	}
+	return best[len(best)-1]
+}
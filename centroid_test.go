@@ -0,0 +1,75 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCentroids(t *testing.T) {
+	// a C shape, whose centroid is outside of it
+	c := [][2]float64{{0, 0}, {30, 0}, {30, 10}, {10, 10}, {10, 20}, {30, 20},
+		{30, 30}, {0, 30}, {0, 0}}
+	if p := areaCentroid([][][2]float64{c}); math.Abs(p[0]-95.0/7) > 1e-9 ||
+		math.Abs(p[1]-15) > 1e-9 {
+		t.Fatalf("unexpected centroid %v", p)
+	}
+	// a hole, wound the other way, moves the centroid
+	hole := [][2]float64{{20, 2}, {20, 8}, {28, 8}, {28, 2}, {20, 2}}
+	if p := areaCentroid([][][2]float64{c, hole}); p[1] <= 15 {
+		t.Fatalf("expected the centroid to move away from the hole, got %v", p)
+	}
+	line := [][2]float64{{0, 0}, {10, 0}, {10, 30}}
+	if p := lengthCentroid([][][2]float64{line}); p != [2]float64{8.75, 11.25} {
+		t.Fatalf("unexpected centroid %v", p)
+	}
+	if p := lineMidpoint([][][2]float64{{{0, 0}, {1, 0}}, line}); p !=
+		[2]float64{10, 10} {
+		t.Fatalf("unexpected midpoint %v", p)
+	}
+
+	var tile Tile
+	l := tile.AddLayer("areas")
+	l.SetCentroids("areas_points", Centroid)
+	tags := map[string]interface{}{"name": "c"}
+	geo := func(path [][2]float64, typ GeometryType) Geometry {
+		return Geometry{Type: typ, Paths: [][][2]float64{path}}
+	}
+	l.AddGeoFeature(GeoFeature{geo(c, Polygon), tags, 1})
+	l.AddGeoFeature(GeoFeature{geo(line, LineString), tags, 2})
+	l.AddGeoFeature(GeoFeature{geo([][2]float64{{1, 1}}, Point), tags, 3})
+	points := tile.GetLayer("areas_points")
+	if points == nil || len(points.Features()) != 2 {
+		t.Fatal("expected two centroid points")
+	}
+	f := points.Features()[0]
+	if v, _ := f.Tag("name"); v != "c" || f.GeomType() != Point {
+		t.Fatalf("unexpected point %v %v", f.GeomType(), v)
+	}
+	x, y := f.geom.coords[0], f.geom.coords[1]
+	lat, lon := PixelToLatLon(x, y, 0)
+	if lon < 10 || lon > 15 || lat < 14 || lat > 16 {
+		t.Fatalf("unexpected centroid %v,%v", lat, lon)
+	}
+
+	// representative points are on the geometry, in the same layer
+	l = tile.AddLayer("shapes")
+	l.SetCentroids("shapes", RepresentativePoint)
+	l.AddGeoFeature(GeoFeature{geo(c, Polygon), tags, 1})
+	if len(l.Features()) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(l.Features()))
+	}
+	p := l.Features()[1].geom.coords
+	lat, lon = PixelToLatLon(p[0], p[1], 0)
+	if lon > 10 && lat > 10 && lat < 20 {
+		t.Fatalf("expected the point to be in the C, got %v,%v", lat, lon)
+	}
+	l.SetCentroids("", Centroid)
+	l.AddGeoFeature(GeoFeature{geo(c, Polygon), tags, 1})
+	if len(l.Features()) != 3 {
+		t.Fatal("expected no point")
+	}
+}
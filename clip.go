@@ -0,0 +1,191 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// clipBuffer is how far past the edges of the canvas, in pixels, that
+// geometry is kept when it is clipped to a tile.
+const clipBuffer = 8
+
+// clipRect is an axis aligned rectangle on the canvas
+type clipRect struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (r clipRect) contains(x, y float64) bool {
+	return x >= r.minX && x <= r.maxX && y >= r.minY && y <= r.maxY
+}
+
+// clip returns the geometry of the feature that is inside of the
+// rectangle. Lines are split where they leave the rectangle, and the rings
+// of polygons are cut along its sides. A polygon ring that is clipped
+// away takes the holes that follow it with it.
+func (f *Feature) clip(r clipRect) geometry {
+	var g geometry
+	switch f.geomType {
+	case Point:
+		// a ClosePath has no coordinates, which are read with their own
+		// cursor
+		var j int
+		for _, op := range f.geom.ops {
+			if op == closePath {
+				continue
+			}
+			x, y := f.geom.coords[j], f.geom.coords[j+1]
+			j += 2
+			if op == moveTo && r.contains(x, y) {
+				g.push(moveTo, x, y)
+			}
+		}
+	case LineString:
+		for _, path := range f.paths() {
+			clipLine(&g, pathPoints(path), r)
+		}
+	case Polygon:
+		var exterior float64
+		keep := false
+		for i, path := range f.paths() {
+			ring := pathPoints(path)
+			area := ringArea(ring)
+			if i == 0 || (area > 0) == (exterior > 0) {
+				exterior = area
+				keep = true
+			}
+			if !keep {
+				continue
+			}
+			ring = clipRing(ring, r)
+			if len(ring) < 3 || ringArea(ring) == 0 {
+				if (area > 0) == (exterior > 0) {
+					// the holes of the ring go with it
+					keep = false
+				}
+				continue
+			}
+			for j, p := range ring {
+				which := lineTo
+				if j == 0 {
+					which = moveTo
+				}
+				g.push(which, p.x, p.y)
+			}
+			g.push(closePath, 0, 0)
+		}
+	}
+	return g
+}
+
+// clipLine appends the parts of the line that are inside of the rectangle
+// to the geometry.
+func clipLine(g *geometry, points []command, r clipRect) {
+	drawing := false
+	for i := 1; i < len(points); i++ {
+		ax, ay := points[i-1].x, points[i-1].y
+		bx, by := points[i].x, points[i].y
+		cax, cay, cbx, cby, ok := clipSegment(ax, ay, bx, by, r)
+		if !ok {
+			drawing = false
+			continue
+		}
+		if !drawing || cax != ax || cay != ay {
+			g.push(moveTo, cax, cay)
+		}
+		g.push(lineTo, cbx, cby)
+		drawing = cbx == bx && cby == by
+	}
+}
+
+// clipSegment clips a segment to the rectangle using the Liang-Barsky
+// algorithm, returning false when none of it is inside.
+func clipSegment(ax, ay, bx, by float64, r clipRect,
+) (cax, cay, cbx, cby float64, ok bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := bx-ax, by-ay
+	for _, e := range [4][2]float64{
+		{-dx, ax - r.minX}, {dx, r.maxX - ax},
+		{-dy, ay - r.minY}, {dy, r.maxY - ay},
+	} {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return 0, 0, 0, 0, false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return 0, 0, 0, 0, false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	cax, cay = ax+t0*dx, ay+t0*dy
+	cbx, cby = ax+t1*dx, ay+t1*dy
+	if t0 == 0 {
+		cax, cay = ax, ay
+	}
+	if t1 == 1 {
+		cbx, cby = bx, by
+	}
+	return cax, cay, cbx, cby, true
+}
+
+// clipRing clips a ring to the rectangle using the Sutherland-Hodgman
+// algorithm, which keeps the winding order of the ring.
+func clipRing(ring []command, r clipRect) []command {
+	if n := len(ring); n > 1 &&
+		ring[0].x == ring[n-1].x && ring[0].y == ring[n-1].y {
+		ring = ring[:n-1]
+	}
+	edges := [4]struct {
+		inside func(x, y float64) bool
+		cross  func(ax, ay, bx, by float64) (float64, float64)
+	}{
+		{func(x, y float64) bool { return x >= r.minX },
+			func(ax, ay, bx, by float64) (float64, float64) {
+				return r.minX, ay + (r.minX-ax)*(by-ay)/(bx-ax)
+			}},
+		{func(x, y float64) bool { return x <= r.maxX },
+			func(ax, ay, bx, by float64) (float64, float64) {
+				return r.maxX, ay + (r.maxX-ax)*(by-ay)/(bx-ax)
+			}},
+		{func(x, y float64) bool { return y >= r.minY },
+			func(ax, ay, bx, by float64) (float64, float64) {
+				return ax + (r.minY-ay)*(bx-ax)/(by-ay), r.minY
+			}},
+		{func(x, y float64) bool { return y <= r.maxY },
+			func(ax, ay, bx, by float64) (float64, float64) {
+				return ax + (r.maxY-ay)*(bx-ax)/(by-ay), r.maxY
+			}},
+	}
+	for _, e := range edges {
+		if len(ring) == 0 {
+			break
+		}
+		in := ring
+		ring = make([]command, 0, len(in)+4)
+		for i, b := range in {
+			a := in[(i+len(in)-1)%len(in)]
+			ain, bin := e.inside(a.x, a.y), e.inside(b.x, b.y)
+			if bin != ain {
+				x, y := e.cross(a.x, a.y, b.x, b.y)
+				ring = append(ring, command{which: lineTo, x: x, y: y})
+			}
+			if bin {
+				ring = append(ring, command{which: lineTo, x: b.x, y: b.y})
+			}
+		}
+	}
+	return ring
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// clusterOptions are the settings of a clustered layer.
+type clusterOptions struct {
+	radius     float64
+	minPoints  int
+	aggregates []aggregate
+}
+
+// SetCluster sets the layer to cluster its single point features when it
+// is rendered. Points within the radius, in pixels on the 512x512 canvas,
+// of another point are grouped, and each group of at least minPoints is
+// replaced by one point at their center. Cluster features have a
+// "cluster" tag of true and a "point_count" tag of the number of points
+// in them. A radius of zero turns clustering off.
+func (l *Layer) SetCluster(radius float64, minPoints int) {
+	if radius <= 0 {
+		l.cluster = nil
+		return
+	}
+	if l.cluster == nil {
+		l.cluster = &clusterOptions{}
+	}
+	l.cluster.radius = radius
+	l.cluster.minPoints = minPoints
+}
+
+// SetClusterAggregate adds a tag to each cluster feature that is computed
+// by the aggregator from the values of the key in the clustered points.
+func (l *Layer) SetClusterAggregate(tag, key string, agg Aggregator) {
+	if l.cluster != nil {
+		l.cluster.aggregates = append(l.cluster.aggregates,
+			aggregate{tag: tag, key: key, agg: agg})
+	}
+}
+
+// SetClusterSum adds a "<key>_sum" tag to each cluster feature that is the
+// sum of the numeric values of the key in the clustered points.
+func (l *Layer) SetClusterSum(keys ...string) {
+	for _, key := range keys {
+		l.SetClusterAggregate(key+"_sum", key, SumAggregator())
+	}
+}
+
+// SetClusterMean adds a "<key>_mean" tag to each cluster feature that is
+// the mean of the numeric values of the key in the clustered points.
+// Points without a numeric value for the key are not counted.
+func (l *Layer) SetClusterMean(keys ...string) {
+	for _, key := range keys {
+		l.SetClusterAggregate(key+"_mean", key, MeanAggregator())
+	}
+}
+
+// clusterPoints replaces groups of nearby single point features with
+// cluster features.
+func (l *Layer) clusterPoints(features []*Feature) []*Feature {
+	opts := l.cluster
+	points := make(map[*Feature]*clusterPoint)
+	grid := make(map[[2]int][]*Feature)
+	cell := func(x, y float64) [2]int {
+		return [2]int{int(math.Floor(x / opts.radius)),
+			int(math.Floor(y / opts.radius))}
+	}
+	var order []*Feature
+	for _, f := range features {
+		if f.geomType != Point || len(f.geom.ops) != 1 {
+			continue
+		}
+		x, y := f.geom.current()
+		points[f] = &clusterPoint{x: x, y: y}
+		c := cell(x, y)
+		grid[c] = append(grid[c], f)
+		order = append(order, f)
+	}
+	for _, f := range order {
+		p := points[f]
+		if p.done {
+			continue
+		}
+		members := []*Feature{f}
+		c := cell(p.x, p.y)
+		for cx := c[0] - 1; cx <= c[0]+1; cx++ {
+			for cy := c[1] - 1; cy <= c[1]+1; cy++ {
+				for _, g := range grid[[2]int{cx, cy}] {
+					q := points[g]
+					if g != f && !q.done &&
+						math.Hypot(q.x-p.x, q.y-p.y) <= opts.radius {
+						members = append(members, g)
+					}
+				}
+			}
+		}
+		p.done = true
+		if len(members) < opts.minPoints || len(members) < 2 {
+			continue
+		}
+		cluster := l.newCluster(members, points)
+		for _, g := range members {
+			points[g].done = true
+			points[g].cluster = cluster
+		}
+		p.first = true
+	}
+	clustered := make([]*Feature, 0, len(features))
+	for _, f := range features {
+		if p := points[f]; p != nil && p.cluster != nil {
+			if p.first {
+				clustered = append(clustered, p.cluster)
+			}
+			continue
+		}
+		clustered = append(clustered, f)
+	}
+	return clustered
+}
+
+// clusterPoint is a single point feature that is being clustered.
+type clusterPoint struct {
+	x, y    float64
+	cluster *Feature
+	first   bool
+	done    bool
+}
+
+// newCluster returns a cluster feature for the points.
+func (l *Layer) newCluster(members []*Feature,
+	points map[*Feature]*clusterPoint,
+) *Feature {
+	var x, y float64
+	for _, f := range members {
+		x += points[f].x
+		y += points[f].y
+	}
+	n := float64(len(members))
+	cluster := &Feature{geomType: Point, layer: l}
+	cluster.geom.push(moveTo, x/n, y/n)
+	cluster.tags = append(cluster.tags,
+		Tag{"cluster", true},
+		Tag{"point_count", uint64(len(members))},
+	)
+	cluster.tags = aggregateTags(cluster.tags, l.cluster.aggregates, members)
+	return cluster
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestCluster(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	l.SetAutoID(1)
+	for i, xy := range [][2]float64{{10, 10}, {14, 10}, {300, 300}, {12, 16}} {
+		f := l.AddFeature(Point)
+		f.MoveTo(xy[0], xy[1])
+		f.AddTag("pop", i+1)
+	}
+	l.AddFeature(LineString).MoveTo(10, 10)
+	l.SetCluster(8, 2)
+	l.SetClusterSum("pop")
+	l.SetClusterMean("pop")
+	features := l.render()
+	if ids := featureIDs(features); ids != "[0 3 5]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	cluster := features[0]
+	if x, y := cluster.geom.current(); x != 12 || y != 12 {
+		t.Fatalf("expected 12,12, got %v,%v", x, y)
+	}
+	for key, expect := range map[string]interface{}{
+		"cluster":     true,
+		"point_count": uint64(3),
+		"pop_sum":     7.0,
+		"pop_mean":    7.0 / 3,
+	} {
+		if v, _ := cluster.Tag(key); v != expect {
+			t.Fatalf("expected %s=%v, got %v", key, expect, v)
+		}
+	}
+	if len(l.Features()) != 5 {
+		t.Fatal("expected the layer to keep all features")
+	}
+	l.SetCluster(8, 4)
+	if ids := featureIDs(l.render()); ids != "[1 2 3 4 5]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetCluster(0, 0)
+	if len(l.render()) != 5 {
+		t.Fatal("expected clustering to be off")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidGrid is returned for a grid of values that is not rectangular
+// or that has fewer than two rows or columns
+var ErrInvalidGrid = errors.New("invalid grid")
+
+// contourEdge is an edge between two adjacent values of a grid. A
+// horizontal edge goes right from the value at the row and column, and a
+// vertical edge goes down from it.
+type contourEdge struct {
+	row, col int
+	vertical bool
+}
+
+// contourSegments are the segments of the cells of the marching squares
+// cases, indexed by the corners that are at or above the level, with the
+// top-left as 8, top-right as 4, bottom-right as 2, and bottom-left as 1.
+// The sides of a cell are 0 top, 1 right, 2 bottom, and 3 left. Each
+// segment goes from one side to another with the higher values on its
+// right. The saddle cases, 5 and 10, are the ones for a low center and
+// are swapped when the center is high.
+var contourSegments = [16][][2]int{
+	1: {{3, 2}}, 2: {{2, 1}}, 3: {{3, 1}}, 4: {{1, 0}},
+	5: {{1, 0}, {3, 2}}, 6: {{2, 0}}, 7: {{3, 0}}, 8: {{0, 3}},
+	9: {{0, 2}}, 10: {{0, 3}, {2, 1}}, 11: {{0, 1}}, 12: {{1, 3}},
+	13: {{1, 2}}, 14: {{2, 3}},
+}
+
+// AddContours adds a LineString feature for each of the levels with the
+// isolines of the grid of values at that level, such as elevations sampled
+// from a DEM or the output of a weather model. The grid covers the tile,
+// with its first row at the top and its first column at the left, and its
+// last row and column at the bottom and right, so that each value is a
+// corner of the cells between them. Values that are NaN have no data, and
+// no lines go through the cells around them. Each feature has an
+// "elevation" tag of its level. Closed isolines repeat their first point.
+// Levels without lines are skipped.
+func (l *Layer) AddContours(values [][]float64, levels []float64) (
+	[]*Feature, error,
+) {
+	if len(values) < 2 || len(values[0]) < 2 {
+		return nil, ErrInvalidGrid
+	}
+	for _, row := range values {
+		if len(row) != len(values[0]) {
+			return nil, ErrInvalidGrid
+		}
+	}
+	var features []*Feature
+	for _, level := range levels {
+		lines := contourLines(values, level)
+		if len(lines) == 0 {
+			continue
+		}
+		f := l.AddFeature(LineString)
+		for _, line := range lines {
+			f.MoveTo(line[0][0], line[0][1])
+			for _, p := range line[1:] {
+				f.LineTo(p[0], p[1])
+			}
+		}
+		f.AddTag("elevation", level)
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// contourLines returns the isolines of the grid at the level, in pixels
+func contourLines(values [][]float64, level float64) [][][2]float64 {
+	rows, cols := len(values), len(values[0])
+	next := make(map[contourEdge]contourEdge)
+	var starts []contourEdge
+	for r := 0; r < rows-1; r++ {
+		for c := 0; c < cols-1; c++ {
+			corners := [4]float64{values[r][c], values[r][c+1],
+				values[r+1][c+1], values[r+1][c]}
+			var index int
+			var nodata bool
+			for i, v := range corners {
+				if math.IsNaN(v) {
+					nodata = true
+				} else if v >= level {
+					index |= 8 >> i
+				}
+			}
+			if nodata {
+				continue
+			}
+			segments := contourSegments[index]
+			if index == 5 || index == 10 {
+				center := (corners[0] + corners[1] + corners[2] +
+					corners[3]) / 4
+				if center >= level {
+					segments = contourSegments[15-index]
+					segments = [][2]int{
+						{segments[0][1], segments[0][0]},
+						{segments[1][1], segments[1][0]},
+					}
+				}
+			}
+			sides := [4]contourEdge{{r, c, false}, {r, c + 1, true},
+				{r + 1, c, false}, {r, c, true}}
+			for _, s := range segments {
+				from, to := sides[s[0]], sides[s[1]]
+				next[from] = to
+				starts = append(starts, from)
+			}
+		}
+	}
+	// follow the lines from the edges that nothing goes into, and then
+	// the remaining closed lines
+	into := make(map[contourEdge]bool, len(next))
+	for _, to := range next {
+		into[to] = true
+	}
+	var lines [][][2]float64
+	follow := func(e contourEdge) {
+		var line [][2]float64
+		first := e
+		line = append(line, contourPoint(values, e, level))
+		for {
+			to, ok := next[e]
+			if !ok {
+				break
+			}
+			delete(next, e)
+			line = append(line, contourPoint(values, to, level))
+			if to == first {
+				break
+			}
+			e = to
+		}
+		lines = append(lines, line)
+	}
+	for _, e := range starts {
+		if _, ok := next[e]; ok && !into[e] {
+			follow(e)
+		}
+	}
+	for _, e := range starts {
+		if _, ok := next[e]; ok {
+			follow(e)
+		}
+	}
+	return lines
+}
+
+// contourPoint returns the point on the edge of the grid where the values
+// cross the level, in pixels
+func contourPoint(values [][]float64, e contourEdge,
+	level float64) [2]float64 {
+
+	cw := float64(gTileSize) / float64(len(values[0])-1)
+	ch := float64(gTileSize) / float64(len(values)-1)
+	a := values[e.row][e.col]
+	var b float64
+	if e.vertical {
+		b = values[e.row+1][e.col]
+	} else {
+		b = values[e.row][e.col+1]
+	}
+	t := (level - a) / (b - a)
+	if e.vertical {
+		return [2]float64{float64(e.col) * cw, (float64(e.row) + t) * ch}
+	}
+	return [2]float64{(float64(e.col) + t) * cw, float64(e.row) * ch}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestContours(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("contours")
+	for _, values := range [][][]float64{{{1, 2}}, {{1, 2}, {1}}} {
+		if _, err := l.AddContours(values, []float64{1}); err != ErrInvalidGrid {
+			t.Fatalf("expected %v, got %v", ErrInvalidGrid, err)
+		}
+	}
+	features, err := l.AddContours([][]float64{
+		{0, 0, 0},
+		{0, 10, 0},
+		{0, 0, 0},
+	}, []float64{5, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(features))
+	}
+	// a closed line around the peak, with the peak on its right
+	f := features[0]
+	expect := "[128 256 256 128 384 256 256 384 128 256] [{elevation 5}]"
+	if s := fmt.Sprint(f.geom.coords, " ", f.Tags()); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+
+	// an open slope, with the lines at 15 only in cells without data
+	features, err = l.AddContours([][]float64{
+		{0, 10, 20},
+		{0, 10, math.NaN()},
+		{0, 10, 20},
+	}, []float64{5, 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(features))
+	}
+	f = features[0]
+	expect = "[128 512 128 256 128 0] [{elevation 5}]"
+	if s := fmt.Sprint(f.geom.coords, " ", f.Tags()); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCSV is returned for a CSV file that is malformed or has a row
+// without a valid location.
+var ErrInvalidCSV = errors.New("invalid csv")
+
+// CSVReader is a FeatureSource that reads the rows of a CSV file with a
+// header as point features. The location is read from the lat and lon
+// columns, and the other columns become tags, with numbers as int64 or
+// float64. Empty values are left out.
+type CSVReader struct {
+	rd       *csv.Reader
+	header   []string
+	latCol   string
+	lonCol   string
+	idCol    string
+	lat, lon int
+	id       int
+	policy   ErrorPolicy
+}
+
+// NewCSVReader returns a reader of the CSV file. The lat and lon columns
+// are found by name, such as "lat", "latitude", or "y" and "lon", "lng",
+// "longitude", or "x", unless they are set with SetColumns.
+func NewCSVReader(r io.Reader) *CSVReader {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.ReuseRecord = true
+	rd.LazyQuotes = true
+	return &CSVReader{rd: rd, policy: StopOnError}
+}
+
+// SetComma sets the field delimiter, such as '\t' for TSV files. Default
+// is ','. It must be called before the first Next.
+func (r *CSVReader) SetComma(comma rune) {
+	r.rd.Comma = comma
+}
+
+// SetColumns sets the names of the lat and lon columns, which may be the
+// y and x columns of lon/lat data. It must be called before the first
+// Next.
+func (r *CSVReader) SetColumns(lat, lon string) {
+	r.latCol, r.lonCol = lat, lon
+}
+
+// SetIDColumn sets the name of the column of the feature ids. It must be
+// called before the first Next.
+func (r *CSVReader) SetIDColumn(name string) {
+	r.idCol = name
+}
+
+// SetErrorPolicy sets what the reader does with invalid rows. Default is
+// StopOnError.
+func (r *CSVReader) SetErrorPolicy(policy ErrorPolicy) {
+	r.policy = policy
+}
+
+// findColumn returns the index of the first column with one of the names,
+// ignoring case, or -1 when there is none.
+func (r *CSVReader) findColumn(names ...string) int {
+	for _, name := range names {
+		for i, col := range r.header {
+			if strings.EqualFold(strings.TrimSpace(col), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// readHeader reads the header and finds the columns
+func (r *CSVReader) readHeader() error {
+	header, err := r.rd.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%w: no header", ErrInvalidCSV)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	r.header = append([]string(nil), header...)
+	if len(r.header) > 0 {
+		// a byte order mark, as written by spreadsheets
+		r.header[0] = strings.TrimPrefix(r.header[0], "\ufeff")
+	}
+	if r.latCol != "" {
+		r.lat, r.lon = r.findColumn(r.latCol), r.findColumn(r.lonCol)
+	} else {
+		r.lat = r.findColumn("lat", "latitude", "y")
+		r.lon = r.findColumn("lon", "lng", "long", "longitude", "x")
+	}
+	if r.lat == -1 || r.lon == -1 {
+		return fmt.Errorf("%w: no lat/lon columns", ErrInvalidCSV)
+	}
+	r.id = -1
+	if r.idCol != "" {
+		if r.id = r.findColumn(r.idCol); r.id == -1 {
+			return fmt.Errorf("%w: no %q column", ErrInvalidCSV, r.idCol)
+		}
+	}
+	return nil
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (r *CSVReader) Next() (geom Geometry, tags map[string]interface{},
+	id interface{}, err error) {
+	if r.header == nil {
+		if err := r.readHeader(); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+	}
+	for {
+		record, err := r.rd.Read()
+		if err == io.EOF {
+			return Geometry{}, nil, nil, io.EOF
+		}
+		if err == nil {
+			geom, tags, id, err = r.parseRecord(record)
+			if err == nil {
+				return geom, tags, id, nil
+			}
+		} else {
+			err = fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		line, _ := r.rd.FieldPos(0)
+		if err := r.policy(line, err); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+	}
+}
+
+// parseRecord returns the point feature of a row
+func (r *CSVReader) parseRecord(record []string) (geom Geometry,
+	tags map[string]interface{}, id interface{}, err error) {
+	if r.lat >= len(record) || r.lon >= len(record) {
+		return Geometry{}, nil, nil, fmt.Errorf("%w: missing lat/lon",
+			ErrInvalidCSV)
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(record[r.lat]), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(record[r.lon]), 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 ||
+		lon < -180 || lon > 180 {
+		return Geometry{}, nil, nil, fmt.Errorf("%w: bad lat/lon %q, %q",
+			ErrInvalidCSV, record[r.lat], record[r.lon])
+	}
+	geom = Geometry{Type: Point, Paths: [][][2]float64{{{lon, lat}}}}
+	tags = make(map[string]interface{}, len(record))
+	for i, value := range record {
+		if i == r.lat || i == r.lon || i >= len(r.header) || value == "" {
+			continue
+		}
+		v := csvValue(value)
+		if i == r.id {
+			id = v
+		} else {
+			tags[r.header[i]] = v
+		}
+	}
+	return geom, tags, id, nil
+}
+
+// csvValue converts a field to an int64 or float64 when it is a number
+func csvValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil &&
+		!strings.ContainsAny(s, "nNiI") {
+		return n
+	}
+	return s
+}
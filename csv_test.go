@@ -0,0 +1,75 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	data := "\ufeffname,Latitude,Longitude,pop,area\n" +
+		"tempe,33.4,-111.9,180000,40.2\n" +
+		"null island,0,0,,\n" +
+		"nowhere,,,1,\n" +
+		"paris,48.85,2.35,2100000,NaN\n"
+	r := NewCSVReader(strings.NewReader(data))
+	geom, tags, _, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.Type != Point || fmt.Sprint(geom.Paths) != "[[[-111.9 33.4]]]" {
+		t.Fatalf("unexpected geometry %v", geom)
+	}
+	if tags["name"] != "tempe" || tags["pop"] != int64(180000) ||
+		tags["area"] != 40.2 || len(tags) != 3 {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	_, tags, _, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(tags) != "map[name:null island]" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	_, _, _, err = r.Next()
+	if !errors.Is(err, ErrInvalidCSV) || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Fatalf("expected an invalid csv error for line 4, got %v", err)
+	}
+
+	// tab separated, with named columns and ids, skipping invalid rows
+	data = "id\tname\tx\ty\n" +
+		"1\tline \"one\"\t-111.9\t33.4\n" +
+		"2\tbad\t-200\t0\n" +
+		"3\tthree\t10\t50\n"
+	r = NewCSVReader(strings.NewReader(data))
+	r.SetComma('\t')
+	r.SetIDColumn("id")
+	r.SetErrorPolicy(SkipInvalid)
+	features := readAllGeo(t, r)
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+	if s := fmt.Sprint(features[1].Geometry, features[1].Tags,
+		features[1].ID); s != "{1 [[[10 50]]] []} map[name:three] 3" {
+		t.Fatalf("unexpected feature %s", s)
+	}
+
+	r = NewCSVReader(strings.NewReader("a,b\n1,2\n"))
+	if _, _, _, err := r.Next(); !errors.Is(err, ErrInvalidCSV) {
+		t.Fatalf("expected ErrInvalidCSV, got %v", err)
+	}
+	r = NewCSVReader(strings.NewReader("a,b\n1,2\n"))
+	r.SetColumns("b", "a")
+	if _, _, _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// Dash splits the lines of a LineString feature into the dashes of the
+// pattern, which are the lengths in pixels of alternating dashes and gaps,
+// like an SVG dash array. A pattern of an odd number of lengths is
+// repeated to make it even. The offset is how far into the pattern each
+// line starts, so that the dashes of lines that continue across tiles may
+// line up. Features that are not LineStrings, and patterns that have a
+// negative length or no length at all, are left as they are.
+func (f *Feature) Dash(pattern []float64, offset float64) {
+	if f.geomType != LineString || len(pattern) == 0 {
+		return
+	}
+	if len(pattern)%2 == 1 {
+		pattern = append(append([]float64(nil), pattern...), pattern...)
+	}
+	var total float64
+	for _, n := range pattern {
+		if n < 0 || math.IsNaN(n) {
+			return
+		}
+		total += n
+	}
+	if !(total > 0) || math.IsInf(total, 0) {
+		return
+	}
+	var g geometry
+	for _, path := range f.paths() {
+		points := pathPoints(path)
+		// find where in the pattern the line starts
+		i := 0
+		left := pattern[0]
+		for pos := math.Mod(math.Mod(offset, total)+total, total); pos > 0; {
+			if pos < left {
+				left -= pos
+				break
+			}
+			pos -= left
+			i = (i + 1) % len(pattern)
+			left = pattern[i]
+		}
+		on := i%2 == 0
+		if on {
+			g.push(moveTo, points[0].x, points[0].y)
+		}
+		for j := 1; j < len(points); j++ {
+			a, b := points[j-1], points[j]
+			seg := math.Hypot(b.x-a.x, b.y-a.y)
+			var t float64
+			for seg-t > left {
+				t += left
+				x, y := a.x+(b.x-a.x)*t/seg, a.y+(b.y-a.y)*t/seg
+				if on {
+					// not again at a vertex that was just drawn to
+					if t > 0 || g.lastOp() != lineTo {
+						g.push(lineTo, x, y)
+					}
+				} else {
+					g.push(moveTo, x, y)
+				}
+				on = !on
+				i = (i + 1) % len(pattern)
+				left = pattern[i]
+			}
+			left -= seg - t
+			if on {
+				g.push(lineTo, b.x, b.y)
+			}
+		}
+	}
+	f.geom = dropEmptyDashes(g)
+}
+
+// dropEmptyDashes removes the MoveTos that are not followed by a LineTo,
+// which are dashes that ended where they started.
+func dropEmptyDashes(g geometry) geometry {
+	var out geometry
+	cmds := g.commands()
+	for i, cmd := range cmds {
+		if cmd.which == moveTo && (i+1 == len(cmds) ||
+			cmds[i+1].which != lineTo) {
+			continue
+		}
+		out.push(cmd.which, cmd.x, cmd.y)
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDash(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("lines")
+	line := func() *Feature {
+		f := l.AddFeature(LineString)
+		f.MoveTo(0, 0)
+		f.LineTo(10, 0)
+		f.LineTo(10, 10)
+		return f
+	}
+	f := line()
+	f.Dash([]float64{4, 2}, 0)
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(f.geom.commands()); s != "[{1 0 0} {2 4 0} {1 6 0} "+
+		"{2 10 0} {1 10 2} {2 10 6} {1 10 8} {2 10 10}]" {
+		t.Fatalf("unexpected commands %s", s)
+	}
+
+	// an odd pattern repeats, and the offset shifts the dashes
+	f = line()
+	f.Dash([]float64{3}, 4)
+	if s := fmt.Sprint(f.geom.commands()); s != "[{1 2 0} {2 5 0} {1 8 0} "+
+		"{2 10 0} {2 10 1} {1 10 4} {2 10 7}]" {
+		t.Fatalf("unexpected commands %s", s)
+	}
+
+	for _, pattern := range [][]float64{nil, {0, 0}, {5, -1}} {
+		f = line()
+		f.Dash(pattern, 0)
+		if len(f.geom.ops) != 3 {
+			t.Fatalf("expected the line to be left as it is for %v", pattern)
+		}
+	}
+	p := l.AddFeature(Polygon)
+	p.Rect(0, 0, 10, 10)
+	p.Dash([]float64{1, 1}, 0)
+	if len(p.geom.ops) != 5 {
+		t.Fatal("expected the polygon to be left as it is")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrLimitExceeded is returned by DecodeLimited for a tile that is larger
+// than one of its limits
+var ErrLimitExceeded = errors.New("decode limit exceeded")
+
+// DecodeLimits are the limits of DecodeLimited on the size of a tile,
+// which keep untrusted tiles, such as those uploaded to a server, from
+// using too much memory or time. A limit of zero is no limit.
+type DecodeLimits struct {
+	// MaxLayers is the most layers of the tile
+	MaxLayers int
+	// MaxFeatures is the most features of a layer
+	MaxFeatures int
+	// MaxCommands is the most geometry commands of a feature, where each
+	// point of a MoveTo or LineTo is one command
+	MaxCommands int
+	// MaxKeys and MaxValues are the most entries of the key and value
+	// tables of a layer
+	MaxKeys, MaxValues int
+}
+
+// Decode decodes an encoded tile of version 1 or 2 of the spec, such as one
+// from another tool, into a tile that can be changed and encoded again.
+// Coordinates are scaled from the extent of each layer to the 512x512
+// canvas, and back when the tile is encoded. ErrInvalidTile is returned for
+// a malformed tile, and ErrUnsupportedVersion for a layer of another
+// version. Fields that the spec does not define, such as extensions, are
+// kept and encoded again as they are. Use DecodeLimited for untrusted
+// tiles.
+func Decode(tile []byte) (*Tile, error) {
+	return DecodeLimited(tile, DecodeLimits{})
+}
+
+// DecodeLimited is Decode, but returns ErrLimitExceeded for a tile that is
+// larger than one of the limits, before decoding the rest of it.
+func DecodeLimited(tile []byte, limits DecodeLimits) (*Tile, error) {
+	t := new(Tile)
+	pr := pbfReader{data: tile}
+	for {
+		start := pr.data
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		if field != 3 || wire != pbfBytes {
+			pr.skip(wire)
+			t.other = append(t.other, start[:len(start)-len(pr.data)]...)
+			continue
+		}
+		msg := pr.bytes()
+		if pr.err != nil {
+			break
+		}
+		if limits.MaxLayers > 0 && len(t.layers) == limits.MaxLayers {
+			return nil, fmt.Errorf("%w: more than %d layers",
+				ErrLimitExceeded, limits.MaxLayers)
+		}
+		if err := t.decodeLayer(msg, limits); err != nil {
+			return nil, err
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	return t, nil
+}
+
+// decodeLayer decodes a layer message and adds it to the tile
+func (t *Tile) decodeLayer(msg []byte, limits DecodeLimits) error {
+	name, err := layerName(msg)
+	if err != nil {
+		return err
+	}
+	layer, err := parseLayerLimited(msg, limits)
+	if err != nil {
+		return fmt.Errorf("layer %q: %w", name, err)
+	}
+	if layer.version != 1 && layer.version != 2 {
+		return fmt.Errorf("layer %q: %w %d", name, ErrUnsupportedVersion,
+			layer.version)
+	}
+	if layer.extent == 0 || layer.extent > math.MaxUint32 {
+		return fmt.Errorf("layer %q: %w: extent %d", name, ErrInvalidTile,
+			layer.extent)
+	}
+	values := make([]interface{}, len(layer.values))
+	for i, msg := range layer.values {
+		if values[i], err = decodeValue(msg); err != nil {
+			return fmt.Errorf("layer %q: value %d: %w", name, i, err)
+		}
+	}
+	l := t.AddLayer(name)
+	l.SetVersion(uint32(layer.version))
+	l.SetExtent(uint32(layer.extent))
+	l.other = layer.other
+	for i, msg := range layer.features {
+		f, err := parseFeature(msg)
+		if err == nil {
+			err = l.decodeFeature(f, layer.keys, values, limits)
+		}
+		if err != nil {
+			return fmt.Errorf("layer %q: feature %d: %w", name, i, err)
+		}
+	}
+	return nil
+}
+
+// parseLayerLimited is parseLayer, but checks the limits on the features
+// and tables of the layer as they are read
+func parseLayerLimited(msg []byte, limits DecodeLimits) (*layerMessage,
+	error,
+) {
+	var features, keys, values int
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		pr.skip(wire)
+		if wire != pbfBytes {
+			continue
+		}
+		switch field {
+		case 2:
+			features++
+		case 3:
+			keys++
+		case 4:
+			values++
+		}
+	}
+	switch {
+	case limits.MaxFeatures > 0 && features > limits.MaxFeatures:
+		return nil, fmt.Errorf("%w: %d features", ErrLimitExceeded, features)
+	case limits.MaxKeys > 0 && keys > limits.MaxKeys:
+		return nil, fmt.Errorf("%w: %d keys", ErrLimitExceeded, keys)
+	case limits.MaxValues > 0 && values > limits.MaxValues:
+		return nil, fmt.Errorf("%w: %d values", ErrLimitExceeded, values)
+	}
+	return parseLayer(msg)
+}
+
+// decodeFeature adds the decoded feature message to the layer
+func (l *Layer) decodeFeature(msg *featureMessage, keys []string,
+	values []interface{}, limits DecodeLimits,
+) error {
+	if msg.geomType > Polygon {
+		return fmt.Errorf("%w: geometry type %d", ErrInvalidTile,
+			msg.geomType)
+	}
+	f := &Feature{geomType: msg.geomType, layer: l, other: msg.other}
+	if msg.hasID {
+		f.SetID(msg.id)
+	}
+	for i := 0; i < len(msg.tags); i += 2 {
+		k, v := msg.tags[i], msg.tags[i+1]
+		if k >= uint64(len(keys)) || v >= uint64(len(values)) {
+			return fmt.Errorf("%w: tag index out of range", ErrInvalidTile)
+		}
+		f.tags = append(f.tags, Tag{keys[k], values[v]})
+	}
+	if err := f.decodeGeometry(msg.geometry, float64(l.Extent()),
+		limits.MaxCommands); err != nil {
+		return err
+	}
+	l.features = append(l.features, f)
+	return nil
+}
+
+// decodeGeometry draws the encoded geometry commands, scaled from the
+// extent to the canvas. Repeated ClosePath commands are drawn once.
+func (f *Feature) decodeGeometry(cmds []uint64, extent float64,
+	maxCommands int,
+) error {
+	var x, y int64
+	var total int
+	for i := 0; i < len(cmds); {
+		which, count := int(cmds[i]&7), int(cmds[i]>>3)
+		i++
+		if which == closePath {
+			count = 1
+		} else if which != moveTo && which != lineTo {
+			return fmt.Errorf("%w: unknown command %d", ErrInvalidTile,
+				which)
+		} else if count > (len(cmds)-i)/2 {
+			return fmt.Errorf("%w: command is cut short", ErrInvalidTile)
+		}
+		if total += count; maxCommands > 0 && total > maxCommands {
+			return fmt.Errorf("%w: more than %d commands",
+				ErrLimitExceeded, maxCommands)
+		}
+		if which == closePath {
+			f.geom.push(closePath, 0, 0)
+			continue
+		}
+		for j := 0; j < count; j++ {
+			x += unzigzag(cmds[i])
+			y += unzigzag(cmds[i+1])
+			i += 2
+			f.geom.push(which, decodeCoord(x, extent),
+				decodeCoord(y, extent))
+		}
+	}
+	return nil
+}
+
+// decodeCoord returns the canvas coordinate of a coordinate of the
+// extent, nudged so that encoding it gives back the same coordinate.
+func decodeCoord(v int64, extent float64) float64 {
+	c := float64(v) * 512 / extent
+	for i := 0; i < 8; i++ {
+		switch e := int64(c / 512 * extent); {
+		case e < v:
+			c = math.Nextafter(c, math.Inf(1))
+		case e > v:
+			c = math.Nextafter(c, math.Inf(-1))
+		default:
+			return c
+		}
+	}
+	return c
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func encodeDecodeTestTile(t testing.TB) []byte {
+	t.Helper()
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	l.SetExtent(1000)
+	p := l.AddFeature(Point)
+	p.SetID(7)
+	p.MoveTo(10.3, 20.7)
+	p.MoveTo(-3, 515)
+	p.AddTag("name", "points")
+	p.AddTag("rank", int64(-2))
+	ls := l.AddFeature(LineString)
+	ls.MoveTo(10, 10)
+	ls.LineTo(200, 20)
+	ls.LineTo(300, 111.1)
+	ls.AddTag("name", "line")
+	ls.AddTag("width", 1.5)
+	pg := l.AddFeature(Polygon)
+	pg.MoveTo(10, 10)
+	pg.LineTo(100, 10)
+	pg.LineTo(100, 100)
+	pg.ClosePath()
+	pg.AddTag("open", true)
+	pg.AddTag("size", float32(2.5))
+	pg.AddTag("count", uint64(3))
+	legacy := tile.AddLayer("legacy")
+	legacy.SetVersion(1)
+	legacy.AddFeature(Point).MoveTo(1, 1)
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb
+}
+
+func TestDecode(t *testing.T) {
+	pb := encodeDecodeTestTile(t)
+	tile, err := Decode(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tile.Layers()) != 2 {
+		t.Fatalf("expected 2 layers, got %d", len(tile.Layers()))
+	}
+	l := tile.GetLayer("shapes")
+	if l.Extent() != 1000 || l.Version() != 2 {
+		t.Fatalf("expected extent 1000 and version 2, got %d and %d",
+			l.Extent(), l.Version())
+	}
+	if tile.GetLayer("legacy").Version() != 1 {
+		t.Fatal("expected version 1")
+	}
+	features := l.Features()
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	if id, ok := features[0].ID(); !ok || id != 7 {
+		t.Fatalf("expected id 7, got %d", id)
+	}
+	if v, _ := features[0].Tag("rank"); v != int64(-2) {
+		t.Fatalf("expected rank -2, got %v", v)
+	}
+	if v, _ := features[2].Tag("size"); v != float32(2.5) {
+		t.Fatalf("expected size 2.5, got %v", v)
+	}
+	if features[2].GeomType() != Polygon {
+		t.Fatalf("expected a polygon, got %d", features[2].GeomType())
+	}
+	// the tile encodes back to the same bytes
+	out, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, pb) {
+		t.Fatal("expected the same tile after decoding and encoding")
+	}
+	// a decoded tile can be changed
+	features[1].AddTag("lanes", int64(2))
+	if out, err = tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	tile, err = Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tile.GetLayer("shapes").Features()[1]
+	if v, _ := f.Tag("lanes"); v != int64(2) {
+		t.Fatalf("expected lanes 2, got %v", v)
+	}
+}
+
+func TestDecodeUnknownFields(t *testing.T) {
+	// a feature with field 20, a layer with field 16, and a tile with
+	// field 16
+	pb := encodeTestLayer(10, 1, 'a',
+		18, 10, 24, 1, 34, 3, 9, 2, 2, 160, 1, 5,
+		128, 1, 1, 120, 2)
+	pb = append(pb, 128, 1, 9)
+	tile, err := Decode(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, pb) {
+		t.Fatalf("expected %v, got %v", pb, out)
+	}
+	tile.GetLayer("a").Features()[0].AddTag("name", "b")
+	if out, err = tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range [][]byte{{160, 1, 5}, {128, 1, 1, 120, 2},
+		{128, 1, 9}} {
+		if !bytes.Contains(out, field) {
+			t.Fatalf("expected field %v to be kept", field)
+		}
+	}
+	tile.Reset(TileID{})
+	if out, _ = tile.Encode(); len(out) != 0 {
+		t.Fatalf("expected an empty tile after a reset, got %v", out)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	pb := encodeDecodeTestTile(t)
+	tests := []struct {
+		name string
+		tile []byte
+		err  error
+	}{
+		{"cut short", pb[:len(pb)-1], ErrInvalidTile},
+		{"version", encodeTestLayer(10, 1, 'a', 120, 3), ErrUnsupportedVersion},
+		{"extent", encodeTestLayer(10, 1, 'a', 40, 0, 120, 2),
+			ErrInvalidTile},
+		{"tags", encodeTestLayer(10, 1, 'a', 18, 4, 18, 2, 0, 0, 120, 2),
+			ErrInvalidTile},
+		{"type", encodeTestLayer(10, 1, 'a', 18, 2, 24, 4, 120, 2),
+			ErrInvalidTile},
+		{"command", encodeTestLayer(10, 1, 'a', 18, 5, 34, 3, 11, 2, 2,
+			120, 2), ErrInvalidTile},
+		{"params", encodeTestLayer(10, 1, 'a', 18, 5, 34, 3, 17, 2, 2,
+			120, 2), ErrInvalidTile},
+	}
+	for _, tt := range tests {
+		if _, err := Decode(tt.tile); !errors.Is(err, tt.err) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// encodeTestLayer encodes a tile of one layer message
+func encodeTestLayer(msg ...byte) []byte {
+	return append([]byte{26, byte(len(msg))}, msg...)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	pb := encodeDecodeTestTile(t)
+	tests := []struct {
+		limits DecodeLimits
+		err    error
+	}{
+		{DecodeLimits{}, nil},
+		{DecodeLimits{MaxLayers: 2, MaxFeatures: 3, MaxCommands: 4,
+			MaxKeys: 6, MaxValues: 7}, nil},
+		{DecodeLimits{MaxLayers: 1}, ErrLimitExceeded},
+		{DecodeLimits{MaxFeatures: 2}, ErrLimitExceeded},
+		{DecodeLimits{MaxCommands: 3}, ErrLimitExceeded},
+		{DecodeLimits{MaxKeys: 5}, ErrLimitExceeded},
+		{DecodeLimits{MaxValues: 6}, ErrLimitExceeded},
+	}
+	for i, tt := range tests {
+		_, err := DecodeLimited(pb, tt.limits)
+		if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+			t.Fatalf("%d: expected %v, got %v", i, tt.err, err)
+		}
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add(encodeDecodeTestTile(f))
+	f.Add(encodeTestLayer(10, 1, 'a', 18, 5, 34, 3, 9, 2, 2, 120, 2))
+	f.Fuzz(func(t *testing.T, pb []byte) {
+		tile, err := DecodeLimited(pb, DecodeLimits{MaxLayers: 100,
+			MaxFeatures: 1000, MaxCommands: 10000, MaxKeys: 1000,
+			MaxValues: 1000})
+		if err != nil {
+			return
+		}
+		out, err := tile.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(out); err != nil {
+			t.Fatalf("encoded tile does not decode: %v", err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// DensifyGreatCircle returns the geometry with points added to the
+// segments of its lines and rings that are longer than the max segment
+// length in meters, so that they follow the great circle between their
+// ends, as long flight paths and cables do, rather than a straight line on
+// the map. Segments that cross the antimeridian go the short way around,
+// with longitudes past 180 that are continuous with the previous point.
+// Points are left as they are.
+func (g Geometry) DensifyGreatCircle(maxSegment float64) Geometry {
+	if g.Type == Point || !(maxSegment > 0) {
+		return g
+	}
+	out := Geometry{Type: g.Type, Paths: make([][][2]float64, len(g.Paths)),
+		Rings: g.Rings}
+	for i, path := range g.Paths {
+		if g.Type == Polygon && len(path) > 1 && path[0] != path[len(path)-1] {
+			path = append(path[:len(path):len(path)], path[0])
+		}
+		var dense [][2]float64
+		for j, p := range path {
+			if j == 0 {
+				dense = append(dense, p)
+				continue
+			}
+			dense = greatCircle(dense, dense[len(dense)-1], p, maxSegment)
+		}
+		out.Paths[i] = dense
+	}
+	return out
+}
+
+// greatCircle appends the points of the great circle from a to b, not
+// including a, with segments no longer than the max segment length.
+func greatCircle(dst [][2]float64, a, b [2]float64, maxSegment float64,
+) [][2]float64 {
+	// unwrap b to be continuous with a
+	b[0] -= 360 * math.Round((b[0]-a[0])/360)
+	lon1, lat1 := a[0]*math.Pi/180, a[1]*math.Pi/180
+	lon2, lat2 := b[0]*math.Pi/180, b[1]*math.Pi/180
+	// the angle between the points, by the haversine formula
+	h := math.Pow(math.Sin((lat2-lat1)/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((lon2-lon1)/2), 2)
+	d := 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+	n := math.Ceil(d * earthRadius / maxSegment)
+	if n <= 1 || math.Sin(d) == 0 {
+		// short, or antipodal with no single great circle
+		return append(dst, b)
+	}
+	x1, y1, z1 := math.Cos(lat1)*math.Cos(lon1), math.Cos(lat1)*math.Sin(lon1),
+		math.Sin(lat1)
+	x2, y2, z2 := math.Cos(lat2)*math.Cos(lon2), math.Cos(lat2)*math.Sin(lon2),
+		math.Sin(lat2)
+	prev := a[0]
+	for i := 1.0; i < n; i++ {
+		f := i / n
+		ka := math.Sin((1-f)*d) / math.Sin(d)
+		kb := math.Sin(f*d) / math.Sin(d)
+		x, y, z := ka*x1+kb*x2, ka*y1+kb*y2, ka*z1+kb*z2
+		lat := math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+		lon := math.Atan2(y, x) * 180 / math.Pi
+		lon -= 360 * math.Round((lon-prev)/360)
+		dst = append(dst, [2]float64{lon, lat})
+		prev = lon
+	}
+	return append(dst, b)
+}
+
+// SetDensifyGreatCircle sets the max segment length in meters of the
+// lines and rings of the lat/lon geometries that are added to the layer,
+// which are made to follow great circles, see Geometry.DensifyGreatCircle.
+// Default is zero, which leaves them as they are.
+func (l *Layer) SetDensifyGreatCircle(maxSegment float64) {
+	l.densify = maxSegment
+}
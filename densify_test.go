@@ -0,0 +1,64 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDensifyGreatCircle(t *testing.T) {
+	nyc, paris := [2]float64{-74, 40.7}, [2]float64{2.35, 48.85}
+	g := Geometry{Type: LineString, Paths: [][][2]float64{{nyc, paris}}}
+	dense := g.DensifyGreatCircle(100000)
+	path := dense.Paths[0]
+	n := int(math.Ceil(haversine(nyc, paris) / 100000))
+	if len(path) != n+1 || path[0] != nyc || path[n] != paris {
+		t.Fatalf("expected %d points from nyc to paris, got %d", n+1,
+			len(path))
+	}
+	var maxLat float64
+	for i := 1; i < len(path); i++ {
+		if d := haversine(path[i-1], path[i]); d > 100000 {
+			t.Fatalf("segment %d is %v meters", i, d)
+		}
+		maxLat = math.Max(maxLat, path[i][1])
+	}
+	// the great circle bends north of both ends
+	if maxLat < 50 {
+		t.Fatalf("expected the path to go north, got a max lat of %v", maxLat)
+	}
+	if len(g.Paths[0]) != 2 {
+		t.Fatal("expected the geometry to be left as it is")
+	}
+
+	// across the antimeridian, the short way around
+	g = Geometry{Type: LineString, Paths: [][][2]float64{{{170, 0}, {-170, 0}}}}
+	path = g.DensifyGreatCircle(500000).Paths[0]
+	for i := 1; i < len(path); i++ {
+		if path[i][0] <= path[i-1][0] || math.Abs(path[i][1]) > 1e-9 {
+			t.Fatalf("unexpected point %v after %v", path[i], path[i-1])
+		}
+	}
+	if last := path[len(path)-1]; last != [2]float64{190, 0} {
+		t.Fatalf("unexpected end %v", last)
+	}
+
+	// the closing segment of a ring
+	g = Geometry{Type: Polygon, Paths: [][][2]float64{{{0, 0}, {10, 0}, {10, 10}}}}
+	ring := g.DensifyGreatCircle(200000).Paths[0]
+	if ring[len(ring)-1] != ring[0] || len(ring) < 20 {
+		t.Fatalf("expected a closed, dense ring, got %v", ring)
+	}
+
+	var tile Tile
+	l := tile.AddLayer("routes")
+	g = Geometry{Type: LineString, Paths: [][][2]float64{{nyc, paris}}}
+	plain := len(l.AddGeometry(g).geom.ops)
+	l.SetDensifyGreatCircle(100000)
+	if dense := len(l.AddGeometry(g).geom.ops); dense <= plain {
+		t.Fatalf("expected more than %d points, got %d", plain, dense)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TileDiff is the difference between two encoded tiles, see DiffTiles
+type TileDiff struct {
+	// AddedLayers are the names of the layers that are only in the second
+	// tile, and RemovedLayers are those that are only in the first
+	AddedLayers, RemovedLayers []string
+	// Layers are the differences of the layers that are in both tiles, for
+	// those that differ
+	Layers []LayerDiff
+}
+
+// LayerDiff is the difference between two layers of the same name
+type LayerDiff struct {
+	Name string
+	// Added are the features that are only in the second layer, and
+	// Removed are those that are only in the first
+	Added, Removed []FeatureRef
+	// Changed are the differences of the features that are in both layers,
+	// for those that differ
+	Changed []FeatureDiff
+}
+
+// FeatureRef identifies a feature of a layer by its id, or, for a feature
+// without one, by its index among the features of the layer without ids.
+type FeatureRef struct {
+	ID    uint64
+	HasID bool
+	Index int
+	// Layer is the name of the layer, which is only set for the features
+	// of more than one layer, such as those of QueryPoint
+	Layer string
+}
+
+// String returns the id of the feature, or its index with a "#" prefix
+func (ref FeatureRef) String() string {
+	if ref.HasID {
+		return fmt.Sprint(ref.ID)
+	}
+	return fmt.Sprintf("#%d", ref.Index)
+}
+
+// FeatureDiff is the difference between two features with the same id
+type FeatureDiff struct {
+	FeatureRef
+	// AddedTags are the keys of the tags that are only in the second
+	// feature, RemovedTags are those only in the first, and ChangedTags
+	// are those in both with different values
+	AddedTags, RemovedTags, ChangedTags []string
+	// FromType and ToType are the geometry types of the features
+	FromType, ToType GeometryType
+	// FromVertices and ToVertices are the numbers of vertices of the
+	// geometries of the features
+	FromVertices, ToVertices int
+	// GeometryChanged is true when the geometries are encoded differently
+	GeometryChanged bool
+}
+
+// Empty returns true when the tiles are the same
+func (d *TileDiff) Empty() bool {
+	return len(d.AddedLayers) == 0 && len(d.RemovedLayers) == 0 &&
+		len(d.Layers) == 0
+}
+
+// String returns the difference as lines of text, with a "+" for what was
+// added, a "-" for what was removed, and a "~" for what changed.
+func (d *TileDiff) String() string {
+	var sb strings.Builder
+	for _, name := range d.AddedLayers {
+		fmt.Fprintf(&sb, "+ layer %q\n", name)
+	}
+	for _, name := range d.RemovedLayers {
+		fmt.Fprintf(&sb, "- layer %q\n", name)
+	}
+	for _, layer := range d.Layers {
+		fmt.Fprintf(&sb, "~ layer %q\n", layer.Name)
+		for _, ref := range layer.Added {
+			fmt.Fprintf(&sb, "  + feature %s\n", ref)
+		}
+		for _, ref := range layer.Removed {
+			fmt.Fprintf(&sb, "  - feature %s\n", ref)
+		}
+		for _, f := range layer.Changed {
+			fmt.Fprintf(&sb, "  ~ feature %s:", f.FeatureRef)
+			for _, key := range f.AddedTags {
+				fmt.Fprintf(&sb, " +%s", key)
+			}
+			for _, key := range f.RemovedTags {
+				fmt.Fprintf(&sb, " -%s", key)
+			}
+			for _, key := range f.ChangedTags {
+				fmt.Fprintf(&sb, " ~%s", key)
+			}
+			if f.FromType != f.ToType {
+				fmt.Fprintf(&sb, " type %s -> %s", geomTypeName(f.FromType),
+					geomTypeName(f.ToType))
+			}
+			if f.FromVertices != f.ToVertices {
+				fmt.Fprintf(&sb, " vertices %d -> %d", f.FromVertices,
+					f.ToVertices)
+			} else if f.GeometryChanged {
+				sb.WriteString(" geometry")
+			}
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// geomTypeName returns the name of the geometry type
+func geomTypeName(geomType GeometryType) string {
+	if int(geomType) < len(geomTypeNames) {
+		return geomTypeNames[geomType]
+	}
+	return fmt.Sprint(int(geomType))
+}
+
+// DiffTiles returns the difference between two encoded tiles, which is of
+// their layers by name, of the features of their layers by id, and of the
+// tags, geometry types, and vertices of their features, such as to check
+// the output of a new pipeline against that of a reference. Features
+// without ids are compared in the order of the layer. Only layers of
+// version 1 or 2 can be compared.
+func DiffTiles(a, b []byte) (*TileDiff, error) {
+	layersA, _, err := splitTile(a)
+	if err != nil {
+		return nil, err
+	}
+	layersB, _, err := splitTile(b)
+	if err != nil {
+		return nil, err
+	}
+	msgsB := make(map[string][]byte, len(layersB))
+	for _, layer := range layersB {
+		if _, ok := msgsB[layer.name]; !ok {
+			msgsB[layer.name] = layer.msg
+		}
+	}
+	d := &TileDiff{}
+	seen := make(map[string]bool, len(layersA))
+	for _, layer := range layersA {
+		if seen[layer.name] {
+			continue
+		}
+		seen[layer.name] = true
+		msgB, ok := msgsB[layer.name]
+		if !ok {
+			d.RemovedLayers = append(d.RemovedLayers, layer.name)
+			continue
+		}
+		ld, err := diffLayers(layer.msg, msgB)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", layer.name, err)
+		}
+		if len(ld.Added) > 0 || len(ld.Removed) > 0 || len(ld.Changed) > 0 {
+			d.Layers = append(d.Layers, ld)
+		}
+	}
+	for _, layer := range layersB {
+		if !seen[layer.name] {
+			seen[layer.name] = true
+			d.AddedLayers = append(d.AddedLayers, layer.name)
+		}
+	}
+	return d, nil
+}
+
+// diffFeature is a decoded feature that is being compared
+type diffFeature struct {
+	ref      FeatureRef
+	msg      *featureMessage
+	tags     map[string]interface{}
+	vertices int
+}
+
+// diffFeatures decodes the features of a layer message for comparing
+func diffFeatures(msg []byte) (*layerMessage, []diffFeature, error) {
+	layer, err := parseLayer(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if layer.version > 2 {
+		return nil, nil, fmt.Errorf("%w %d", ErrUnsupportedVersion,
+			layer.version)
+	}
+	features := make([]diffFeature, len(layer.features))
+	var index int
+	for i, fmsg := range layer.features {
+		f, err := parseFeature(fmsg)
+		if err != nil {
+			return nil, nil, err
+		}
+		df := diffFeature{msg: f, tags: make(map[string]interface{})}
+		if f.hasID {
+			df.ref = FeatureRef{ID: f.id, HasID: true}
+		} else {
+			df.ref = FeatureRef{Index: index}
+			index++
+		}
+		for j := 0; j < len(f.tags); j += 2 {
+			if f.tags[j] >= uint64(len(layer.keys)) ||
+				f.tags[j+1] >= uint64(len(layer.values)) {
+				return nil, nil, fmt.Errorf("%w: tag index out of range",
+					ErrInvalidTile)
+			}
+			v, err := decodeValue(layer.values[f.tags[j+1]])
+			if err != nil {
+				return nil, nil, err
+			}
+			df.tags[layer.keys[f.tags[j]]] = v
+		}
+		if df.vertices, err = countVertices(f.geometry); err != nil {
+			return nil, nil, err
+		}
+		features[i] = df
+	}
+	return layer, features, nil
+}
+
+// countVertices returns the number of MoveTo and LineTo vertices of the
+// commands of an encoded geometry
+func countVertices(cmds []uint64) (int, error) {
+	var n int
+	for i := 0; i < len(cmds); {
+		which, count := int(cmds[i]&7), int(cmds[i]>>3)
+		i++
+		switch which {
+		case moveTo, lineTo:
+			if count > (len(cmds)-i)/2 {
+				return 0, fmt.Errorf("%w: geometry is cut short",
+					ErrInvalidTile)
+			}
+			n += count
+			i += count * 2
+		case closePath:
+		default:
+			return 0, fmt.Errorf("%w: unknown command %d", ErrInvalidTile,
+				which)
+		}
+	}
+	return n, nil
+}
+
+// diffLayers returns the difference between two layer messages
+func diffLayers(a, b []byte) (LayerDiff, error) {
+	layer, featuresA, err := diffFeatures(a)
+	if err != nil {
+		return LayerDiff{}, err
+	}
+	_, featuresB, err := diffFeatures(b)
+	if err != nil {
+		return LayerDiff{}, err
+	}
+	ld := LayerDiff{Name: layer.name}
+	byRef := make(map[FeatureRef]*diffFeature, len(featuresB))
+	for i := range featuresB {
+		if _, ok := byRef[featuresB[i].ref]; !ok {
+			byRef[featuresB[i].ref] = &featuresB[i]
+		}
+	}
+	seen := make(map[FeatureRef]bool, len(featuresA))
+	for _, fa := range featuresA {
+		if seen[fa.ref] {
+			continue
+		}
+		seen[fa.ref] = true
+		fb, ok := byRef[fa.ref]
+		if !ok {
+			ld.Removed = append(ld.Removed, fa.ref)
+			continue
+		}
+		fd := FeatureDiff{FeatureRef: fa.ref,
+			FromType: fa.msg.geomType, ToType: fb.msg.geomType,
+			FromVertices: fa.vertices, ToVertices: fb.vertices,
+			GeometryChanged: !equalCommands(fa.msg.geometry,
+				fb.msg.geometry),
+		}
+		for key, va := range fa.tags {
+			if vb, ok := fb.tags[key]; !ok {
+				fd.RemovedTags = append(fd.RemovedTags, key)
+			} else if va != vb {
+				fd.ChangedTags = append(fd.ChangedTags, key)
+			}
+		}
+		for key := range fb.tags {
+			if _, ok := fa.tags[key]; !ok {
+				fd.AddedTags = append(fd.AddedTags, key)
+			}
+		}
+		sort.Strings(fd.AddedTags)
+		sort.Strings(fd.RemovedTags)
+		sort.Strings(fd.ChangedTags)
+		if len(fd.AddedTags) > 0 || len(fd.RemovedTags) > 0 ||
+			len(fd.ChangedTags) > 0 || fd.FromType != fd.ToType ||
+			fd.GeometryChanged {
+			ld.Changed = append(ld.Changed, fd)
+		}
+	}
+	for _, fb := range featuresB {
+		if !seen[fb.ref] {
+			seen[fb.ref] = true
+			ld.Added = append(ld.Added, fb.ref)
+		}
+	}
+	return ld, nil
+}
+
+// equalCommands returns true when the encoded geometries are the same
+func equalCommands(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiffTiles(t *testing.T) {
+	encode := func(second bool) []byte {
+		var tile Tile
+		roads := tile.AddLayer("roads")
+		for i := 1; i <= 3; i++ {
+			if second && i == 1 {
+				continue
+			}
+			f := roads.AddFeature(LineString)
+			f.SetID(uint64(i))
+			f.MoveTo(0, 0)
+			f.LineTo(10, 10)
+			f.AddTag("class", "primary")
+			if i == 2 {
+				f.AddTag("lanes", 2)
+				if second {
+					f.LineTo(20, 10)
+					f.AddTag("name", "Main St")
+				}
+			}
+			if i == 3 && !second {
+				f.AddTag("name", "Old St")
+			}
+		}
+		if second {
+			roads.AddFeature(Point).MoveTo(5, 5)
+			tile.AddLayer("places").AddFeature(Point).MoveTo(1, 1)
+		} else {
+			tile.AddLayer("water").AddFeature(Point).MoveTo(1, 1)
+		}
+		tile.AddLayer("same").AddFeature(Point).MoveTo(1, 1)
+		pb, err := tile.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pb
+	}
+	a, b := encode(false), encode(true)
+	d, err := DiffTiles(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `+ layer "places"
+- layer "water"
+~ layer "roads"
+  + feature #0
+  - feature 1
+  ~ feature 2: +name vertices 2 -> 3
+  ~ feature 3: -name
+`
+	if s := d.String(); s != expect {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expect, s)
+	}
+	if d.Empty() {
+		t.Fatal("expected a difference")
+	}
+	if d, err = DiffTiles(a, a); err != nil || !d.Empty() {
+		t.Fatalf("expected no difference, got %v", d)
+	}
+	if _, err = DiffTiles(a, b[:len(b)-1]); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+	// a MoveTo without its x and y
+	if _, err = countVertices([]uint64{9, 0}); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "fmt"
+
+// dissolveOptions are the settings of a dissolved layer.
+type dissolveOptions struct {
+	key        string
+	aggregates []aggregate
+}
+
+// DissolveBy sets the layer to union its polygons that have the same value
+// for the tag key when it is rendered. Each set of polygons becomes one
+// polygon, where the edges that are shared by neighboring polygons are
+// removed, and whose only tags are the key and those of
+// SetDissolveAggregate. Polygons must share the exact vertices of an edge
+// to be joined along it, and polygons that overlap rather than touch are
+// kept as separate parts. Features without the key are left as they are.
+// An empty key turns dissolving off.
+func (l *Layer) DissolveBy(key string) {
+	if key == "" {
+		l.dissolve = nil
+		return
+	}
+	if l.dissolve == nil {
+		l.dissolve = &dissolveOptions{}
+	}
+	l.dissolve.key = key
+}
+
+// SetDissolveAggregate adds a tag to each dissolved polygon that is
+// computed by the aggregator from the values of the key in the polygons
+// that it was made from.
+func (l *Layer) SetDissolveAggregate(tag, key string, agg Aggregator) {
+	if l.dissolve != nil {
+		l.dissolve.aggregates = append(l.dissolve.aggregates,
+			aggregate{tag: tag, key: key, agg: agg})
+	}
+}
+
+// dissolvePolygons replaces the polygons of each value of the dissolve key
+// with their union.
+func (l *Layer) dissolvePolygons(features []*Feature) []*Feature {
+	groups := make(map[string][]*Feature)
+	var keys []string
+	group := make(map[*Feature]string)
+	for _, f := range features {
+		if f.geomType != Polygon {
+			continue
+		}
+		v, ok := f.Tag(l.dissolve.key)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%T:%v", v, v)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], f)
+		group[f] = key
+	}
+	dissolved := make(map[string]*Feature)
+	for _, key := range keys {
+		members := groups[key]
+		v, _ := members[0].Tag(l.dissolve.key)
+		poly := &Feature{geomType: Polygon, layer: l}
+		poly.tags = append(poly.tags, Tag{l.dissolve.key, v})
+		poly.tags = aggregateTags(poly.tags, l.dissolve.aggregates, members)
+		poly.geom = unionRings(members)
+		dissolved[key] = poly
+	}
+	// each polygon takes the place of the first of its features
+	kept := make([]*Feature, 0, len(features))
+	for _, f := range features {
+		key, ok := group[f]
+		if !ok {
+			kept = append(kept, f)
+			continue
+		}
+		if poly := dissolved[key]; poly != nil {
+			kept = append(kept, poly)
+			dissolved[key] = nil
+		}
+	}
+	return kept
+}
+
+// ringEdge is a directed edge of a polygon ring
+type ringEdge struct {
+	ax, ay, bx, by float64
+}
+
+// unionRings returns the rings of the polygons with their shared edges
+// removed, as exterior rings that are each followed by their holes.
+func unionRings(polys []*Feature) geometry {
+	var edges []ringEdge
+	alive := make(map[ringEdge][]int)
+	for _, f := range polys {
+		var exterior float64
+		for i, path := range f.paths() {
+			ring := pathPoints(path)
+			if n := len(ring); n > 1 &&
+				ring[0].x == ring[n-1].x && ring[0].y == ring[n-1].y {
+				ring = ring[:n-1]
+			}
+			area := ringArea(ring)
+			if len(ring) < 3 || area == 0 {
+				continue
+			}
+			if i == 0 || exterior == 0 {
+				exterior = area
+			}
+			// exteriors are made positive and holes negative, so that
+			// neighbors run along their shared edges in opposite directions
+			reverse := area < 0
+			if (area > 0) != (exterior > 0) {
+				reverse = !reverse
+			}
+			for j := range ring {
+				a, b := ring[j], ring[(j+1)%len(ring)]
+				if reverse {
+					a, b = b, a
+				}
+				if a.x == b.x && a.y == b.y {
+					continue
+				}
+				e := ringEdge{a.x, a.y, b.x, b.y}
+				back := ringEdge{b.x, b.y, a.x, a.y}
+				if idx := alive[back]; len(idx) > 0 {
+					// a shared edge, which is inside of the union
+					edges[idx[len(idx)-1]] = ringEdge{}
+					alive[back] = idx[:len(idx)-1]
+					continue
+				}
+				alive[e] = append(alive[e], len(edges))
+				edges = append(edges, e)
+			}
+		}
+	}
+	return traceRings(edges)
+}
+
+// traceRings walks the directed edges back into rings, and returns them as
+// exterior rings that are each followed by their holes. Exteriors are the
+// rings with a positive area. Zero edges are skipped.
+func traceRings(edges []ringEdge) geometry {
+	type point struct{ x, y float64 }
+	outgoing := make(map[point][]int)
+	for i, e := range edges {
+		if e != (ringEdge{}) {
+			a := point{e.ax, e.ay}
+			outgoing[a] = append(outgoing[a], i)
+		}
+	}
+	used := make([]bool, len(edges))
+	var exteriors, holes [][]command
+	addRing := func(ring []command) {
+		switch area := ringArea(ring); {
+		case len(ring) < 3:
+		case area > 0:
+			exteriors = append(exteriors, ring)
+		case area < 0:
+			holes = append(holes, ring)
+		}
+	}
+	for i, e := range edges {
+		if used[i] || e == (ringEdge{}) {
+			continue
+		}
+		used[i] = true
+		start := point{e.ax, e.ay}
+		ring := []command{{which: moveTo, x: e.ax, y: e.ay}}
+		seen := map[point]int{start: 0}
+		at := point{e.bx, e.by}
+		for at != start {
+			if k, ok := seen[at]; ok {
+				// the walk came back to a point of the ring, so the loop
+				// since then is a ring of its own
+				loop := append([]command{{which: moveTo, x: at.x, y: at.y}},
+					ring[k+1:]...)
+				for _, cmd := range ring[k+1:] {
+					delete(seen, point{cmd.x, cmd.y})
+				}
+				addRing(loop)
+				ring = ring[:k+1]
+			} else {
+				seen[at] = len(ring)
+				ring = append(ring, command{which: lineTo, x: at.x, y: at.y})
+			}
+			next := -1
+			for _, j := range outgoing[at] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next == -1 {
+				break
+			}
+			used[next] = true
+			at = point{edges[next].bx, edges[next].by}
+		}
+		addRing(ring)
+	}
+	// each hole goes with the smallest exterior around it
+	owned := make([][][]command, len(exteriors))
+	for _, hole := range holes {
+		best, bestArea := -1, 0.0
+		for i, ext := range exteriors {
+			area := ringArea(ext)
+			if pointInRing(hole[0].x, hole[0].y, ext) &&
+				(best == -1 || area < bestArea) {
+				best, bestArea = i, area
+			}
+		}
+		if best != -1 {
+			owned[best] = append(owned[best], hole)
+		}
+	}
+	var g geometry
+	for i, ext := range exteriors {
+		for _, ring := range append([][]command{ext}, owned[i]...) {
+			for _, cmd := range ring {
+				g.push(cmd.which, cmd.x, cmd.y)
+			}
+			g.push(closePath, 0, 0)
+		}
+	}
+	return g
+}
+
+// pointInRing returns true when the point is inside of the ring, or on
+// its boundary.
+func pointInRing(x, y float64, ring []command) bool {
+	in := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if (a.y > y) != (b.y > y) {
+			cx := a.x + (y-a.y)*(b.x-a.x)/(b.y-a.y)
+			if cx == x {
+				return true
+			}
+			if cx > x {
+				in = !in
+			}
+		} else if a.y == y && b.y == y &&
+			x >= min(a.x, b.x) && x <= max(a.x, b.x) {
+			return true
+		}
+	}
+	return in
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestDissolveBy(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("parcels")
+	square := func(x, y float64, zone string, ccw bool) {
+		f := l.AddFeature(Polygon)
+		f.MoveTo(x, y)
+		if ccw {
+			f.LineTo(x, y+10)
+			f.LineTo(x+10, y+10)
+			f.LineTo(x+10, y)
+		} else {
+			f.LineTo(x+10, y)
+			f.LineTo(x+10, y+10)
+			f.LineTo(x, y+10)
+		}
+		f.ClosePath()
+		f.AddTag("zone", zone)
+		f.AddTag("acres", 2)
+	}
+	// a 3x3 grid of squares that is missing its center
+	for i := 0; i < 9; i++ {
+		if i != 4 {
+			square(float64(i%3)*10, float64(i/3)*10, "farm", i == 5)
+		}
+	}
+	square(100, 100, "town", false)
+	square(110, 100, "town", false)
+	square(200, 200, "farm", false)
+	l.AddFeature(Point).MoveTo(0, 0)
+	l.DissolveBy("zone")
+	l.SetDissolveAggregate("acres", "acres", SumAggregator())
+	features := l.render()
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	farm, town := features[0], features[1]
+	if len(farm.paths()) != 3 || farm.area() != 900 {
+		t.Fatalf("expected 3 rings with an area of 900, got %d rings of %v",
+			len(farm.paths()), farm.area())
+	}
+	if err := farm.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(town.paths()) != 1 || town.area() != 200 {
+		t.Fatalf("expected 1 ring with an area of 200, got %d rings of %v",
+			len(town.paths()), town.area())
+	}
+	if tags := town.Tags(); len(tags) != 2 || tags[0] != (Tag{"zone", "town"}) ||
+		tags[1] != (Tag{"acres", 4.0}) {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if features[2].geomType != Point {
+		t.Fatal("expected the point to be left as it is")
+	}
+	l.DissolveBy("")
+	if len(l.render()) != 12 {
+		t.Fatal("expected dissolving to be off")
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"sort"
+)
+
+// DropPolicy chooses which features of a layer are encoded, given the
+// features in the order that they were added and the zoom of the tile. It
+// returns the features to keep, and may reorder the slice that it is
+// given.
+type DropPolicy func(features []*Feature, zoom int) []*Feature
+
+// SetDropPolicy sets the policy that drops features when the layer is
+// rendered, which keeps low zoom tiles small. Default is nil, which keeps
+// all features.
+func (l *Layer) SetDropPolicy(policy DropPolicy) {
+	l.dropPolicy = policy
+}
+
+// DropByRate returns a policy that keeps every feature at the base zoom
+// and above, and one in rate^(baseZoom-zoom) of them below it, such that
+// each zoom out keeps 1/rate of the features of the zoom before it.
+func DropByRate(rate float64, baseZoom int) DropPolicy {
+	return func(features []*Feature, zoom int) []*Feature {
+		if zoom >= baseZoom || rate <= 1 {
+			return features
+		}
+		every := math.Pow(rate, float64(baseZoom-zoom))
+		kept := features[:0]
+		next := 0.0
+		for i, f := range features {
+			if float64(i) >= next {
+				kept = append(kept, f)
+				next += every
+			}
+		}
+		return kept
+	}
+}
+
+// DropSmallest returns a policy that keeps at most n features, dropping
+// those with the smallest size first. The size of a polygon is its area,
+// of a line its length, and points are the smallest of all.
+func DropSmallest(n int) DropPolicy {
+	return keepBest(n, func(f *Feature) [2]float64 {
+		if f.geomType == Polygon {
+			// lengths and areas do not compare, so any area wins
+			return [2]float64{1, f.area()}
+		}
+		return [2]float64{0, f.length()}
+	})
+}
+
+// DropByRank returns a policy that keeps at most n features, dropping
+// those with the lowest numeric value for the tag key first. Features
+// without a numeric value for the key are dropped before all others.
+func DropByRank(key string, n int) DropPolicy {
+	return keepBest(n, func(f *Feature) [2]float64 {
+		if v, ok := f.Tag(key); ok {
+			if rank, ok := toFloat(v); ok {
+				return [2]float64{1, rank}
+			}
+		}
+		return [2]float64{}
+	})
+}
+
+// keepBest returns a policy that keeps the n features with the highest
+// score, in their original order. Scores are compared by their first
+// value, and then by their second.
+func keepBest(n int, score func(f *Feature) [2]float64) DropPolicy {
+	return func(features []*Feature, zoom int) []*Feature {
+		if n < 0 {
+			n = 0
+		}
+		if len(features) <= n {
+			return features
+		}
+		scores := make(map[*Feature][2]float64, len(features))
+		for _, f := range features {
+			scores[f] = score(f)
+		}
+		order := append([]*Feature(nil), features...)
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := scores[order[i]], scores[order[j]]
+			if a[0] != b[0] {
+				return a[0] > b[0]
+			}
+			return a[1] > b[1]
+		})
+		keep := make(map[*Feature]bool, n)
+		for _, f := range order[:n] {
+			keep[f] = true
+		}
+		kept := features[:0]
+		for _, f := range features {
+			if keep[f] {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+}
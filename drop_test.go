@@ -0,0 +1,104 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func featureIDs(features []*Feature) string {
+	var ids []uint64
+	for _, f := range features {
+		id, _ := f.ID()
+		ids = append(ids, id)
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestDropByRate(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	l.SetAutoID(0)
+	for i := 0; i < 10; i++ {
+		l.AddFeature(Point).MoveTo(float64(i), 0)
+	}
+	l.SetDropPolicy(DropByRate(2, 4))
+	tile.SetTileID(TileID{Z: 4})
+	if ids := featureIDs(l.render()); ids != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	tile.SetTileID(TileID{Z: 3})
+	if ids := featureIDs(l.render()); ids != "[0 2 4 6 8]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	tile.SetTileID(TileID{Z: 1})
+	if ids := featureIDs(l.render()); ids != "[0 8]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if len(l.Features()) != 10 {
+		t.Fatal("expected the layer to keep all features")
+	}
+}
+
+func TestDropSmallest(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	l.SetAutoID(0)
+	square := func(size float64) {
+		f := l.AddFeature(Polygon)
+		f.MoveTo(0, 0)
+		f.LineTo(size, 0)
+		f.LineTo(size, size)
+		f.LineTo(0, size)
+		f.ClosePath()
+	}
+	square(10)
+	l.AddFeature(Point).MoveTo(1, 1)
+	square(30)
+	line := l.AddFeature(LineString)
+	line.MoveTo(0, 0)
+	line.LineTo(100, 0)
+	square(20)
+	l.SetDropPolicy(DropSmallest(3))
+	if ids := featureIDs(l.render()); ids != "[0 2 4]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetDropPolicy(DropSmallest(4))
+	if ids := featureIDs(l.render()); ids != "[0 2 3 4]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+
+	// polygons by their areas
+	l = tile.AddLayer("squares")
+	l.SetAutoID(0)
+	for _, size := range []float64{10, 30, 5, 20} {
+		square(size)
+	}
+	l.SetDropPolicy(DropSmallest(2))
+	if ids := featureIDs(l.render()); ids != "[1 3]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+}
+
+func TestDropByRank(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("places")
+	l.SetAutoID(0)
+	for _, pop := range []interface{}{500, 10000, "many", 20, 7000.5} {
+		f := l.AddFeature(Point)
+		f.MoveTo(0, 0)
+		f.AddTag("population", pop)
+	}
+	l.SetDropPolicy(DropByRank("population", 3))
+	if ids := featureIDs(l.render()); ids != "[0 1 4]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	pb := tile.Render()
+	l.SetDropPolicy(nil)
+	if len(pb) >= len(tile.Render()) {
+		t.Fatal("expected a smaller tile with dropped features")
+	}
+}
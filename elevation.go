@@ -0,0 +1,79 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// SampleFunc returns a value at a lat/lon, such as an elevation from a
+// DEM, or NaN where there is no data
+type SampleFunc func(lat, lon float64) float64
+
+// Sample returns the values of the function at each of the vertices of the
+// geometry, in the order of its paths.
+func (g Geometry) Sample(fn SampleFunc) [][]float64 {
+	values := make([][]float64, len(g.Paths))
+	for i, path := range g.Paths {
+		values[i] = make([]float64, len(path))
+		for j, p := range path {
+			values[i][j] = fn(p[1], p[0])
+		}
+	}
+	return values
+}
+
+// SampleGrid returns a grid of the values of the function over the tile,
+// with the size of rows and columns, that can be passed to AddContours.
+// The first and last rows and columns are at the edges of the tile.
+func (id TileID) SampleGrid(fn SampleFunc, size int) [][]float64 {
+	if size < 2 {
+		return nil
+	}
+	step := float64(gTileSize) / float64(size-1)
+	offX, offY := float64(id.X*gTileSize), float64(id.Y*gTileSize)
+	values := make([][]float64, size)
+	for r := range values {
+		values[r] = make([]float64, size)
+		for c := range values[r] {
+			lat, lon := PixelToLatLon(offX+float64(c)*step,
+				offY+float64(r)*step, id.Z)
+			values[r][c] = fn(lat, lon)
+		}
+	}
+	return values
+}
+
+// SetElevation sets the function that the elevations of the geometries
+// that are added to the layer with AddGeometry, AddGeoFeature, or AddFrom
+// are sampled from. A point gets an "elevation" tag, and other geometries
+// get "elevation_min" and "elevation_max" tags of their vertices, after
+// any densifying. Vertices without data are skipped. Default is nil, which
+// samples nothing.
+func (l *Layer) SetElevation(fn SampleFunc) {
+	l.elevation = fn
+}
+
+// addElevation adds the elevation tags of the lat/lon geometry to the
+// feature
+func (l *Layer) addElevation(f *Feature, g Geometry) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	var n int
+	for _, path := range g.Sample(l.elevation) {
+		for _, v := range path {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return
+	}
+	if g.Type == Point && n == 1 {
+		f.AddTag("elevation", lo)
+		return
+	}
+	f.AddTag("elevation_min", lo)
+	f.AddTag("elevation_max", hi)
+}
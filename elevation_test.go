@@ -0,0 +1,49 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestElevation(t *testing.T) {
+	// rises to the east, with no data in the west
+	dem := func(lat, lon float64) float64 {
+		if lon < 0 {
+			return math.NaN()
+		}
+		return math.Round(lon * 10)
+	}
+	var tile Tile
+	tile.SetTileID(TileID{Z: 0, X: 0, Y: 0})
+	l := tile.AddLayer("places")
+	l.SetElevation(dem)
+	for i, expect := range []string{
+		"[{elevation 100}]",
+		"[{elevation_min 0} {elevation_max 200}]",
+		"[]",
+	} {
+		g := []Geometry{
+			{Type: Point, Paths: [][][2]float64{{{10, 5}}}},
+			{Type: LineString, Paths: [][][2]float64{{{-10, 0}, {0, 0},
+				{20, 0}}}},
+			{Type: Point, Paths: [][][2]float64{{{-10, 5}}}},
+		}[i]
+		f := l.AddGeometry(g)
+		if s := fmt.Sprint(f.Tags()); s != expect {
+			t.Fatalf("%d: expected %s, got %s", i, expect, s)
+		}
+	}
+
+	values := TileID{Z: 1, X: 1, Y: 0}.SampleGrid(dem, 3)
+	if s := fmt.Sprint(values); s != "[[0 900 1800] [0 900 1800] [0 900 1800]]" {
+		t.Fatalf("unexpected grid %s", s)
+	}
+	if values := (TileID{}).SampleGrid(dem, 1); values != nil {
+		t.Fatal("expected no grid")
+	}
+}
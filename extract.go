@@ -0,0 +1,28 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// ExtractLayers returns an encoded tile with only the layers of the
+// encoded tile that have one of the names, in the order of the tile, such
+// as to serve slimmed down tiles to clients that need only some of their
+// layers. The layers are copied without decoding their features, and
+// other fields of the tile, such as extensions, are kept.
+func ExtractLayers(tile []byte, names ...string) ([]byte, error) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	layers, other, err := splitTile(tile)
+	if err != nil {
+		return nil, err
+	}
+	var pb []byte
+	for _, layer := range layers {
+		if keep[layer.name] {
+			pb = appendTileLayer(pb, layer.msg)
+		}
+	}
+	return append(pb, other...), nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestExtractLayers(t *testing.T) {
+	pb := encodeTestTile(t, "roads", "water", "places")
+	extracted, err := ExtractLayers(pb, "places", "roads", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := encodeTestTile(t, "roads", "places")
+	if !bytes.Equal(extracted, expect) {
+		t.Fatalf("expected %v, got %v", expect, extracted)
+	}
+	if extracted, err = ExtractLayers(pb); err != nil || len(extracted) != 0 {
+		t.Fatal("expected an empty tile")
+	}
+	_, err = ExtractLayers(pb[:len(pb)-1], "roads")
+	if !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
@@ -0,0 +1,653 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// ErrInvalidFlatGeobuf is returned for a FlatGeobuf file that is malformed
+// or uses a feature that is not supported.
+var ErrInvalidFlatGeobuf = errors.New("invalid flatgeobuf")
+
+// fgbMagic is the start of a FlatGeobuf file, other than its patch version
+var fgbMagic = []byte{'f', 'g', 'b', 3, 'f', 'g', 'b'}
+
+// FlatGeobuf geometry types
+const (
+	fgbUnknown         = 0
+	fgbPoint           = 1
+	fgbLineString      = 2
+	fgbPolygon         = 3
+	fgbMultiPoint      = 4
+	fgbMultiLineString = 5
+	fgbMultiPolygon    = 6
+)
+
+// fgbNodeSize is the size of a node of the packed R-tree
+const fgbNodeSize = 40
+
+// fgbColumn is a column of the properties of the features
+type fgbColumn struct {
+	name string
+	typ  byte
+}
+
+// FlatGeobufReader is a FeatureSource that reads the features of a
+// FlatGeobuf file, with its columns as tags. Coordinates must be lon/lat
+// (EPSG:4326) or Web Mercator (EPSG:3857) meters. Features have no ids.
+type FlatGeobufReader struct {
+	r        io.Reader
+	seeker   io.ReadSeeker
+	start    int64 // file offset of the first feature
+	index    int64 // file offset of the index
+	geomType byte
+	mercator bool
+	columns  []fgbColumn
+	count    uint64
+	nodeSize uint64
+	began    bool
+	// filtering
+	bounds  *[4]float64 // minX, minY, maxX, maxY in the file coordinates
+	offsets []uint64    // offsets of the features from the index
+	scan    bool        // filter by bounds while reading
+}
+
+// NewFlatGeobufReader returns a reader of the FlatGeobuf data, having read
+// its header. When the reader is an io.ReadSeeker, Filter can use the
+// spatial index of the file to only read the features in some bounds.
+func NewFlatGeobufReader(r io.Reader) (*FlatGeobufReader, error) {
+	fr := &FlatGeobufReader{r: r}
+	var pos int64
+	if s, ok := r.(io.ReadSeeker); ok {
+		var err error
+		if pos, err = s.Seek(0, io.SeekCurrent); err == nil {
+			fr.seeker = s
+		}
+	}
+	var head [12]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, fgbError(err)
+	}
+	if !bytes.Equal(head[:7], fgbMagic) {
+		return nil, fmt.Errorf("%w: bad magic bytes", ErrInvalidFlatGeobuf)
+	}
+	size := binary.LittleEndian.Uint32(head[8:])
+	if size > 1<<30 {
+		return nil, fmt.Errorf("%w: header too large", ErrInvalidFlatGeobuf)
+	}
+	buf, err := fgbRead(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := fr.readHeader(buf); err != nil {
+		return nil, err
+	}
+	fr.index = pos + 12 + int64(size)
+	fr.start = fr.index
+	if fr.nodeSize > 0 && fr.count > 0 {
+		fr.start += int64(fgbTreeSize(fr.count, fr.nodeSize)) * fgbNodeSize
+	}
+	return fr, nil
+}
+
+// fgbRead reads the size in bytes, which grows the buffer as the bytes
+// are read, rather than trusting a size from the file.
+func fgbRead(r io.Reader, size uint32) ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) < int(size) {
+		return nil, fgbError(io.ErrUnexpectedEOF)
+	}
+	return buf, nil
+}
+
+// fgbError converts a read error to an invalid file error
+func fgbError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of file", ErrInvalidFlatGeobuf)
+	}
+	return err
+}
+
+// readHeader reads the header table.
+func (fr *FlatGeobufReader) readHeader(buf []byte) error {
+	fb := flatbuf{buf: buf}
+	h := fb.root()
+	fr.geomType = fb.uint8(h, 2)
+	if fb.uint8(h, 3) != 0 || fb.uint8(h, 4) != 0 || fb.uint8(h, 5) != 0 ||
+		fb.uint8(h, 6) != 0 {
+		return fmt.Errorf("%w: z, m, t, and tm are not supported",
+			ErrInvalidFlatGeobuf)
+	}
+	for _, c := range fb.tables(h, 7) {
+		fr.columns = append(fr.columns, fgbColumn{
+			name: string(fb.bytes(c, 0)), typ: fb.uint8(c, 1)})
+	}
+	fr.count = fb.uint64(h, 8)
+	fr.nodeSize = 16
+	if fb.has(h, 9) {
+		// zero is no index, and one would never reach the root
+		fr.nodeSize = uint64(fb.uint16(h, 9))
+		if fr.nodeSize == 1 {
+			return fmt.Errorf("%w: bad index node size",
+				ErrInvalidFlatGeobuf)
+		}
+	}
+	if crs := fb.table(h, 10); crs != 0 {
+		switch code := int32(fb.uint32(crs, 1)); code {
+		case 0, 4326:
+		case 3857:
+			fr.mercator = true
+		default:
+			return fmt.Errorf("%w: unsupported crs %d", ErrInvalidFlatGeobuf,
+				code)
+		}
+	}
+	return fb.err
+}
+
+// Filter sets the reader to only return the features whose bounds
+// intersect the lat/lon bounds. It must be called before the first Next.
+// With an io.ReadSeeker and a file that has a spatial index, only those
+// features are read, otherwise all features are read and filtered.
+func (fr *FlatGeobufReader) Filter(minLat, minLon, maxLat, maxLon float64,
+) error {
+	if fr.began {
+		return errors.New("filter after reading features")
+	}
+	b := [4]float64{minLon, minLat, maxLon, maxLat}
+	if fr.mercator {
+		b[0], b[1] = lonLatMercator(minLon, minLat)
+		b[2], b[3] = lonLatMercator(maxLon, maxLat)
+	}
+	fr.bounds = &b
+	if fr.seeker == nil || fr.nodeSize == 0 || fr.count == 0 {
+		fr.scan = true
+		return nil
+	}
+	offsets, err := fr.search(b)
+	if err != nil {
+		return err
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	fr.offsets = offsets
+	return nil
+}
+
+// FilterTile sets the reader to only return the features in the map tile,
+// plus a small buffer, see Filter.
+func (fr *FlatGeobufReader) FilterTile(id TileID) error {
+	minX := float64(id.X*gTileSize) - clipBuffer
+	minY := float64(id.Y*gTileSize) - clipBuffer
+	maxLat, minLon := PixelToLatLon(minX, minY, id.Z)
+	minLat, maxLon := PixelToLatLon(minX+gTileSize+clipBuffer*2,
+		minY+gTileSize+clipBuffer*2, id.Z)
+	return fr.Filter(minLat, minLon, maxLat, maxLon)
+}
+
+// lonLatMercator converts a lon/lat to Web Mercator meters
+func lonLatMercator(lon, lat float64) (mx, my float64) {
+	lat = clamp(lat, gMinLat, gMaxLat)
+	mx = lon * originShift / 180
+	my = math.Log(math.Tan((90+lat)*math.Pi/360)) / math.Pi * originShift
+	return mx, my
+}
+
+// mercatorLonLat converts Web Mercator meters to a lon/lat
+func mercatorLonLat(mx, my float64) (lon, lat float64) {
+	lon = mx / originShift * 180
+	lat = math.Atan(math.Exp(my/originShift*math.Pi))*360/math.Pi - 90
+	return lon, lat
+}
+
+// fgbTreeSize returns the number of nodes of the packed R-tree
+func fgbTreeSize(count, nodeSize uint64) uint64 {
+	n, nodes := count, count
+	for {
+		n = (n + nodeSize - 1) / nodeSize
+		nodes += n
+		if n <= 1 {
+			return nodes
+		}
+	}
+}
+
+// search returns the offsets of the features whose bounds intersect the
+// bounds using the packed Hilbert R-tree of the file.
+func (fr *FlatGeobufReader) search(b [4]float64) ([]uint64, error) {
+	// the levels of the tree, from the leaves up to the root
+	counts := []uint64{fr.count}
+	for n := fr.count; n > 1 || len(counts) == 1; {
+		n = (n + fr.nodeSize - 1) / fr.nodeSize
+		counts = append(counts, n)
+	}
+	nodes := fgbTreeSize(fr.count, fr.nodeSize)
+	levels := make([][2]uint64, len(counts))
+	end := nodes
+	for i, n := range counts {
+		levels[i] = [2]uint64{end - n, end}
+		end -= n
+	}
+	leaves := levels[0][0]
+	type item struct {
+		node  uint64
+		level int
+	}
+	var offsets []uint64
+	queue := []item{{0, len(levels) - 1}}
+	// each node is read once, as a bad index may share them
+	seen := make(map[uint64]bool)
+	buf := make([]byte, fr.nodeSize*fgbNodeSize)
+	for len(queue) > 0 {
+		it := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		end := min(it.node+fr.nodeSize, levels[it.level][1])
+		if it.node >= end {
+			return nil, fmt.Errorf("%w: bad index", ErrInvalidFlatGeobuf)
+		}
+		data := buf[:(end-it.node)*fgbNodeSize]
+		_, err := fr.seeker.Seek(fr.index+int64(it.node*fgbNodeSize),
+			io.SeekStart)
+		if err == nil {
+			_, err = io.ReadFull(fr.seeker, data)
+		}
+		if err != nil {
+			return nil, fgbError(err)
+		}
+		for i := 0; i < len(data); i += fgbNodeSize {
+			var box [4]float64
+			for j := range box {
+				box[j] = math.Float64frombits(
+					binary.LittleEndian.Uint64(data[i+j*8:]))
+			}
+			if box[0] > b[2] || box[2] < b[0] || box[1] > b[3] ||
+				box[3] < b[1] {
+				continue
+			}
+			offset := binary.LittleEndian.Uint64(data[i+32:])
+			if it.node >= leaves {
+				offsets = append(offsets, offset)
+			} else if it.level > 0 && offset >= levels[it.level-1][0] &&
+				offset < levels[it.level-1][1] {
+				if !seen[offset] {
+					seen[offset] = true
+					queue = append(queue, item{offset, it.level - 1})
+				}
+			} else {
+				return nil, fmt.Errorf("%w: bad index", ErrInvalidFlatGeobuf)
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (fr *FlatGeobufReader) Next() (geom Geometry,
+	tags map[string]interface{}, id interface{}, err error) {
+	if !fr.began {
+		fr.began = true
+		if fr.offsets == nil && fr.seeker == nil && fr.start > fr.index {
+			// skip the index
+			_, err := io.CopyN(io.Discard, fr.r, fr.start-fr.index)
+			if err != nil {
+				return Geometry{}, nil, nil, fgbError(err)
+			}
+		} else if fr.seeker != nil && fr.offsets == nil {
+			if _, err := fr.seeker.Seek(fr.start, io.SeekStart); err != nil {
+				return Geometry{}, nil, nil, err
+			}
+		}
+	}
+	for {
+		if fr.offsets != nil {
+			if len(fr.offsets) == 0 {
+				return Geometry{}, nil, nil, io.EOF
+			}
+			_, err := fr.seeker.Seek(fr.start+int64(fr.offsets[0]),
+				io.SeekStart)
+			if err != nil {
+				return Geometry{}, nil, nil, err
+			}
+			fr.offsets = fr.offsets[1:]
+		}
+		var size [4]byte
+		if _, err := io.ReadFull(fr.r, size[:]); err != nil {
+			if err == io.EOF {
+				return Geometry{}, nil, nil, io.EOF
+			}
+			return Geometry{}, nil, nil, fgbError(err)
+		}
+		n := binary.LittleEndian.Uint32(size[:])
+		if n > 1<<30 {
+			return Geometry{}, nil, nil, fmt.Errorf("%w: feature too large",
+				ErrInvalidFlatGeobuf)
+		}
+		buf, err := fgbRead(fr.r, n)
+		if err != nil {
+			return Geometry{}, nil, nil, err
+		}
+		geom, tags, err := fr.readFeature(buf)
+		if err != nil {
+			return Geometry{}, nil, nil, err
+		}
+		if fr.scan && !intersectsBounds(geom, fr.bounds) {
+			continue
+		}
+		if fr.mercator {
+			for _, path := range geom.Paths {
+				for i, p := range path {
+					path[i][0], path[i][1] = mercatorLonLat(p[0], p[1])
+				}
+			}
+		}
+		return geom, tags, nil, nil
+	}
+}
+
+// readFeature reads a feature table.
+func (fr *FlatGeobufReader) readFeature(buf []byte,
+) (Geometry, map[string]interface{}, error) {
+	fb := flatbuf{buf: buf}
+	f := fb.root()
+	var geom Geometry
+	if g := fb.table(f, 0); g != 0 {
+		geom = fr.readGeometry(&fb, g, fr.geomType)
+	}
+	columns := fr.columns
+	if cs := fb.tables(f, 2); len(cs) > 0 {
+		columns = nil
+		for _, c := range cs {
+			columns = append(columns, fgbColumn{
+				name: string(fb.bytes(c, 0)), typ: fb.uint8(c, 1)})
+		}
+	}
+	tags, err := readFGBProperties(fb.bytes(f, 1), columns)
+	if fb.err != nil {
+		err = fb.err
+	}
+	if err != nil {
+		return Geometry{}, nil, err
+	}
+	return geom, tags, nil
+}
+
+// readGeometry reads a geometry table, whose type is from the header
+// unless the header type is unknown.
+func (fr *FlatGeobufReader) readGeometry(fb *flatbuf, g int, typ byte,
+) Geometry {
+	if typ == fgbUnknown {
+		typ = fb.uint8(g, 6)
+	}
+	var points [][2]float64
+	xy := fb.elems(g, 1, 8)
+	for i := 0; i+16 <= len(xy); i += 16 {
+		points = append(points, [2]float64{
+			math.Float64frombits(binary.LittleEndian.Uint64(xy[i:])),
+			math.Float64frombits(binary.LittleEndian.Uint64(xy[i+8:])),
+		})
+	}
+	// the ends split the points into lines or rings
+	paths := [][][2]float64{points}
+	if ends := fb.elems(g, 0, 4); len(ends) > 0 {
+		paths = paths[:0]
+		var start uint32
+		for i := 0; i+4 <= len(ends); i += 4 {
+			end := binary.LittleEndian.Uint32(ends[i:])
+			if end < start || int(end) > len(points) {
+				fb.fail("bad geometry ends")
+				break
+			}
+			paths = append(paths, points[start:end])
+			start = end
+		}
+	}
+	switch typ {
+	case fgbPoint, fgbMultiPoint:
+		geom := Geometry{Type: Point}
+		for _, p := range points {
+			geom.Paths = append(geom.Paths, [][2]float64{p})
+		}
+		return geom
+	case fgbLineString:
+		return Geometry{Type: LineString, Paths: [][][2]float64{points}}
+	case fgbMultiLineString:
+		return Geometry{Type: LineString, Paths: paths}
+	case fgbPolygon:
+		return Geometry{Type: Polygon, Paths: paths}
+	case fgbMultiPolygon:
+		geom := Geometry{Type: Polygon}
+		for _, part := range fb.tables(g, 7) {
+			poly := fr.readGeometry(fb, part, fgbPolygon)
+			geom.Paths = append(geom.Paths, poly.Paths...)
+			geom.Rings = append(geom.Rings, len(poly.Paths))
+		}
+		return geom
+	}
+	fb.fail(fmt.Sprintf("unsupported geometry type %d", typ))
+	return Geometry{}
+}
+
+// readFGBProperties reads the properties of a feature, which are each a
+// column index followed by a value of the type of the column.
+func readFGBProperties(props []byte, columns []fgbColumn,
+) (map[string]interface{}, error) {
+	tags := make(map[string]interface{})
+	bad := fmt.Errorf("%w: bad properties", ErrInvalidFlatGeobuf)
+	le := binary.LittleEndian
+	for len(props) > 0 {
+		if len(props) < 2 {
+			return nil, bad
+		}
+		i := int(le.Uint16(props))
+		props = props[2:]
+		if i >= len(columns) {
+			return nil, bad
+		}
+		sizes := [...]int{1, 1, 1, 2, 2, 4, 4, 8, 8, 4, 8}
+		typ := int(columns[i].typ)
+		var size int
+		if typ < len(sizes) {
+			size = sizes[typ]
+		} else if typ <= 14 {
+			if len(props) < 4 {
+				return nil, bad
+			}
+			size = int(le.Uint32(props))
+			props = props[4:]
+		} else {
+			return nil, fmt.Errorf("%w: unsupported column type %d",
+				ErrInvalidFlatGeobuf, typ)
+		}
+		if size < 0 || len(props) < size {
+			return nil, bad
+		}
+		b := props[:size]
+		props = props[size:]
+		var v interface{}
+		switch typ {
+		case 0:
+			v = int8(b[0])
+		case 1:
+			v = b[0]
+		case 2:
+			v = b[0] != 0
+		case 3:
+			v = int16(le.Uint16(b))
+		case 4:
+			v = le.Uint16(b)
+		case 5:
+			v = int32(le.Uint32(b))
+		case 6:
+			v = le.Uint32(b)
+		case 7:
+			v = int64(le.Uint64(b))
+		case 8:
+			v = le.Uint64(b)
+		case 9:
+			v = math.Float32frombits(le.Uint32(b))
+		case 10:
+			v = math.Float64frombits(le.Uint64(b))
+		case 14:
+			v = append([]byte(nil), b...)
+		default:
+			// string, json, and datetime
+			v = string(b)
+		}
+		tags[columns[i].name] = v
+	}
+	return tags, nil
+}
+
+// flatbuf reads the tables of a flatbuffer. Reads that are out of range
+// return zero values and set err.
+type flatbuf struct {
+	buf []byte
+	err error
+}
+
+func (fb *flatbuf) fail(msg string) {
+	if fb.err == nil {
+		fb.err = fmt.Errorf("%w: %s", ErrInvalidFlatGeobuf, msg)
+	}
+}
+
+// u32 returns the uint32 at pos
+func (fb *flatbuf) u32(pos int) uint32 {
+	if pos < 0 || pos+4 > len(fb.buf) {
+		fb.fail("out of range")
+		return 0
+	}
+	return binary.LittleEndian.Uint32(fb.buf[pos:])
+}
+
+// u16 returns the uint16 at pos
+func (fb *flatbuf) u16(pos int) uint16 {
+	if pos < 0 || pos+2 > len(fb.buf) {
+		fb.fail("out of range")
+		return 0
+	}
+	return binary.LittleEndian.Uint16(fb.buf[pos:])
+}
+
+// root returns the position of the root table
+func (fb *flatbuf) root() int {
+	return int(fb.u32(0))
+}
+
+// field returns the position of a field of a table, or zero when the
+// field is not set.
+func (fb *flatbuf) field(table, field int) int {
+	if table == 0 {
+		return 0
+	}
+	vtable := table - int(int32(fb.u32(table)))
+	if int(fb.u16(vtable)) <= 4+field*2 {
+		return 0
+	}
+	off := int(fb.u16(vtable + 4 + field*2))
+	if off == 0 {
+		return 0
+	}
+	return table + off
+}
+
+func (fb *flatbuf) has(table, field int) bool {
+	return fb.field(table, field) != 0
+}
+
+func (fb *flatbuf) uint8(table, field int) byte {
+	pos := fb.field(table, field)
+	if pos == 0 {
+		return 0
+	}
+	if pos >= len(fb.buf) {
+		fb.fail("out of range")
+		return 0
+	}
+	return fb.buf[pos]
+}
+
+func (fb *flatbuf) uint16(table, field int) uint16 {
+	if pos := fb.field(table, field); pos != 0 {
+		return fb.u16(pos)
+	}
+	return 0
+}
+
+func (fb *flatbuf) uint32(table, field int) uint32 {
+	if pos := fb.field(table, field); pos != 0 {
+		return fb.u32(pos)
+	}
+	return 0
+}
+
+func (fb *flatbuf) uint64(table, field int) uint64 {
+	if pos := fb.field(table, field); pos != 0 {
+		return uint64(fb.u32(pos)) | uint64(fb.u32(pos+4))<<32
+	}
+	return 0
+}
+
+// table returns the position of a table field, or zero when it is not set
+func (fb *flatbuf) table(table, field int) int {
+	if pos := fb.field(table, field); pos != 0 {
+		return pos + int(fb.u32(pos))
+	}
+	return 0
+}
+
+// vector returns the position of the first element and the length of a
+// vector field.
+func (fb *flatbuf) vector(table, field int) (int, int) {
+	pos := fb.field(table, field)
+	if pos == 0 {
+		return 0, 0
+	}
+	pos += int(fb.u32(pos))
+	return pos + 4, int(fb.u32(pos))
+}
+
+// bytes returns the bytes of a string or byte vector field
+func (fb *flatbuf) bytes(table, field int) []byte {
+	return fb.elems(table, field, 1)
+}
+
+// elems returns the bytes of the elements of a scalar vector field, whose
+// elements are size bytes each.
+func (fb *flatbuf) elems(table, field, size int) []byte {
+	start, n := fb.vector(table, field)
+	if n == 0 {
+		return nil
+	}
+	if n < 0 || n > len(fb.buf)/size || start+n*size > len(fb.buf) {
+		fb.fail("out of range")
+		return nil
+	}
+	return fb.buf[start : start+n*size]
+}
+
+// tables returns the positions of the tables of a vector field
+func (fb *flatbuf) tables(table, field int) []int {
+	start, n := fb.vector(table, field)
+	if n < 0 || n > len(fb.buf)/4 || start+n*4 > len(fb.buf) {
+		fb.fail("out of range")
+		return nil
+	}
+	tables := make([]int, n)
+	for i := range tables {
+		pos := start + i*4
+		tables[i] = pos + int(fb.u32(pos))
+	}
+	return tables
+}
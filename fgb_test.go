@@ -0,0 +1,306 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+)
+
+// fbt is a flatbuffer table for tests, with its fields by index
+type fbt []interface{}
+
+// buildFlatbuf returns a flatbuffer of the root table.
+func buildFlatbuf(root fbt) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(writeFBTable(&buf, root)))
+	return buf
+}
+
+func writeFBTable(buf *[]byte, t fbt) int {
+	le := binary.LittleEndian
+	vtable := len(*buf)
+	*buf = append(*buf, make([]byte, 4+2*len(t))...)
+	le.PutUint16((*buf)[vtable:], uint16(4+2*len(t)))
+	table := len(*buf)
+	*buf = le.AppendUint32(*buf, uint32(table-vtable))
+	offsets := make(map[int]int)
+	for i, v := range t {
+		if v == nil {
+			continue
+		}
+		le.PutUint16((*buf)[vtable+4+i*2:], uint16(len(*buf)-table))
+		switch v := v.(type) {
+		case uint8:
+			*buf = append(*buf, v)
+		case uint16:
+			*buf = le.AppendUint16(*buf, v)
+		case int32:
+			*buf = le.AppendUint32(*buf, uint32(v))
+		case uint64:
+			*buf = le.AppendUint64(*buf, v)
+		default:
+			offsets[i] = len(*buf)
+			*buf = append(*buf, 0, 0, 0, 0)
+		}
+	}
+	le.PutUint16((*buf)[vtable+2:], uint16(len(*buf)-table))
+	for i, v := range t {
+		if pos, ok := offsets[i]; ok {
+			child := writeFBValue(buf, v)
+			le.PutUint32((*buf)[pos:], uint32(child-pos))
+		}
+	}
+	return table
+}
+
+func writeFBValue(buf *[]byte, v interface{}) int {
+	le := binary.LittleEndian
+	pos := len(*buf)
+	switch v := v.(type) {
+	case string:
+		*buf = le.AppendUint32(*buf, uint32(len(v)))
+		*buf = append(append(*buf, v...), 0)
+	case []byte:
+		*buf = le.AppendUint32(*buf, uint32(len(v)))
+		*buf = append(*buf, v...)
+	case []uint32:
+		*buf = le.AppendUint32(*buf, uint32(len(v)))
+		for _, n := range v {
+			*buf = le.AppendUint32(*buf, n)
+		}
+	case []float64:
+		*buf = le.AppendUint32(*buf, uint32(len(v)))
+		for _, n := range v {
+			*buf = le.AppendUint64(*buf, math.Float64bits(n))
+		}
+	case fbt:
+		return writeFBTable(buf, v)
+	case []fbt:
+		*buf = le.AppendUint32(*buf, uint32(len(v)))
+		start := len(*buf)
+		*buf = append(*buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			at := start + i*4
+			child := writeFBTable(buf, t)
+			le.PutUint32((*buf)[at:], uint32(child-at))
+		}
+	default:
+		panic(fmt.Sprintf("unsupported %T", v))
+	}
+	return pos
+}
+
+// fgbProps returns the properties of a feature with a name and a pop
+func fgbProps(name string, pop int64) []byte {
+	le := binary.LittleEndian
+	props := le.AppendUint16(nil, 0)
+	props = le.AppendUint32(props, uint32(len(name)))
+	props = append(props, name...)
+	if pop != 0 {
+		props = le.AppendUint16(props, 1)
+		props = le.AppendUint64(props, uint64(pop))
+	}
+	return props
+}
+
+// testFlatGeobuf returns a FlatGeobuf file of three features, with an
+// index of node size 2.
+func testFlatGeobuf() []byte {
+	return flatGeobufFile(2)
+}
+
+// flatGeobufFile returns the file of testFlatGeobuf with the node size in
+// its header, which only has an index for a node size of 2.
+func flatGeobufFile(nodeSize uint16) []byte {
+	le := binary.LittleEndian
+	features := []fbt{{
+		fbt{nil, []float64{-111.9, 33.4}, nil, nil, nil, nil, uint8(fgbPoint)},
+		fgbProps("tempe", 180000),
+	}, {
+		fbt{nil, []float64{0, 0, 1, 0, 1, 1, 0, 1, 0, 0}, nil, nil, nil, nil,
+			uint8(fgbPolygon)},
+		fgbProps("null island", 0),
+	}, {
+		fbt{[]uint32{2, 4}, []float64{10, 50, 11, 50, 10, 51, 11, 51}, nil,
+			nil, nil, nil, uint8(fgbMultiLineString)},
+		fgbProps("lines", 0),
+	}}
+	boxes := [][4]float64{
+		{-111.9, 33.4, -111.9, 33.4}, {0, 0, 1, 1}, {10, 50, 11, 51}}
+	header := buildFlatbuf(fbt{
+		"test", nil, uint8(fgbUnknown), nil, nil, nil, nil,
+		[]fbt{{"name", uint8(11)}, {"pop", uint8(7)}},
+		uint64(len(features)), nodeSize,
+	})
+	var data []byte
+	var offsets []uint64
+	for _, f := range features {
+		offsets = append(offsets, uint64(len(data)))
+		fb := buildFlatbuf(f)
+		data = le.AppendUint32(data, uint32(len(fb)))
+		data = append(data, fb...)
+	}
+	node := func(index []byte, box [4]float64, offset uint64) []byte {
+		for _, v := range box {
+			index = le.AppendUint64(index, math.Float64bits(v))
+		}
+		return le.AppendUint64(index, offset)
+	}
+	union := func(a, b [4]float64) [4]float64 {
+		return [4]float64{math.Min(a[0], b[0]), math.Min(a[1], b[1]),
+			math.Max(a[2], b[2]), math.Max(a[3], b[3])}
+	}
+	var index []byte
+	if nodeSize == 2 {
+		index = node(index, union(union(boxes[0], boxes[1]), boxes[2]), 1)
+		index = node(index, union(boxes[0], boxes[1]), 3)
+		index = node(index, boxes[2], 5)
+		for i, box := range boxes {
+			index = node(index, box, offsets[i])
+		}
+	}
+	file := append([]byte(nil), fgbMagic...)
+	file = append(file, 0)
+	file = le.AppendUint32(file, uint32(len(header)))
+	file = append(file, header...)
+	file = append(file, index...)
+	return append(file, data...)
+}
+
+func readAllGeo(t *testing.T, src FeatureSource) []GeoFeature {
+	t.Helper()
+	var features []GeoFeature
+	for {
+		geom, tags, id, err := src.Next()
+		if err == io.EOF {
+			return features
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		features = append(features, GeoFeature{geom, tags, id})
+	}
+}
+
+func TestFlatGeobufReader(t *testing.T) {
+	file := testFlatGeobuf()
+	r, err := NewFlatGeobufReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := readAllGeo(t, r)
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	for i, expect := range []string{
+		"{1 [[[-111.9 33.4]]] []} map[name:tempe pop:180000]",
+		"{3 [[[0 0] [1 0] [1 1] [0 1] [0 0]]] []} map[name:null island]",
+		"{2 [[[10 50] [11 50]] [[10 51] [11 51]]] []} map[name:lines]",
+	} {
+		s := fmt.Sprint(features[i].Geometry, " ", features[i].Tags)
+		if s != expect {
+			t.Fatalf("feature %d: expected %s, got %s", i, expect, s)
+		}
+	}
+	if v := features[0].Tags["pop"]; v != int64(180000) {
+		t.Fatalf("expected an int64 pop, got %T", v)
+	}
+
+	// with the index, and by scanning a reader that cannot seek
+	for _, rd := range []io.Reader{
+		bytes.NewReader(file), struct{ io.Reader }{bytes.NewReader(file)},
+	} {
+		r, err := NewFlatGeobufReader(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Filter(0.5, 0.5, 50.5, 10.5); err != nil {
+			t.Fatal(err)
+		}
+		features := readAllGeo(t, r)
+		if len(features) != 2 || features[0].Tags["name"] != "null island" ||
+			features[1].Tags["name"] != "lines" {
+			t.Fatalf("unexpected features %v", features)
+		}
+	}
+
+	var tile Tile
+	tx, ty, _, _ := LatLonToTile(33.4, -111.9, 10)
+	tile.SetTileID(TileID{Z: 10, X: tx, Y: ty})
+	r, _ = NewFlatGeobufReader(bytes.NewReader(file))
+	if err := r.FilterTile(tile.TileID()); err != nil {
+		t.Fatal(err)
+	}
+	l := tile.AddLayer("places")
+	if err := l.AddFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Features()) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(l.Features()))
+	}
+
+	bad := append([]byte(nil), file...)
+	bad[0] = 'x'
+	if _, err := NewFlatGeobufReader(bytes.NewReader(bad)); !errors.Is(err,
+		ErrInvalidFlatGeobuf) {
+		t.Fatalf("expected ErrInvalidFlatGeobuf, got %v", err)
+	}
+	r, _ = NewFlatGeobufReader(bytes.NewReader(file[:len(file)-10]))
+	readErr := func() error {
+		for {
+			if _, _, _, err := r.Next(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := readErr(); !errors.Is(err, ErrInvalidFlatGeobuf) {
+		t.Fatalf("expected ErrInvalidFlatGeobuf, got %v", err)
+	}
+}
+
+func TestFlatGeobufNodeSize(t *testing.T) {
+	// a node size of one would never reach the root of the index
+	_, err := NewFlatGeobufReader(bytes.NewReader(flatGeobufFile(1)))
+	if !errors.Is(err, ErrInvalidFlatGeobuf) {
+		t.Fatalf("expected ErrInvalidFlatGeobuf, got %v", err)
+	}
+	// zero is no index, which is scanned
+	r, err := NewFlatGeobufReader(bytes.NewReader(flatGeobufFile(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Filter(0.5, 0.5, 50.5, 10.5); err != nil {
+		t.Fatal(err)
+	}
+	if features := readAllGeo(t, r); len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+}
+
+func FuzzFlatGeobuf(f *testing.F) {
+	f.Add(testFlatGeobuf())
+	f.Add(flatGeobufFile(0))
+	f.Add(flatGeobufFile(1))
+	f.Fuzz(func(t *testing.T, file []byte) {
+		r, err := NewFlatGeobufReader(bytes.NewReader(file))
+		if err != nil {
+			return
+		}
+		if err := r.Filter(-90, -180, 90, 180); err != nil {
+			return
+		}
+		for i := 0; i < 100; i++ {
+			if _, _, _, err := r.Next(); err != nil {
+				return
+			}
+		}
+	})
+}
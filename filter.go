@@ -0,0 +1,345 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidFilter is returned for a filter expression that is malformed
+// or has an unknown operator
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// geomTypeNames are the names of the geometry types in filter expressions
+var geomTypeNames = [...]string{Unknown: "Unknown", Point: "Point",
+	LineString: "LineString", Polygon: "Polygon"}
+
+// Filter is a compiled filter expression that matches features by their
+// tags, geometry type, and id, see ParseFilter.
+type Filter struct {
+	match func(t *filterTarget) bool
+}
+
+// filterTarget is a feature that a filter is matched against
+type filterTarget struct {
+	geomType GeometryType
+	id       uint64
+	hasID    bool
+	tag      func(key string) (interface{}, bool)
+}
+
+// value returns the value of the key of the feature, where "$type" is its
+// geometry type and "$id" is its id.
+func (t *filterTarget) value(key string) (interface{}, bool) {
+	switch key {
+	case "$type":
+		if int(t.geomType) < len(geomTypeNames) {
+			return geomTypeNames[t.geomType], true
+		}
+		return nil, false
+	case "$id":
+		return t.id, t.hasID
+	}
+	return t.tag(key)
+}
+
+// ParseFilter compiles a filter expression in the JSON syntax of the
+// filters of Mapbox GL styles, such as ["==", "class", "motorway"]. The
+// operators are "==", "!=", "<", "<=", ">", and ">=" of a key and a
+// value, "in" and "!in" of a key and values, "has" and "!has" of a key,
+// and "all", "any", and "none" of other filters, and "!" of one. The keys
+// "$type" and "$id" are the geometry type, which is "Point",
+// "LineString", or "Polygon", and the id of a feature. Numbers of any type
+// compare as numbers, and values of different types are not equal.
+func ParseFilter(expr string) (*Filter, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(expr), &v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return NewFilter(v)
+}
+
+// NewFilter compiles a filter expression that is already decoded from
+// JSON, such as from a style, see ParseFilter.
+func NewFilter(expr interface{}) (*Filter, error) {
+	match, err := compileFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{match}, nil
+}
+
+// compileFilter compiles an expression into a func that matches it
+func compileFilter(expr interface{}) (func(t *filterTarget) bool, error) {
+	args, ok := expr.([]interface{})
+	if !ok || len(args) == 0 {
+		return nil, fmt.Errorf("%w: %v is not an expression",
+			ErrInvalidFilter, expr)
+	}
+	op, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v is not an operator", ErrInvalidFilter,
+			args[0])
+	}
+	args = args[1:]
+	switch op {
+	case "all", "any", "none":
+		filters := make([]func(t *filterTarget) bool, len(args))
+		for i, arg := range args {
+			var err error
+			if filters[i], err = compileFilter(arg); err != nil {
+				return nil, err
+			}
+		}
+		return func(t *filterTarget) bool {
+			for _, f := range filters {
+				if f(t) != (op == "all") {
+					// the first that decides
+					return op == "any"
+				}
+			}
+			return op != "any"
+		}, nil
+	case "!":
+		if len(args) != 1 {
+			break
+		}
+		f, err := compileFilter(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return func(t *filterTarget) bool { return !f(t) }, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: %q without a key", ErrInvalidFilter, op)
+	}
+	key, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v is not a key", ErrInvalidFilter,
+			args[0])
+	}
+	values := args[1:]
+	for _, v := range values {
+		switch v.(type) {
+		case string, float64, bool:
+		default:
+			return nil, fmt.Errorf("%w: %v is not a value", ErrInvalidFilter,
+				v)
+		}
+	}
+	switch op {
+	case "has", "!has":
+		if len(values) != 0 {
+			break
+		}
+		return func(t *filterTarget) bool {
+			_, ok := t.value(key)
+			return ok == (op == "has")
+		}, nil
+	case "in", "!in":
+		return func(t *filterTarget) bool {
+			v, ok := t.value(key)
+			if ok {
+				for _, value := range values {
+					if c, ok := compareValues(v, value); ok && c == 0 {
+						return op == "in"
+					}
+				}
+			}
+			return op == "!in"
+		}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		if len(values) != 1 {
+			break
+		}
+		value := values[0]
+		return func(t *filterTarget) bool {
+			v, ok := t.value(key)
+			if !ok {
+				return op == "!="
+			}
+			c, ok := compareValues(v, value)
+			if !ok {
+				return op == "!="
+			}
+			switch op {
+			case "==":
+				return c == 0
+			case "!=":
+				return c != 0
+			case "<":
+				return c < 0
+			case "<=":
+				return c <= 0
+			case ">":
+				return c > 0
+			}
+			return c >= 0
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter,
+			op)
+	}
+	return nil, fmt.Errorf("%w: wrong number of arguments for %q",
+		ErrInvalidFilter, op)
+}
+
+// compareValues compares a tag value to a filter value, returning false
+// when they are of types that do not compare. Booleans are only equal or
+// not, with true as greater.
+func compareValues(v, value interface{}) (int, bool) {
+	switch value := value.(type) {
+	case string:
+		if s, ok := v.(string); ok {
+			return strings.Compare(s, value), true
+		}
+	case float64:
+		if n, ok := toFloat(v); ok {
+			switch {
+			case n < value:
+				return -1, true
+			case n > value:
+				return 1, true
+			case n == value:
+				return 0, true
+			}
+		}
+	case bool:
+		if b, ok := v.(bool); ok {
+			switch {
+			case b == value:
+				return 0, true
+			case b:
+				return 1, true
+			}
+			return -1, true
+		}
+	}
+	return 0, false
+}
+
+// Match returns true when the feature matches the filter
+func (f *Filter) Match(feature *Feature) bool {
+	return f.match(&filterTarget{geomType: feature.geomType, id: feature.id,
+		hasID: feature.hasID, tag: feature.Tag})
+}
+
+// SetFeatureFilter sets the filter that the features of the layer must
+// match to be encoded, which is applied when the tile is rendered, before
+// the other render time options. Default is nil, which keeps all features.
+func (l *Layer) SetFeatureFilter(filter *Filter) {
+	l.filter = filter
+}
+
+// filterFeatures returns the features that match the filter of the layer
+func (l *Layer) filterFeatures(features []*Feature) []*Feature {
+	var matched []*Feature
+	for _, f := range features {
+		if l.filter.Match(f) {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// FilterTile returns the encoded tile with only the features of its layers
+// that match the filter, and with the key and value tables of the layers
+// rebuilt from the tags that remain. Only the layers with the names are
+// filtered, or all of them when there are none, and other layers are
+// copied without decoding their features.
+func FilterTile(tile []byte, filter *Filter, layers ...string) ([]byte,
+	error,
+) {
+	names := make(map[string]bool, len(layers))
+	for _, name := range layers {
+		names[name] = true
+	}
+	tls, other, err := splitTile(tile)
+	if err != nil {
+		return nil, err
+	}
+	pb := make([]byte, 0, len(tile))
+	for _, tl := range tls {
+		if len(names) == 0 || names[tl.name] {
+			msg, err := filterLayer(tl.msg, filter)
+			if err != nil {
+				return nil, fmt.Errorf("layer %q: %w", tl.name, err)
+			}
+			tl.msg = msg
+		}
+		pb = appendTileLayer(pb, tl.msg)
+	}
+	return append(pb, other...), nil
+}
+
+// filterLayer returns the layer message with only the features that match
+// the filter, see FilterTile.
+func filterLayer(msg []byte, filter *Filter) ([]byte, error) {
+	layer, err := parseLayer(msg)
+	if err != nil {
+		return nil, err
+	}
+	if layer.version > 2 {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, layer.version)
+	}
+	values := make([]interface{}, len(layer.values))
+	for i, val := range layer.values {
+		if values[i], err = decodeValue(val); err != nil {
+			return nil, err
+		}
+	}
+	// the tables are rebuilt with only the entries in the matches
+	keys, vals := layer.keys, layer.values
+	layer.keys, layer.values = nil, nil
+	keymap := make(map[uint64]uint64)
+	valmap := make(map[uint64]uint64)
+	features := layer.features[:0]
+	for _, fmsg := range layer.features {
+		f, err := parseFeature(fmsg)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(f.tags); i += 2 {
+			if f.tags[i] >= uint64(len(keys)) ||
+				f.tags[i+1] >= uint64(len(vals)) {
+				return nil, fmt.Errorf("%w: tag index out of range",
+					ErrInvalidTile)
+			}
+		}
+		target := &filterTarget{geomType: f.geomType, id: f.id,
+			hasID: f.hasID, tag: func(key string) (interface{}, bool) {
+				for i := 0; i < len(f.tags); i += 2 {
+					if keys[f.tags[i]] == key {
+						return values[f.tags[i+1]], true
+					}
+				}
+				return nil, false
+			}}
+		if !filter.match(target) {
+			continue
+		}
+		for i := 0; i < len(f.tags); i += 2 {
+			k, ok := keymap[f.tags[i]]
+			if !ok {
+				k = uint64(len(layer.keys))
+				keymap[f.tags[i]] = k
+				layer.keys = append(layer.keys, keys[f.tags[i]])
+			}
+			v, ok := valmap[f.tags[i+1]]
+			if !ok {
+				v = uint64(len(layer.values))
+				valmap[f.tags[i+1]] = v
+				layer.values = append(layer.values, vals[f.tags[i+1]])
+			}
+			f.tags[i], f.tags[i+1] = k, v
+		}
+		features = append(features, f.append(nil))
+	}
+	layer.features = features
+	return layer.append(nil), nil
+}
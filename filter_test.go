@@ -0,0 +1,119 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("roads")
+	f := l.AddFeature(LineString)
+	f.SetID(7)
+	f.AddTag("class", "motorway")
+	f.AddTag("lanes", 4)
+	f.AddTag("toll", true)
+	for expr, expect := range map[string]bool{
+		`["==", "class", "motorway"]`:                  true,
+		`["==", "class", "primary"]`:                   false,
+		`["!=", "class", "primary"]`:                   true,
+		`["!=", "name", "Main St"]`:                    true,
+		`["==", "lanes", 4]`:                           true,
+		`["==", "lanes", "4"]`:                         false,
+		`[">", "lanes", 2]`:                            true,
+		`["<=", "lanes", 2]`:                           false,
+		`[">=", "class", "m"]`:                         true,
+		`["<", "name", "z"]`:                           false,
+		`["==", "toll", true]`:                         true,
+		`["in", "class", "trunk", "motorway"]`:         true,
+		`["!in", "class", "trunk", "motorway"]`:        false,
+		`["!in", "name", "x"]`:                         true,
+		`["has", "lanes"]`:                             true,
+		`["!has", "lanes"]`:                            false,
+		`["==", "$type", "LineString"]`:                true,
+		`["in", "$type", "Point", "Polygon"]`:          false,
+		`["==", "$id", 7]`:                             true,
+		`["all", ["has", "class"], [">", "lanes", 4]]`: false,
+		`["any", ["has", "name"], [">", "lanes", 3]]`:  true,
+		`["none", ["has", "name"], [">", "lanes", 4]]`: true,
+		`["none", ["has", "name"], [">", "lanes", 3]]`: false,
+		`["all"]`:                            true,
+		`["any"]`:                            false,
+		`["!", ["==", "class", "motorway"]]`: false,
+	} {
+		filter, err := ParseFilter(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if filter.Match(f) != expect {
+			t.Fatalf("%s: expected %t", expr, expect)
+		}
+	}
+	for _, expr := range []string{
+		`["==", "class"]`, `["has"]`, `["~=", "a", "b"]`, `"class"`, `[1]`,
+		`["==", "a", null]`, `["!", ["has", "a"], ["has", "b"]]`,
+		`["all", ["?"]]`, `[`,
+	} {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("%s: expected %v, got %v", expr, ErrInvalidFilter, err)
+		}
+	}
+}
+
+func TestFeatureFilter(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("roads")
+	l.SetAutoID(1)
+	for _, class := range []string{"motorway", "primary", "motorway"} {
+		f := l.AddFeature(LineString)
+		f.MoveTo(0, 0)
+		f.LineTo(1, 1)
+		f.AddTag("class", class)
+		f.AddTag("ref", class[:1])
+	}
+	filter, err := ParseFilter(`["==", "class", "motorway"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetFeatureFilter(filter)
+	if ids := featureIDs(l.render()); ids != "[1 3]" {
+		t.Fatalf("expected [1 3], got %s", ids)
+	}
+	l.SetFeatureFilter(nil)
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter, err = ParseFilter(`["!=", "class", "motorway"]`); err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := FilterTile(pb, filter, "roads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := fmt.Sprint(layerTags(t, filtered, "roads"))
+	if s != "[class=primary ref=p]" {
+		t.Fatalf("unexpected features %s", s)
+	}
+	// the tables only have the tags that remain
+	layers, _, err := splitTile(filtered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer, err := parseLayer(layers[0].msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layer.keys) != 2 || len(layer.values) != 2 {
+		t.Fatalf("unexpected tables %v %q", layer.keys, layer.values)
+	}
+	if filtered, err = FilterTile(pb, filter, "other"); err != nil ||
+		string(filtered) != string(pb) {
+		t.Fatal("expected the tile as it was")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// Space is the coordinates of the points of ForEachPoint and ForEachRing
+type Space int
+
+const (
+	// CanvasSpace is the x/y of the 512x512 canvas
+	CanvasSpace Space = iota
+	// LonLatSpace is the lon/lat degrees, which are placed using the
+	// z/x/y of the tile, see Tile.SetTileID
+	LonLatSpace
+)
+
+// project returns the canvas point in the space
+func (f *Feature) project(space Space, x, y float64) (float64, float64) {
+	if space != LonLatSpace {
+		return x, y
+	}
+	offX, offY := f.layer.tileOffset()
+	lat, lon := f.layer.fromPixel(offX+x, offY+y)
+	return lon, lat
+}
+
+// ForEachPoint calls fn with each point of the feature, in the order they
+// were drawn, until fn returns false. Such as for the features of a
+// decoded tile, see Decode.
+func (f *Feature) ForEachPoint(space Space, fn func(x, y float64) bool) {
+	coords := f.geom.coords
+	for i := 0; i < len(coords); i += 2 {
+		if !fn(f.project(space, coords[i], coords[i+1])) {
+			return
+		}
+	}
+}
+
+// ForEachRing calls fn with the points of each part of the feature, which
+// are the points of a Point, the lines of a LineString, and the rings of a
+// Polygon, until fn returns false. Each part begins with a MoveTo, and a
+// part that is closed with ClosePath repeats its first point at its end,
+// as in GeoJSON. The points are reused by the following calls.
+func (f *Feature) ForEachRing(space Space, fn func(ring [][2]float64) bool) {
+	var ring [][2]float64
+	var c int
+	for _, op := range f.geom.ops {
+		if op == closePath {
+			if len(ring) > 0 {
+				ring = append(ring, ring[0])
+			}
+			continue
+		}
+		if op == moveTo && len(ring) > 0 {
+			if !fn(ring) {
+				return
+			}
+			ring = ring[:0]
+		}
+		x, y := f.project(space, f.geom.coords[c], f.geom.coords[c+1])
+		ring = append(ring, [2]float64{x, y})
+		c += 2
+	}
+	if len(ring) > 0 {
+		fn(ring)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestForEachPoint(t *testing.T) {
+	tile, err := Decode(encodeDecodeTestTile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for _, f := range tile.GetLayer("shapes").Features() {
+		f.ForEachPoint(CanvasSpace, func(x, y float64) bool {
+			n++
+			return true
+		})
+	}
+	if n != 8 {
+		t.Fatalf("expected 8 points, got %d", n)
+	}
+	f := tile.GetLayer("shapes").Features()[1]
+	n = 0
+	f.ForEachPoint(CanvasSpace, func(x, y float64) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Fatalf("expected 2 points, got %d", n)
+	}
+
+	tile.SetTileID(TileID{Z: 1})
+	f = tile.AddLayer("geo").AddFeature(Point)
+	f.MoveTo(512, 512)
+	f.MoveTo(0, 256)
+	var points []string
+	f.ForEachPoint(LonLatSpace, func(lon, lat float64) bool {
+		points = append(points, fmt.Sprintf("%.4f,%.4f", lon, lat))
+		return true
+	})
+	if got := fmt.Sprint(points); got != "[0.0000,0.0000 -180.0000,66.5133]" {
+		t.Fatalf("unexpected points %s", got)
+	}
+}
+
+func TestForEachRing(t *testing.T) {
+	var tile Tile
+	f := tile.AddLayer("shapes").AddFeature(Polygon)
+	f.MoveTo(0, 0)
+	f.LineTo(10, 0)
+	f.LineTo(10, 10)
+	f.ClosePath()
+	f.MoveTo(2, 2)
+	f.LineTo(2, 8)
+	f.LineTo(8, 8)
+	f.ClosePath()
+	var rings []string
+	f.ForEachRing(CanvasSpace, func(ring [][2]float64) bool {
+		rings = append(rings, fmt.Sprint(ring))
+		return true
+	})
+	exp := "[[[0 0] [10 0] [10 10] [0 0]] [[2 2] [2 8] [8 8] [2 2]]]"
+	if got := fmt.Sprint(rings); got != exp {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+	tile.SetTileID(TileID{Z: 20, X: 1 << 19, Y: 1 << 19})
+	var area float64
+	f.ForEachRing(LonLatSpace, func(ring [][2]float64) bool {
+		for i := 1; i < len(ring); i++ {
+			area += ring[i-1][0]*ring[i][1] - ring[i][0]*ring[i-1][1]
+		}
+		return false
+	})
+	if area == 0 || math.Abs(area) > 1e-9 {
+		t.Fatalf("unexpected area %v", area)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// Geometry is a point, line, or polygon geometry in lat/lon degrees. Each
+// path is one point of a multipoint, one line of a multiline, or one ring
+// of a polygon, and each position is a lon/lat pair, as in GeoJSON.
+// Polygon rings may repeat their first position at their end.
+type Geometry struct {
+	Type  GeometryType
+	Paths [][][2]float64
+	// Rings is the number of rings of each polygon of a multipolygon, in
+	// the order of the paths. The first ring of each polygon is its shell
+	// and the others are its holes, no matter how they are wound. Nil is a
+	// single polygon, and rings past those that are counted are another.
+	Rings []int
+}
+
+// polygons returns the rings of each polygon of a Polygon geometry
+func (g Geometry) polygons() [][][][2]float64 {
+	var polys [][][][2]float64
+	paths := g.Paths
+	for _, n := range g.Rings {
+		n = max(0, min(n, len(paths)))
+		if n > 0 {
+			polys = append(polys, paths[:n])
+		}
+		paths = paths[n:]
+	}
+	if len(paths) > 0 {
+		polys = append(polys, paths)
+	}
+	return polys
+}
+
+// orient returns a copy of a Polygon geometry with the shell of each
+// polygon wound clockwise and its holes counterclockwise, which are wound
+// as the spec says once they are drawn on the canvas, where y is down.
+// Other geometries are returned as they are.
+func (g Geometry) orient() Geometry {
+	if g.Type != Polygon {
+		return g
+	}
+	out := Geometry{Type: Polygon, Rings: g.Rings,
+		Paths: make([][][2]float64, 0, len(g.Paths))}
+	for _, poly := range g.polygons() {
+		for i, ring := range poly {
+			if area := pathArea(ring); area != 0 && (area < 0) != (i == 0) {
+				rev := make([][2]float64, len(ring))
+				for j, p := range ring {
+					rev[len(ring)-1-j] = p
+				}
+				ring = rev
+			}
+			out.Paths = append(out.Paths, ring)
+		}
+	}
+	return out
+}
+
+// pathArea returns the signed area of the ring in degrees, which is
+// positive when it is wound counterclockwise.
+func pathArea(ring [][2]float64) float64 {
+	var area float64
+	for i := range ring {
+		p, q := ring[i], ring[(i+1)%len(ring)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	return area / 2
+}
+
+// Bounds returns the lat/lon bounds of the geometry
+func (g Geometry) Bounds() (minLat, minLon, maxLat, maxLon float64) {
+	minLat, minLon = math.Inf(1), math.Inf(1)
+	maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+	for _, path := range g.Paths {
+		for _, p := range path {
+			minLon, maxLon = math.Min(minLon, p[0]), math.Max(maxLon, p[0])
+			minLat, maxLat = math.Min(minLat, p[1]), math.Max(maxLat, p[1])
+		}
+	}
+	return minLat, minLon, maxLat, maxLon
+}
+
+// AddGeometry adds a feature that is drawn from the lat/lon geometry,
+// which is placed using the z/x/y of the tile, see Tile.SetTileID. The
+// rings of polygons are wound as the spec says, taking the first ring of
+// each polygon as its exterior and the others as its holes, see
+// Geometry.Rings, however they were wound before. The geometry
+// is clipped to the canvas, plus a small buffer, and nil is
+// returned without adding a feature when none of it is in the tile, or
+// when it is smaller than the min feature size, see SetMinFeatureSize.
+func (l *Layer) AddGeometry(g Geometry) *Feature {
+	if l.tooSmall(g) {
+		return nil
+	}
+	if l.densify > 0 {
+		g = g.DensifyGreatCircle(l.densify)
+	}
+	f := &Feature{geomType: g.Type, layer: l}
+	offX, offY := l.tileOffset()
+	f.geom = g.orient().pixels(l.toPixel, offX, offY)
+	geom := f.clip(clipRect{-clipBuffer, -clipBuffer,
+		gTileSize + clipBuffer, gTileSize + clipBuffer})
+	if len(geom.ops) == 0 {
+		return nil
+	}
+	f = l.AddFeature(g.Type)
+	f.geom = geom
+	if l.bboxes != 0 {
+		l.addBBoxes(f, g)
+	}
+	if l.elevation != nil {
+		l.addElevation(f, g)
+	}
+	if l.metrics {
+		l.addMetrics(f, g)
+	}
+	return f
+}
+
+// pixels returns the geometry on the whole map that toPixel converts to,
+// less the offset, such as that of a tile. Polygon rings are closed with a
+// ClosePath rather than by repeating their first position.
+func (g Geometry) pixels(toPixel func(lat, lon float64) (x, y float64),
+	offX, offY float64,
+) geometry {
+	var geom geometry
+	for _, path := range g.Paths {
+		if g.Type == Polygon && len(path) > 1 && path[0] == path[len(path)-1] {
+			path = path[:len(path)-1]
+		}
+		for i, p := range path {
+			x, y := toPixel(p[1], p[0])
+			which := lineTo
+			if i == 0 || g.Type == Point {
+				which = moveTo
+			}
+			geom.push(which, x-offX, y-offY)
+		}
+		if g.Type == Polygon && len(path) > 0 {
+			geom.push(closePath, 0, 0)
+		}
+	}
+	return geom
+}
+
+// cellPolygon returns the polygon of the boundary of a grid cell, such as
+// an H3 or S2 cell, from its lon/lat vertices. The ring is closed, and the
+// longitudes of a cell that crosses the antimeridian are unwrapped to be
+// continuous with its first vertex.
+func cellPolygon(vertices [][2]float64) Geometry {
+	ring := make([][2]float64, 0, len(vertices)+1)
+	for _, p := range vertices {
+		if len(ring) > 0 {
+			switch lon := ring[0][0]; {
+			case p[0]-lon > 180:
+				p[0] -= 360
+			case lon-p[0] > 180:
+				p[0] += 360
+			}
+		}
+		ring = append(ring, p)
+	}
+	if len(ring) > 0 {
+		ring = append(ring, ring[0])
+	}
+	return Geometry{Type: Polygon, Paths: [][][2]float64{ring}}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAddGeometry(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{Z: 1, X: 1, Y: 0})
+	l := tile.AddLayer("geo")
+	poly := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{-90, 0}, {90, 0}, {90, 45}, {-90, 45}, {-90, 0}},
+	}}
+	minLat, minLon, maxLat, maxLon := poly.Bounds()
+	if s := fmt.Sprint(minLat, minLon, maxLat, maxLon); s != "0 -90 45 90" {
+		t.Fatalf("unexpected bounds %s", s)
+	}
+	f := l.AddGeometry(poly)
+	if f == nil {
+		t.Fatal("expected a feature")
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	x0, _ := LatLonToPixel(0, 0, 1)
+	x1, _ := LatLonToPixel(0, 90, 1)
+	for i := 0; i < len(f.geom.coords); i += 2 {
+		x := f.geom.coords[i]
+		if x != -clipBuffer && x != x1-x0 {
+			t.Fatalf("unexpected x %v", x)
+		}
+	}
+	point := Geometry{Type: Point, Paths: [][][2]float64{{{-100, 10}}}}
+	if l.AddGeometry(point) != nil {
+		t.Fatal("expected no feature")
+	}
+	if len(l.Features()) != 1 {
+		t.Fatal("expected one feature")
+	}
+}
+
+func TestAddGeometryHoles(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("geo")
+	ccw := func(x0, y0, x1, y1 float64) [][2]float64 {
+		return [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {x0, y0}}
+	}
+	cw := func(x0, y0, x1, y1 float64) [][2]float64 {
+		return [][2]float64{{x0, y0}, {x0, y1}, {x1, y1}, {x1, y0}, {x0, y0}}
+	}
+	// the hole of the first polygon winds like its shell, and the second
+	// polygon winds the other way
+	multi := Geometry{Type: Polygon, Paths: [][][2]float64{
+		ccw(0, 0, 40, 40), ccw(10, 10, 20, 20), cw(-40, 0, -10, 30),
+	}, Rings: []int{2, 1}}
+	f := l.AddGeometry(multi)
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var signs []bool
+	for _, path := range f.paths() {
+		signs = append(signs, ringArea(pathPoints(path)) > 0)
+	}
+	if s := fmt.Sprint(signs); s != "[true false true]" {
+		t.Fatalf("expected exterior, hole, exterior, got %s", s)
+	}
+	// without rings it is one polygon, whose other rings are holes
+	multi.Rings = nil
+	f = l.AddGeometry(multi)
+	signs = signs[:0]
+	for _, path := range f.paths() {
+		signs = append(signs, ringArea(pathPoints(path)) > 0)
+	}
+	if s := fmt.Sprint(signs); s != "[true false false]" {
+		t.Fatalf("expected exterior, hole, hole, got %s", s)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("lines")
+	f := l.AddFeature(LineString)
+	f.MoveTo(0, 0)
+	f.LineTo(10, 0.5)
+	f.LineTo(20, 0)
+	f.LineTo(20, 10)
+	f.Simplify(1)
+	if s := fmt.Sprint(f.geom.coords); s != "[0 0 20 0 20 10]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+	tri := l.AddFeature(Polygon)
+	tri.MoveTo(0, 0)
+	tri.LineTo(1, 0)
+	tri.LineTo(0, 1)
+	tri.ClosePath()
+	tri.Simplify(5)
+	if len(tri.geom.coords) != 6 {
+		t.Fatal("expected the ring to be left as it is")
+	}
+}
+
+func TestCellPolygon(t *testing.T) {
+	g := cellPolygon([][2]float64{{10, 10}, {11, 10}, {11, 11}})
+	if s := fmt.Sprint(g); s != "{3 [[[10 10] [11 10] [11 11] [10 10]]] []}" {
+		t.Fatalf("unexpected polygon %s", s)
+	}
+	// across the antimeridian
+	g = cellPolygon([][2]float64{{179, 0}, {-179, 0}, {-179, 1}, {179, 1}})
+	if s := fmt.Sprint(g); s !=
+		"{3 [[[179 0] [181 0] [181 1] [179 1] [179 0]]] []}" {
+		t.Fatalf("unexpected polygon %s", s)
+	}
+}
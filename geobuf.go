@@ -0,0 +1,320 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrInvalidGeobuf is returned for geobuf data that is malformed or of a
+// type that is not supported.
+var ErrInvalidGeobuf = errors.New("invalid geobuf")
+
+// Geobuf geometry types
+const (
+	geobufPoint = iota
+	geobufMultiPoint
+	geobufLineString
+	geobufMultiLineString
+	geobufPolygon
+	geobufMultiPolygon
+	geobufGeometryCollection
+)
+
+// GeobufReader is a FeatureSource that reads the features of geobuf data,
+// which is a protobuf encoding of a GeoJSON feature collection, feature,
+// or geometry. Any elevations are dropped.
+type GeobufReader struct {
+	keys       []string
+	dims       int
+	precision  float64
+	collection pbfReader // the remaining fields of a feature collection
+	single     []byte    // a feature or geometry that is not in a collection
+	singleGeom bool
+	n          int
+	policy     ErrorPolicy
+}
+
+// NewGeobufReader returns a reader of the geobuf data
+func NewGeobufReader(data []byte) (*GeobufReader, error) {
+	r := &GeobufReader{dims: 2, precision: 1e6, policy: StopOnError}
+	pr := pbfReader{data: data}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			r.keys = append(r.keys, string(pr.bytes()))
+		case field == 2 && wire == pbfVarint:
+			r.dims = int(pr.uvarint())
+		case field == 3 && wire == pbfVarint:
+			r.precision = math.Pow(10, float64(pr.uvarint()))
+		case field == 4 && wire == pbfBytes:
+			r.collection = pbfReader{data: pr.bytes()}
+		case (field == 5 || field == 6) && wire == pbfBytes:
+			r.single = pr.bytes()
+			r.singleGeom = field == 6
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGeobuf, pr.err)
+	}
+	if r.dims < 2 || r.dims > 4 {
+		return nil, fmt.Errorf("%w: %d dimensions", ErrInvalidGeobuf, r.dims)
+	}
+	return r, nil
+}
+
+// SetErrorPolicy sets what the reader does with invalid features, which
+// are numbered from 1 in place of a line. Default is StopOnError.
+func (r *GeobufReader) SetErrorPolicy(policy ErrorPolicy) {
+	r.policy = policy
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (r *GeobufReader) Next() (geom Geometry, tags map[string]interface{},
+	id interface{}, err error) {
+	for {
+		msg, isGeom := r.single, r.singleGeom
+		r.single = nil
+		for msg == nil {
+			field, wire, ok := r.collection.next()
+			if !ok {
+				break
+			}
+			if field == 1 && wire == pbfBytes {
+				msg = r.collection.bytes()
+			} else {
+				r.collection.skip(wire)
+			}
+		}
+		if r.collection.err != nil {
+			return Geometry{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidGeobuf,
+				r.collection.err)
+		}
+		if msg == nil {
+			return Geometry{}, nil, nil, io.EOF
+		}
+		r.n++
+		if isGeom {
+			geom, err = r.geometry(msg)
+		} else {
+			geom, tags, id, err = r.feature(msg)
+		}
+		if err == nil {
+			return geom, tags, id, nil
+		}
+		if err := r.policy(r.n, err); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+	}
+}
+
+// feature parses a feature message
+func (r *GeobufReader) feature(msg []byte) (geom Geometry,
+	tags map[string]interface{}, id interface{}, err error) {
+	var values []interface{}
+	var props []uint64
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			if geom, err = r.geometry(pr.bytes()); err != nil {
+				return Geometry{}, nil, nil, err
+			}
+		case field == 11 && wire == pbfBytes:
+			id = string(pr.bytes())
+		case field == 12 && wire == pbfVarint:
+			id = pr.varint()
+		case field == 13 && wire == pbfBytes:
+			var value interface{}
+			if value, err = geobufValue(pr.bytes()); err != nil {
+				return Geometry{}, nil, nil, err
+			}
+			values = append(values, value)
+		case field == 14:
+			props = append(props, pr.packed(wire)...)
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return Geometry{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidGeobuf,
+			pr.err)
+	}
+	tags = make(map[string]interface{}, len(props)/2)
+	for i := 0; i+1 < len(props); i += 2 {
+		if props[i] >= uint64(len(r.keys)) ||
+			props[i+1] >= uint64(len(values)) {
+			return Geometry{}, nil, nil, fmt.Errorf("%w: property out of "+
+				"range", ErrInvalidGeobuf)
+		}
+		tags[r.keys[props[i]]] = values[props[i+1]]
+	}
+	return geom, tags, id, nil
+}
+
+// geobufValue parses a value message
+func geobufValue(msg []byte) (interface{}, error) {
+	var value interface{}
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			value = string(pr.bytes())
+		case field == 2 && wire == pbfFixed64:
+			value = pr.double()
+		case field == 3 && wire == pbfVarint:
+			if n := pr.uvarint(); n > math.MaxInt64 {
+				value = n
+			} else {
+				value = int64(n)
+			}
+		case field == 4 && wire == pbfVarint:
+			value = -int64(pr.uvarint())
+		case field == 5 && wire == pbfVarint:
+			value = pr.uvarint() != 0
+		case field == 6 && wire == pbfBytes:
+			dec := json.NewDecoder(bytes.NewReader(pr.bytes()))
+			dec.UseNumber()
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidGeobuf, err)
+			}
+			value = jsonValue(value)
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGeobuf, pr.err)
+	}
+	return value, nil
+}
+
+// geometry parses a geometry message, whose coordinates are delta
+// encoded within each part.
+func (r *GeobufReader) geometry(msg []byte) (Geometry, error) {
+	typ := uint64(geobufPoint)
+	var lengths []uint64
+	var coords []int64
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfVarint:
+			typ = pr.uvarint()
+		case field == 2:
+			lengths = append(lengths, pr.packed(wire)...)
+		case field == 3:
+			for _, n := range pr.packed(wire) {
+				coords = append(coords, unzigzag(n))
+			}
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return Geometry{}, fmt.Errorf("%w: %v", ErrInvalidGeobuf, pr.err)
+	}
+	bad := fmt.Errorf("%w: bad lengths", ErrInvalidGeobuf)
+	// part returns n points of the coordinates as a path
+	part := func(n uint64, closed bool) ([][2]float64, bool) {
+		if n > uint64(len(coords)/r.dims) {
+			return nil, false
+		}
+		path := make([][2]float64, 0, n+1)
+		var x, y int64
+		for i := uint64(0); i < n; i++ {
+			x, y = x+coords[0], y+coords[1]
+			coords = coords[r.dims:]
+			path = append(path, [2]float64{float64(x) / r.precision,
+				float64(y) / r.precision})
+		}
+		if closed && len(path) > 0 {
+			path = append(path, path[0])
+		}
+		return path, true
+	}
+	all := uint64(len(coords) / r.dims)
+	var g Geometry
+	switch typ {
+	case geobufPoint:
+		g.Type = Point
+		if len(coords) >= 2 {
+			g.Paths = [][][2]float64{{{float64(coords[0]) / r.precision,
+				float64(coords[1]) / r.precision}}}
+		}
+	case geobufMultiPoint:
+		g.Type = Point
+		path, _ := part(all, false)
+		for _, p := range path {
+			g.Paths = append(g.Paths, [][2]float64{p})
+		}
+	case geobufLineString, geobufMultiLineString, geobufPolygon:
+		g.Type = LineString
+		if typ == geobufPolygon {
+			g.Type = Polygon
+		}
+		if len(lengths) == 0 {
+			lengths = []uint64{all}
+		}
+		for _, n := range lengths {
+			path, ok := part(n, typ == geobufPolygon)
+			if !ok {
+				return Geometry{}, bad
+			}
+			g.Paths = append(g.Paths, path)
+		}
+	case geobufMultiPolygon:
+		g.Type = Polygon
+		if len(lengths) == 0 {
+			path, _ := part(all, true)
+			g.Paths = append(g.Paths, path)
+			break
+		}
+		// the number of polygons, then for each the number of rings
+		// followed by their lengths
+		npolys, lengths := lengths[0], lengths[1:]
+		for i := uint64(0); i < npolys; i++ {
+			if len(lengths) == 0 || lengths[0] >= uint64(len(lengths)) {
+				return Geometry{}, bad
+			}
+			nrings := lengths[0]
+			for _, n := range lengths[1 : 1+nrings] {
+				path, ok := part(n, true)
+				if !ok {
+					return Geometry{}, bad
+				}
+				g.Paths = append(g.Paths, path)
+			}
+			g.Rings = append(g.Rings, int(nrings))
+			lengths = lengths[1+nrings:]
+		}
+	default:
+		return Geometry{}, fmt.Errorf("%w: unsupported geometry type %d",
+			ErrInvalidGeobuf, typ)
+	}
+	return g, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// pbBytes returns a length-delimited field
+func pbBytes(field int, b []byte) []byte {
+	pb := appendUvarint(nil, uint64(field<<3|pbfBytes))
+	pb = appendUvarint(pb, uint64(len(b)))
+	return append(pb, b...)
+}
+
+// pbVarint returns a varint field
+func pbVarint(field int, n uint64) []byte {
+	return appendUvarint(appendUvarint(nil, uint64(field<<3|pbfVarint)), n)
+}
+
+// geobufGeom returns a geometry message with packed lengths and zigzag
+// encoded coordinates.
+func geobufGeom(typ uint64, lengths []uint64, coords ...int64) []byte {
+	pb := pbVarint(1, typ)
+	if len(lengths) > 0 {
+		pb = appendPacked(pb, 2<<3|pbfBytes, lengths)
+	}
+	var cpb []byte
+	for _, n := range coords {
+		cpb = appendVarint(cpb, n)
+	}
+	return append(pb, pbBytes(3, cpb)...)
+}
+
+// testGeobuf returns a collection of four features, with three dimensions
+// and a precision of one decimal.
+func testGeobuf() []byte {
+	str := func(s string) []byte { return pbBytes(1, []byte(s)) }
+	features := [][]byte{
+		append(append(append(append(append(
+			pbBytes(1, geobufGeom(geobufPoint, nil, -1119, 334, 500)),
+			pbVarint(12, 7<<1)...),
+			pbBytes(13, str("tempe"))...),
+			pbBytes(13, pbVarint(3, 180000))...),
+			pbBytes(13, pbBytes(6, []byte(`{"a":1}`)))...),
+			appendPacked(nil, 14<<3|pbfBytes, []int{0, 0, 1, 1, 2, 2})...),
+		append(append(append(
+			pbBytes(1, geobufGeom(geobufMultiPolygon, []uint64{2, 1, 3, 1, 3},
+				0, 0, 0, 10, 0, 0, 0, 10, 0, 20, 20, 0, 10, 0, 0, 0, 10, 0)),
+			pbBytes(11, []byte("x"))...),
+			pbBytes(13, pbVarint(4, 5))...),
+			appendPacked(nil, 14<<3|pbfBytes, []int{1, 0})...),
+		pbBytes(1, geobufGeom(geobufGeometryCollection, nil)),
+		pbBytes(1, geobufGeom(geobufLineString, nil, 0, 0, 0, 10, 10, 0)),
+	}
+	var coll []byte
+	for _, f := range features {
+		coll = append(coll, pbBytes(1, f)...)
+	}
+	var data []byte
+	for _, key := range []string{"name", "pop", "meta"} {
+		data = append(data, pbBytes(1, []byte(key))...)
+	}
+	data = append(data, pbVarint(2, 3)...)
+	data = append(data, pbVarint(3, 1)...)
+	return append(data, pbBytes(4, coll)...)
+}
+
+func TestGeobufReader(t *testing.T) {
+	data := testGeobuf()
+	r, err := NewGeobufReader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geom, tags, id, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprintf("%v %v %v", geom, tags, id); s !=
+		"{1 [[[-111.9 33.4]]] []} map[meta:map[a:1] name:tempe pop:180000] 7" {
+		t.Fatalf("unexpected feature %s", s)
+	}
+	if tags["pop"] != int64(180000) || id != int64(7) {
+		t.Fatalf("expected int64 values, got %T and %T", tags["pop"], id)
+	}
+	geom, tags, id, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprintf("%v %v %v", geom, tags, id); s != "{3 [[[0 0] [1 0] [1 1] [0 0]] "+
+		"[[2 2] [3 2] [3 3] [2 2]]] [1 1]} map[pop:-5] x" {
+		t.Fatalf("unexpected feature %s", s)
+	}
+	if _, _, _, err = r.Next(); !errors.Is(err, ErrInvalidGeobuf) {
+		t.Fatalf("expected ErrInvalidGeobuf, got %v", err)
+	}
+
+	r, _ = NewGeobufReader(data)
+	r.SetErrorPolicy(SkipInvalid)
+	features := readAllGeo(t, r)
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	if s := fmt.Sprint(features[2].Geometry); s != "{2 [[[0 0] [1 1]]] []}" {
+		t.Fatalf("unexpected geometry %s", s)
+	}
+
+	// a single geometry
+	r, err = NewGeobufReader(pbBytes(6, geobufGeom(geobufPolygon, nil,
+		0, 0, 1000000, 0, 0, 1000000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	features = readAllGeo(t, r)
+	if len(features) != 1 || fmt.Sprint(features[0].Geometry) !=
+		"{3 [[[0 0] [1 0] [1 1] [0 0]]] []}" {
+		t.Fatalf("unexpected features %v", features)
+	}
+
+	if _, err := NewGeobufReader(data[:len(data)-1]); !errors.Is(err,
+		ErrInvalidGeobuf) {
+		t.Fatalf("expected ErrInvalidGeobuf, got %v", err)
+	}
+	r, _ = NewGeobufReader(pbBytes(6, geobufGeom(geobufMultiPolygon,
+		[]uint64{1, 2, 3}, 0, 0, 1, 1)))
+	if _, _, _, err := r.Next(); !errors.Is(err, ErrInvalidGeobuf) {
+		t.Fatalf("expected ErrInvalidGeobuf, got %v", err)
+	}
+	if v, _ := geobufValue(pbVarint(3, math.MaxUint64)); v != uint64(math.MaxUint64) {
+		t.Fatalf("expected a uint64, got %T", v)
+	}
+}
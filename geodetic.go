@@ -0,0 +1,168 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// The geodetic scheme tiles plain WGS84 (EPSG:4326) lat/lons, with two
+// tiles side by side at zoom 0, each covering 180 degrees of longitude and
+// all of latitude. It is the WorldCRS84Quad grid that Cesium and many
+// terrain pipelines use.
+
+// TilingScheme is the grid of tiles that the z/x/y of a tile is on, which
+// places the lat/lons of its geometries, see Tile.SetTilingScheme.
+type TilingScheme int
+
+const (
+	// WebMercator is the EPSG:3857 scheme of XYZ tiles, with one tile at
+	// zoom 0, see LatLonXY.
+	WebMercator TilingScheme = iota
+	// Geodetic is the EPSG:4326 scheme, with two tiles at zoom 0, see
+	// LatLonXYGeodetic.
+	Geodetic
+)
+
+// SetTilingScheme sets the tiling scheme of the tile, which places the
+// lat/lon geometries that are added with AddGeometry, AddGeoFeature,
+// AddFrom, and AddCircleLatLon, along with their labels and centroids, and
+// the points of ForEachPoint and ForEachRing in LonLatSpace. Overzoom keeps
+// the scheme of the tile. It is not used for a tile that has a tile matrix,
+// see SetTileMatrix. Default is WebMercator.
+func (t *Tile) SetTilingScheme(scheme TilingScheme) {
+	t.scheme = scheme
+}
+
+// projection returns the tiling scheme and the tile matrix, if any, of the
+// tile of the layer
+func (l *Layer) projection() (TilingScheme, *tileMatrix) {
+	if l == nil || l.tile == nil {
+		return WebMercator, nil
+	}
+	return l.tile.scheme, l.tile.matrix
+}
+
+// toPixel converts a lat/lon to a pixel on the whole map of the tile of the
+// layer, which is the canvas of the tile itself for a tile matrix.
+func (l *Layer) toPixel(lat, lon float64) (x, y float64) {
+	scheme, tm := l.projection()
+	if tm != nil {
+		return tm.toPixel(lat, lon, l.tileID())
+	}
+	return scheme.latLonToPixel(lat, lon, l.tileID().Z)
+}
+
+// fromPixel converts a pixel on the whole map of the tile of the layer to a
+// lat/lon, see toPixel.
+func (l *Layer) fromPixel(x, y float64) (lat, lon float64) {
+	scheme, tm := l.projection()
+	if tm != nil {
+		return tm.fromPixel(x, y, l.tileID())
+	}
+	return scheme.pixelToLatLon(x, y, l.tileID().Z)
+}
+
+// tileOffset returns the pixel of the northwest corner of the tile of the
+// layer on its whole map, see toPixel.
+func (l *Layer) tileOffset() (x, y float64) {
+	if _, tm := l.projection(); tm != nil {
+		return 0, 0
+	}
+	id := l.tileID()
+	return float64(id.X * gTileSize), float64(id.Y * gTileSize)
+}
+
+// metersPerPixel returns the ground resolution of a pixel of the tile of
+// the layer at the latitude.
+func (l *Layer) metersPerPixel(lat float64) float64 {
+	scheme, tm := l.projection()
+	if tm != nil {
+		return tm.m.CellSize * float64(tm.m.TileWidth) / gTileSize
+	}
+	return scheme.metersPerPixel(l.tileID().Z, lat)
+}
+
+// latLonToPixel converts a lat/lon to a pixel on the whole map of the
+// scheme at the zoom, see LatLonToPixel.
+func (s TilingScheme) latLonToPixel(lat, lon float64, z int) (x, y float64) {
+	if s != Geodetic {
+		return LatLonToPixel(lat, lon, z)
+	}
+	span := geodeticSpan(z)
+	lat = clamp(lat, -90, 90)
+	lon = clamp(lon, gMinLon, gMaxLon)
+	return (lon + 180) / span * gTileSize, (90 - lat) / span * gTileSize
+}
+
+// pixelToLatLon converts a pixel on the whole map of the scheme at the
+// zoom to a lat/lon, see PixelToLatLon.
+func (s TilingScheme) pixelToLatLon(x, y float64, z int) (lat, lon float64) {
+	if s != Geodetic {
+		return PixelToLatLon(x, y, z)
+	}
+	span := geodeticSpan(z)
+	n := float64(uint64(1) << uint(z))
+	x = clamp(x/gTileSize, 0, 2*n)
+	y = clamp(y/gTileSize, 0, n)
+	return 90 - y*span, x*span - 180
+}
+
+// metersPerPixel returns the ground resolution of a tile pixel of the
+// scheme at the zoom and latitude.
+func (s TilingScheme) metersPerPixel(z int, lat float64) float64 {
+	if s != Geodetic {
+		return MetersPerPixel(z, lat)
+	}
+	return MetersPerPixelGeodetic(z, lat)
+}
+
+// geodeticSpan returns the width and height of a geodetic tile in degrees.
+func geodeticSpan(tileZ int) float64 {
+	return 180 / float64(uint64(1)<<uint(tileZ))
+}
+
+// LatLonXYGeodetic converts a lat/lon to a point x/y for the specified map
+// tile in the geodetic scheme. The tile is 512x512.
+func LatLonXYGeodetic(lat, lon float64, tileX, tileY, tileZ int,
+) (x, y float64) {
+	span := geodeticSpan(tileZ)
+	lat = clamp(lat, -90, 90)
+	lon = clamp(lon, gMinLon, gMaxLon)
+	x = ((lon+180)/span - float64(tileX)) * gTileSize
+	y = ((90-lat)/span - float64(tileY)) * gTileSize
+	return x, y
+}
+
+// TileBoundsGeodetic returns the lat/lon bounds around a tile in the
+// geodetic scheme.
+func TileBoundsGeodetic(tileX, tileY, tileZ int,
+) (minLat, minLon, maxLat, maxLon float64) {
+	span := geodeticSpan(tileZ)
+	minLon = -180 + float64(tileX)*span
+	maxLat = 90 - float64(tileY)*span
+	return maxLat - span, minLon, maxLat, minLon + span
+}
+
+// LatLonToTileGeodetic returns the map tile at the zoom in the geodetic
+// scheme that contains a lat/lon, along with the point x/y of the lat/lon
+// within that tile. The tile is 512x512.
+func LatLonToTileGeodetic(lat, lon float64, tileZ int,
+) (tileX, tileY int, x, y float64) {
+	span := geodeticSpan(tileZ)
+	n := float64(uint64(1) << uint(tileZ))
+	tileX = int(clamp(math.Floor((clamp(lon, gMinLon, gMaxLon)+180)/span),
+		0, 2*n-1))
+	tileY = int(clamp(math.Floor((90-clamp(lat, -90, 90))/span), 0, n-1))
+	x, y = LatLonXYGeodetic(lat, lon, tileX, tileY, tileZ)
+	return tileX, tileY, x, y
+}
+
+// MetersPerPixelGeodetic returns the east-west ground resolution of a tile
+// pixel in the geodetic scheme at the zoom and latitude. The tile is
+// 512x512. It is half that of the Web Mercator scheme, as the geodetic
+// map is twice as wide at each zoom.
+func MetersPerPixelGeodetic(tileZ int, lat float64) float64 {
+	return math.Cos(lat*math.Pi/180) * geodeticSpan(tileZ) / gTileSize *
+		math.Pi / 180 * earthRadius
+}
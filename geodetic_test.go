@@ -0,0 +1,92 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestGeodetic(t *testing.T) {
+	minLat, minLon, maxLat, maxLon := TileBoundsGeodetic(1, 0, 0)
+	if minLat != -90 || minLon != 0 || maxLat != 90 || maxLon != 180 {
+		t.Fatalf("unexpected bounds %f %f %f %f", minLat, minLon, maxLat, maxLon)
+	}
+	minLat, minLon, maxLat, maxLon = TileBoundsGeodetic(2, 1, 1)
+	if minLat != -90 || minLon != 0 || maxLat != 0 || maxLon != 90 {
+		t.Fatalf("unexpected bounds %f %f %f %f", minLat, minLon, maxLat, maxLon)
+	}
+	x, y := LatLonXYGeodetic(-45, 45, 2, 1, 1)
+	if x != 256 || y != 256 {
+		t.Fatalf("unexpected point %f %f", x, y)
+	}
+	tx, ty, x, y := LatLonToTileGeodetic(-45, 45, 1)
+	if tx != 2 || ty != 1 || x != 256 || y != 256 {
+		t.Fatalf("unexpected tile %d/%d %f %f", tx, ty, x, y)
+	}
+	tx, ty, _, _ = LatLonToTileGeodetic(-90, 180, 0)
+	if tx != 1 || ty != 0 {
+		t.Fatalf("unexpected tile %d/%d", tx, ty)
+	}
+}
+
+func TestTilingScheme(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{1, 2, 1})
+	tile.SetTilingScheme(Geodetic)
+	l := tile.AddLayer("geo")
+	f := l.AddGeometry(Geometry{Type: Point,
+		Paths: [][][2]float64{{{45, -45}}}})
+	if f == nil {
+		t.Fatal("expected a feature")
+	}
+	var pts []float64
+	f.ForEachPoint(CanvasSpace, func(x, y float64) bool {
+		pts = append(pts, x, y)
+		return true
+	})
+	f.ForEachPoint(LonLatSpace, func(lon, lat float64) bool {
+		pts = append(pts, lon, lat)
+		return true
+	})
+	if s := fmt.Sprint(pts); s != "[256 256 45 -45]" {
+		t.Fatalf("unexpected points %s", s)
+	}
+	// in the eastern half of the map, which Web Mercator puts in 1/1/0
+	f = l.AddGeometry(Geometry{Type: Point,
+		Paths: [][][2]float64{{{100, 10}}}})
+	if f != nil {
+		t.Fatal("expected no feature")
+	}
+	ct, err := tile.Overzoom(2, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := ct.Layers()[0]
+	if len(cl.Features()) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(cl.Features()))
+	}
+	cl.Features()[0].ForEachPoint(LonLatSpace, func(lon, lat float64) bool {
+		if lon != 45 || lat != -45 {
+			t.Fatalf("unexpected point %f %f", lon, lat)
+		}
+		return true
+	})
+	var merc Tile
+	merc.SetTileID(TileID{1, 1, 1})
+	circle := l.AddCircleLatLon(-45, 45, 1000000)
+	if circle == nil {
+		t.Fatal("expected a circle")
+	}
+	other := merc.AddLayer("merc").AddCircleLatLon(-45, 45, 1000000)
+	if n, m := len(circle.geom.ops), len(other.geom.ops); n <= m {
+		t.Fatalf("expected more vertices than %d, got %d", m, n)
+	}
+	mpp := MetersPerPixelGeodetic(0, 0)
+	if math.Abs(mpp*2-MetersPerPixel(0, 0)) > 1e-9 {
+		t.Fatalf("unexpected resolution %f", mpp)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// geometry is the packed drawing commands of a feature. ops holds one
+// entry per command and coords holds the x/y pair of each MoveTo and
+// LineTo, in the same order. This takes 17 bytes per vertex, compared to
+// 24 for a command. zs holds the elevation of each vertex once one has
+// been drawn in 3D, and is otherwise nil.
+type geometry struct {
+	ops    []byte
+	coords []float64
+	zs     []float64
+}
+
+// push appends a command. A ClosePath has no coordinates.
+func (g *geometry) push(which int, x, y float64) {
+	g.ops = append(g.ops, byte(which))
+	if which != closePath {
+		g.coords = append(g.coords, x, y)
+		if g.zs != nil {
+			g.zs = append(g.zs, 0)
+		}
+	}
+}
+
+// push3 appends a MoveTo or LineTo with an elevation. The vertices that
+// were drawn before without one are at zero.
+func (g *geometry) push3(which int, x, y, z float64) {
+	if g.zs == nil {
+		g.zs = make([]float64, len(g.coords)/2, cap(g.coords)/2+1)
+	}
+	g.ops = append(g.ops, byte(which))
+	g.coords = append(g.coords, x, y)
+	g.zs = append(g.zs, z)
+}
+
+// elevations returns the elevations of the vertices, or nil when there are
+// none or when they no longer match the vertices, such as after the
+// geometry was rebuilt by clipping.
+func (g *geometry) elevations() []float64 {
+	if len(g.zs) == 0 || len(g.zs)*2 != len(g.coords) {
+		return nil
+	}
+	return g.zs
+}
+
+// lastOp returns the last command, or zero when there are none.
+func (g *geometry) lastOp() int {
+	if len(g.ops) == 0 {
+		return 0
+	}
+	return int(g.ops[len(g.ops)-1])
+}
+
+// current returns the point that the last command ended on. Following a
+// ClosePath, or with no commands, this is the origin.
+func (g *geometry) current() (x, y float64) {
+	if len(g.ops) == 0 || g.lastOp() == closePath {
+		return 0, 0
+	}
+	return g.coords[len(g.coords)-2], g.coords[len(g.coords)-1]
+}
+
+// commands unpacks the geometry into commands.
+func (g *geometry) commands() []command {
+	cmds := make([]command, len(g.ops))
+	var c int
+	for i, op := range g.ops {
+		cmds[i].which = int(op)
+		if op != closePath {
+			cmds[i].x, cmds[i].y = g.coords[c], g.coords[c+1]
+			c += 2
+		}
+	}
+	return cmds
+}
+
+// packCommands packs the commands into a geometry.
+func packCommands(cmds []command) geometry {
+	var g geometry
+	g.ops = make([]byte, 0, len(cmds))
+	g.coords = make([]float64, 0, len(cmds)*2)
+	for _, cmd := range cmds {
+		g.push(cmd.which, cmd.x, cmd.y)
+	}
+	return g
+}
+
+// area returns the area of a Polygon feature on the 512x512 canvas, with
+// the area of its holes taken out. Other features have no area.
+func (f *Feature) area() float64 {
+	if f.geomType != Polygon {
+		return 0
+	}
+	var area float64
+	for _, path := range f.paths() {
+		area += ringArea(pathPoints(path))
+	}
+	return math.Abs(area)
+}
+
+// length returns the length of a LineString feature, or the perimeter of
+// a Polygon feature, on the 512x512 canvas. Points have no length.
+func (f *Feature) length() float64 {
+	if f.geomType != LineString && f.geomType != Polygon {
+		return 0
+	}
+	var length float64
+	for _, path := range f.paths() {
+		points := pathPoints(path)
+		if f.geomType == Polygon && len(points) > 0 {
+			points = append(points, points[0])
+		}
+		for i := 1; i < len(points); i++ {
+			length += math.Hypot(points[i].x-points[i-1].x,
+				points[i].y-points[i-1].y)
+		}
+	}
+	return length
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPackedGeometry(t *testing.T) {
+	cmds := []command{
+		{moveTo, 1, 2}, {lineTo, 3, 4}, {lineTo, 5, 6}, {closePath, 0, 0},
+		{moveTo, 7, 8},
+	}
+	g := packCommands(cmds)
+	if len(g.ops) != 5 || len(g.coords) != 8 {
+		t.Fatalf("unexpected sizes %d %d", len(g.ops), len(g.coords))
+	}
+	if fmt.Sprint(g.commands()) != fmt.Sprint(cmds) {
+		t.Fatalf("expected %v, got %v", cmds, g.commands())
+	}
+	if x, y := g.current(); x != 7 || y != 8 {
+		t.Fatalf("unexpected current point %f %f", x, y)
+	}
+	g.push(closePath, 0, 0)
+	if x, y := g.current(); x != 0 || y != 0 || g.lastOp() != closePath {
+		t.Fatalf("unexpected current point %f %f", x, y)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidGeoPackage is returned for a GeoPackage that is malformed or
+// uses a feature that is not supported.
+var ErrInvalidGeoPackage = errors.New("invalid geopackage")
+
+// GeoPackageReader is a FeatureSource that reads the features of a table
+// of a GeoPackage, with its columns as tags and its primary key as ids.
+// The database is opened with any database/sql driver for SQLite, which
+// this package does not import. Coordinates must be lon/lat (EPSG:4326) or
+// Web Mercator (EPSG:3857) meters.
+type GeoPackageReader struct {
+	db       *sql.DB
+	table    string
+	geomCol  string
+	pkCol    string
+	rtree    string
+	mercator bool
+	bounds   *[4]float64 // minX, minY, maxX, maxY in the table coordinates
+	rows     *sql.Rows
+	cols     []string
+}
+
+// quoteIdent quotes an SQL identifier
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// NewGeoPackageReader returns a reader of the features of the table, which
+// must be listed in gpkg_geometry_columns.
+func NewGeoPackageReader(db *sql.DB, table string) (*GeoPackageReader, error) {
+	r := &GeoPackageReader{db: db, table: table}
+	var srsID int64
+	err := db.QueryRow(`SELECT column_name, srs_id FROM gpkg_geometry_columns
+		WHERE table_name = ?`, table).Scan(&r.geomCol, &srsID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: no geometry column for %q",
+			ErrInvalidGeoPackage, table)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var org string
+	var code int64
+	err = db.QueryRow(`SELECT organization, organization_coordsys_id
+		FROM gpkg_spatial_ref_sys WHERE srs_id = ?`, srsID).Scan(&org, &code)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	switch {
+	case err == sql.ErrNoRows || srsID == 0 || srsID == -1:
+		// undefined systems are taken to be lon/lat
+	case strings.EqualFold(org, "epsg") && code == 4326:
+	case strings.EqualFold(org, "epsg") && code == 3857:
+		r.mercator = true
+	default:
+		return nil, fmt.Errorf("%w: unsupported srs %s:%d",
+			ErrInvalidGeoPackage, org, code)
+	}
+	// the primary key, which the rtree refers to
+	rows, err := db.Query(`PRAGMA table_info(` + quoteIdent(table) + `)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int64
+		var name, typ string
+		var def interface{}
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+		if pk == 1 {
+			r.pkCol = name
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rtree := "rtree_" + table + "_" + r.geomCol
+	var name string
+	err = db.QueryRow(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name = ?`, rtree).Scan(&name)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && r.pkCol != "" {
+		r.rtree = rtree
+	}
+	return r, nil
+}
+
+// Filter sets the reader to only return the features whose bounds
+// intersect the lat/lon bounds. It must be called before the first Next.
+// The rtree index of the table is used when it has one, otherwise all
+// features are read and filtered.
+func (r *GeoPackageReader) Filter(minLat, minLon, maxLat, maxLon float64,
+) error {
+	if r.rows != nil {
+		return errors.New("filter after reading features")
+	}
+	b := [4]float64{minLon, minLat, maxLon, maxLat}
+	if r.mercator {
+		b[0], b[1] = lonLatMercator(minLon, minLat)
+		b[2], b[3] = lonLatMercator(maxLon, maxLat)
+	}
+	r.bounds = &b
+	return nil
+}
+
+// Close stops reading before all features are read
+func (r *GeoPackageReader) Close() error {
+	if r.rows != nil {
+		return r.rows.Close()
+	}
+	return nil
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (r *GeoPackageReader) Next() (geom Geometry,
+	tags map[string]interface{}, id interface{}, err error) {
+	if r.rows == nil {
+		query := `SELECT t.* FROM ` + quoteIdent(r.table) + ` t`
+		var args []interface{}
+		if r.bounds != nil && r.rtree != "" {
+			query += ` JOIN ` + quoteIdent(r.rtree) + ` r ON t.` +
+				quoteIdent(r.pkCol) + ` = r.id WHERE r.minx <= ? AND ` +
+				`r.maxx >= ? AND r.miny <= ? AND r.maxy >= ?`
+			args = []interface{}{r.bounds[2], r.bounds[0], r.bounds[3],
+				r.bounds[1]}
+		}
+		if r.rows, err = r.db.Query(query, args...); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+		if r.cols, err = r.rows.Columns(); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+	}
+	values := make([]interface{}, len(r.cols))
+	ptrs := make([]interface{}, len(r.cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for r.rows.Next() {
+		if err := r.rows.Scan(ptrs...); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+		tags = make(map[string]interface{})
+		geom = Geometry{}
+		id = nil
+		for i, col := range r.cols {
+			switch {
+			case col == r.geomCol:
+				blob, _ := values[i].([]byte)
+				if geom, err = parseGPKGGeometry(blob); err != nil {
+					return Geometry{}, nil, nil, err
+				}
+			case col == r.pkCol:
+				id = values[i]
+			case values[i] != nil:
+				tags[col] = values[i]
+			}
+		}
+		if r.bounds != nil && r.rtree == "" && !intersectsBounds(geom, r.bounds) {
+			continue
+		}
+		if r.mercator {
+			for _, path := range geom.Paths {
+				for i, p := range path {
+					path[i][0], path[i][1] = mercatorLonLat(p[0], p[1])
+				}
+			}
+		}
+		return geom, tags, id, nil
+	}
+	if err := r.rows.Err(); err != nil {
+		return Geometry{}, nil, nil, err
+	}
+	return Geometry{}, nil, nil, io.EOF
+}
+
+// intersectsBounds returns true when the bounds of the geometry, in its
+// own coordinates, intersect the minX, minY, maxX, maxY bounds.
+func intersectsBounds(g Geometry, b *[4]float64) bool {
+	minY, minX, maxY, maxX := g.Bounds()
+	return !(minX > b[2] || maxX < b[0] || minY > b[3] || maxY < b[1])
+}
+
+// parseGPKGGeometry parses a GeoPackage geometry blob, which is a header
+// followed by WKB. A nil blob is an empty geometry.
+func parseGPKGGeometry(blob []byte) (Geometry, error) {
+	if blob == nil {
+		return Geometry{}, nil
+	}
+	if len(blob) < 8 || !bytes.HasPrefix(blob, []byte("GP")) {
+		return Geometry{}, fmt.Errorf("%w: bad geometry header",
+			ErrInvalidGeoPackage)
+	}
+	flags := blob[3]
+	if flags&0x20 != 0 {
+		return Geometry{}, fmt.Errorf("%w: extended geometry types are "+
+			"not supported", ErrInvalidGeoPackage)
+	}
+	if flags&0x10 != 0 {
+		return Geometry{}, nil
+	}
+	size := 8 + [...]int{0, 32, 48, 48, 64, -1, -1, -1}[flags>>1&7]
+	if size < 8 || len(blob) < size {
+		return Geometry{}, fmt.Errorf("%w: bad geometry envelope",
+			ErrInvalidGeoPackage)
+	}
+	g, _, err := parseWKB(blob[size:])
+	return g, err
+}
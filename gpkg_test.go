@@ -0,0 +1,204 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeResult is the result of the queries that contain a string
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeDriver is a database/sql driver of canned results, which records the
+// queries and statements that it is sent.
+type fakeDriver struct {
+	mu      sync.Mutex
+	results map[string][]fakeResult
+	queries []string
+	args    [][]driver.Value
+}
+
+var testDriver = &fakeDriver{results: make(map[string][]fakeResult)}
+
+func init() {
+	sql.Register("mvtfake", testDriver)
+}
+
+// openFakeDB returns a database of the results
+func openFakeDB(t *testing.T, results []fakeResult) *sql.DB {
+	testDriver.mu.Lock()
+	testDriver.results[t.Name()] = results
+	testDriver.queries = nil
+	testDriver.args = nil
+	testDriver.mu.Unlock()
+	db, err := sql.Open("mvtfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d, name}, nil
+}
+
+type fakeConn struct {
+	d    *fakeDriver
+	name string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c, query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("no") }
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, fmt.Sprint(s.query, args))
+	d.args = append(d.args, args)
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, fmt.Sprint(s.query, args))
+	d.args = append(d.args, args)
+	for _, r := range d.results[s.c.name] {
+		if strings.Contains(s.query, r.match) {
+			return &fakeRows{columns: r.columns, rows: r.rows}, nil
+		}
+	}
+	return nil, fmt.Errorf("no result for %s", s.query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// gpkgPoint returns a GeoPackage geometry blob of a point, with a little
+// endian header, an envelope, and an srs id of 4326.
+func gpkgPoint(x, y float64) []byte {
+	blob := []byte{'G', 'P', 0, 1<<1 | 1, 0xe6, 0x10, 0, 0}
+	for _, v := range []float64{x, x, y, y} {
+		blob = binary.LittleEndian.AppendUint64(blob, math.Float64bits(v))
+	}
+	return append(blob, wkb(1, x, y)...)
+}
+
+func TestGeoPackageReader(t *testing.T) {
+	rows := [][]driver.Value{
+		{int64(1), gpkgPoint(-111.9, 33.4), "tempe"},
+		{int64(2), gpkgPoint(2.35, 48.85), "paris"},
+		{int64(3), nil, nil},
+	}
+	results := []fakeResult{
+		{"gpkg_geometry_columns", []string{"column_name", "srs_id"},
+			[][]driver.Value{{"geom", int64(4326)}}},
+		{"gpkg_spatial_ref_sys", []string{"organization", "id"},
+			[][]driver.Value{{"EPSG", int64(4326)}}},
+		{"PRAGMA", []string{"cid", "name", "type", "notnull", "dflt", "pk"},
+			[][]driver.Value{
+				{int64(0), "fid", "INTEGER", int64(1), nil, int64(1)},
+				{int64(1), "geom", "POINT", int64(0), nil, int64(0)},
+				{int64(2), "name", "TEXT", int64(0), nil, int64(0)},
+			}},
+		{"sqlite_master", []string{"name"},
+			[][]driver.Value{{"rtree_places_geom"}}},
+		{"JOIN", []string{"fid", "geom", "name"}, rows[1:2]},
+		{"SELECT t.*", []string{"fid", "geom", "name"}, rows},
+	}
+	db := openFakeDB(t, results)
+	defer db.Close()
+	r, err := NewGeoPackageReader(db, "places")
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := readAllGeo(t, r)
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	f := features[0]
+	if s := fmt.Sprint(f.Geometry, f.Tags, f.ID); s !=
+		"{1 [[[-111.9 33.4]]] []} map[name:tempe] 1" {
+		t.Fatalf("unexpected feature %s", s)
+	}
+	if len(features[2].Geometry.Paths) != 0 || len(features[2].Tags) != 0 {
+		t.Fatalf("expected an empty feature, got %v", features[2])
+	}
+
+	r, err = NewGeoPackageReader(db, "places")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Filter(48, 2, 49, 3); err != nil {
+		t.Fatal(err)
+	}
+	features = readAllGeo(t, r)
+	if len(features) != 1 || features[0].Tags["name"] != "paris" {
+		t.Fatalf("unexpected features %v", features)
+	}
+	last := testDriver.queries[len(testDriver.queries)-1]
+	if !strings.Contains(last, `JOIN "rtree_places_geom" r ON t."fid" = r.id`) ||
+		!strings.HasSuffix(last, "[3 2 49 48]") {
+		t.Fatalf("unexpected query %s", last)
+	}
+
+	// without an rtree, filtering reads all rows
+	results[3].rows = nil
+	db = openFakeDB(t, results)
+	defer db.Close()
+	r, err = NewGeoPackageReader(db, "places")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Filter(30, -115, 35, -110); err != nil {
+		t.Fatal(err)
+	}
+	features = readAllGeo(t, r)
+	if len(features) != 1 || features[0].Tags["name"] != "tempe" {
+		t.Fatalf("unexpected features %v", features)
+	}
+
+	_, err = parseGPKGGeometry([]byte("XX\x00\x01\x00\x00\x00\x00"))
+	if !errors.Is(err, ErrInvalidGeoPackage) {
+		t.Fatalf("expected ErrInvalidGeoPackage, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// GridShape is the shape of the features of the cells of a grid
+type GridShape int
+
+const (
+	// GridSquares are square polygons that fill the cells
+	GridSquares GridShape = iota
+	// GridPoints are points at the centers of the cells
+	GridPoints
+)
+
+// gridOptions are the settings of a gridded layer.
+type gridOptions struct {
+	size       float64
+	shape      GridShape
+	weight     string
+	aggregates []aggregate
+}
+
+// SetGrid sets the layer to bin the points of its point features into a
+// grid of square cells of the size, in pixels on the 512x512 canvas, when
+// it is rendered, such as for heatmaps. Each cell that has points is one
+// feature of the shape, which has a "point_count" tag of the number of
+// points in it. The cells are aligned to the tile, so sizes that divide
+// 512 line up across tiles. A size of zero turns the grid off.
+func (l *Layer) SetGrid(size float64, shape GridShape) {
+	if !(size > 0) {
+		l.grid = nil
+		return
+	}
+	if l.grid == nil {
+		l.grid = &gridOptions{}
+	}
+	l.grid.size = size
+	l.grid.shape = shape
+}
+
+// SetGridWeight adds a "weight" tag to each cell of the grid that is the
+// sum of the weights of its points, which are the numeric values of the
+// key, or one for points without one.
+func (l *Layer) SetGridWeight(key string) {
+	if l.grid != nil {
+		l.grid.weight = key
+	}
+}
+
+// SetGridAggregate adds a tag to each cell of the grid that is computed by
+// the aggregator from the values of the key in the features of its points.
+func (l *Layer) SetGridAggregate(tag, key string, agg Aggregator) {
+	if l.grid != nil {
+		l.grid.aggregates = append(l.grid.aggregates,
+			aggregate{tag: tag, key: key, agg: agg})
+	}
+}
+
+// gridCell is a cell of a grid that is being filled
+type gridCell struct {
+	count    uint64
+	weight   float64
+	features []*Feature
+}
+
+// gridPoints replaces the point features with the cells of the grid that
+// their points are in. Each cell takes the place of the first feature with
+// a point in it.
+func (l *Layer) gridPoints(features []*Feature) []*Feature {
+	opts := l.grid
+	cells := make(map[[2]int]*gridCell)
+	gridded := make([]*Feature, 0, len(features))
+	var keys [][2]int
+	for _, f := range features {
+		if f.geomType != Point {
+			gridded = append(gridded, f)
+			continue
+		}
+		weight := 1.0
+		if opts.weight != "" {
+			if v, ok := f.Tag(opts.weight); ok {
+				if n, ok := toFloat(v); ok {
+					weight = n
+				}
+			}
+		}
+		for i := 0; i < len(f.geom.coords); i += 2 {
+			key := [2]int{int(math.Floor(f.geom.coords[i] / opts.size)),
+				int(math.Floor(f.geom.coords[i+1] / opts.size))}
+			c := cells[key]
+			if c == nil {
+				c = &gridCell{}
+				cells[key] = c
+				keys = append(keys, key)
+				// a placeholder for the cell feature
+				gridded = append(gridded, nil)
+			}
+			c.count++
+			c.weight += weight
+			if n := len(c.features); n == 0 || c.features[n-1] != f {
+				c.features = append(c.features, f)
+			}
+		}
+	}
+	k := 0
+	for i, f := range gridded {
+		if f == nil {
+			gridded[i] = l.newGridCell(keys[k], cells[keys[k]])
+			k++
+		}
+	}
+	return gridded
+}
+
+// newGridCell returns the feature of a cell of the grid
+func (l *Layer) newGridCell(key [2]int, c *gridCell) *Feature {
+	opts := l.grid
+	x, y := float64(key[0])*opts.size, float64(key[1])*opts.size
+	var f *Feature
+	if opts.shape == GridPoints {
+		f = &Feature{geomType: Point, layer: l}
+		f.geom.push(moveTo, x+opts.size/2, y+opts.size/2)
+	} else {
+		f = &Feature{geomType: Polygon, layer: l}
+		f.Rect(x, y, opts.size, opts.size)
+	}
+	f.tags = append(f.tags, Tag{"point_count", c.count})
+	if opts.weight != "" {
+		f.tags = append(f.tags, Tag{"weight", c.weight})
+	}
+	f.tags = aggregateTags(f.tags, opts.aggregates, c.features)
+	return f
+}
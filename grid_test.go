@@ -0,0 +1,59 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGrid(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	l.SetAutoID(1)
+	line := l.AddFeature(LineString)
+	line.MoveTo(0, 0)
+	line.LineTo(10, 10)
+	for i, xy := range [][2]float64{{10, 10}, {300, 300}, {60, 20}, {63, 1}} {
+		f := l.AddFeature(Point)
+		f.MoveTo(xy[0], xy[1])
+		f.AddTag("mag", i+1)
+	}
+	multi := l.AddFeature(Point)
+	multi.MoveTo(1, 1)
+	multi.MoveTo(2, 2)
+	l.SetGrid(64, GridSquares)
+	l.SetGridWeight("mag")
+	l.SetGridAggregate("mag_max", "mag", MaxAggregator())
+	features := l.render()
+	if len(features) != 3 {
+		t.Fatalf("expected a line and 2 cells, got %d", len(features))
+	}
+	if features[0] != line {
+		t.Fatal("expected the line to be left as it is")
+	}
+	for i, expect := range []string{
+		"[0 0 64 0 64 64 0 64] [{point_count 5} {weight 10} {mag_max 4}]",
+		"[256 256 320 256 320 320 256 320] [{point_count 1} {weight 2} " +
+			"{mag_max 2}]",
+	} {
+		f := features[i+1]
+		if s := fmt.Sprint(f.geom.coords, " ", f.Tags()); s != expect {
+			t.Fatalf("cell %d: expected %s, got %s", i, expect, s)
+		}
+	}
+	l.SetGrid(128, GridPoints)
+	features = l.render()
+	if len(features) != 3 || features[1].geomType != Point ||
+		fmt.Sprint(features[1].geom.coords) != "[64 64]" ||
+		fmt.Sprint(features[2].geom.coords) != "[320 320]" {
+		t.Fatal("expected points at the centers of the cells")
+	}
+	l.SetGrid(0, GridSquares)
+	if features = l.render(); len(features) != 6 {
+		t.Fatalf("expected the grid to be off, got %d features",
+			len(features))
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build h3
+
+package mvt
+
+import "github.com/uber/h3-go/v4"
+
+// AddH3Cell adds a polygon feature of the hexagon, or pentagon, of the H3
+// cell, with the id and tags, see AddGeoFeature. It returns nil when none
+// of the cell is in the tile, or when the cell is not valid.
+//
+// This requires the h3 build tag, as H3 is a cgo library, and is written
+// against github.com/uber/h3-go/v4 v4.1.0.
+func (l *Layer) AddH3Cell(id uint64, cell h3.Cell,
+	tags map[string]interface{}) *Feature {
+	if !cell.IsValid() {
+		return nil
+	}
+	boundary := cell.Boundary()
+	vertices := make([][2]float64, len(boundary))
+	for i, ll := range boundary {
+		vertices[i] = [2]float64{ll.Lng, ll.Lat}
+	}
+	return l.AddGeoFeature(GeoFeature{cellPolygon(vertices), tags, id})
+}
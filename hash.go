@@ -0,0 +1,28 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "crypto/sha256"
+
+// Hash returns the SHA-256 hash of the encoded tile, which is the same
+// for the same bytes, such as for cache keys and ETags.
+func (t *Tile) Hash() ([32]byte, error) {
+	pb, err := t.Encode()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(pb), nil
+}
+
+// ContentHash returns the SHA-256 hash of the tile encoded in its
+// canonical form, see SetCanonical, which is the same for tiles with the
+// same layers, features, and tags, no matter the order that they were
+// drawn in, such as for finding duplicate tiles.
+func (t *Tile) ContentHash() ([32]byte, error) {
+	canonical := t.canonical
+	t.canonical = true
+	defer func() { t.canonical = canonical }()
+	return t.Hash()
+}
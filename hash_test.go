@@ -0,0 +1,41 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	draw := func(ids ...uint64) *Tile {
+		var tile Tile
+		l := tile.AddLayer("points")
+		for _, id := range ids {
+			f := l.AddFeature(Point)
+			f.SetID(id)
+			f.MoveTo(float64(id), 0)
+		}
+		return &tile
+	}
+	a, b := draw(1, 2), draw(2, 1)
+	ha, err := a.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ := b.Hash(); ha == hb {
+		t.Fatal("expected different hashes of different bytes")
+	}
+	ca, err := a.ContentHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb, _ := b.ContentHash(); ca != cb {
+		t.Fatal("expected the same hash of the same content")
+	}
+	if cc, _ := draw(1, 3).ContentHash(); ca == cc {
+		t.Fatal("expected a different hash of different content")
+	}
+	if a.canonical {
+		t.Fatal("expected the canonical setting to be restored")
+	}
+}
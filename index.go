@@ -0,0 +1,169 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"sort"
+)
+
+// indexCells is the number of rows and columns of the grid of a
+// FeatureIndex, which covers the canvas. Features outside of it are in the
+// cells at its edges.
+const indexCells = 16
+
+// FeatureIndex is a grid index of the bounds of the features of a layer on
+// the canvas, for finding the features near a point or in a box, such as
+// for hit-testing or for finding the features that overlap, on large
+// layers. It is safe for concurrent queries.
+type FeatureIndex struct {
+	features []*Feature
+	bounds   []clipRect
+	cells    [indexCells * indexCells][]int32
+}
+
+// Index returns an index of the features of the layer. The index is of the
+// features as they are when it is made, so it needs to be made again after
+// features are added, removed, or drawn.
+func (l *Layer) Index() *FeatureIndex {
+	l.lock()
+	defer l.unlock()
+	idx := &FeatureIndex{}
+	for _, f := range l.features {
+		coords := f.geom.coords
+		if len(coords) == 0 {
+			continue
+		}
+		b := clipRect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for i := 0; i < len(coords); i += 2 {
+			b.minX, b.maxX = min(b.minX, coords[i]), max(b.maxX, coords[i])
+			b.minY = min(b.minY, coords[i+1])
+			b.maxY = max(b.maxY, coords[i+1])
+		}
+		n := int32(len(idx.features))
+		idx.features = append(idx.features, f)
+		idx.bounds = append(idx.bounds, b)
+		x0, y0, x1, y1 := indexCellRange(b)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				cell := &idx.cells[y*indexCells+x]
+				*cell = append(*cell, n)
+			}
+		}
+	}
+	return idx
+}
+
+// indexCellRange returns the range of cells of the rectangle
+func indexCellRange(r clipRect) (x0, y0, x1, y1 int) {
+	cell := func(v float64) int {
+		return int(clamp(math.Floor(v/gTileSize*indexCells), 0,
+			indexCells-1))
+	}
+	return cell(r.minX), cell(r.minY), cell(r.maxX), cell(r.maxY)
+}
+
+// Search calls fn with each feature whose bounds intersect the box, until
+// fn returns false. The features are in no particular order.
+func (idx *FeatureIndex) Search(minX, minY, maxX, maxY float64,
+	fn func(f *Feature) bool,
+) {
+	idx.search(clipRect{minX, minY, maxX, maxY}, func(n int32) bool {
+		return fn(idx.features[n])
+	})
+}
+
+// search calls fn with the index of each feature whose bounds intersect
+// the box, until fn returns false
+func (idx *FeatureIndex) search(q clipRect, fn func(n int32) bool) {
+	qx0, qy0, qx1, qy1 := indexCellRange(q)
+	for y := qy0; y <= qy1; y++ {
+		for x := qx0; x <= qx1; x++ {
+			for _, n := range idx.cells[y*indexCells+x] {
+				b := idx.bounds[n]
+				if b.minX > q.maxX || b.maxX < q.minX ||
+					b.minY > q.maxY || b.maxY < q.minY {
+					continue
+				}
+				// a feature in more than one cell is only found in the
+				// first cell that it shares with the box
+				fx0, fy0, _, _ := indexCellRange(b)
+				if x != max(fx0, qx0) || y != max(fy0, qy0) {
+					continue
+				}
+				if !fn(n) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// QueryPoint returns the features within the radius of the point on the
+// canvas, in the order of the layer, as QueryPoint does for encoded tiles.
+func (idx *FeatureIndex) QueryPoint(px, py, radius float64) []*Feature {
+	q := clipRect{px - radius, py - radius, px + radius, py + radius}
+	return idx.query(q,
+		func(geomType GeometryType, parts [][][2]float64) bool {
+			return hitPoint(geomType, parts, px, py, radius)
+		})
+}
+
+// QueryBox returns the features that intersect the box on the canvas, in
+// the order of the layer, as QueryBox does for encoded tiles.
+func (idx *FeatureIndex) QueryBox(minX, minY, maxX, maxY float64,
+) []*Feature {
+	r := clipRect{minX, minY, maxX, maxY}
+	return idx.query(r,
+		func(geomType GeometryType, parts [][][2]float64) bool {
+			return hitBox(geomType, parts, r)
+		})
+}
+
+// query returns the features in the box whose geometry parts are hit
+func (idx *FeatureIndex) query(q clipRect,
+	hit func(geomType GeometryType, parts [][][2]float64) bool,
+) []*Feature {
+	var found []int
+	var parts [][][2]float64
+	idx.search(q, func(n int32) bool {
+		f := idx.features[n]
+		parts = featureParts(parts, f)
+		if hit(f.geomType, parts) {
+			found = append(found, int(n))
+		}
+		return true
+	})
+	sort.Ints(found)
+	features := make([]*Feature, len(found))
+	for i, n := range found {
+		features[i] = idx.features[n]
+	}
+	return features
+}
+
+// featureParts returns the parts of the geometry of the feature, in the
+// memory of parts. Each part begins with a MoveTo.
+func featureParts(parts [][][2]float64, f *Feature) [][][2]float64 {
+	parts = parts[:0]
+	var c int
+	for _, op := range f.geom.ops {
+		if op == closePath {
+			continue
+		}
+		if op == moveTo || len(parts) == 0 {
+			if n := len(parts); n < cap(parts) {
+				parts = append(parts, parts[:n+1][n][:0])
+			} else {
+				parts = append(parts, nil)
+			}
+		}
+		n := len(parts) - 1
+		parts[n] = append(parts[n],
+			[2]float64{f.geom.coords[c], f.geom.coords[c+1]})
+		c += 2
+	}
+	return parts
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFeatureIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var tile Tile
+	l := tile.AddLayer("features")
+	for i := 0; i < 500; i++ {
+		x, y := rng.Float64()*600-44, rng.Float64()*600-44
+		switch i % 3 {
+		case 0:
+			l.AddFeature(Point).MoveTo(x, y)
+		case 1:
+			f := l.AddFeature(LineString)
+			f.MoveTo(x, y)
+			f.LineTo(x+rng.Float64()*100-50, y+rng.Float64()*100-50)
+		case 2:
+			w := rng.Float64() * 80
+			f := l.AddFeature(Polygon)
+			f.MoveTo(x, y)
+			f.LineTo(x+w, y)
+			f.LineTo(x+w, y+w)
+			f.ClosePath()
+		}
+	}
+	l.AddFeature(Point)
+	idx := l.Index()
+	brute := func(hit func(GeometryType, [][][2]float64) bool) []*Feature {
+		var found []*Feature
+		for _, f := range l.Features() {
+			if hit(f.geomType, featureParts(nil, f)) {
+				found = append(found, f)
+			}
+		}
+		return found
+	}
+	equal := func(a, b []*Feature) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	var hits int
+	for i := 0; i < 200; i++ {
+		x, y := rng.Float64()*600-44, rng.Float64()*600-44
+		r := rng.Float64() * 20
+		got := idx.QueryPoint(x, y, r)
+		exp := brute(func(gt GeometryType, parts [][][2]float64) bool {
+			return hitPoint(gt, parts, x, y, r)
+		})
+		if !equal(got, exp) {
+			t.Fatalf("point %v,%v %v: expected %d features, got %d", x, y,
+				r, len(exp), len(got))
+		}
+		box := clipRect{x, y, x + r*3, y + r*2}
+		got = idx.QueryBox(box.minX, box.minY, box.maxX, box.maxY)
+		exp = brute(func(gt GeometryType, parts [][][2]float64) bool {
+			return hitBox(gt, parts, box)
+		})
+		if !equal(got, exp) {
+			t.Fatalf("box %v: expected %d features, got %d", box,
+				len(exp), len(got))
+		}
+		hits += len(got)
+	}
+	if hits == 0 {
+		t.Fatal("expected hits")
+	}
+	var n int
+	idx.Search(-1000, -1000, 1000, 1000, func(f *Feature) bool {
+		n++
+		return true
+	})
+	if n != 500 {
+		t.Fatalf("expected 500 features, got %d", n)
+	}
+	n = 0
+	idx.Search(0, 0, 512, 512, func(f *Feature) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatalf("expected 10 features, got %d", n)
+	}
+}
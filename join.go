@@ -0,0 +1,191 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JoinTags returns the encoded tile with tags that are joined to the
+// features of the layer from the rows of a table, like the tile-join of
+// tippecanoe, such as to enrich a tileset with a CSV file that is read
+// with ReadJoinTable. A feature is joined to the row of the value of its
+// key tag, in its string form, or of its id when the key is empty. The
+// tags of the row replace those of the feature with the same keys.
+// Features without a row, and other layers, are left as they are.
+func JoinTags(tile []byte, layer, key string,
+	rows map[string]map[string]interface{},
+) ([]byte, error) {
+	layers, other, err := splitTile(tile)
+	if err != nil {
+		return nil, err
+	}
+	pb := make([]byte, 0, len(tile))
+	for _, tl := range layers {
+		if tl.name == layer {
+			msg, err := joinLayer(tl.msg, key, rows)
+			if err != nil {
+				return nil, fmt.Errorf("layer %q: %w", layer, err)
+			}
+			tl.msg = msg
+		}
+		pb = appendTileLayer(pb, tl.msg)
+	}
+	return append(pb, other...), nil
+}
+
+// joinLayer returns the layer message with the rows joined to its
+// features, see JoinTags.
+func joinLayer(msg []byte, key string,
+	rows map[string]map[string]interface{},
+) ([]byte, error) {
+	layer, err := parseLayer(msg)
+	if err != nil {
+		return nil, err
+	}
+	if layer.version > 2 {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, layer.version)
+	}
+	keyidx := make(map[string]uint64, len(layer.keys))
+	for i, k := range layer.keys {
+		if _, ok := keyidx[k]; !ok {
+			keyidx[k] = uint64(i)
+		}
+	}
+	validx := make(map[string]uint64, len(layer.values))
+	for i, val := range layer.values {
+		if _, ok := validx[string(val)]; !ok {
+			validx[string(val)] = uint64(i)
+		}
+	}
+	for i, fmsg := range layer.features {
+		f, err := parseFeature(fmsg)
+		if err != nil {
+			return nil, err
+		}
+		var match string
+		if key == "" {
+			if !f.hasID {
+				continue
+			}
+			match = strconv.FormatUint(f.id, 10)
+		} else {
+			idx, ok := keyidx[key]
+			if !ok {
+				continue
+			}
+			var found bool
+			for j := 0; j < len(f.tags); j += 2 {
+				if f.tags[j] == idx {
+					if f.tags[j+1] >= uint64(len(layer.values)) {
+						return nil, fmt.Errorf("%w: tag index out of range",
+							ErrInvalidTile)
+					}
+					v, err := decodeValue(layer.values[f.tags[j+1]])
+					if err != nil {
+						return nil, err
+					}
+					match, found = fmt.Sprint(v), true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		row, ok := rows[match]
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(row))
+		for k := range row {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		joined := make(map[uint64]bool, len(keys))
+		var tags []uint64
+		for _, k := range keys {
+			kidx, ok := keyidx[k]
+			if !ok {
+				kidx = uint64(len(layer.keys))
+				keyidx[k] = kidx
+				layer.keys = append(layer.keys, k)
+			}
+			joined[kidx] = true
+			// the value message, without its field key and size
+			pr := pbfReader{data: []byte(encodeValue(row[k]))}
+			pr.next()
+			val := pr.bytes()
+			vidx, ok := validx[string(val)]
+			if !ok {
+				vidx = uint64(len(layer.values))
+				validx[string(val)] = vidx
+				layer.values = append(layer.values, val)
+			}
+			tags = append(tags, kidx, vidx)
+		}
+		for j := 0; j < len(f.tags); j += 2 {
+			if !joined[f.tags[j]] {
+				tags = append(tags, f.tags[j], f.tags[j+1])
+			}
+		}
+		f.tags = tags
+		layer.features[i] = f.append(nil)
+	}
+	return layer.append(nil), nil
+}
+
+// ReadJoinTable reads a CSV file with a header into the rows of a table
+// for JoinTags, keyed by the values of the column. The other columns
+// become the tags of the rows, with numbers as int64 or float64, and
+// empty values are left out. A later row replaces an earlier one with the
+// same key.
+func ReadJoinTable(r io.Reader, column string) (
+	map[string]map[string]interface{}, error,
+) {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	rd.LazyQuotes = true
+	header, err := rd.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	col := -1
+	for i, name := range header {
+		if strings.TrimSpace(name) == column {
+			col = i
+			break
+		}
+	}
+	if col == -1 {
+		return nil, fmt.Errorf("%w: no %q column", ErrInvalidCSV, column)
+	}
+	rows := make(map[string]map[string]interface{})
+	for {
+		record, err := rd.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		if col >= len(record) {
+			continue
+		}
+		row := make(map[string]interface{})
+		for i, s := range record {
+			if i != col && i < len(header) && s != "" {
+				row[strings.TrimSpace(header[i])] = csvValue(s)
+			}
+		}
+		rows[record[col]] = row
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// layerTags returns the tags of the features of a layer of an encoded tile
+func layerTags(t *testing.T, pb []byte, name string) []string {
+	t.Helper()
+	layers, _, err := splitTile(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, tl := range layers {
+		if tl.name != name {
+			continue
+		}
+		layer, err := parseLayer(tl.msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range layer.features {
+			f, err := parseFeature(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s []string
+			for i := 0; i < len(f.tags); i += 2 {
+				v, err := decodeValue(layer.values[f.tags[i+1]])
+				if err != nil {
+					t.Fatal(err)
+				}
+				s = append(s, fmt.Sprintf("%s=%v", layer.keys[f.tags[i]], v))
+			}
+			tags = append(tags, strings.Join(s, " "))
+		}
+	}
+	return tags
+}
+
+func TestJoinTags(t *testing.T) {
+	rows, err := ReadJoinTable(strings.NewReader(
+		"\ufeffcode,pop,name\nUS,331,United States\nFR,67,\nFR,68,France\n"),
+		"code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(rows) != "map[FR:map[name:France pop:68] "+
+		"US:map[name:United States pop:331]]" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	_, err = ReadJoinTable(strings.NewReader("a,b\n"), "code")
+	if !errors.Is(err, ErrInvalidCSV) {
+		t.Fatalf("expected %v, got %v", ErrInvalidCSV, err)
+	}
+
+	var tile Tile
+	l := tile.AddLayer("countries")
+	for i, code := range []string{"US", "FR", "DE"} {
+		f := l.AddFeature(Point)
+		f.SetID(uint64(i + 1))
+		f.MoveTo(1, 1)
+		f.AddTag("code", code)
+		f.AddTag("name", "?")
+	}
+	other := tile.AddLayer("other")
+	f := other.AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("code", "US")
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined, err := JoinTags(pb, "countries", "code", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "[name=United States pop=331 code=US " +
+		"name=France pop=68 code=FR code=DE name=?]"
+	if s := fmt.Sprint(layerTags(t, joined, "countries")); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+	if s := fmt.Sprint(layerTags(t, joined, "other")); s != "[code=US]" {
+		t.Fatalf("expected the other layer as it was, got %s", s)
+	}
+
+	// by id
+	joined, err = JoinTags(pb, "countries", "",
+		map[string]map[string]interface{}{"3": {"eu": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = "[code=US name=? code=FR name=? eu=true code=DE name=?]"
+	if s := fmt.Sprint(layerTags(t, joined, "countries")); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+}
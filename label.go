@@ -0,0 +1,205 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Polylabel returns the pole of inaccessibility of the polygon, which is
+// the point inside of it that is furthest from its edges, and is where a
+// label is best placed. It is found in Web Mercator meters to within the
+// precision, or a thousandth of the size of the polygon when the precision
+// is zero, by the polylabel algorithm. For a multipolygon, this is the
+// pole of its largest polygon. The rings are taken as exteriors and holes
+// the same way as AddGeometry does.
+func Polylabel(poly Geometry, precision float64) (lat, lon float64) {
+	var rings [][][2]float64
+	for _, path := range poly.Paths {
+		ring := make([][2]float64, len(path))
+		for i, p := range path {
+			ring[i][0], ring[i][1] = lonLatMercator(p[0], p[1])
+		}
+		rings = append(rings, ring)
+	}
+	merc := Geometry{Type: Polygon, Paths: rings, Rings: poly.Rings}
+	p := polylabel(largestPolygon(merc), precision)
+	lon, lat = mercatorLonLat(p[0], p[1])
+	return lat, lon
+}
+
+// largestPolygon returns the rings of the polygon of the multipolygon that
+// has the largest exterior, see Geometry.Rings.
+func largestPolygon(g Geometry) [][][2]float64 {
+	var best [][][2]float64
+	var bestArea float64
+	for _, poly := range g.polygons() {
+		if ext := math.Abs(pathArea(poly[0])); best == nil || ext > bestArea {
+			best, bestArea = poly, ext
+		}
+	}
+	return best
+}
+
+// labelCell is a square cell of the polylabel search
+type labelCell struct {
+	x, y float64 // the center
+	h    float64 // half of the size
+	d    float64 // the distance from the center to the polygon
+	max  float64 // the most that the distance may be within the cell
+}
+
+func newLabelCell(x, y, h float64, rings [][][2]float64) labelCell {
+	d := polygonDist(x, y, rings)
+	return labelCell{x: x, y: y, h: h, d: d, max: d + h*math.Sqrt2}
+}
+
+// labelQueue is a max heap of cells by their max distance
+type labelQueue []labelCell
+
+func (q labelQueue) Len() int            { return len(q) }
+func (q labelQueue) Less(i, j int) bool  { return q[i].max > q[j].max }
+func (q labelQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *labelQueue) Push(x interface{}) { *q = append(*q, x.(labelCell)) }
+func (q *labelQueue) Pop() interface{} {
+	c := (*q)[len(*q)-1]
+	*q = (*q)[:len(*q)-1]
+	return c
+}
+
+// polylabel returns the pole of inaccessibility of the rings
+func polylabel(rings [][][2]float64, precision float64) [2]float64 {
+	if len(rings) == 0 || len(rings[0]) == 0 {
+		return [2]float64{math.NaN(), math.NaN()}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range rings[0] {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	size := math.Min(maxX-minX, maxY-minY)
+	if size == 0 {
+		return [2]float64{minX, minY}
+	}
+	if !(precision > 0) {
+		precision = size / 1000
+	}
+	// cover the polygon with square cells
+	var q labelQueue
+	h := size / 2
+	for x := minX; x < maxX; x += size {
+		for y := minY; y < maxY; y += size {
+			q = append(q, newLabelCell(x+h, y+h, h, rings))
+		}
+	}
+	heap.Init(&q)
+	// start with the centroid, or the center of the bounds
+	cx, cy := polygonCentroid(rings[0])
+	best := newLabelCell(cx, cy, 0, rings)
+	if c := newLabelCell((minX+maxX)/2, (minY+maxY)/2, 0, rings); c.d > best.d {
+		best = c
+	}
+	for q.Len() > 0 {
+		c := heap.Pop(&q).(labelCell)
+		if c.d > best.d {
+			best = c
+		}
+		if c.max-best.d <= precision {
+			continue
+		}
+		h := c.h / 2
+		heap.Push(&q, newLabelCell(c.x-h, c.y-h, h, rings))
+		heap.Push(&q, newLabelCell(c.x+h, c.y-h, h, rings))
+		heap.Push(&q, newLabelCell(c.x-h, c.y+h, h, rings))
+		heap.Push(&q, newLabelCell(c.x+h, c.y+h, h, rings))
+	}
+	return [2]float64{best.x, best.y}
+}
+
+// polygonCentroid returns the centroid of the ring, or its first point
+// when it has no area.
+func polygonCentroid(ring [][2]float64) (x, y float64) {
+	var area float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		f := a[0]*b[1] - b[0]*a[1]
+		x += (a[0] + b[0]) * f
+		y += (a[1] + b[1]) * f
+		area += f * 3
+	}
+	if area == 0 {
+		return ring[0][0], ring[0][1]
+	}
+	return x / area, y / area
+}
+
+// polygonDist returns the distance from the point to the nearest edge of
+// the rings, which is negative when the point is outside of the polygon.
+func polygonDist(x, y float64, rings [][][2]float64) float64 {
+	inside := false
+	minDist := math.Inf(1)
+	for _, ring := range rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[1] > y) != (b[1] > y) &&
+				x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+				inside = !inside
+			}
+			d := segmentDist(command{x: x, y: y}, command{x: a[0], y: a[1]},
+				command{x: b[0], y: b[1]})
+			minDist = math.Min(minDist, d)
+		}
+	}
+	if !inside {
+		return -minDist
+	}
+	return minDist
+}
+
+// SetLabelPoints sets whether polygons that are added to the layer with
+// AddGeoFeature, or AddFrom, have a point feature added at their pole of
+// inaccessibility, see Polylabel, to a layer of the tile that is named
+// like this layer with a "_label" suffix. The point has the tags and id of
+// the polygon, and is only added to the tile that it is in. Default is
+// false.
+func (l *Layer) SetLabelPoints(labels bool) {
+	l.labels = labels
+}
+
+// addLabelPoint adds the label point of the polygon to the label layer,
+// when the point is in the tile.
+func (l *Layer) addLabelPoint(gf GeoFeature) {
+	// to within half of a pixel
+	precision := originShift / float64(gMapSize(l.tileID().Z))
+	lat, lon := Polylabel(gf.Geometry, precision)
+	l.addPointTo(l.name+"_label", lat, lon, gf)
+}
+
+// addPointTo adds a point feature at the lat/lon, with the tags and id of
+// the feature, to the named layer of the tile, which is added if the tile
+// does not have it. Nothing is added when the point is not in the tile.
+func (l *Layer) addPointTo(name string, lat, lon float64, gf GeoFeature) {
+	if l.tile == nil || math.IsNaN(lat) || math.IsNaN(lon) {
+		return
+	}
+	x, y := l.toPixel(lat, lon)
+	offX, offY := l.tileOffset()
+	x, y = x-offX, y-offY
+	if !(x >= 0 && x < gTileSize && y >= 0 && y < gTileSize) {
+		return
+	}
+	pl := l.tile.GetLayer(name)
+	if pl == nil {
+		pl = l.tile.AddLayer(name)
+	}
+	f := pl.AddFeature(Point)
+	f.MoveTo(x, y)
+	if id, ok := parseID(gf.ID); ok {
+		f.SetID(id)
+	}
+	f.AddTags(gf.Tags)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPolylabel(t *testing.T) {
+	square := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+	}}
+	lat, lon := Polylabel(square, 1)
+	if math.Abs(lon-5) > 0.01 || math.Abs(lat-5) > 0.1 {
+		t.Fatalf("expected about 5,5, got %v,%v", lat, lon)
+	}
+	// a multipolygon labels its largest polygon
+	multi := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{20, 0}, {21, 0}, {21, 1}, {20, 1}, {20, 0}},
+		square.Paths[0],
+	}, Rings: []int{1, 1}}
+	if lat, lon := Polylabel(multi, 1); math.Abs(lon-5) > 0.01 ||
+		math.Abs(lat-5) > 0.1 {
+		t.Fatalf("expected about 5,5, got %v,%v", lat, lon)
+	}
+
+	// a frame, whose pole is in a corner, as far from the outer edges as
+	// from the corner of the hole
+	rings := [][][2]float64{
+		{{0, 0}, {100, 0}, {100, 100}, {0, 100}},
+		{{20, 20}, {20, 80}, {80, 80}, {80, 20}},
+	}
+	p := polylabel(rings, 0.01)
+	expect := 20 * math.Sqrt2 / (1 + math.Sqrt2)
+	if d := polygonDist(p[0], p[1], rings); math.Abs(d-expect) > 0.01 {
+		t.Fatalf("expected a distance of %v at %v, got %v", expect, p, d)
+	}
+	if d := polygonDist(50, 50, rings); d != -30 {
+		t.Fatalf("expected the hole to be outside, got %v", d)
+	}
+	if p := polylabel([][][2]float64{{{5, 5}, {5, 5}, {5, 5}}}, 1); p !=
+		[2]float64{5, 5} {
+		t.Fatalf("unexpected pole %v", p)
+	}
+}
+
+func TestLabelPoints(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{Z: 1, X: 1, Y: 0})
+	l := tile.AddLayer("areas")
+	l.SetLabelPoints(true)
+	square := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{10, 10}, {20, 10}, {20, 20}, {10, 20}, {10, 10}},
+	}}
+	if l.AddGeoFeature(GeoFeature{square, map[string]interface{}{
+		"name": "square"}, 5}) == nil {
+		t.Fatal("expected a feature")
+	}
+	labels := tile.GetLayer("areas_label")
+	if labels == nil || len(labels.Features()) != 1 {
+		t.Fatal("expected a label point")
+	}
+	f := labels.Features()[0]
+	if id, _ := f.ID(); f.GeomType() != Point || id != 5 {
+		t.Fatalf("unexpected label %v %v", f.GeomType(), id)
+	}
+	if v, _ := f.Tag("name"); v != "square" {
+		t.Fatalf("unexpected tag %v", v)
+	}
+	lat, lon := Polylabel(square, 0)
+	x, y := LatLonToPixel(lat, lon, 1)
+	if math.Abs(f.geom.coords[0]-(x-512)) > 0.5 ||
+		math.Abs(f.geom.coords[1]-y) > 0.5 {
+		t.Fatalf("unexpected label point %v", f.geom.coords)
+	}
+
+	// a polygon whose label is in another tile
+	wide := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{-20, 10}, {1, 10}, {1, 20}, {-20, 20}, {-20, 10}},
+	}}
+	if l.AddGeoFeature(GeoFeature{Geometry: wide}) == nil {
+		t.Fatal("expected a feature")
+	}
+	if len(labels.Features()) != 1 {
+		t.Fatal("expected no label point")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetMergeLines sets the layer to join its LineString features when it is
+// rendered. Lines with identical tags are joined where the end of one is
+// exactly on the end of another, and no third line of the same tags ends
+// there, which keeps intersections intact. A joined line follows the
+// direction of its first line, reversing the others as needed, and takes
+// its ID. Only lines of a single path are joined. Default is false.
+func (l *Layer) SetMergeLines(merge bool) {
+	l.mergeLines = merge
+}
+
+// tagsKey returns a string that is the same for features with the same
+// tags, regardless of their order.
+func tagsKey(tags []Tag) string {
+	tags = append([]Tag(nil), tags...)
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Key < tags[j].Key
+	})
+	var sb strings.Builder
+	for _, tag := range tags {
+		fmt.Fprintf(&sb, "%q:%T:%v;", tag.Key, tag.Value, tag.Value)
+	}
+	return sb.String()
+}
+
+// lineEnd is the end of a line among the lines with the same tags
+type lineEnd struct {
+	tags string
+	x, y float64
+}
+
+// joinLines replaces the lines that meet end to end with joined lines.
+func (l *Layer) joinLines(features []*Feature) []*Feature {
+	var lines []*Feature
+	keys := make(map[*Feature]string)
+	ends := make(map[lineEnd][]int)
+	for _, f := range features {
+		if !isSimpleLine(f) {
+			continue
+		}
+		key := tagsKey(f.tags)
+		c := f.geom.coords
+		first := lineEnd{key, c[0], c[1]}
+		last := lineEnd{key, c[len(c)-2], c[len(c)-1]}
+		if first == last {
+			// rings are left as they are
+			continue
+		}
+		keys[f] = key
+		ends[first] = append(ends[first], len(lines))
+		ends[last] = append(ends[last], len(lines))
+		lines = append(lines, f)
+	}
+	used := make([]bool, len(lines))
+	// next returns the unused line that continues from the end of coords,
+	// with its coordinates in the direction away from that end.
+	next := func(key string, coords []float64) (int, []float64) {
+		x, y := coords[len(coords)-2], coords[len(coords)-1]
+		at := ends[lineEnd{key, x, y}]
+		if len(at) != 2 {
+			return -1, nil
+		}
+		for _, i := range at {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			c := lines[i].geom.coords
+			if c[0] == x && c[1] == y {
+				return i, c[2:]
+			}
+			return i, reversePoints(c)[2:]
+		}
+		return -1, nil
+	}
+	joined := make(map[*Feature]*Feature)
+	for i, f := range lines {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		key := keys[f]
+		coords := append([]float64(nil), f.geom.coords...)
+		members := []int{i}
+		for pass := 0; pass < 2; pass++ {
+			// extend from the end, then from the start
+			coords = reversePoints(coords)
+			for {
+				j, more := next(key, coords)
+				if j == -1 {
+					break
+				}
+				coords = append(coords, more...)
+				members = append(members, j)
+			}
+		}
+		if len(members) == 1 {
+			continue
+		}
+		line := &Feature{geomType: LineString, id: f.id, hasID: f.hasID,
+			tags: f.Tags(), layer: l}
+		line.geom.push(moveTo, coords[0], coords[1])
+		for j := 2; j < len(coords); j += 2 {
+			line.geom.push(lineTo, coords[j], coords[j+1])
+		}
+		for _, j := range members {
+			joined[lines[j]] = line
+		}
+	}
+	// each joined line takes the place of the first of its lines
+	merged := make([]*Feature, 0, len(features))
+	placed := make(map[*Feature]bool)
+	for _, f := range features {
+		if line, ok := joined[f]; ok {
+			if !placed[line] {
+				merged = append(merged, line)
+				placed[line] = true
+			}
+			continue
+		}
+		merged = append(merged, f)
+	}
+	return merged
+}
+
+// isSimpleLine returns true for a LineString of a single path
+func isSimpleLine(f *Feature) bool {
+	if f.geomType != LineString || len(f.geom.ops) < 2 ||
+		f.geom.ops[0] != moveTo {
+		return false
+	}
+	for _, op := range f.geom.ops[1:] {
+		if op != lineTo {
+			return false
+		}
+	}
+	return true
+}
+
+// reversePoints returns a copy of the x/y pairs in reverse order
+func reversePoints(coords []float64) []float64 {
+	rev := make([]float64, len(coords))
+	for i := 0; i < len(coords); i += 2 {
+		j := len(coords) - 2 - i
+		rev[j], rev[j+1] = coords[i], coords[i+1]
+	}
+	return rev
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("roads")
+	l.SetAutoID(0)
+	line := func(kind string, xy ...float64) {
+		f := l.AddFeature(LineString)
+		f.MoveTo(xy[0], xy[1])
+		for i := 2; i < len(xy); i += 2 {
+			f.LineTo(xy[i], xy[i+1])
+		}
+		f.AddTag("kind", kind)
+	}
+	line("road", 10, 0, 20, 0)        // 0
+	line("road", 0, 0, 10, 0)         // 1, before 0
+	line("path", 20, 0, 30, 0)        // 2, other tags
+	line("road", 30, 0, 20, 0)        // 3, reversed after 0
+	line("road", 50, 0, 60, 0)        // 4, a junction with 5 and 6
+	line("road", 60, 0, 70, 0)        // 5
+	line("road", 60, 0, 60, 10)       // 6
+	line("road", 0, 50, 0, 60, 0, 50) // 7, a ring
+	l.AddFeature(Point).MoveTo(0, 0)  // 8
+	l.SetMergeLines(true)
+	features := l.render()
+	if ids := featureIDs(features); ids != "[0 2 4 5 6 7 8]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if s := fmt.Sprint(features[0].geom.coords); s != "[0 0 10 0 20 0 30 0]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+	if len(l.Features()) != 9 {
+		t.Fatal("expected the layer to keep all features")
+	}
+	l.SetMergeLines(false)
+	if len(l.render()) != 9 {
+		t.Fatal("expected merging to be off")
+	}
+}
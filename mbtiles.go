@@ -0,0 +1,221 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// MBTilesWriter writes the tiles of a tileset to an MBTiles database, which
+// is opened by the caller with a SQLite driver, and its metadata, such as
+// from the emit func of pyramid.Build.
+type MBTilesWriter struct {
+	db       *sql.DB
+	tiles    *TileJSONBuilder
+	metadata map[string]string
+	dedup    bool
+	created  bool
+	images   map[string]bool
+}
+
+// NewMBTilesWriter returns a writer of the database, creating its metadata
+// table when it does not exist. The tables of the tiles are created by the
+// first WriteTile.
+func NewMBTilesWriter(ctx context.Context, db *sql.DB) (*MBTilesWriter,
+	error,
+) {
+	_, err := db.ExecContext(ctx,
+		"CREATE TABLE IF NOT EXISTS metadata (name text, value text)")
+	if err != nil {
+		return nil, err
+	}
+	return &MBTilesWriter{db: db, tiles: NewTileJSONBuilder(),
+		metadata: make(map[string]string)}, nil
+}
+
+// SetMetadata sets a row of the metadata, such as "name", "description",
+// or "attribution", which replaces the one that Finish would write
+func (w *MBTilesWriter) SetMetadata(name, value string) {
+	w.metadata[name] = value
+}
+
+// SetDedup sets the writer to store each distinct tile once, such as the
+// many empty ocean tiles of a tileset, in an "images" table that is keyed
+// by a hash of their data, which a "map" table maps the z/x/y of the tiles
+// to, with a "tiles" view of both. Default is false, which stores the
+// tiles in a "tiles" table. It must be called before the first WriteTile.
+func (w *MBTilesWriter) SetDedup(dedup bool) {
+	w.dedup = dedup
+}
+
+// createTables creates the tables of the tiles when they do not exist
+func (w *MBTilesWriter) createTables(ctx context.Context) error {
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS tiles (zoom_level integer, " +
+			"tile_column integer, tile_row integer, tile_data blob)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS tile_index ON tiles " +
+			"(zoom_level, tile_column, tile_row)",
+	}
+	if w.dedup {
+		stmts = []string{
+			"CREATE TABLE IF NOT EXISTS map (zoom_level integer, " +
+				"tile_column integer, tile_row integer, tile_id text)",
+			"CREATE UNIQUE INDEX IF NOT EXISTS map_index ON map " +
+				"(zoom_level, tile_column, tile_row)",
+			"CREATE TABLE IF NOT EXISTS images (tile_id text, " +
+				"tile_data blob)",
+			"CREATE UNIQUE INDEX IF NOT EXISTS images_id ON images " +
+				"(tile_id)",
+			"CREATE VIEW IF NOT EXISTS tiles AS SELECT " +
+				"map.zoom_level AS zoom_level, " +
+				"map.tile_column AS tile_column, " +
+				"map.tile_row AS tile_row, " +
+				"images.tile_data AS tile_data " +
+				"FROM map JOIN images ON images.tile_id = map.tile_id",
+		}
+		w.images = make(map[string]bool)
+	}
+	for _, stmt := range stmts {
+		if _, err := w.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	w.created = true
+	return nil
+}
+
+// WriteTile writes the encoded tile, which is gzipped as MBTiles requires,
+// replacing the tile that is already at its z/x/y.
+func (w *MBTilesWriter) WriteTile(ctx context.Context, id TileID,
+	tile []byte,
+) error {
+	if !w.created {
+		if err := w.createTables(ctx); err != nil {
+			return err
+		}
+	}
+	if err := w.tiles.AddTile(id, tile); err != nil {
+		return fmt.Errorf("%s: %w", id, err)
+	}
+	row := FlipY(id.Y, id.Z)
+	if !w.dedup {
+		data, err := gzipTile(tile)
+		if err != nil {
+			return err
+		}
+		_, err = w.db.ExecContext(ctx, "INSERT OR REPLACE INTO tiles "+
+			"(zoom_level, tile_column, tile_row, tile_data) "+
+			"VALUES (?, ?, ?, ?)", id.Z, id.X, row, data)
+		return err
+	}
+	sum := sha256.Sum256(tile)
+	hash := hex.EncodeToString(sum[:])
+	if !w.images[hash] {
+		data, err := gzipTile(tile)
+		if err != nil {
+			return err
+		}
+		_, err = w.db.ExecContext(ctx, "INSERT OR IGNORE INTO images "+
+			"(tile_id, tile_data) VALUES (?, ?)", hash, data)
+		if err != nil {
+			return err
+		}
+		w.images[hash] = true
+	}
+	_, err := w.db.ExecContext(ctx, "INSERT OR REPLACE INTO map "+
+		"(zoom_level, tile_column, tile_row, tile_id) VALUES (?, ?, ?, ?)",
+		id.Z, id.X, row, hash)
+	return err
+}
+
+// gzipTile returns the gzipped data of an encoded tile
+func gzipTile(tile []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(tile); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Finish writes the metadata of the tiles that were written, which are the
+// "format", "bounds", "center", "minzoom", and "maxzoom" rows, and the
+// "json" row of the vector layers and their fields that clients such as
+// MapLibre and QGIS use to find the layers, along with those that are set
+// with SetMetadata. The "name" is "tiles" unless it is set. The rows that
+// are already in the database with these names are replaced, and those
+// with other names, such as an "attribution" from another tool, are kept.
+func (w *MBTilesWriter) Finish(ctx context.Context) error {
+	rows, err := w.metadataRows()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// the table has no unique name to replace on
+		_, err := w.db.ExecContext(ctx,
+			"DELETE FROM metadata WHERE name = ?", name)
+		if err != nil {
+			return err
+		}
+		_, err = w.db.ExecContext(ctx,
+			"INSERT INTO metadata (name, value) VALUES (?, ?)",
+			name, rows[name])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataRows returns the rows of the metadata
+func (w *MBTilesWriter) metadataRows() (map[string]string, error) {
+	tj := w.tiles.TileJSON()
+	layers, err := json.Marshal(struct {
+		VectorLayers []VectorLayer `json:"vector_layers"`
+	}{tj.VectorLayers})
+	if err != nil {
+		return nil, err
+	}
+	floats := func(vals ...float64) string {
+		var b []byte
+		for i, v := range vals {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendFloat(b, v, 'f', -1, 64)
+		}
+		return string(b)
+	}
+	rows := map[string]string{
+		"name":    "tiles",
+		"format":  "pbf",
+		"bounds":  floats(tj.Bounds[:]...),
+		"center":  floats(tj.Center[:]...),
+		"minzoom": strconv.Itoa(tj.MinZoom),
+		"maxzoom": strconv.Itoa(tj.MaxZoom),
+		"json":    string(layers),
+	}
+	for name, value := range w.metadata {
+		rows[name] = value
+	}
+	return rows, nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMBTilesWriter(t *testing.T) {
+	ctx := context.Background()
+	db := openFakeDB(t, nil)
+	w, err := NewMBTilesWriter(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tile Tile
+	f := tile.AddLayer("roads").AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("name", "Main St")
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteTile(ctx, TileID{Z: 1, X: 1, Y: 0}, pb); err != nil {
+		t.Fatal(err)
+	}
+	w.SetMetadata("name", "roads")
+	if err := w.Finish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	queries := testDriver.queries
+	if len(queries) != 18 || !strings.HasPrefix(queries[0], "CREATE") {
+		t.Fatalf("unexpected statements %q", queries)
+	}
+	// the tile row is flipped, and the data is gzipped
+	args := testDriver.args[3]
+	if !strings.HasPrefix(queries[3], "INSERT OR REPLACE INTO tiles") ||
+		fmt.Sprint(args[:3]) != "[1 1 1]" {
+		t.Fatalf("unexpected statement %q", queries[3])
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(args[3].([]byte)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(zr); err != nil || !bytes.Equal(data, pb) {
+		t.Fatal("expected the gzipped tile")
+	}
+	for i, expect := range []string{
+		"[bounds 0,0,180,85.05112877980659]",
+		"[center 90,42.525564389903295,1]",
+		"[format pbf]",
+		`[json {"vector_layers":[{"id":"roads","minzoom":1,"maxzoom":1,` +
+			`"fields":{"name":"String"}}]}]`,
+		"[maxzoom 1]",
+		"[minzoom 1]",
+		"[name roads]",
+	} {
+		// only the rows that are written are deleted
+		name := strings.Fields(expect[1:])[0]
+		del := "DELETE FROM metadata WHERE name = ?[" + name + "]"
+		if queries[4+i*2] != del {
+			t.Fatalf("expected %s, got %s", del, queries[4+i*2])
+		}
+		if !strings.HasSuffix(queries[5+i*2], expect) {
+			t.Fatalf("expected %s, got %s", expect, queries[5+i*2])
+		}
+	}
+	if err := w.WriteTile(ctx, TileID{}, []byte{26, 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMBTilesDedup(t *testing.T) {
+	ctx := context.Background()
+	db := openFakeDB(t, nil)
+	w, err := NewMBTilesWriter(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetDedup(true)
+	ocean := encodeTestTile(t, "water")
+	land := encodeTestTile(t, "roads")
+	for i, pb := range [][]byte{ocean, land, ocean, ocean} {
+		if err := w.WriteTile(ctx, TileID{Z: 2, X: i, Y: 1}, pb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var images, tiles int
+	hashes := make(map[interface{}]bool)
+	for i, query := range testDriver.queries {
+		switch {
+		case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS tiles"):
+			t.Fatal("expected a view of the tiles")
+		case strings.HasPrefix(query, "INSERT OR IGNORE INTO images"):
+			images++
+		case strings.HasPrefix(query, "INSERT OR REPLACE INTO map"):
+			tiles++
+			hashes[testDriver.args[i][3]] = true
+		}
+	}
+	if images != 2 || tiles != 4 || len(hashes) != 2 {
+		t.Fatalf("expected 2 images of 4 tiles, got %d of %d", images, tiles)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// MercatorXY converts a Web Mercator (EPSG:3857) x/y in meters to a point
+// x/y for the specified map tile. The tile is 512x512.
+func MercatorXY(mx, my float64, tileX, tileY, tileZ int) (x, y float64) {
+	mapSize := float64(gMapSize(tileZ))
+	x = (mx+originShift)/(2*originShift)*mapSize - float64(tileX*gTileSize)
+	y = (originShift-my)/(2*originShift)*mapSize - float64(tileY*gTileSize)
+	return x, y
+}
+
+// mercatorXY converts a Web Mercator x/y in meters to a point x/y in the
+// tile that the feature belongs to.
+func (f *Feature) mercatorXY(mx, my float64) (x, y float64) {
+	id := f.layer.tileID()
+	return MercatorXY(mx, my, id.X, id.Y, id.Z)
+}
+
+// MoveToMercator moves to a Web Mercator (EPSG:3857) x/y in meters, which
+// is placed using the z/x/y of the tile, see Tile.SetTileID.
+func (f *Feature) MoveToMercator(mx, my float64) {
+	f.MoveTo(f.mercatorXY(mx, my))
+}
+
+// LineToMercator draws a line to a Web Mercator (EPSG:3857) x/y in meters,
+// which is placed using the z/x/y of the tile, see Tile.SetTileID.
+func (f *Feature) LineToMercator(mx, my float64) {
+	f.LineTo(f.mercatorXY(mx, my))
+}
+
+// AddMercatorPoint adds a Point feature at a Web Mercator (EPSG:3857) x/y
+// in meters, which is placed using the z/x/y of the tile, see
+// Tile.SetTileID.
+func (l *Layer) AddMercatorPoint(mx, my float64) *Feature {
+	f := l.AddFeature(Point)
+	f.MoveToMercator(mx, my)
+	return f
+}
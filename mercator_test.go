@@ -0,0 +1,26 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestMercatorXY(t *testing.T) {
+	if x, y := MercatorXY(0, 0, 0, 0, 0); x != 256 || y != 256 {
+		t.Fatalf("unexpected point %f %f", x, y)
+	}
+	var tile Tile
+	tile.SetTileID(TileID{1, 1, 0})
+	l := tile.AddLayer("projected")
+	f := l.AddMercatorPoint(originShift/2, originShift/2)
+	if c := f.geom.commands()[0]; c.x != 256 || c.y != 256 {
+		t.Fatalf("unexpected point %f %f", c.x, c.y)
+	}
+	f = l.AddFeature(LineString)
+	f.MoveToMercator(0, originShift)
+	f.LineToMercator(originShift, 0)
+	if c := f.geom.commands()[1]; c.x != 512 || c.y != 512 {
+		t.Fatalf("unexpected point %f %f", c.x, c.y)
+	}
+}
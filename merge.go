@@ -0,0 +1,426 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrInvalidTile is returned for an encoded tile that is malformed
+var ErrInvalidTile = errors.New("invalid tile")
+
+// tileLayer is a layer message of an encoded tile
+type tileLayer struct {
+	name string
+	msg  []byte
+}
+
+// splitTile returns the layers of an encoded tile, and its other fields,
+// such as extensions, as they are encoded.
+func splitTile(pb []byte) (layers []tileLayer, other []byte, err error) {
+	pr := pbfReader{data: pb}
+	for {
+		start := pr.data
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		if field != 3 || wire != pbfBytes {
+			pr.skip(wire)
+			other = append(other, start[:len(start)-len(pr.data)]...)
+			continue
+		}
+		msg := pr.bytes()
+		name, err := layerName(msg)
+		if err != nil {
+			return nil, nil, err
+		}
+		layers = append(layers, tileLayer{name, msg})
+	}
+	if pr.err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	return layers, other, nil
+}
+
+// layerName returns the name of a layer message
+func layerName(msg []byte) (string, error) {
+	pr := pbfReader{data: msg}
+	var name string
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		if field == 1 && wire == pbfBytes {
+			name = string(pr.bytes())
+		} else {
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	return name, nil
+}
+
+// renameLayer returns the layer message with a new name, and its other
+// fields as they are encoded.
+func renameLayer(msg []byte, name string) []byte {
+	pb := append([]byte{10}, appendString(nil, name)...)
+	pr := pbfReader{data: msg}
+	for {
+		start := pr.data
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		pr.skip(wire)
+		if field != 1 {
+			pb = append(pb, start[:len(start)-len(pr.data)]...)
+		}
+	}
+	return pb
+}
+
+// layerMessage is a decoded layer message, with its features and the
+// entries of its key and value tables as they are encoded
+type layerMessage struct {
+	name     string
+	version  uint64
+	extent   uint64
+	features [][]byte
+	keys     []string
+	values   [][]byte
+	other    []byte
+}
+
+// parseLayer decodes a layer message. Other fields, such as extensions,
+// are kept as they are encoded.
+func parseLayer(msg []byte) (*layerMessage, error) {
+	layer := &layerMessage{version: 1, extent: 4096}
+	pr := pbfReader{data: msg}
+	for {
+		start := pr.data
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			layer.name = string(pr.bytes())
+		case field == 2 && wire == pbfBytes:
+			layer.features = append(layer.features, pr.bytes())
+		case field == 3 && wire == pbfBytes:
+			layer.keys = append(layer.keys, string(pr.bytes()))
+		case field == 4 && wire == pbfBytes:
+			layer.values = append(layer.values, pr.bytes())
+		case field == 5 && wire == pbfVarint:
+			layer.extent = pr.uvarint()
+		case field == 15 && wire == pbfVarint:
+			layer.version = pr.uvarint()
+		default:
+			pr.skip(wire)
+			layer.other = append(layer.other,
+				start[:len(start)-len(pr.data)]...)
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	return layer, nil
+}
+
+// mergeLayers returns a layer message with the features of both of the
+// layer messages, and with key and value tables that are rebuilt from both
+// of theirs, to which the tags of the features are remapped. Only layers
+// of version 2 with the same extent can be merged, and false is returned
+// for others. The other fields of the first layer are kept.
+func mergeLayers(a, b []byte) ([]byte, bool, error) {
+	la, err := parseLayer(a)
+	if err != nil {
+		return nil, false, err
+	}
+	lb, err := parseLayer(b)
+	if err != nil {
+		return nil, false, err
+	}
+	if la.version != 2 || lb.version != 2 || la.extent != lb.extent {
+		return nil, false, nil
+	}
+	merged := &layerMessage{name: la.name, version: 2, extent: la.extent,
+		other: la.other}
+	keyidx := make(map[string]uint64)
+	validx := make(map[string]uint64)
+	for _, layer := range []*layerMessage{la, lb} {
+		keymap := make([]uint64, len(layer.keys))
+		for i, key := range layer.keys {
+			idx, ok := keyidx[key]
+			if !ok {
+				idx = uint64(len(merged.keys))
+				keyidx[key] = idx
+				merged.keys = append(merged.keys, key)
+			}
+			keymap[i] = idx
+		}
+		valmap := make([]uint64, len(layer.values))
+		for i, val := range layer.values {
+			idx, ok := validx[string(val)]
+			if !ok {
+				idx = uint64(len(merged.values))
+				validx[string(val)] = idx
+				merged.values = append(merged.values, val)
+			}
+			valmap[i] = idx
+		}
+		for _, feature := range layer.features {
+			feature, err := remapTags(feature, keymap, valmap)
+			if err != nil {
+				return nil, false, err
+			}
+			merged.features = append(merged.features, feature)
+		}
+	}
+	return merged.append(nil), true, nil
+}
+
+// remapTags returns the feature message with the key and value indexes of
+// its tags remapped.
+func remapTags(msg []byte, keymap, valmap []uint64) ([]byte, error) {
+	f, err := parseFeature(msg)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(f.tags); i += 2 {
+		if f.tags[i] >= uint64(len(keymap)) ||
+			f.tags[i+1] >= uint64(len(valmap)) {
+			return nil, fmt.Errorf("%w: tag index out of range",
+				ErrInvalidTile)
+		}
+		f.tags[i], f.tags[i+1] = keymap[f.tags[i]], valmap[f.tags[i+1]]
+	}
+	return f.append(nil), nil
+}
+
+// append appends the layer message, with its fields in the order that
+// Layer encodes them
+func (layer *layerMessage) append(pb []byte) []byte {
+	pb = append(pb, 10)
+	pb = appendString(pb, layer.name)
+	for _, feature := range layer.features {
+		pb = append(pb, 18)
+		pb = appendUvarint(pb, uint64(len(feature)))
+		pb = append(pb, feature...)
+	}
+	for _, key := range layer.keys {
+		pb = append(pb, encodeKey(key)...)
+	}
+	for _, val := range layer.values {
+		pb = append(pb, 34)
+		pb = appendUvarint(pb, uint64(len(val)))
+		pb = append(pb, val...)
+	}
+	if layer.extent != 4096 {
+		pb = append(pb, 40)
+		pb = appendUvarint(pb, layer.extent)
+	}
+	pb = append(pb, layer.other...)
+	pb = append(pb, 120)
+	return appendUvarint(pb, layer.version)
+}
+
+// mergeSharedLayers returns the encoded tile with each layer of version 2
+// merged into the first layer with its name, when they can be merged.
+func mergeSharedLayers(pb []byte) ([]byte, error) {
+	layers, other, err := splitTile(pb)
+	if err != nil {
+		return nil, err
+	}
+	var merged []tileLayer
+	names := make(map[string]int, len(layers))
+	for _, layer := range layers {
+		if i, ok := names[layer.name]; ok {
+			msg, ok, err := mergeLayers(merged[i].msg, layer.msg)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				merged[i].msg = msg
+				continue
+			}
+		} else {
+			names[layer.name] = len(merged)
+		}
+		merged = append(merged, layer)
+	}
+	tile := make([]byte, 0, len(pb))
+	for _, layer := range merged {
+		tile = appendTileLayer(tile, layer.msg)
+	}
+	return append(tile, other...), nil
+}
+
+// decodeValue decodes a value message of a layer
+func decodeValue(msg []byte) (interface{}, error) {
+	var v interface{}
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			v = string(pr.bytes())
+		case field == 2 && wire == pbfFixed32:
+			v = math.Float32frombits(pr.fixed32())
+		case field == 3 && wire == pbfFixed64:
+			v = pr.double()
+		case field == 4 && wire == pbfVarint:
+			v = int64(pr.uvarint())
+		case field == 5 && wire == pbfVarint:
+			v = pr.uvarint()
+		case field == 6 && wire == pbfVarint:
+			v = pr.varint()
+		case field == 7 && wire == pbfVarint:
+			v = pr.uvarint() != 0
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%w: value without a type", ErrInvalidTile)
+	}
+	return v, nil
+}
+
+// featureMessage is a decoded feature message, with its other fields as
+// they are encoded
+type featureMessage struct {
+	id       uint64
+	hasID    bool
+	tags     []uint64
+	geomType GeometryType
+	geometry []uint64
+	other    []byte
+}
+
+// parseFeature decodes a feature message
+func parseFeature(msg []byte) (*featureMessage, error) {
+	f := &featureMessage{}
+	pr := pbfReader{data: msg}
+	for {
+		start := pr.data
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfVarint:
+			f.id, f.hasID = pr.uvarint(), true
+		case field == 2:
+			f.tags = append(f.tags, pr.packed(wire)...)
+		case field == 3 && wire == pbfVarint:
+			f.geomType = GeometryType(pr.uvarint())
+		case field == 4:
+			f.geometry = append(f.geometry, pr.packed(wire)...)
+		default:
+			pr.skip(wire)
+			f.other = append(f.other, start[:len(start)-len(pr.data)]...)
+		}
+	}
+	if pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	if len(f.tags)%2 != 0 {
+		return nil, fmt.Errorf("%w: odd number of tag indexes",
+			ErrInvalidTile)
+	}
+	return f, nil
+}
+
+// append appends the feature message, with its id, tags, type, and
+// geometry first, as Feature encodes them
+func (f *featureMessage) append(pb []byte) []byte {
+	if f.hasID {
+		pb = append(pb, 8)
+		pb = appendUvarint(pb, f.id)
+	}
+	pb = appendPacked(pb, 18, f.tags)
+	if f.geomType != Unknown {
+		pb = append(pb, 24)
+		pb = appendUvarint(pb, uint64(f.geomType))
+	}
+	pb = appendPacked(pb, 34, f.geometry)
+	return append(pb, f.other...)
+}
+
+// appendTileLayer appends a layer message to an encoded tile
+func appendTileLayer(pb []byte, msg []byte) []byte {
+	pb = append(pb, 26)
+	pb = appendUvarint(pb, uint64(len(msg)))
+	return append(pb, msg...)
+}
+
+// MergeTiles returns an encoded tile with the layers of both of the
+// encoded tiles, those of a followed by those of b, such as to composite
+// tiles from separate tilesets when they are served. The layers are
+// copied without decoding their features. A layer of b with the name of a
+// layer that is already in the tile is merged into it when both are of
+// version 2 with the same extent, with their key and value tables rebuilt
+// into one, and is otherwise renamed with a numeric suffix, so that
+// "roads" becomes "roads_2". Other fields of the tiles, such as
+// extensions, are kept.
+func MergeTiles(a, b []byte) ([]byte, error) {
+	layersA, otherA, err := splitTile(a)
+	if err != nil {
+		return nil, err
+	}
+	layersB, otherB, err := splitTile(b)
+	if err != nil {
+		return nil, err
+	}
+	layers := layersA
+	names := make(map[string]int, len(layersA)+len(layersB))
+	for i, layer := range layersA {
+		if _, ok := names[layer.name]; !ok {
+			names[layer.name] = i
+		}
+	}
+	for _, layer := range layersB {
+		if i, ok := names[layer.name]; ok {
+			msg, ok, err := mergeLayers(layers[i].msg, layer.msg)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				layers[i].msg = msg
+				continue
+			}
+			name := layer.name
+			for n := 2; ; n++ {
+				if _, ok := names[name]; !ok {
+					break
+				}
+				name = layer.name + "_" + strconv.Itoa(n)
+			}
+			layer = tileLayer{name, renameLayer(layer.msg, name)}
+		}
+		names[layer.name] = len(layers)
+		layers = append(layers, layer)
+	}
+	pb := make([]byte, 0, len(a)+len(b))
+	for _, layer := range layers {
+		pb = appendTileLayer(pb, layer.msg)
+	}
+	pb = append(pb, otherA...)
+	return append(pb, otherB...), nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func encodeTestTile(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var tile Tile
+	for _, name := range names {
+		f := tile.AddLayer(name).AddFeature(Point)
+		f.MoveTo(1, 1)
+		f.AddTag("layer", name)
+	}
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb
+}
+
+func TestMergeTiles(t *testing.T) {
+	a := encodeTestTile(t, "roads", "water")
+	b := encodeTestTile(t, "places", "roads")
+	var tile Tile
+	l := tile.AddLayer("roads")
+	l.SetExtent(1024)
+	l.AddFeature(Point).MoveTo(1, 1)
+	c, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := MergeTiles(a, b)
+	if err == nil {
+		pb, err = MergeTiles(pb, c)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, other, err := splitTile(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, layer := range layers {
+		names = append(names, layer.name)
+	}
+	if fmt.Sprint(names) != "[roads water places roads_2]" || len(other) != 0 {
+		t.Fatalf("unexpected layers %v", names)
+	}
+	// the layers of the same extent are merged, and the other is renamed
+	roads, err := parseLayer(layers[0].msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roads.features) != 2 || fmt.Sprint(roads.keys) != "[layer]" ||
+		len(roads.values) != 1 {
+		t.Fatalf("unexpected merged layer %+v", roads)
+	}
+	if !bytes.HasPrefix(layers[3].msg, []byte("\x0a\x07roads_2")) {
+		t.Fatalf("unexpected layer %q", layers[3].msg)
+	}
+	if pb, err = MergeTiles(a, nil); err != nil || !bytes.Equal(pb, a) {
+		t.Fatal("expected the tile to be copied as it is")
+	}
+	if _, err = MergeTiles(a, b[:len(b)-1]); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
+
+func TestMergeLayers(t *testing.T) {
+	var tile Tile
+	tile.SetStrict(true)
+	for i, tags := range []map[string]interface{}{
+		{"class": "road", "lanes": 2},
+		{"lanes": 2, "name": "Main St"},
+	} {
+		f := tile.AddLayer("roads").AddFeature(Point)
+		f.MoveTo(float64(i), 1)
+		f.AddTags(tags)
+	}
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, _, err := splitTile(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("expected 1 layer, got %d", len(layers))
+	}
+	roads, err := parseLayer(layers[0].msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(roads.keys) != "[class lanes name]" ||
+		len(roads.values) != 3 {
+		t.Fatalf("unexpected tables %v %q", roads.keys, roads.values)
+	}
+	// class=road lanes=2, and lanes=2 name=Main St
+	for i, expect := range []string{"[0 0 1 1]", "[1 1 2 2]"} {
+		pr := pbfReader{data: roads.features[i]}
+		var tags []uint64
+		for {
+			field, wire, ok := pr.next()
+			if !ok {
+				break
+			}
+			if field == 2 {
+				tags = pr.packed(wire)
+			} else {
+				pr.skip(wire)
+			}
+		}
+		if fmt.Sprint(tags) != expect {
+			t.Fatalf("feature %d: expected tags %s, got %v", i, expect,
+				tags)
+		}
+	}
+	tile.Layers()[1].SetExtent(1024)
+	if _, err := tile.Encode(); !errors.Is(err, ErrDuplicateLayer) {
+		t.Fatalf("expected %v, got %v", ErrDuplicateLayer, err)
+	}
+}
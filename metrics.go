@@ -0,0 +1,88 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// haversine returns the distance in meters between the lon/lat points, on
+// a sphere of the radius of Web Mercator
+func haversine(a, b [2]float64) float64 {
+	lat1, lat2 := a[1]*math.Pi/180, b[1]*math.Pi/180
+	dlat, dlon := lat2-lat1, (b[0]-a[0])*math.Pi/180
+	h := math.Pow(math.Sin(dlat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dlon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Length returns the length in meters of the lines of a LineString, or of
+// the rings of a Polygon, along great circles. It is zero for a Point.
+func (g Geometry) Length() float64 {
+	if g.Type != LineString && g.Type != Polygon {
+		return 0
+	}
+	var length float64
+	for _, path := range g.Paths {
+		for i := 1; i < len(path); i++ {
+			length += haversine(path[i-1], path[i])
+		}
+		if g.Type == Polygon && len(path) > 2 {
+			length += haversine(path[len(path)-1], path[0])
+		}
+	}
+	return length
+}
+
+// Area returns the area in square meters of a Polygon on the sphere, with
+// the areas of its holes taken out, see Geometry.Rings. It is zero for
+// other geometries.
+func (g Geometry) Area() float64 {
+	if g.Type != Polygon {
+		return 0
+	}
+	// the holes are wound against their shells, which takes them out
+	var area float64
+	for _, ring := range g.orient().Paths {
+		var a float64
+		for i := range ring {
+			p, q := ring[i], ring[(i+1)%len(ring)]
+			dlon := math.Remainder(q[0]-p[0], 360) * math.Pi / 180
+			a += dlon * (2 + math.Sin(p[1]*math.Pi/180) +
+				math.Sin(q[1]*math.Pi/180))
+		}
+		area += a * earthRadius * earthRadius / 2
+	}
+	return math.Abs(area)
+}
+
+// Length returns the length of the lines of a LineString, or of the rings
+// of a Polygon, on the 512x512 canvas. It is zero for a Point.
+func (f *Feature) Length() float64 {
+	return f.length()
+}
+
+// Area returns the area of a Polygon on the 512x512 canvas, with the areas
+// of its holes taken out. It is zero for other geometries.
+func (f *Feature) Area() float64 {
+	return f.area()
+}
+
+// SetMetricTags sets whether the lat/lon geometries that are added to the
+// layer with AddGeometry, AddGeoFeature, or AddFrom get an "area_m2" tag,
+// for polygons, or a "length_m" tag, for lines, see Geometry.Area and
+// Geometry.Length. These are of the whole geometry, before it is clipped
+// to the tile. Default is false.
+func (l *Layer) SetMetricTags(metrics bool) {
+	l.metrics = metrics
+}
+
+// addMetrics adds the metric tags of the lat/lon geometry to the feature
+func (l *Layer) addMetrics(f *Feature, g Geometry) {
+	switch g.Type {
+	case LineString:
+		f.AddTag("length_m", g.Length())
+	case Polygon:
+		f.AddTag("area_m2", g.Area())
+	}
+}
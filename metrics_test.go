@@ -0,0 +1,88 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeometryMetrics(t *testing.T) {
+	line := Geometry{Type: LineString,
+		Paths: [][][2]float64{{{0, 0}, {1, 0}}, {{10, 0}, {10, 1}}}}
+	if l := line.Length(); math.Abs(l-2*111319.49) > 1 {
+		t.Fatalf("expected 222639 meters, got %v", l)
+	}
+	if line.Area() != 0 {
+		t.Fatal("expected no area for a line")
+	}
+	square := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+		{{0.25, 0.25}, {0.25, 0.75}, {0.75, 0.75}, {0.75, 0.25}},
+	}}
+	exp := 1.23907e10 * 0.75
+	if a := square.Area(); math.Abs(a-exp)/exp > 1e-4 {
+		t.Fatalf("expected %v square meters, got %v", exp, a)
+	}
+	if l := square.Length(); math.Abs(l-6*111319.49)/l > 1e-3 {
+		t.Fatalf("expected 667917 meters, got %v", l)
+	}
+	// a hole wound like its shell is still taken out, and a second
+	// polygon is added
+	same := Geometry{Type: Polygon, Paths: [][][2]float64{
+		square.Paths[0],
+		{{0.25, 0.25}, {0.75, 0.25}, {0.75, 0.75}, {0.25, 0.75}},
+		{{10, 0}, {11, 0}, {11, 1}, {10, 1}},
+	}, Rings: []int{2, 1}}
+	exp = 1.23907e10 * 1.75
+	if a := same.Area(); math.Abs(a-exp)/exp > 1e-4 {
+		t.Fatalf("expected %v square meters, got %v", exp, a)
+	}
+}
+
+func TestFeatureMetrics(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	f := l.AddFeature(Polygon)
+	f.MoveTo(0, 0)
+	f.LineTo(10, 0)
+	f.LineTo(10, 10)
+	f.LineTo(0, 10)
+	f.ClosePath()
+	f.MoveTo(2, 2)
+	f.LineTo(2, 4)
+	f.LineTo(4, 4)
+	f.LineTo(4, 2)
+	f.ClosePath()
+	if f.Area() != 96 || f.Length() != 48 {
+		t.Fatalf("expected 96 and 48, got %v and %v", f.Area(), f.Length())
+	}
+	ls := l.AddFeature(LineString)
+	ls.MoveTo(0, 0)
+	ls.LineTo(3, 4)
+	ls.LineTo(3, 10)
+	if ls.Length() != 11 || ls.Area() != 0 {
+		t.Fatalf("expected 11 and 0, got %v and %v", ls.Length(), ls.Area())
+	}
+
+	tile.SetTileID(TileID{Z: 0})
+	l.SetMetricTags(true)
+	f = l.AddGeometry(Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+	}})
+	v, ok := f.Tag("area_m2")
+	if !ok || math.Abs(v.(float64)-1.23907e10) > 1e6 {
+		t.Fatalf("unexpected area_m2 %v", v)
+	}
+	f = l.AddGeometry(Geometry{Type: LineString,
+		Paths: [][][2]float64{{{0, 0}, {1, 0}}}})
+	v, ok = f.Tag("length_m")
+	if !ok || math.Abs(v.(float64)-111319.49) > 1 {
+		t.Fatalf("unexpected length_m %v", v)
+	}
+	if _, ok := f.Tag("area_m2"); ok {
+		t.Fatal("expected no area_m2 for a line")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// SetMinFeatureSize sets the size, in pixels of the canvas, that the lines
+// and polygons that are added to the layer with AddGeometry, AddGeoFeature,
+// or AddFrom must reach at the zoom of the tile, see Tile.SetTileID, in
+// width or height. Smaller ones are not added, which leaves out the many
+// features that are too small to see in tiles of low zooms. The size is of
+// the whole geometry, before it is clipped to the tile. Default is zero,
+// which adds them all.
+func (l *Layer) SetMinFeatureSize(px float64) {
+	l.minSize = px
+}
+
+// tooSmall returns true when the lat/lon geometry is a line or polygon
+// that is smaller than the min feature size at the zoom of the tile
+func (l *Layer) tooSmall(g Geometry) bool {
+	if l.minSize <= 0 || g.Type == Point {
+		return false
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, path := range g.Paths {
+		for _, p := range path {
+			x, y := l.toPixel(p[1], p[0])
+			minX, maxX = min(minX, x), max(maxX, x)
+			minY, maxY = min(minY, y), max(maxY, y)
+		}
+	}
+	return maxX-minX < l.minSize && maxY-minY < l.minSize
+}
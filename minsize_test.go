@@ -0,0 +1,50 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestMinFeatureSize(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{Z: 10, X: 511, Y: 511})
+	l := tile.AddLayer("shapes")
+	l.SetMinFeatureSize(4)
+	// a pixel at zoom 10 is about 0.0007 degrees of longitude
+	square := func(size float64) Geometry {
+		return Geometry{Type: Polygon, Paths: [][][2]float64{{
+			{-0.1, 0.1}, {-0.1 + size, 0.1}, {-0.1 + size, 0.1 + size},
+			{-0.1, 0.1 + size},
+		}}}
+	}
+	if l.AddGeometry(square(0.002)) != nil {
+		t.Fatal("expected a small polygon to be left out")
+	}
+	if l.AddGeometry(square(0.004)) == nil {
+		t.Fatal("expected a large polygon")
+	}
+	line := Geometry{Type: LineString,
+		Paths: [][][2]float64{{{-0.1, 0.1}, {-0.1, 0.102}}}}
+	if l.AddGeometry(line) != nil {
+		t.Fatal("expected a small line to be left out")
+	}
+	point := Geometry{Type: Point, Paths: [][][2]float64{{{-0.1, 0.1}}}}
+	if l.AddGeometry(point) == nil {
+		t.Fatal("expected a point")
+	}
+	// the size is of the whole geometry, not of the part in the tile
+	long := Geometry{Type: LineString,
+		Paths: [][][2]float64{{{-0.1, 0.1}, {10, 0.1}}}}
+	if l.AddGeometry(long) == nil {
+		t.Fatal("expected a long line")
+	}
+	// the same polygon is large enough at a higher zoom
+	tile.SetTileID(TileID{Z: 12, X: 2046, Y: 2046})
+	if l.AddGeometry(square(0.002)) == nil {
+		t.Fatal("expected the polygon at zoom 12")
+	}
+	if n := len(l.Features()); n != 4 {
+		t.Fatalf("expected 4 features, got %d", n)
+	}
+}
@@ -5,36 +5,431 @@
 package mvt
 
 import (
+	"context"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRingNotClosed is returned when a polygon ring does not end with a
+	// ClosePath.
+	ErrRingNotClosed = errors.New("ring not closed")
+	// ErrRingRepeatsFirst is returned when a polygon ring ends with an
+	// explicit LineTo back to its first point.
+	ErrRingRepeatsFirst = errors.New("ring repeats first point")
+	// ErrTooFewPoints is returned when a line has fewer than two points or
+	// a polygon ring has fewer than three.
+	ErrTooFewPoints = errors.New("too few points")
+	// ErrBadWinding is returned when a polygon ring has no area or the
+	// first ring of a polygon is not an exterior ring.
+	ErrBadWinding = errors.New("bad winding order")
+	// ErrUnsupportedValue is returned for a tag value that has no encoding
+	// in the vector tile spec.
+	ErrUnsupportedValue = errors.New("unsupported tag value type")
+	// ErrUnsupportedVersion is returned for a layer version other than 1,
+	// 2, or 3.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrDuplicateLayer is returned when a layer has the same name as an
+	// earlier layer that it cannot be merged into, which version 2 and
+	// later of the spec forbid.
+	ErrDuplicateLayer = errors.New("duplicate layer name")
+	// ErrCommandCount is returned when a feature has a run of more
+	// geometry commands than the 29 bits of a command count can hold.
+	ErrCommandCount = errors.New("command count out of range")
+	// ErrTileTooLarge is returned when an encoded tile is larger than the
+	// size set with SetMaxSize.
+	ErrTileTooLarge = errors.New("tile too large")
 )
 
 // Tile represents a Mapbox Vector Tile
 type Tile struct {
-	layers []*Layer
+	layers    []*Layer
+	strict    bool
+	dropEmpty bool
+	maxSize   int
+	canonical bool
+	scheme    TilingScheme
+	matrix    *tileMatrix
+	id        TileID
+	other     []byte
 }
 
 // Layer represents a layer
 type Layer struct {
-	name      string
-	features  []*Feature
-	extent    uint32
-	hasExtent bool
+	tile       *Tile
+	name       string
+	features   []*Feature
+	extent     uint32
+	hasExtent  bool
+	autoClose  bool
+	sortTags   bool
+	nestedJSON bool
+	flatten    int
+	version    uint32
+	timeFormat TimeFormat
+	include    []string
+	exclude    []string
+	mapper     func(key string, val interface{}) (string, interface{}, bool)
+	idProperty string
+	promoted   map[uint64]bool
+	collisions int
+	autoID     bool
+	nextID     uint64
+	concurrent bool
+	mu         sync.Mutex
+	dropPolicy DropPolicy
+	cluster    *clusterOptions
+	mergeLines bool
+	dissolve   *dissolveOptions
+	densify    float64
+	labels     bool
+	centroids  *centroidOptions
+	bboxes     BBoxMode
+	grid       *gridOptions
+	elevation  SampleFunc
+	filter     *Filter
+	metrics    bool
+	minSize    float64
+	repair     bool
+	orient     bool
+	outPolicy  RangePolicy
+	outBuffer  float64
+	outVerts   int
+	outFeats   int
+	idPolicy   IDPolicy
+	less       func(a, b *Feature) bool
+	duplicates int
+	other      []byte
 }
 
+// TimeFormat is how time.Time tag values are encoded
+type TimeFormat int
+
+const (
+	// TimeRFC3339 encodes times as RFC 3339 strings
+	TimeRFC3339 TimeFormat = iota
+	// TimeUnix encodes times as seconds since the Unix epoch
+	TimeUnix
+	// TimeUnixMilli encodes times as milliseconds since the Unix epoch
+	TimeUnixMilli
+)
+
 // SetExtent sets the layers extent. Default is 4096.
 func (l *Layer) SetExtent(extent uint32) {
 	l.extent = extent
 	l.hasExtent = true
 }
 
-// AddLayer adds a layer
+// Extent returns the layers extent
+func (l *Layer) Extent() uint32 {
+	if l.hasExtent {
+		return l.extent
+	}
+	return 4096
+}
+
+// SetVersion sets the vector tile spec version that the layer is
+// encoded with. Default is 2. Version 1 is for legacy consumers and is
+// encoded the same as version 2, but its layer names need not be unique.
+// Version 3 is experimental and encodes tags as attributes from the 3.0
+// draft of the spec, which allows nested values. Encode returns
+// ErrUnsupportedVersion for any other version.
+func (l *Layer) SetVersion(version uint32) {
+	l.version = version
+}
+
+// Version returns the layers spec version
+func (l *Layer) Version() uint32 {
+	if l.version == 0 {
+		return 2
+	}
+	return l.version
+}
+
+// SetSortTags sets whether the key and value tables are sorted when
+// rendered. Sorted tables do not depend on the order that features and
+// tags were added. Default is false.
+func (l *Layer) SetSortTags(sortTags bool) {
+	l.sortTags = sortTags
+}
+
+// SetNestedJSON sets whether map, slice, and array tag values are encoded
+// as JSON strings, which clients can parse back into the original value.
+// Default is false, which encodes them in their Go string form.
+func (l *Layer) SetNestedJSON(nestedJSON bool) {
+	l.nestedJSON = nestedJSON
+}
+
+// SetTimeFormat sets how time.Time tag values are encoded. Default is
+// TimeRFC3339.
+func (l *Layer) SetTimeFormat(format TimeFormat) {
+	l.timeFormat = format
+}
+
+// SetConcurrentSafe sets whether features can be added to the layer from
+// multiple goroutines at once. Each feature must still be drawn by only
+// one goroutine at a time, and the tile must not be rendered until all
+// features are added. It must be set before the layer is shared.
+// Default is false.
+func (l *Layer) SetConcurrentSafe(concurrent bool) {
+	l.concurrent = concurrent
+}
+
+func (l *Layer) lock() {
+	if l.concurrent {
+		l.mu.Lock()
+	}
+}
+
+func (l *Layer) unlock() {
+	if l.concurrent {
+		l.mu.Unlock()
+	}
+}
+
+// SetAutoID sets the layer to give each feature a sequential id, beginning
+// with start, as it is added. A feature that is later given an id, with
+// SetID or SetIDProperty, keeps that id instead.
+func (l *Layer) SetAutoID(start uint64) {
+	l.autoID = true
+	l.nextID = start
+}
+
+// SetIDProperty sets the tag key whose value becomes the feature id when
+// the tag is added. The key and value are those that the tag mapper
+// returns, see SetPropertyMapper, and tags that it drops are not used.
+// Integers, integral floats, and numeric strings are used as ids, other
+// values are ignored. A feature whose id was already taken by an earlier
+// feature in the layer is left without an id, see IDCollisions. Default
+// is "", which disables it.
+func (l *Layer) SetIDProperty(key string) {
+	l.idProperty = key
+}
+
+// IDCollisions returns the number of features that were left without an
+// id because another feature already had it.
+func (l *Layer) IDCollisions() int {
+	return l.collisions
+}
+
+// promoteID sets the feature id from the tag value.
+func (l *Layer) promoteID(f *Feature, value interface{}) {
+	l.lock()
+	defer l.unlock()
+	id, ok := parseID(value)
+	if !ok {
+		return
+	}
+	if l.promoted[id] {
+		l.collisions++
+		return
+	}
+	if l.promoted == nil {
+		l.promoted = make(map[uint64]bool)
+	}
+	l.promoted[id] = true
+	f.SetID(id)
+}
+
+// parseID converts a tag value to a feature id.
+func parseID(value interface{}) (uint64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if rv.Int() >= 0 {
+			return uint64(rv.Int()), true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return rv.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		v := rv.Float()
+		if v >= 0 && v < math.MaxUint64 && v == math.Trunc(v) {
+			return uint64(v), true
+		}
+	case reflect.String:
+		id, err := strconv.ParseUint(strings.TrimSpace(rv.String()), 10, 64)
+		return id, err == nil
+	}
+	return 0, false
+}
+
+// toFloat converts a numeric tag value to a float64.
+func toFloat(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// SetPropertyMapper sets a function that is called for each tag as it is
+// added to the layers features, before flattening and the tag filter. It
+// returns the key and value to add, which allows for renaming and type
+// conversions, or false to drop the tag. Default is nil.
+func (l *Layer) SetPropertyMapper(
+	mapper func(key string, val interface{}) (string, interface{}, bool),
+) {
+	l.mapper = mapper
+}
+
+// SetTagFilter sets which tags are kept as they are added to the layers
+// features. When include is not empty only tags with those keys are kept,
+// and tags with keys in exclude are always dropped. A flattened key such
+// as "a.b" also matches the filter key "a".
+func (l *Layer) SetTagFilter(include, exclude []string) {
+	l.include = append([]string(nil), include...)
+	l.exclude = append([]string(nil), exclude...)
+}
+
+// keepTag returns true when the key passes the tag filter.
+func (l *Layer) keepTag(key string) bool {
+	if len(l.include) > 0 && !matchKey(l.include, key) {
+		return false
+	}
+	return !matchKey(l.exclude, key)
+}
+
+// matchKey returns true when the key, or the key it was flattened from,
+// is in keys.
+func matchKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if key == k || (strings.HasPrefix(key, k) && key[len(k)] == '.') {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFlatten sets how many levels of map tag values are flattened into
+// separate tags with dot-notation keys as they are added. With a depth of
+// one, the tag {"a":{"b":1}} is added as "a.b" = 1. Maps below the depth
+// are left as is. Default is zero, which disables flattening.
+func (l *Layer) SetFlatten(depth int) {
+	l.flatten = depth
+}
+
+// SetAutoClose sets whether polygon rings are closed when rendered.
+// A ring that is missing its ClosePath gets one, and an explicit LineTo
+// back to the first point of the ring is removed, as the spec requires.
+// Default is false.
+func (l *Layer) SetAutoClose(autoClose bool) {
+	l.autoClose = autoClose
+}
+
+// AddLayer adds a layer. Layers of version 2 that have the same name and
+// extent are merged into one when the tile is encoded.
 func (t *Tile) AddLayer(name string) *Layer {
-	t.layers = append(t.layers, &Layer{name: name})
+	if n := len(t.layers); n < cap(t.layers) && t.layers[:n+1][n] != nil {
+		// reuse a layer from before a Reset
+		l := t.layers[:n+1][n]
+		features := l.features[:0]
+		*l = Layer{name: name, tile: t, features: features}
+		t.layers = t.layers[:n+1]
+		return l
+	}
+	t.layers = append(t.layers, &Layer{name: name, tile: t})
 	return t.layers[len(t.layers)-1]
 }
 
+// Reset removes all layers and sets the z/x/y of the map tile, leaving the
+// tile as if it were new, other than keeping its allocated memory. The
+// removed layers and their features are reused by AddLayer and
+// AddFeature, so they must not be used after a Reset. This allows for
+// pooling tiles across requests.
+func (t *Tile) Reset(id TileID) {
+	t.layers = t.layers[:0]
+	t.strict = false
+	t.dropEmpty = false
+	t.maxSize = 0
+	t.canonical = false
+	t.scheme = WebMercator
+	t.matrix = nil
+	t.id = id
+	t.other = nil
+}
+
+// Reset removes all features from the layer, keeping its allocated memory
+// and its settings. The removed features are reused by AddFeature, so
+// they must not be used after a Reset.
+func (l *Layer) Reset() {
+	l.lock()
+	defer l.unlock()
+	l.features = l.features[:0]
+	l.promoted = nil
+	l.collisions = 0
+}
+
+// SetTileID sets the z/x/y of the map tile, which is used by the features
+// that draw in map coordinates rather than on the 512x512 canvas. Default
+// is 0/0/0.
+func (t *Tile) SetTileID(id TileID) {
+	t.id = id
+}
+
+// TileID returns the z/x/y of the map tile
+func (t *Tile) TileID() TileID {
+	return t.id
+}
+
+// tileID returns the z/x/y of the map tile that the layer belongs to.
+func (l *Layer) tileID() TileID {
+	if l == nil || l.tile == nil {
+		return TileID{}
+	}
+	return l.tile.id
+}
+
+// Layers returns the layers in the order they were added
+func (t *Tile) Layers() []*Layer {
+	return append([]*Layer(nil), t.layers...)
+}
+
+// GetLayer returns the first layer with the name, or nil if there is none
+func (t *Tile) GetLayer(name string) *Layer {
+	for _, layer := range t.layers {
+		if layer.name == name {
+			return layer
+		}
+	}
+	return nil
+}
+
+// RemoveLayer removes the first layer with the name. Returns false if
+// there is no such layer.
+func (t *Tile) RemoveLayer(name string) bool {
+	for i, layer := range t.layers {
+		if layer.name == name {
+			t.layers = append(t.layers[:i], t.layers[i+1:]...)
+			t.layers[:len(t.layers)+1][len(t.layers)] = nil
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the layers name
+func (l *Layer) Name() string {
+	return l.name
+}
+
 // GeometryType represents geometry type
 type GeometryType byte
 
@@ -49,9 +444,10 @@ const (
 	Polygon GeometryType = 3
 )
 
-type tag struct {
-	key string
-	val interface{}
+// Tag is a key/value attribute of a feature
+type Tag struct {
+	Key   string
+	Value interface{}
 }
 
 const (
@@ -70,14 +466,78 @@ type Feature struct {
 	geomType GeometryType
 	id       uint64
 	hasID    bool
-	tags     []tag
-	geometry []command
+	tags     []Tag
+	geom     geometry
+	newPath  bool
+	layer    *Layer
+	other    []byte
 }
 
 // AddFeature add a geometry feature
 func (l *Layer) AddFeature(geomType GeometryType) *Feature {
-	l.features = append(l.features, &Feature{geomType: geomType})
-	return l.features[len(l.features)-1]
+	l.lock()
+	defer l.unlock()
+	var f *Feature
+	if n := len(l.features); n < cap(l.features) && l.features[:n+1][n] != nil {
+		// reuse a feature from before a Reset
+		f = l.features[:n+1][n]
+		f.reset(geomType, l)
+		l.features = l.features[:n+1]
+	} else {
+		f = &Feature{geomType: geomType, layer: l}
+		l.features = append(l.features, f)
+	}
+	if l.autoID {
+		f.SetID(l.nextID)
+		l.nextID++
+	}
+	return f
+}
+
+// reset clears the feature for reuse, keeping its allocated memory.
+func (f *Feature) reset(geomType GeometryType, l *Layer) {
+	for i := range f.tags {
+		f.tags[i] = Tag{}
+	}
+	*f = Feature{
+		geomType: geomType,
+		layer:    l,
+		tags:     f.tags[:0],
+		geom:     geometry{ops: f.geom.ops[:0], coords: f.geom.coords[:0]},
+	}
+}
+
+// Features returns the features in the order they were added
+func (l *Layer) Features() []*Feature {
+	l.lock()
+	defer l.unlock()
+	return append([]*Feature(nil), l.features...)
+}
+
+// RemoveFeature removes the feature at index i. An index that is not of a
+// feature removes nothing.
+func (l *Layer) RemoveFeature(i int) {
+	l.lock()
+	defer l.unlock()
+	if i < 0 || i >= len(l.features) {
+		return
+	}
+	l.features = append(l.features[:i], l.features[i+1:]...)
+	l.features[:len(l.features)+1][len(l.features)] = nil
+}
+
+// Truncate removes all but the first n features. A negative n removes all
+// of them, and an n past the number of features removes none.
+func (l *Layer) Truncate(n int) {
+	l.lock()
+	defer l.unlock()
+	n = max(n, 0)
+	if n < len(l.features) {
+		for i := n; i < len(l.features); i++ {
+			l.features[i] = nil
+		}
+		l.features = l.features[:n]
+	}
 }
 
 // SetID set the id
@@ -86,45 +546,535 @@ func (f *Feature) SetID(id uint64) {
 	f.hasID = true
 }
 
+// ID returns the id and whether it was set
+func (f *Feature) ID() (uint64, bool) {
+	return f.id, f.hasID
+}
+
+// GeomType returns the geometry type
+func (f *Feature) GeomType() GeometryType {
+	return f.geomType
+}
+
+// Tags returns a copy of the tags in the order they were added
+func (f *Feature) Tags() []Tag {
+	return append([]Tag(nil), f.tags...)
+}
+
+// Tag returns the value of the first tag with the key
+func (f *Feature) Tag(key string) (value interface{}, ok bool) {
+	for _, tag := range f.tags {
+		if tag.Key == key {
+			return tag.Value, true
+		}
+	}
+	return nil, false
+}
+
 // AddTag adds a tag
 func (f *Feature) AddTag(key string, value interface{}) {
-	f.tags = append(f.tags, tag{key, value})
+	var depth int
+	if f.layer != nil {
+		if f.layer.mapper != nil {
+			var keep bool
+			key, value, keep = f.layer.mapper(key, value)
+			if !keep {
+				return
+			}
+		}
+		if f.layer.idProperty != "" && key == f.layer.idProperty {
+			f.layer.promoteID(f, value)
+		}
+		depth = f.layer.flatten
+	}
+	f.addTag(key, value, depth)
+}
+
+// addTag adds a tag that passes the layer tag filter. Map values are
+// added as separate tags, in key order, down to the depth.
+func (f *Feature) addTag(key string, value interface{}, depth int) {
+	if depth > 0 {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+			keys := rv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			for _, k := range keys {
+				f.addTag(key+"."+k.String(), rv.MapIndex(k).Interface(),
+					depth-1)
+			}
+			return
+		}
+	}
+	if f.layer != nil && !f.layer.keepTag(key) {
+		return
+	}
+	f.tags = append(f.tags, Tag{key, value})
+}
+
+// AddTags adds a tag for each entry in the map. Tags are added in key
+// order so that the same map always renders to the same bytes.
+func (f *Feature) AddTags(tags map[string]interface{}) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f.AddTag(key, tags[key])
+	}
+}
+
+// AddTagString adds a string tag
+func (f *Feature) AddTagString(key string, value string) {
+	f.AddTag(key, value)
+}
+
+// AddTagInt adds a signed integer tag
+func (f *Feature) AddTagInt(key string, value int64) {
+	f.AddTag(key, value)
+}
+
+// AddTagUint adds an unsigned integer tag
+func (f *Feature) AddTagUint(key string, value uint64) {
+	f.AddTag(key, value)
+}
+
+// AddTagFloat adds a double tag
+func (f *Feature) AddTagFloat(key string, value float64) {
+	f.AddTag(key, value)
+}
+
+// AddTagBool adds a boolean tag
+func (f *Feature) AddTagBool(key string, value bool) {
+	f.AddTag(key, value)
+}
+
+// Scalar is the set of types that have a tag value encoding in the spec
+type Scalar interface {
+	~string | ~bool | ~float32 | ~float64 |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// AddTagT adds a tag with a scalar value. Values of named types, such as
+// a string enum, are added as their underlying type.
+func AddTagT[T Scalar](f *Feature, key string, value T) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		f.AddTag(key, rv.String())
+	case reflect.Bool:
+		f.AddTag(key, rv.Bool())
+	case reflect.Float32:
+		f.AddTag(key, float32(rv.Float()))
+	case reflect.Float64:
+		f.AddTag(key, rv.Float())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		f.AddTag(key, rv.Int())
+	default:
+		f.AddTag(key, rv.Uint())
+	}
 }
 
 // MoveTo move to a point. The tile is 512x512.
 func (f *Feature) MoveTo(x, y float64) {
-	f.geometry = append(f.geometry, command{moveTo, x, y})
+	f.newPath = false
+	f.geom.push(moveTo, x, y)
 }
 
 // LineTo draws a line to a point. The tile is 512x512.
+// When called directly after NewPath, the point starts the new part.
 func (f *Feature) LineTo(x, y float64) {
-	f.geometry = append(f.geometry, command{lineTo, x, y})
+	if f.newPath {
+		f.MoveTo(x, y)
+		return
+	}
+	f.geom.push(lineTo, x, y)
+}
+
+// MoveTo3 moves to a point with an elevation, such as meters above sea
+// level, which is encoded only for layers of version 3, see SetVersion,
+// and dropped for others. Elevations are rounded to integers. Vertices that
+// are drawn without one are at zero, and they are all dropped when the
+// geometry is rebuilt, such as by clipping or the auto-closing of rings.
+func (f *Feature) MoveTo3(x, y, z float64) {
+	f.newPath = false
+	f.geom.push3(moveTo, x, y, z)
+}
+
+// LineTo3 draws a line to a point with an elevation, see MoveTo3.
+// When called directly after NewPath, the point starts the new part.
+func (f *Feature) LineTo3(x, y, z float64) {
+	if f.newPath {
+		f.MoveTo3(x, y, z)
+		return
+	}
+	f.geom.push3(lineTo, x, y, z)
 }
 
 // ClosePath closes a path
 func (f *Feature) ClosePath() {
-	f.geometry = append(f.geometry, command{closePath, 0, 0})
+	f.geom.push(closePath, 0, 0)
 }
 
-// Render renders the tile to a protobuf file for displaying on a map.
-func (t *Tile) Render() []byte {
-	var pb []byte
+// NewPath ends the current part and begins a new one, which is how
+// MultiLineString and MultiPolygon geometries are drawn. For Polygon
+// features the current ring is closed if it has not been already.
+// The next MoveTo or LineTo becomes the first point of the new part.
+func (f *Feature) NewPath() {
+	if f.geomType == Polygon && len(f.geom.ops) > 0 &&
+		f.geom.lastOp() != closePath {
+		f.ClosePath()
+	}
+	f.newPath = true
+}
+
+// paths splits the geometry into its parts.
+func (f *Feature) paths() [][]command {
+	return splitPaths(f.geom.commands())
+}
+
+// splitPaths splits geometry into its parts. Each part, other than
+// possibly the first, begins with a MoveTo.
+func splitPaths(geometry []command) [][]command {
+	var paths [][]command
+	var start int
+	for i := 1; i < len(geometry); i++ {
+		if geometry[i].which == moveTo {
+			paths = append(paths, geometry[start:i])
+			start = i
+		}
+	}
+	if len(geometry) > 0 {
+		paths = append(paths, geometry[start:])
+	}
+	return paths
+}
+
+// Validate checks the feature against the vector tile spec. Each Polygon
+// ring must end with a ClosePath, must not repeat its first point as the
+// last point, and must have at least three points. The first ring must
+// be an exterior ring, which has a positive area in tile coordinates.
+// Each LineString part must have at least two points. Tag values must be
+// one of the types that the spec can represent.
+func (f *Feature) Validate() error {
+	return f.validate(f.geom.commands())
+}
+
+func (f *Feature) validate(geometry []command) error {
+	for _, tag := range f.tags {
+		if !f.layer.isSupportedValue(tag.Value) {
+			return fmt.Errorf("tag %q: %w %T", tag.Key, ErrUnsupportedValue,
+				tag.Value)
+		}
+	}
+	switch f.geomType {
+	case LineString:
+		for i, path := range splitPaths(geometry) {
+			if len(pathPoints(path)) < 2 {
+				return fmt.Errorf("line %d: %w", i, ErrTooFewPoints)
+			}
+		}
+	case Polygon:
+		for i, path := range splitPaths(geometry) {
+			if path[len(path)-1].which != closePath {
+				return fmt.Errorf("ring %d: %w", i, ErrRingNotClosed)
+			}
+			if ringRepeatsFirst(path[:len(path)-1]) {
+				return fmt.Errorf("ring %d: %w", i, ErrRingRepeatsFirst)
+			}
+			points := pathPoints(path)
+			if len(points) < 3 {
+				return fmt.Errorf("ring %d: %w", i, ErrTooFewPoints)
+			}
+			area := ringArea(points)
+			if area == 0 || (i == 0 && area < 0) {
+				return fmt.Errorf("ring %d: %w", i, ErrBadWinding)
+			}
+		}
+	}
+	return nil
+}
+
+// pathPoints returns the MoveTo and LineTo commands of a path. A path
+// that does not begin with a MoveTo starts at the origin.
+func pathPoints(path []command) []command {
+	points := make([]command, 0, len(path)+1)
+	if len(path) > 0 && path[0].which != moveTo {
+		points = append(points, command{which: moveTo})
+	}
+	for _, cmd := range path {
+		if cmd.which == moveTo || cmd.which == lineTo {
+			points = append(points, cmd)
+		}
+	}
+	return points
+}
+
+// ringArea returns the area of a ring using the surveyor's formula. The
+// area is positive for exterior rings and negative for interior rings.
+func ringArea(points []command) float64 {
+	var area float64
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		area += a.x*b.y - b.x*a.y
+	}
+	return area / 2
+}
+
+// ringRepeatsFirst returns true when the last LineTo of a ring is at the
+// same point as its first MoveTo.
+func ringRepeatsFirst(ring []command) bool {
+	if len(ring) < 2 || ring[0].which != moveTo {
+		return false
+	}
+	last := ring[len(ring)-1]
+	return last.which == lineTo && last.x == ring[0].x && last.y == ring[0].y
+}
+
+// closeRings returns a copy of the geometry with every ring ending in a
+// single ClosePath and no explicit closing point.
+func closeRings(geometry []command) []command {
+	closed := make([]command, 0, len(geometry)+1)
+	for _, path := range splitPaths(geometry) {
+		for len(path) > 0 && path[len(path)-1].which == closePath {
+			path = path[:len(path)-1]
+		}
+		if ringRepeatsFirst(path) {
+			path = path[:len(path)-1]
+		}
+		if len(path) == 0 {
+			continue
+		}
+		closed = append(closed, path...)
+		closed = append(closed, command{which: closePath})
+	}
+	return closed
+}
+
+// SetStrict sets whether the tile is checked against the vector tile spec
+// when rendered. In strict mode Encode returns an error for any feature
+// that fails Validate. Default is false, in which features are encoded
+// as they were drawn.
+func (t *Tile) SetStrict(strict bool) {
+	t.strict = strict
+}
+
+// SetDropEmptyLayers sets whether the layers without features are left out
+// when the tile is rendered, which are those that have none left after
+// their render time options, such as a feature filter. Default is false,
+// in which every layer is encoded.
+func (t *Tile) SetDropEmptyLayers(drop bool) {
+	t.dropEmpty = drop
+}
+
+// SetMaxSize sets the most bytes that an encoded tile may have, as many
+// servers and caches reject larger tiles. Encode returns ErrTileTooLarge,
+// along with the layer and feature that went over, rather than a tile
+// that fails downstream. Default is zero, for no limit.
+func (t *Tile) SetMaxSize(bytes int) {
+	t.maxSize = bytes
+}
+
+// SetCanonical sets whether the tile is encoded in a canonical form, with
+// the layers sorted by name, the features of each layer by id, see ByID,
+// and the key and value tables sorted as with SetSortTags. Tiles with the
+// same content then encode to the same bytes, no matter the order that
+// they were drawn in, such as for ETags and CDN caching. The id order
+// replaces the order of SortFeatures. Default is false.
+func (t *Tile) SetCanonical(canonical bool) {
+	t.canonical = canonical
+}
+
+// IsEmpty returns true when none of the layers of the tile have features
+// to encode after their render time options, such that a server may
+// respond with no content rather than an empty tile.
+func (t *Tile) IsEmpty() bool {
+	for _, layer := range t.layers {
+		if len(layer.render()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode renders the tile to a protobuf file for displaying on a map. It
+// returns an error for a layer version other than 1 to 3
+// (ErrUnsupportedVersion), a run of commands that is too long
+// (ErrCommandCount), a tile over its max size (ErrTileTooLarge, see
+// SetMaxSize), duplicate feature ids in a layer with the IDError policy
+// (ErrDuplicateID), and shared layers that fail to merge
+// (ErrInvalidTile). In strict mode it also returns the validation errors
+// of the features, such as ErrRingNotClosed, and ErrDuplicateLayer for
+// layers with the same name that cannot be shared.
+func (t *Tile) Encode() ([]byte, error) {
+	return t.RenderContext(context.Background())
+}
+
+// checkInterval is the number of features between checks for a done
+// context.
+const checkInterval = 256
+
+// RenderContext is Encode, but stops and returns the context error when
+// the context is done, such as when an HTTP request is aborted.
+func (t *Tile) RenderContext(ctx context.Context) ([]byte, error) {
 	for _, layer := range t.layers {
-		pb = layer.append(pb)
+		if v := layer.Version(); v < 1 || v > 3 {
+			return nil, fmt.Errorf("layer %q: %w %d", layer.name,
+				ErrUnsupportedVersion, v)
+		}
 	}
+	var shared bool
+	names := make(map[string]*Layer)
+	for _, layer := range t.layers {
+		first := names[layer.name]
+		if first == nil {
+			names[layer.name] = layer
+			continue
+		}
+		shared = true
+		if t.strict && layer.Version() > 1 && !(first.Version() == 2 &&
+			layer.Version() == 2 && first.Extent() == layer.Extent()) {
+			return nil, fmt.Errorf("layer %q: %w", layer.name,
+				ErrDuplicateLayer)
+		}
+	}
+	layers := t.layers
+	if t.canonical {
+		layers = append([]*Layer(nil), layers...)
+		sort.SliceStable(layers, func(i, j int) bool {
+			return layers[i].name < layers[j].name
+		})
+	}
+	var pb []byte
+	for _, layer := range layers {
+		features := layer.render()
+		if t.canonical {
+			features = sortFeatures(features, ByID)
+		}
+		if t.dropEmpty && len(features) == 0 {
+			continue
+		}
+		if t.strict {
+			if err := layer.validate(ctx, features); err != nil {
+				return nil, err
+			}
+		}
+		if layer.idPolicy == IDError {
+			if err := layer.checkIDs(features); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if pb, err = layer.append(ctx, pb, features); err != nil {
+			return nil, err
+		}
+	}
+	pb = append(pb, t.other...)
+	if shared {
+		var err error
+		if pb, err = mergeSharedLayers(pb); err != nil {
+			return nil, err
+		}
+	}
+	if t.maxSize > 0 && len(pb) > t.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes, over %d", ErrTileTooLarge,
+			len(pb), t.maxSize)
+	}
+	return pb, nil
+}
+
+// render returns the features to encode, which are the layers features
+// after its render time options are applied.
+func (l *Layer) render() []*Feature {
+	features := l.features
+	if l.filter != nil {
+		features = l.filterFeatures(features)
+	}
+	if l.repair {
+		features = l.repairPolygons(features)
+	}
+	if l.orient {
+		features = l.orientPolygons(features)
+	}
+	if l.dissolve != nil {
+		features = l.dissolvePolygons(features)
+	}
+	if l.mergeLines {
+		features = l.joinLines(features)
+	}
+	if l.cluster != nil {
+		features = l.clusterPoints(features)
+	}
+	if l.grid != nil {
+		features = l.gridPoints(features)
+	}
+	if l.dropPolicy != nil {
+		if l.filter == nil && !l.repair && !l.orient &&
+			l.dissolve == nil && !l.mergeLines && l.cluster == nil &&
+			l.grid == nil {
+			// the policy may reorder the features of the layer
+			features = append([]*Feature(nil), features...)
+		}
+		features = l.dropPolicy(features, l.tileID().Z)
+	}
+	if l.less != nil {
+		features = sortFeatures(features, l.less)
+	}
+	if l.outPolicy != RangeAllow {
+		features = l.limitRange(features)
+	}
+	if l.idPolicy != IDAllow {
+		features = l.uniqueIDs(features)
+	}
+	return features
+}
+
+func (l *Layer) validate(ctx context.Context, features []*Feature) error {
+	for i, feature := range features {
+		if i%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		g := l.geometry(feature)
+		if err := feature.validate(g.commands()); err != nil {
+			return fmt.Errorf("layer %q: feature %d: %w", l.name, i, err)
+		}
+	}
+	return nil
+}
+
+// geometry returns the feature geometry as it will be encoded.
+func (l *Layer) geometry(f *Feature) geometry {
+	if f.geomType == Polygon && l.autoClose {
+		return packCommands(closeRings(f.geom.commands()))
+	}
+	return f.geom
+}
+
+// Render renders the tile to a protobuf file for displaying on a map.
+// It returns nil when Encode returns an error, such as when the tile is
+// over its max size or, in strict mode, fails validation. Use Encode to
+// get the error.
+func (t *Tile) Render() []byte {
+	pb, _ := t.Encode()
 	return pb
 }
 
-func (l *Layer) collectTags() (
+func (l *Layer) collectTags(features []*Feature) (
 	keysa, valsa []string,
 	tagidxs []int,
 ) {
 	var keyidx, validx int
 	keys := make(map[string]int)
 	vals := make(map[string]int)
-	for _, feature := range l.features {
+	for _, feature := range features {
 		for _, tag := range feature.tags {
-			key := encodeKey(tag.key)
+			key := encodeKey(tag.Key)
 			if idx, ok := keys[key]; !ok {
 				tagidxs = append(tagidxs, keyidx)
 				keys[key] = keyidx
@@ -133,7 +1083,7 @@ func (l *Layer) collectTags() (
 			} else {
 				tagidxs = append(tagidxs, idx)
 			}
-			val := encodeValue(tag.val)
+			val := encodeValue(l.normalizeValue(tag.Value))
 			if idx, ok := vals[val]; !ok {
 				tagidxs = append(tagidxs, validx)
 				vals[val] = validx
@@ -144,63 +1094,171 @@ func (l *Layer) collectTags() (
 			}
 		}
 	}
+	if l.sortTags || l.tile != nil && l.tile.canonical {
+		keyremap := sortTable(keysa)
+		valremap := sortTable(valsa)
+		for i := 0; i < len(tagidxs); i += 2 {
+			tagidxs[i] = keyremap[tagidxs[i]]
+			tagidxs[i+1] = valremap[tagidxs[i+1]]
+		}
+	}
 	return
 }
 
-func (l *Layer) append(vpb []byte) []byte {
-	var pb []byte
-	keysa, valsa, tagidxs := l.collectTags()
+// sortTable sorts the encoded table entries in place and returns a
+// mapping from each entry's old index to its new index.
+func sortTable(table []string) []int {
+	order := make([]int, len(table))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return table[order[i]] < table[order[j]]
+	})
+	sorted := make([]string, len(table))
+	remap := make([]int, len(table))
+	for i, idx := range order {
+		sorted[i] = table[idx]
+		remap[idx] = i
+	}
+	copy(table, sorted)
+	return remap
+}
 
+func (l *Layer) append(ctx context.Context, vpb []byte, features []*Feature,
+) ([]byte, error) {
+	var pb []byte
 	if len(l.name) > 0 {
 		pb = append(pb, 10)
 		pb = appendUvarint(pb, uint64(len(l.name)))
 		pb = append(pb, l.name...)
 	}
-	var extent float64 = 4096
-	if l.hasExtent {
-		extent = float64(l.extent)
-	}
-	for _, feature := range l.features {
-		pb, tagidxs = feature.append(pb, tagidxs, extent)
-	}
-	for _, v := range keysa {
-		pb = append(pb, v...)
+	var err error
+	if l.Version() == 3 {
+		pb, err = l.appendV3(ctx, len(vpb), pb, features)
+	} else {
+		pb, err = l.appendV2(ctx, len(vpb), pb, features)
 	}
-	for _, v := range valsa {
-		pb = append(pb, v...)
+	if err != nil {
+		return nil, err
 	}
 	if l.hasExtent && l.extent != 4096 {
 		pb = append(pb, 40)
 		pb = appendUvarint(pb, uint64(l.extent))
 	}
+	// fields from a decoded tile that the spec does not define
+	pb = append(pb, l.other...)
 	// add version
-	pb = append(pb, 120, 2)
+	pb = append(pb, 120)
+	pb = appendUvarint(pb, uint64(l.Version()))
 
 	// add the size to the beginning
 	vpb = append(vpb, 26)
 	vpb = appendUvarint(vpb, uint64(len(pb)))
 	vpb = append(vpb, pb...)
-	return vpb
+	if limit := l.maxSize(); limit > 0 && len(vpb) > limit {
+		return nil, fmt.Errorf("layer %q: %w: %d bytes, over %d", l.name,
+			ErrTileTooLarge, len(vpb), limit)
+	}
+	return vpb, nil
+}
+
+// maxSize returns the most bytes that the tile of the layer may have, or
+// zero for no limit.
+func (l *Layer) maxSize() int {
+	if l.tile == nil {
+		return 0
+	}
+	return l.tile.maxSize
+}
+
+// appendFeature appends the feature, where used is the size of the tile
+// before the layer, and reports the errors by layer and feature.
+func (l *Layer) appendFeature(pb []byte, used, i int, f *Feature,
+	attrs []byte,
+) ([]byte, error) {
+	pb, err := f.append(pb, attrs, l)
+	if err == nil {
+		if limit := l.maxSize(); limit > 0 && used+len(pb) > limit {
+			err = fmt.Errorf("%w: %d bytes, over %d", ErrTileTooLarge,
+				used+len(pb), limit)
+		}
+	}
+	if err != nil {
+		if f.hasID {
+			return nil, fmt.Errorf("layer %q: feature id %d: %w", l.name,
+				f.id, err)
+		}
+		return nil, fmt.Errorf("layer %q: feature %d: %w", l.name, i, err)
+	}
+	return pb, nil
+}
+
+// appendV2 appends the features along with the key and value tables, where
+// used is the size of the tile before the layer.
+func (l *Layer) appendV2(ctx context.Context, used int, pb []byte,
+	features []*Feature,
+) ([]byte, error) {
+	keysa, valsa, tagidxs := l.collectTags(features)
+	for i, feature := range features {
+		if i%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		n := len(feature.tags) * 2
+		var err error
+		pb, err = l.appendFeature(pb, used, i, feature,
+			appendPacked(nil, 18, tagidxs[:n]))
+		if err != nil {
+			return nil, err
+		}
+		tagidxs = tagidxs[n:]
+	}
+	for _, v := range keysa {
+		pb = append(pb, v...)
+	}
+	for _, v := range valsa {
+		pb = append(pb, v...)
+	}
+	return pb, nil
+}
+
+// appendPacked appends a packed field of varints. Nothing is appended
+// when there are no values.
+func appendPacked[T int | uint64](pb []byte, key byte, vals []T) []byte {
+	if len(vals) == 0 {
+		return pb
+	}
+	var vpb []byte
+	for _, v := range vals {
+		vpb = appendUvarint(vpb, uint64(v))
+	}
+	pb = append(pb, key)
+	pb = appendUvarint(pb, uint64(len(vpb)))
+	return append(pb, vpb...)
 }
 
-func (f *Feature) append(
-	vpb []byte, tagidxs []int, extent float64,
-) ([]byte, []int) {
+// maxCommandCount is the largest count of a geometry command, which is
+// held in 29 bits.
+const maxCommandCount = 1<<29 - 1
+
+// append appends the feature, where attrs is the encoded tags or
+// attributes field. ErrCommandCount is returned for a run of commands
+// that is too long to encode.
+func (f *Feature) append(vpb []byte, attrs []byte, l *Layer,
+) ([]byte, error) {
+	var extent float64 = 4096
+	if l.hasExtent {
+		extent = float64(l.extent)
+	}
+	g := l.geometry(f)
 	var pb []byte
 	if f.hasID {
 		pb = append(pb, 8)
 		pb = appendUvarint(pb, f.id)
 	}
-
-	if len(f.tags) > 0 {
-		pb = append(pb, 18)
-		pb = appendUvarint(pb, uint64(len(f.tags)*2))
-		for range f.tags {
-			pb = appendUvarint(pb, uint64(tagidxs[0]))
-			pb = appendUvarint(pb, uint64(tagidxs[1]))
-			tagidxs = tagidxs[2:]
-		}
-	}
+	pb = append(pb, attrs...)
 
 	switch f.geomType {
 	default:
@@ -210,25 +1268,29 @@ func (f *Feature) append(
 		// optional
 	}
 
-	if len(f.geometry) > 0 {
+	if len(g.ops) > 0 {
 		var gpb []byte
 		var lastx, lasty int64
 		var total int
-		if f.geometry[0].which != moveTo {
+		if g.ops[0] != moveTo {
 			gpb = appendUvarint(gpb, uint64(commandInteger(moveTo, 1)))
 			gpb = appendVarint(gpb, 0)
 			gpb = appendVarint(gpb, 0)
 			total += 3
 		}
-		for i := 0; i < len(f.geometry); {
+		coords := g.coords
+		for i := 0; i < len(g.ops); {
 			count := 1
-			which := f.geometry[i].which
-			for j := i + 1; j < len(f.geometry); j++ {
-				if f.geometry[j].which != which {
+			which := int(g.ops[i])
+			for j := i + 1; j < len(g.ops); j++ {
+				if int(g.ops[j]) != which {
 					break
 				}
 				count++
 			}
+			if count > maxCommandCount {
+				return nil, fmt.Errorf("%w: %d", ErrCommandCount, count)
+			}
 			gpb = appendUvarint(gpb, uint64(commandInteger(which, count)))
 			total++
 			switch which {
@@ -236,8 +1298,9 @@ func (f *Feature) append(
 				i++
 			case moveTo, lineTo:
 				for j := 0; j < count; j++ {
-					x := int64(f.geometry[i+j].x / 512.0 * extent)
-					y := int64(f.geometry[i+j].y / 512.0 * extent)
+					x := int64(coords[0] / 512.0 * extent)
+					y := int64(coords[1] / 512.0 * extent)
+					coords = coords[2:]
 					relx, rely := x-lastx, y-lasty
 					lastx, lasty = x, y
 					gpb = appendVarint(gpb, relx)
@@ -251,13 +1314,17 @@ func (f *Feature) append(
 		pb = appendUvarint(pb, uint64(len(gpb)))
 		pb = append(pb, gpb...)
 
+		if zs := g.elevations(); zs != nil && l.Version() == 3 {
+			pb = appendElevations(pb, zs)
+		}
 	}
+	pb = append(pb, f.other...)
 
 	// add the size to the beginning
 	vpb = append(vpb, 18)
 	vpb = appendUvarint(vpb, uint64(len(pb)))
 	vpb = append(vpb, pb...)
-	return vpb, tagidxs
+	return vpb, nil
 }
 
 func commandInteger(id, count int) uint32 {
@@ -303,6 +1370,11 @@ func encodeValue(v interface{}) string {
 		return encodeValue(int64(v))
 	case int32:
 		return encodeValue(int64(v))
+	case int:
+		// ints and uints were strings, from the default, in earlier versions
+		return encodeValue(int64(v))
+	case uint:
+		return encodeValue(uint64(v))
 	case []byte:
 		return encodeValue(string(v))
 	default:
@@ -315,6 +1387,66 @@ func encodeValue(v interface{}) string {
 	return string(pb)
 }
 
+// normalizeValue converts a tag value into one with a spec encoding when
+// the layer options allow it.
+func (l *Layer) normalizeValue(v interface{}) interface{} {
+	if tm, ok := v.(time.Time); ok {
+		var format TimeFormat
+		if l != nil {
+			format = l.timeFormat
+		}
+		switch format {
+		case TimeUnix:
+			return tm.Unix()
+		case TimeUnixMilli:
+			return tm.UnixMilli()
+		default:
+			return tm.Format(time.RFC3339Nano)
+		}
+	}
+	if l == nil {
+		return v
+	}
+	if l.nestedJSON && isNestedValue(v) {
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return v
+}
+
+// isNestedValue returns true for map, slice, and array values other
+// than []byte.
+func isNestedValue(v interface{}) bool {
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// isSupportedValue returns true when the layer has a spec encoding for v.
+func (l *Layer) isSupportedValue(v interface{}) bool {
+	if l != nil && l.Version() == 3 {
+		return isSupportedAttribute(l.normalizeValue(v))
+	}
+	return isSupportedValue(l.normalizeValue(v))
+}
+
+// isSupportedValue returns true when encodeValue has a spec encoding for v
+// rather than falling back to its string form.
+func isSupportedValue(v interface{}) bool {
+	switch v.(type) {
+	case string, []byte, bool, float32, float64,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
+
 func appendString(pb []byte, s string) []byte {
 	pb = appendUvarint(pb, uint64(len(s)))
 	return append(pb, s...)
@@ -341,11 +1473,7 @@ func quadratic(x0, y0, x1, y1, x2, y2, t float64) (x, y float64) {
 
 // QuadraticTo draw a quadratic curve
 func (f *Feature) QuadraticTo(x1, y1, x2, y2 float64) {
-	var x0, y0 float64
-	if len(f.geometry) > 0 {
-		x0 = f.geometry[len(f.geometry)-1].x
-		y0 = f.geometry[len(f.geometry)-1].y
-	}
+	x0, y0 := f.geom.current()
 	l := (math.Hypot(x1-x0, y1-y0) +
 		math.Hypot(x2-x1, y2-y1))
 	n := int(l + 0.5)
@@ -372,11 +1500,7 @@ func cubic(x0, y0, x1, y1, x2, y2, x3, y3, t float64) (x, y float64) {
 
 // CubicTo draw a cubic curve
 func (f *Feature) CubicTo(x1, y1, x2, y2, x3, y3 float64) {
-	var x0, y0 float64
-	if len(f.geometry) > 0 {
-		x0 = f.geometry[len(f.geometry)-1].x
-		y0 = f.geometry[len(f.geometry)-1].y
-	}
+	x0, y0 := f.geom.current()
 	l := (math.Hypot(x1-x0, y1-y0) +
 		math.Hypot(x2-x1, y2-y1) +
 		math.Hypot(x3-x2, y3-y2))
@@ -400,6 +1524,7 @@ const (
 )
 
 // LatLonXY converts a lat/lon to an point x/y for the specified map tile.
+// The tile is 512x512.
 func LatLonXY(lat, lon float64, tileX, tileY, tileZ int) (x, y float64) {
 	lat = clamp(lat, gMinLat, gMaxLat)
 	lon = clamp(lon, gMinLon, gMaxLon)
@@ -409,7 +1534,31 @@ func LatLonXY(lat, lon float64, tileX, tileY, tileZ int) (x, y float64) {
 	mapSize := float64(uint64(512) << uint(tileZ))
 	pixelX := clamp(lx*mapSize+0, 0, mapSize)
 	pixelY := clamp(ly*mapSize+0, 0, mapSize)
-	return pixelX - float64(tileX<<8), pixelY - float64(tileY<<8)
+	offX, offY := tileXYToPixelXY(tileX, tileY)
+	return pixelX - float64(offX), pixelY - float64(offY)
+}
+
+// LatLonXYBatch converts lat/lons to point x/ys for the specified map tile,
+// like calling LatLonXY for each lat/lon, and stores them in dstX and
+// dstY, which must be at least as long as lats and lons. The tile is
+// 512x512.
+func LatLonXYBatch(lats, lons []float64, tileX, tileY, tileZ int,
+	dstX, dstY []float64,
+) {
+	mapSize := float64(uint64(512) << uint(tileZ))
+	tx, ty := tileXYToPixelXY(tileX, tileY)
+	offX, offY := float64(tx), float64(ty)
+	dstX, dstY = dstX[:len(lats)], dstY[:len(lats)]
+	lons = lons[:len(lats)]
+	for i, lat := range lats {
+		lat = clamp(lat, gMinLat, gMaxLat)
+		lon := clamp(lons[i], gMinLon, gMaxLon)
+		lx := (lon + 180) / 360
+		sinLat := math.Sin(lat * math.Pi / 180)
+		ly := 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
+		dstX[i] = clamp(lx*mapSize, 0, mapSize) - offX
+		dstY[i] = clamp(ly*mapSize, 0, mapSize) - offY
+	}
 }
 
 func clamp(v, lo, hi float64) float64 {
@@ -422,8 +1571,10 @@ func clamp(v, lo, hi float64) float64 {
 	return v
 }
 
+// tileXYToPixelXY returns the pixel of the northwest corner of a map tile
+// on the whole map.
 func tileXYToPixelXY(tileX, tileY int) (pixelX, pixelY int) {
-	return tileX << 8, tileY << 8
+	return tileX * gTileSize, tileY * gTileSize
 }
 
 func gMapSize(levelOfDetail int) uint64 {
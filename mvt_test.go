@@ -5,8 +5,15 @@
 package mvt
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDraw(t *testing.T) {
@@ -89,3 +96,660 @@ func TestParallelLayerPop(t *testing.T) {
 		t.Fatal("Failed to populate tile layers in parallel")
 	}
 }
+
+func TestNewPath(t *testing.T) {
+	var tile Tile
+	f := tile.AddLayer("parts").AddFeature(Polygon)
+	f.MoveTo(0, 0)
+	f.LineTo(10, 0)
+	f.LineTo(10, 10)
+	f.NewPath()
+	f.LineTo(20, 20)
+	f.LineTo(30, 20)
+	f.LineTo(30, 30)
+	f.ClosePath()
+	paths := f.paths()
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 paths, got %d", len(paths))
+	}
+	if paths[0][len(paths[0])-1].which != closePath {
+		t.Fatal("expected first ring to be closed")
+	}
+	if paths[1][0].which != moveTo || paths[1][0].x != 20 {
+		t.Fatal("expected second ring to start with a MoveTo")
+	}
+}
+
+func TestAutoClose(t *testing.T) {
+	var expect Tile
+	f := expect.AddLayer("rings").AddFeature(Polygon)
+	f.MoveTo(128, 96)
+	f.LineTo(148, 128)
+	f.LineTo(108, 128)
+	f.ClosePath()
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tile Tile
+	l := tile.AddLayer("rings")
+	f = l.AddFeature(Polygon)
+	f.MoveTo(128, 96)
+	f.LineTo(148, 128)
+	f.LineTo(108, 128)
+	f.LineTo(128, 96)
+	if err := f.Validate(); !errors.Is(err, ErrRingNotClosed) {
+		t.Fatalf("expected %v, got %v", ErrRingNotClosed, err)
+	}
+	f.ClosePath()
+	if err := f.Validate(); !errors.Is(err, ErrRingRepeatsFirst) {
+		t.Fatalf("expected %v, got %v", ErrRingRepeatsFirst, err)
+	}
+	l.SetAutoClose(true)
+	if !bytes.Equal(tile.Render(), expect.Render()) {
+		t.Fatal("auto closed ring does not match")
+	}
+}
+
+func TestStrict(t *testing.T) {
+	var tile Tile
+	tile.SetStrict(true)
+	l := tile.AddLayer("strict")
+	f := l.AddFeature(Polygon)
+	f.MoveTo(128, 96)
+	f.LineTo(108, 128)
+	f.LineTo(148, 128)
+	f.ClosePath()
+	if _, err := tile.Encode(); !errors.Is(err, ErrBadWinding) {
+		t.Fatalf("expected %v, got %v", ErrBadWinding, err)
+	}
+	if tile.Render() != nil {
+		t.Fatal("expected nil render in strict mode")
+	}
+	tile.SetStrict(false)
+	if tile.Render() == nil {
+		t.Fatal("expected render in lenient mode")
+	}
+
+	tile = Tile{}
+	tile.SetStrict(true)
+	f = tile.AddLayer("strict").AddFeature(LineString)
+	f.MoveTo(0, 0)
+	f.LineTo(10, 10)
+	if _, err := tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	f.MoveTo(20, 20)
+	if _, err := tile.Encode(); !errors.Is(err, ErrTooFewPoints) {
+		t.Fatalf("expected %v, got %v", ErrTooFewPoints, err)
+	}
+
+	tile = Tile{}
+	tile.SetStrict(true)
+	f = tile.AddLayer("strict").AddFeature(Point)
+	f.MoveTo(0, 0)
+	f.AddTag("nested", []int{1, 2})
+	if _, err := tile.Encode(); !errors.Is(err, ErrUnsupportedValue) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedValue, err)
+	}
+}
+
+func TestFeatureAccessors(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("readback")
+	if l.Extent() != 4096 {
+		t.Fatalf("expected default extent, got %d", l.Extent())
+	}
+	f := l.AddFeature(LineString)
+	if _, ok := f.ID(); ok {
+		t.Fatal("expected no id")
+	}
+	f.SetID(7)
+	f.AddTag("name", "main st")
+	f.AddTag("lanes", int64(2))
+	if id, ok := f.ID(); !ok || id != 7 {
+		t.Fatalf("expected id 7, got %d", id)
+	}
+	if f.GeomType() != LineString {
+		t.Fatalf("expected %v, got %v", LineString, f.GeomType())
+	}
+	tags := f.Tags()
+	if len(tags) != 2 || tags[0].Key != "name" || tags[1].Value != int64(2) {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if v, ok := f.Tag("name"); !ok || v != "main st" {
+		t.Fatalf("unexpected tag value %v", v)
+	}
+	if _, ok := f.Tag("missing"); ok {
+		t.Fatal("expected missing tag")
+	}
+}
+
+func TestLayerManagement(t *testing.T) {
+	var tile Tile
+	roads := tile.AddLayer("roads")
+	tile.AddLayer("water")
+	if tile.GetLayer("roads") != roads || roads.Name() != "roads" {
+		t.Fatal("expected to find roads layer")
+	}
+	if tile.GetLayer("buildings") != nil {
+		t.Fatal("expected no buildings layer")
+	}
+	if !tile.RemoveLayer("roads") || tile.RemoveLayer("roads") {
+		t.Fatal("expected roads to be removed once")
+	}
+	layers := tile.Layers()
+	if len(layers) != 1 || layers[0].Name() != "water" {
+		t.Fatalf("unexpected layers %v", layers)
+	}
+}
+
+func TestFeatureRemoval(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	for i := 0; i < 5; i++ {
+		l.AddFeature(Point).SetID(uint64(i))
+	}
+	l.RemoveFeature(1)
+	l.Truncate(3)
+	l.Truncate(10)
+	features := l.Features()
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	for i, expect := range []uint64{0, 2, 3} {
+		if id, _ := features[i].ID(); id != expect {
+			t.Fatalf("expected id %d, got %d", expect, id)
+		}
+	}
+	// indexes that are not of a feature remove nothing
+	l.RemoveFeature(-1)
+	l.RemoveFeature(3)
+	if n := len(l.Features()); n != 3 {
+		t.Fatalf("expected 3 features, got %d", n)
+	}
+	l.RemoveFeature(2)
+	if n := len(l.Features()); n != 2 {
+		t.Fatalf("expected 2 features, got %d", n)
+	}
+	l.Truncate(-1)
+	if n := len(l.Features()); n != 0 {
+		t.Fatalf("expected no features, got %d", n)
+	}
+	l.RemoveFeature(0)
+	l.Truncate(0)
+}
+
+func TestAddTags(t *testing.T) {
+	props := map[string]interface{}{
+		"name": "park", "area": 12.5, "public": true, "id": int64(4),
+	}
+	var first []byte
+	for i := 0; i < 10; i++ {
+		var tile Tile
+		f := tile.AddLayer("parks").AddFeature(Point)
+		f.MoveTo(1, 1)
+		f.AddTags(props)
+		pb := tile.Render()
+		if i == 0 {
+			first = pb
+			tags := f.Tags()
+			if len(tags) != 4 || tags[0].Key != "area" || tags[3].Key != "public" {
+				t.Fatalf("unexpected tag order %v", tags)
+			}
+		} else if !bytes.Equal(pb, first) {
+			t.Fatal("expected identical renders")
+		}
+	}
+}
+
+func TestSortTags(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("sorted")
+	l.SetSortTags(true)
+	f := l.AddFeature(Point)
+	f.AddTag("b", int64(2))
+	f.AddTag("a", "one")
+	f = l.AddFeature(Point)
+	f.AddTag("a", "two")
+	keys, vals, tagidxs := l.collectTags(l.features)
+	if fmt.Sprint(keys) != fmt.Sprint([]string{encodeKey("a"), encodeKey("b")}) {
+		t.Fatalf("unexpected keys %q", keys)
+	}
+	for i := 0; i < len(tagidxs); i += 2 {
+		tag := l.features[i/4].tags[i/2%2]
+		if keys[tagidxs[i]] != encodeKey(tag.Key) ||
+			vals[tagidxs[i+1]] != encodeValue(tag.Value) {
+			t.Fatalf("tag %d does not match the tables", i/2)
+		}
+	}
+}
+
+func TestNestedJSON(t *testing.T) {
+	var tile Tile
+	tile.SetStrict(true)
+	l := tile.AddLayer("nested")
+	f := l.AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("names", []string{"a", "b"})
+	f.AddTag("info", map[string]interface{}{"x": 1})
+	if _, err := tile.Encode(); !errors.Is(err, ErrUnsupportedValue) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedValue, err)
+	}
+	l.SetNestedJSON(true)
+	if _, err := tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	_, vals, _ := l.collectTags(l.features)
+	if vals[0] != encodeValue(`["a","b"]`) || vals[1] != encodeValue(`{"x":1}`) {
+		t.Fatalf("unexpected values %q", vals)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("flat")
+	l.SetFlatten(2)
+	f := l.AddFeature(Point)
+	f.AddTag("a", map[string]interface{}{
+		"c": map[string]interface{}{"d": map[string]int{"e": 1}},
+		"b": 1,
+	})
+	f.AddTag("z", "plain")
+	var keys []string
+	for _, tag := range f.Tags() {
+		keys = append(keys, tag.Key)
+	}
+	if fmt.Sprint(keys) != "[a.b a.c.d z]" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if v, _ := f.Tag("a.c.d"); fmt.Sprint(v) != "map[e:1]" {
+		t.Fatalf("unexpected value %v", v)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	var tile Tile
+	tile.SetStrict(true)
+	a := tile.AddLayer("legacy")
+	b := tile.AddLayer("legacy")
+	a.SetVersion(1)
+	b.SetVersion(1)
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(pb, []byte{120, 1}) {
+		t.Fatal("expected version 1")
+	}
+	b.SetVersion(2)
+	if _, err := tile.Encode(); !errors.Is(err, ErrDuplicateLayer) {
+		t.Fatalf("expected %v, got %v", ErrDuplicateLayer, err)
+	}
+	tile.SetStrict(false)
+	b.SetVersion(4)
+	if _, err := tile.Encode(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedVersion, err)
+	}
+}
+
+func TestTimeValues(t *testing.T) {
+	tm := time.Date(2020, 5, 17, 12, 30, 0, 0, time.UTC)
+	var tile Tile
+	tile.SetStrict(true)
+	l := tile.AddLayer("times")
+	f := l.AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("updated", tm)
+	if _, err := tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		format TimeFormat
+		expect interface{}
+	}{
+		{TimeRFC3339, "2020-05-17T12:30:00Z"},
+		{TimeUnix, int64(1589718600)},
+		{TimeUnixMilli, int64(1589718600000)},
+	} {
+		l.SetTimeFormat(c.format)
+		if _, vals, _ := l.collectTags(l.features); vals[0] != encodeValue(c.expect) {
+			t.Fatalf("expected %v, got %q", c.expect, vals[0])
+		}
+	}
+}
+
+func TestTypedTags(t *testing.T) {
+	type class string
+	var tile Tile
+	f := tile.AddLayer("typed").AddFeature(Point)
+	f.AddTagString("name", "a")
+	f.AddTagInt("rank", -1)
+	f.AddTagUint("pop", 1)
+	f.AddTagFloat("ratio", 0.5)
+	f.AddTagBool("open", true)
+	AddTagT(f, "class", class("motorway"))
+	AddTagT(f, "lanes", 4)
+	AddTagT(f, "width", float32(3.5))
+	expect := []interface{}{"a", int64(-1), uint64(1), 0.5, true, "motorway",
+		int64(4), float32(3.5)}
+	for i, tag := range f.Tags() {
+		if tag.Value != expect[i] {
+			t.Fatalf("tag %q: expected %#v, got %#v", tag.Key, expect[i],
+				tag.Value)
+		}
+	}
+	if encodeValue(4) != encodeValue(int64(4)) {
+		t.Fatal("expected int to encode as an integer")
+	}
+	// the sint_value and uint_value fields of a value message
+	for _, v := range []struct {
+		val    interface{}
+		expect string
+	}{
+		{4, "\x22\x02\x30\x08"},
+		{-1, "\x22\x02\x30\x01"},
+		{uint(4), "\x22\x02\x28\x04"},
+		{uint(300), "\x22\x03\x28\xac\x02"},
+	} {
+		if s := encodeValue(v.val); s != v.expect {
+			t.Fatalf("%v: expected %q, got %q", v.val, v.expect, s)
+		}
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("filtered")
+	l.SetFlatten(1)
+	l.SetTagFilter([]string{"name", "addr"}, []string{"addr.internal"})
+	f := l.AddFeature(Point)
+	f.AddTag("name", "a")
+	f.AddTag("geom_blob", "...")
+	f.AddTag("addr", map[string]interface{}{"street": "main", "internal": 1})
+	var keys []string
+	for _, tag := range f.Tags() {
+		keys = append(keys, tag.Key)
+	}
+	if fmt.Sprint(keys) != "[name addr.street]" {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+}
+
+func TestPropertyMapper(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("mapped")
+	l.SetPropertyMapper(func(key string, val interface{}) (string, interface{}, bool) {
+		switch key {
+		case "height_ft":
+			return "height_m", val.(float64) * 0.3048, true
+		case "internal_id":
+			return "", nil, false
+		}
+		return key, val, true
+	})
+	f := l.AddFeature(Point)
+	f.AddTag("height_ft", 100.0)
+	f.AddTag("internal_id", 9)
+	f.AddTag("name", "tower")
+	tags := f.Tags()
+	if len(tags) != 2 || tags[0].Key != "height_m" || tags[1].Key != "name" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if v := tags[0].Value.(float64); math.Abs(v-30.48) > 1e-9 {
+		t.Fatalf("unexpected height %v", v)
+	}
+}
+
+func TestIDProperty(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("osm")
+	l.SetIDProperty("osm_id")
+	for _, v := range []interface{}{int64(10), "11", 12.0, 10, "x", -1} {
+		l.AddFeature(Point).AddTag("osm_id", v)
+	}
+	var ids []string
+	for _, f := range l.Features() {
+		id, ok := f.ID()
+		ids = append(ids, fmt.Sprint(id, ok))
+	}
+	expect := "[10 true 11 true 12 true 0 false 0 false 0 false]"
+	if fmt.Sprint(ids) != expect {
+		t.Fatalf("expected %v, got %v", expect, ids)
+	}
+	if l.IDCollisions() != 1 {
+		t.Fatalf("expected 1 collision, got %d", l.IDCollisions())
+	}
+
+	// the id is of the mapped tag
+	l = tile.AddLayer("mapped")
+	l.SetIDProperty("id")
+	l.SetPropertyMapper(func(key string, val interface{}) (string,
+		interface{}, bool) {
+		switch key {
+		case "osm_id":
+			return "id", fmt.Sprint("1", val), true
+		case "id":
+			return key, val, false
+		}
+		return key, val, true
+	})
+	a := l.AddFeature(Point)
+	a.AddTag("osm_id", 5)
+	b := l.AddFeature(Point)
+	b.AddTag("id", 6)
+	if id, ok := a.ID(); !ok || id != 15 {
+		t.Fatalf("expected id 15, got %d %t", id, ok)
+	}
+	if id, ok := b.ID(); ok {
+		t.Fatalf("expected no id, got %d", id)
+	}
+}
+
+func TestAutoID(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("auto")
+	l.SetAutoID(100)
+	l.AddFeature(Point)
+	l.AddFeature(Point).SetID(5)
+	l.AddFeature(Point)
+	var ids []uint64
+	for _, f := range l.Features() {
+		id, _ := f.ID()
+		ids = append(ids, id)
+	}
+	if fmt.Sprint(ids) != "[100 5 102]" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+}
+
+func TestLatLonXYBatch(t *testing.T) {
+	lats := []float64{33.4131, 0, -85.1, 45}
+	lons := []float64{-111.9396, 0, 190, -45}
+	xs, ys := make([]float64, 4), make([]float64, 4)
+	LatLonXYBatch(lats, lons, 6195, 13154, 15, xs, ys)
+	for i := range lats {
+		x, y := LatLonXY(lats[i], lons[i], 6195, 13154, 15)
+		if xs[i] != x || ys[i] != y {
+			t.Fatalf("%d: expected %f %f, got %f %f", i, x, y, xs[i], ys[i])
+		}
+	}
+	// the points within the tiles that contain them
+	for i := range lats {
+		tx, ty, x, y := LatLonToTile(lats[i], lons[i], 15)
+		LatLonXYBatch(lats[i:i+1], lons[i:i+1], tx, ty, 15, xs, ys)
+		if math.Abs(xs[0]-x) > 1e-6 || math.Abs(ys[0]-y) > 1e-6 {
+			t.Fatalf("%d: expected %f %f, got %f %f", i, x, y, xs[0], ys[0])
+		}
+	}
+	LatLonXYBatch(lats[:1], lons[:1], 6195, 13154, 15, xs, ys)
+	if s := fmt.Sprintf("%.5f %.5f", xs[0], ys[0]); s != "4.53291 0.12359" {
+		t.Fatalf("unexpected point %s", s)
+	}
+}
+
+func BenchmarkLatLonXYBatch(b *testing.B) {
+	lats, lons := make([]float64, 1000), make([]float64, 1000)
+	for i := range lats {
+		lats[i], lons[i] = float64(i%170-85), float64(i%360-180)
+	}
+	xs, ys := make([]float64, 1000), make([]float64, 1000)
+	for i := 0; i < b.N; i++ {
+		LatLonXYBatch(lats, lons, 0, 0, 4, xs, ys)
+	}
+}
+
+func TestReset(t *testing.T) {
+	var tile Tile
+	draw := func() []byte {
+		l := tile.AddLayer("pooled")
+		f := l.AddFeature(Polygon)
+		f.AddTag("name", "a")
+		f.MoveTo(128, 96)
+		f.LineTo(148, 128)
+		f.LineTo(108, 128)
+		f.ClosePath()
+		return tile.Render()
+	}
+	first := draw()
+	l := tile.GetLayer("pooled")
+	f := l.Features()[0]
+	tile.Reset(TileID{1, 1, 1})
+	if len(tile.Layers()) != 0 || tile.TileID() != (TileID{1, 1, 1}) {
+		t.Fatal("expected an empty tile")
+	}
+	if !bytes.Equal(draw(), first) {
+		t.Fatal("expected a reset tile to render the same")
+	}
+	if tile.GetLayer("pooled") != l || l.Features()[0] != f {
+		t.Fatal("expected the layer and feature to be reused")
+	}
+	l.Reset()
+	if len(l.Features()) != 0 || l.Name() != "pooled" {
+		t.Fatal("expected an empty layer")
+	}
+	if g := l.AddFeature(Point); g != f || len(g.Tags()) != 0 ||
+		len(g.geom.ops) != 0 {
+		t.Fatal("expected a cleared feature to be reused")
+	}
+}
+
+func TestRenderContext(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("big")
+	for i := 0; i < 1000; i++ {
+		f := l.AddFeature(Point)
+		f.MoveTo(float64(i%512), 1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pb, err := tile.RenderContext(ctx)
+	if err != nil || !bytes.Equal(pb, tile.Render()) {
+		t.Fatalf("expected a full render, got %v", err)
+	}
+	cancel()
+	if _, err := tile.RenderContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestConcurrentSafe(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("parallel")
+	l.SetConcurrentSafe(true)
+	l.SetAutoID(1)
+	l.SetIDProperty("src_id")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				f := l.AddFeature(Point)
+				f.MoveTo(float64(i), float64(j))
+				f.AddTag("src_id", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	features := l.Features()
+	if len(features) != 800 {
+		t.Fatalf("expected 800 features, got %d", len(features))
+	}
+	if l.IDCollisions() != 700 {
+		t.Fatalf("expected 700 collisions, got %d", l.IDCollisions())
+	}
+}
+
+func TestDropEmptyLayers(t *testing.T) {
+	var tile Tile
+	if !tile.IsEmpty() {
+		t.Fatal("expected an empty tile")
+	}
+	tile.AddLayer("empty")
+	roads := tile.AddLayer("roads")
+	if !tile.IsEmpty() {
+		t.Fatal("expected an empty tile")
+	}
+	f := roads.AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("class", "minor")
+	if tile.IsEmpty() {
+		t.Fatal("expected a tile with features")
+	}
+	filter, err := ParseFilter(`["==", "class", "major"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roads.SetFeatureFilter(filter)
+	if !tile.IsEmpty() {
+		t.Fatal("expected the filter to leave no features")
+	}
+	roads.SetFeatureFilter(nil)
+	tile.SetDropEmptyLayers(true)
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layers, _, err := splitTile(pb); err != nil || len(layers) != 1 ||
+		layers[0].name != "roads" {
+		t.Fatalf("expected only the roads layer, got %v", layers)
+	}
+	tile.SetDropEmptyLayers(false)
+	if pb, err = tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if layers, _, err := splitTile(pb); err != nil || len(layers) != 2 {
+		t.Fatalf("expected both layers, got %v", layers)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	for i := 0; i < 100; i++ {
+		f := l.AddFeature(Point)
+		f.SetID(uint64(i))
+		f.MoveTo(float64(i), float64(i))
+	}
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tile.SetMaxSize(len(pb))
+	if _, err := tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	tile.SetMaxSize(len(pb) / 2)
+	_, err = tile.Encode()
+	if !errors.Is(err, ErrTileTooLarge) {
+		t.Fatalf("expected %v, got %v", ErrTileTooLarge, err)
+	}
+	if !strings.Contains(err.Error(), `layer "points": feature id `) {
+		t.Fatalf("expected the layer and feature, got %v", err)
+	}
+	// the layer name goes over the size before any feature
+	tile.SetMaxSize(3)
+	if _, err := tile.Encode(); !errors.Is(err, ErrTileTooLarge) {
+		t.Fatalf("expected %v, got %v", ErrTileTooLarge, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrInvalidGeoJSON is returned for a GeoJSON object that is malformed or
+// of a type that is not supported.
+var ErrInvalidGeoJSON = errors.New("invalid geojson")
+
+// ErrorPolicy decides what a reader does with a record that it cannot
+// read, given the line number of the record and the error. Returning nil
+// skips the record, and returning an error stops the reader with it.
+type ErrorPolicy func(line int, err error) error
+
+// StopOnError is an ErrorPolicy that stops at the first invalid record
+func StopOnError(line int, err error) error {
+	return fmt.Errorf("line %d: %w", line, err)
+}
+
+// SkipInvalid is an ErrorPolicy that skips invalid records
+func SkipInvalid(line int, err error) error {
+	return nil
+}
+
+// NDJSONReader is a FeatureSource that reads newline-delimited GeoJSON
+// features, one per line, as written by tippecanoe and ogr2ogr. This also
+// reads GeoJSON text sequences (RFC 8142), whose records begin with a
+// record separator. Lines may also be bare geometries, and blank lines are
+// skipped.
+type NDJSONReader struct {
+	rd     *bufio.Reader
+	line   int
+	policy ErrorPolicy
+}
+
+// NewNDJSONReader returns a reader of the newline-delimited GeoJSON
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{rd: bufio.NewReader(r), policy: StopOnError}
+}
+
+// SetErrorPolicy sets what the reader does with invalid lines. Default is
+// StopOnError.
+func (r *NDJSONReader) SetErrorPolicy(policy ErrorPolicy) {
+	r.policy = policy
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (r *NDJSONReader) Next() (geom Geometry, tags map[string]interface{},
+	id interface{}, err error) {
+	for {
+		line, rerr := r.rd.ReadBytes('\n')
+		if rerr != nil && rerr != io.EOF {
+			return Geometry{}, nil, nil, rerr
+		}
+		r.line++
+		line = bytes.TrimSpace(bytes.TrimLeft(line, "\x1e"))
+		if len(line) > 0 {
+			geom, tags, id, err = parseGeoJSONFeature(line)
+			if err == nil {
+				return geom, tags, id, nil
+			}
+			if err := r.policy(r.line, err); err != nil {
+				return Geometry{}, nil, nil, err
+			}
+		}
+		if rerr == io.EOF {
+			return Geometry{}, nil, nil, io.EOF
+		}
+	}
+}
+
+// geojsonObject is a GeoJSON feature or geometry
+type geojsonObject struct {
+	Type        string                 `json:"type"`
+	Geometry    *geojsonObject         `json:"geometry"`
+	Properties  map[string]interface{} `json:"properties"`
+	ID          interface{}            `json:"id"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+}
+
+// parseGeoJSONFeature parses a GeoJSON feature, or a bare geometry
+func parseGeoJSONFeature(data []byte,
+) (geom Geometry, tags map[string]interface{}, id interface{}, err error) {
+	var obj geojsonObject
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return Geometry{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidGeoJSON,
+			err)
+	}
+	if obj.Type != "Feature" {
+		geom, err = parseGeoJSONGeometry(&obj)
+		return geom, nil, nil, err
+	}
+	if obj.Geometry != nil {
+		if geom, err = parseGeoJSONGeometry(obj.Geometry); err != nil {
+			return Geometry{}, nil, nil, err
+		}
+	}
+	tags = make(map[string]interface{}, len(obj.Properties))
+	for key, value := range obj.Properties {
+		tags[key] = jsonValue(value)
+	}
+	return geom, tags, jsonValue(obj.ID), nil
+}
+
+// parseGeoJSONGeometry parses the coordinates of a GeoJSON geometry. The
+// parts of multi geometries become the paths of one geometry.
+func parseGeoJSONGeometry(obj *geojsonObject) (Geometry, error) {
+	var g Geometry
+	var err error
+	switch obj.Type {
+	case "Point", "MultiPoint":
+		// each point is its own path
+		var points [][]float64
+		if obj.Type == "Point" {
+			points = make([][]float64, 1)
+			err = json.Unmarshal(obj.Coordinates, &points[0])
+		} else {
+			err = json.Unmarshal(obj.Coordinates, &points)
+		}
+		if err == nil {
+			g.Type = Point
+			for _, p := range points {
+				var paths [][][2]float64
+				if paths, err = geojsonPaths([][]float64{p}); err != nil {
+					break
+				}
+				g.Paths = append(g.Paths, paths...)
+			}
+		}
+	case "LineString":
+		var line [][]float64
+		if err = json.Unmarshal(obj.Coordinates, &line); err == nil {
+			g.Type = LineString
+			g.Paths, err = geojsonPaths(line)
+		}
+	case "MultiLineString", "Polygon":
+		var lines [][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &lines); err == nil {
+			g.Type = LineString
+			if obj.Type == "Polygon" {
+				g.Type = Polygon
+			}
+			g.Paths, err = geojsonPaths(lines...)
+		}
+	case "MultiPolygon":
+		var polys [][][][]float64
+		if err = json.Unmarshal(obj.Coordinates, &polys); err == nil {
+			g.Type = Polygon
+			for _, rings := range polys {
+				var paths [][][2]float64
+				if paths, err = geojsonPaths(rings...); err != nil {
+					break
+				}
+				g.Paths = append(g.Paths, paths...)
+				g.Rings = append(g.Rings, len(paths))
+			}
+		}
+	default:
+		return Geometry{}, fmt.Errorf("%w: unsupported type %q",
+			ErrInvalidGeoJSON, obj.Type)
+	}
+	if err != nil {
+		return Geometry{}, fmt.Errorf("%w: %s: %v", ErrInvalidGeoJSON,
+			obj.Type, err)
+	}
+	return g, nil
+}
+
+// geojsonPaths converts lists of GeoJSON positions to paths. Any
+// elevation is dropped.
+func geojsonPaths(lists ...[][]float64) ([][][2]float64, error) {
+	paths := make([][][2]float64, len(lists))
+	for i, list := range lists {
+		paths[i] = make([][2]float64, len(list))
+		for j, p := range list {
+			if len(p) < 2 {
+				return nil, errors.New("position without a lon/lat")
+			}
+			paths[i][j] = [2]float64{p[0], p[1]}
+		}
+	}
+	return paths, nil
+}
+
+// jsonValue converts the numbers of a decoded JSON value to int64 when
+// they are whole and fit, and to float64 otherwise.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		if math.IsInf(f, 0) {
+			return v.String()
+		}
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = jsonValue(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+	}
+	return value
+}
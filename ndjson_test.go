@@ -0,0 +1,83 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testNDJSON = `{"type":"Feature","id":3,"geometry":{"type":"Point","coordinates":[-111.9,33.4]},"properties":{"name":"tempe","pop":180000,"area":40.2}}
+
+{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]},"properties":null}
+not json
+{"type":"GeometryCollection","geometries":[]}
+` + "\x1e" + `{"type":"LineString","coordinates":[[0,0,100],[1,1,200]]}`
+
+func TestNDJSONReader(t *testing.T) {
+	r := NewNDJSONReader(strings.NewReader(testNDJSON))
+	geom, tags, id, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.Type != Point || fmt.Sprint(geom.Paths) != "[[[-111.9 33.4]]]" {
+		t.Fatalf("unexpected geometry %v", geom)
+	}
+	if id != int64(3) || tags["pop"] != int64(180000) || tags["area"] != 40.2 {
+		t.Fatalf("unexpected id %v and tags %v", id, tags)
+	}
+	geom, _, _, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.Type != Polygon || len(geom.Paths) != 2 {
+		t.Fatalf("unexpected geometry %v", geom)
+	}
+	_, _, _, err = r.Next()
+	if !errors.Is(err, ErrInvalidGeoJSON) || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Fatalf("expected an invalid geojson error for line 4, got %v", err)
+	}
+
+	var skipped []int
+	r = NewNDJSONReader(strings.NewReader(testNDJSON))
+	r.SetErrorPolicy(func(line int, err error) error {
+		skipped = append(skipped, line)
+		return nil
+	})
+	var n int
+	var last Geometry
+	for {
+		geom, _, _, err = r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		last = geom
+	}
+	if n != 3 || fmt.Sprint(skipped) != "[4 5]" {
+		t.Fatalf("expected 3 features and skipped lines 4 and 5, got %d %v",
+			n, skipped)
+	}
+	if last.Type != LineString || fmt.Sprint(last.Paths) != "[[[0 0] [1 1]]]" {
+		t.Fatalf("unexpected geometry %v", last)
+	}
+
+	var tile Tile
+	l := tile.AddLayer("places")
+	r = NewNDJSONReader(strings.NewReader(testNDJSON))
+	r.SetErrorPolicy(SkipInvalid)
+	if err := l.AddFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Features()) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(l.Features()))
+	}
+}
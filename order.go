@@ -0,0 +1,53 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "sort"
+
+// SortFeatures sets the order of the features when the layer is rendered,
+// which is the order that most clients draw them in, such that the later
+// features are drawn over the earlier ones. The sort is stable, leaving
+// the features that are not less than each other in the order that they
+// were added, and is after the other render time options, other than the
+// range and id policies. The features of the layer are not reordered.
+// Default is nil, which keeps the order that they were added in.
+func (l *Layer) SortFeatures(less func(a, b *Feature) bool) {
+	l.less = less
+}
+
+// ByID orders features by id, with features without ids first, for
+// SortFeatures.
+func ByID(a, b *Feature) bool {
+	if a.hasID != b.hasID {
+		return !a.hasID
+	}
+	return a.id < b.id
+}
+
+// ByAreaDesc orders polygons by area, largest first, such that smaller
+// polygons are drawn over the larger ones that they overlap, for
+// SortFeatures. Lines and points have no area and come after polygons.
+func ByAreaDesc(a, b *Feature) bool {
+	return featureArea(a) > featureArea(b)
+}
+
+// featureArea returns the canvas area of a polygon, and -1 for other
+// features.
+func featureArea(f *Feature) float64 {
+	if f.geomType != Polygon {
+		return -1
+	}
+	return f.area()
+}
+
+// sortFeatures returns a copy of the features in a stable order.
+func sortFeatures(features []*Feature, less func(a, b *Feature) bool,
+) []*Feature {
+	sorted := append([]*Feature(nil), features...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestSortFeatures(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	square := func(id uint64, size float64) {
+		f := l.AddFeature(Polygon)
+		f.SetID(id)
+		f.MoveTo(0, 0)
+		f.LineTo(size, 0)
+		f.LineTo(size, size)
+		f.LineTo(0, size)
+		f.ClosePath()
+	}
+	square(3, 10)
+	square(1, 30)
+	l.AddFeature(Point).MoveTo(5, 5)
+	square(2, 20)
+
+	l.SortFeatures(ByID)
+	if ids := featureIDs(l.render()); ids != "[0 1 2 3]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SortFeatures(ByAreaDesc)
+	if ids := featureIDs(l.render()); ids != "[1 2 3 0]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if ids := featureIDs(l.features); ids != "[3 1 0 2]" {
+		t.Fatalf("expected the layer order to be kept, got %s", ids)
+	}
+	l.SortFeatures(nil)
+	if ids := featureIDs(l.render()); ids != "[3 1 0 2]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	draw := func(reverse bool) []byte {
+		var tile Tile
+		tile.SetCanonical(true)
+		names := []string{"roads", "places"}
+		ids := []uint64{2, 1}
+		if reverse {
+			names[0], names[1] = names[1], names[0]
+			ids[0], ids[1] = ids[1], ids[0]
+		}
+		for _, name := range names {
+			l := tile.AddLayer(name)
+			for _, id := range ids {
+				f := l.AddFeature(Point)
+				f.SetID(id)
+				f.MoveTo(float64(id), 0)
+				f.AddTag("name", name)
+				f.AddTag("id", id)
+			}
+		}
+		pb, err := tile.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pb
+	}
+	a, b := draw(false), draw(true)
+	if string(a) != string(b) {
+		t.Fatal("expected the same bytes")
+	}
+	layers, _, err := splitTile(a)
+	if err != nil || len(layers) != 2 || layers[0].name != "places" {
+		t.Fatalf("expected the layers sorted by name, got %v", layers)
+	}
+}
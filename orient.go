@@ -0,0 +1,151 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// OrientRings winds the rings of a Polygon feature as the spec says, by
+// which rings they are inside of rather than by how they were drawn. Rings
+// that are inside of an even number of others are exteriors, and the
+// others are holes, which are wound the other way from their exterior and
+// placed after the smallest exterior around them. Exteriors keep their
+// order. Rings with fewer than three points are dropped. The rings must
+// not cross, see ValidateTopology.
+func (f *Feature) OrientRings() {
+	if f.geomType == Polygon {
+		f.geom = orientRings(polygonRings(f))
+	}
+}
+
+// SetOrientRings sets whether the rings of polygons are wound by
+// containment when the layer is rendered, see Feature.OrientRings, such as
+// for polygons from sources that do not wind their rings. Only polygons
+// whose rings change are copied. Default is false.
+func (l *Layer) SetOrientRings(orient bool) {
+	l.orient = orient
+}
+
+// orientPolygons returns the features with copies of the polygons whose
+// rings are wound by containment
+func (l *Layer) orientPolygons(features []*Feature) []*Feature {
+	kept := make([]*Feature, len(features))
+	for i, f := range features {
+		kept[i] = f
+		if f.geomType != Polygon {
+			continue
+		}
+		g := orientRings(polygonRings(f))
+		if !equalGeometry(g, f.geom) {
+			oriented := *f
+			oriented.geom = g
+			kept[i] = &oriented
+		}
+	}
+	return kept
+}
+
+// equalGeometry returns true when the geometries have the same commands
+func equalGeometry(a, b geometry) bool {
+	if len(a.ops) != len(b.ops) || len(a.coords) != len(b.coords) {
+		return false
+	}
+	for i := range a.ops {
+		if a.ops[i] != b.ops[i] {
+			return false
+		}
+	}
+	for i := range a.coords {
+		if a.coords[i] != b.coords[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// orientRings returns the polygon of the rings, with the rings that are
+// inside of an even number of others as exteriors, each followed by the
+// holes that are directly inside of it. Nil rings are skipped.
+func orientRings(rings [][]command) geometry {
+	depth := make([]int, len(rings))
+	for i, ring := range rings {
+		for j, other := range rings {
+			if i != j && ring != nil && other != nil &&
+				ringInside(ring, other) {
+				depth[i]++
+			}
+		}
+	}
+	owned := make([][]int, len(rings))
+	for i, ring := range rings {
+		if ring == nil || depth[i]%2 == 0 {
+			continue
+		}
+		best, bestArea := -1, math.Inf(1)
+		for j, other := range rings {
+			if other == nil || depth[j] != depth[i]-1 {
+				continue
+			}
+			area := math.Abs(ringArea(other))
+			if area < bestArea && ringInside(ring, other) {
+				best, bestArea = j, area
+			}
+		}
+		if best != -1 {
+			owned[best] = append(owned[best], i)
+		}
+	}
+	var g geometry
+	push := func(ring []command, exterior bool) {
+		if (ringArea(ring) > 0) != exterior {
+			// wind the other way, from the same first point
+			for a, b := 1, len(ring)-1; a < b; a, b = a+1, b-1 {
+				ring[a], ring[b] = ring[b], ring[a]
+			}
+		}
+		for i, cmd := range ring {
+			which := lineTo
+			if i == 0 {
+				which = moveTo
+			}
+			g.push(which, cmd.x, cmd.y)
+		}
+		g.push(closePath, 0, 0)
+	}
+	for i, ring := range rings {
+		if ring == nil || depth[i]%2 != 0 {
+			continue
+		}
+		push(ring, true)
+		for _, hole := range owned[i] {
+			push(rings[hole], false)
+		}
+	}
+	return g
+}
+
+// ringInside returns true when the inner ring is inside of the outer ring,
+// by the first of its points that is not on the outer ring, or by the
+// middle of its first edge when all of them are
+func ringInside(inner, outer []command) bool {
+	for _, p := range inner {
+		if !pointOnRing(p.x, p.y, outer) {
+			return pointInRing(p.x, p.y, outer)
+		}
+	}
+	x, y := (inner[0].x+inner[1].x)/2, (inner[0].y+inner[1].y)/2
+	return pointInRing(x, y, outer) && !pointOnRing(x, y, outer)
+}
+
+// pointOnRing returns true when the point is on an edge of the ring
+func pointOnRing(x, y float64, ring []command) bool {
+	p := [2]float64{x, y}
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if onSegment(p, [2]float64{a.x, a.y}, [2]float64{b.x, b.y}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+// ringAreas returns the areas of the rings of the feature
+func ringAreas(f *Feature) string {
+	var areas []float64
+	for _, path := range f.paths() {
+		areas = append(areas, ringArea(pathPoints(path)))
+	}
+	return fmt.Sprint(areas)
+}
+
+func TestOrientRings(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	ccw := func(x0, y0, x1, y1 float64) [][2]float64 {
+		return [][2]float64{{x0, y0}, {x0, y1}, {x1, y1}, {x1, y0}}
+	}
+	// all wound the same way, with a hole before its shell, an island in
+	// the hole, and a second shell
+	f := addRings(l, ccw(2, 2, 8, 8), ccw(0, 0, 10, 10), ccw(4, 4, 6, 6),
+		ccw(20, 0, 30, 10))
+	if err := f.Validate(); err == nil {
+		t.Fatal("expected the polygon to be invalid")
+	}
+	l.SetOrientRings(true)
+	oriented := l.render()[0]
+	if oriented == f {
+		t.Fatal("expected a copy of the polygon")
+	}
+	f.OrientRings()
+	if ringAreas(f) != ringAreas(oriented) {
+		t.Fatalf("expected %s, got %s", ringAreas(f), ringAreas(oriented))
+	}
+	if areas := ringAreas(f); areas != "[100 -36 4 100]" {
+		t.Fatalf("unexpected areas %s", areas)
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.ValidateTopology(); err != nil {
+		t.Fatal(err)
+	}
+	// a hole that touches its shell
+	f = addRings(l, ccw(0, 0, 10, 10), [][2]float64{{0, 5}, {5, 2}, {5, 8}})
+	f.OrientRings()
+	if areas := ringAreas(f); areas != "[100 -15]" {
+		t.Fatalf("unexpected areas %s", areas)
+	}
+	// polygons that are already wound are kept as they are
+	valid := addRings(l, topoSquare, topoHole)
+	features := l.render()
+	if features[2] != valid || features[1] != f {
+		t.Fatal("expected wound polygons to be kept")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// RangePolicy is what is done with the vertices of a layer that are out of
+// the range of the canvas and its buffer when it is rendered, as
+// coordinates far outside of the extent overflow some renderers
+type RangePolicy int
+
+const (
+	// RangeAllow encodes the vertices as they are
+	RangeAllow RangePolicy = iota
+	// RangeClamp moves the vertices to the nearest point in range
+	RangeClamp
+	// RangeDropVertices drops the vertices, along with the lines and rings
+	// that are left with too few of them, and the features that are left
+	// with nothing
+	RangeDropVertices
+	// RangeDropFeatures drops the features that have any of the vertices
+	RangeDropFeatures
+)
+
+// SetRangePolicy sets what is done with vertices that are more than the
+// buffer, in pixels, outside of the 512x512 canvas when the layer is
+// rendered, see OutOfRange. Features that are drawn with AddGeometry are
+// already clipped to the canvas. Default is RangeAllow.
+func (l *Layer) SetRangePolicy(policy RangePolicy, buffer float64) {
+	l.outPolicy = policy
+	l.outBuffer = buffer
+}
+
+// OutOfRange returns the numbers of vertices, and of the features that
+// had them, that were out of range the last time that the layer was
+// rendered with a range policy, see SetRangePolicy.
+func (l *Layer) OutOfRange() (vertices, features int) {
+	return l.outVerts, l.outFeats
+}
+
+// limitRange returns the features with copies of those that have vertices
+// out of range, which are changed by the range policy
+func (l *Layer) limitRange(features []*Feature) []*Feature {
+	lo, hi := -l.outBuffer, gTileSize+l.outBuffer
+	inRange := func(x, y float64) bool {
+		return x >= lo && x <= hi && y >= lo && y <= hi
+	}
+	l.outVerts, l.outFeats = 0, 0
+	kept := make([]*Feature, 0, len(features))
+	for _, f := range features {
+		var out int
+		coords := f.geom.coords
+		for i := 0; i < len(coords); i += 2 {
+			if !inRange(coords[i], coords[i+1]) {
+				out++
+			}
+		}
+		if out == 0 {
+			kept = append(kept, f)
+			continue
+		}
+		l.outVerts += out
+		l.outFeats++
+		var g geometry
+		switch l.outPolicy {
+		case RangeClamp:
+			g.ops = f.geom.ops
+			g.coords = make([]float64, len(coords))
+			for i, v := range coords {
+				g.coords[i] = clamp(v, lo, hi)
+			}
+		case RangeDropVertices:
+			g = dropVertices(f, inRange)
+		}
+		if len(g.ops) == 0 {
+			continue
+		}
+		limited := *f
+		limited.geom = g
+		kept = append(kept, &limited)
+	}
+	return kept
+}
+
+// dropVertices returns the geometry of the feature without the vertices
+// that are not in range. Lines with fewer than two vertices left, and
+// rings with fewer than three, are dropped.
+func dropVertices(f *Feature, inRange func(x, y float64) bool) geometry {
+	least := 1
+	switch f.geomType {
+	case LineString:
+		least = 2
+	case Polygon:
+		least = 3
+	}
+	var g geometry
+	for _, path := range f.paths() {
+		var points []command
+		for _, p := range pathPoints(path) {
+			if inRange(p.x, p.y) {
+				points = append(points, p)
+			}
+		}
+		if len(points) < least {
+			continue
+		}
+		for i, p := range points {
+			which := lineTo
+			if i == 0 || f.geomType == Point {
+				which = moveTo
+			}
+			g.push(which, p.x, p.y)
+		}
+		if path[len(path)-1].which == closePath {
+			g.push(closePath, 0, 0)
+		}
+	}
+	return g
+}
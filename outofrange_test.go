@@ -0,0 +1,72 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestRangePolicy(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	l.SetAutoID(0)
+	l.AddFeature(Point).MoveTo(100, 100)
+	l.AddFeature(Point).MoveTo(-50, 100)
+	line := l.AddFeature(LineString)
+	line.MoveTo(10, 10)
+	line.LineTo(20, 20)
+	line.LineTo(600, 20)
+	ring := l.AddFeature(Polygon)
+	ring.MoveTo(10, 10)
+	ring.LineTo(600, 10)
+	ring.LineTo(600, 600)
+	ring.LineTo(10, 600)
+	ring.ClosePath()
+
+	l.SetRangePolicy(RangeAllow, 0)
+	if n := len(l.render()); n != 4 {
+		t.Fatalf("expected 4 features, got %d", n)
+	}
+	if v, f := l.OutOfRange(); v != 0 || f != 0 {
+		t.Fatalf("expected no counts, got %d %d", v, f)
+	}
+
+	l.SetRangePolicy(RangeClamp, 8)
+	features := l.render()
+	if ids := featureIDs(features); ids != "[0 1 2 3]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if v, f := l.OutOfRange(); v != 5 || f != 3 {
+		t.Fatalf("expected 5 vertices of 3 features, got %d %d", v, f)
+	}
+	if x := features[1].geom.coords[0]; x != -8 {
+		t.Fatalf("expected a clamped x of -8, got %v", x)
+	}
+	if x := features[2].geom.coords[4]; x != 520 {
+		t.Fatalf("expected a clamped x of 520, got %v", x)
+	}
+	if x := l.features[2].geom.coords[4]; x != 600 {
+		t.Fatal("expected the layer features to be unchanged")
+	}
+
+	l.SetRangePolicy(RangeDropVertices, 8)
+	features = l.render()
+	if ids := featureIDs(features); ids != "[0 2]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if n := len(features[1].geom.coords); n != 4 {
+		t.Fatalf("expected a line of 2 vertices, got %d", n/2)
+	}
+
+	l.SetRangePolicy(RangeDropFeatures, 1000)
+	if n := len(l.render()); n != 4 {
+		t.Fatalf("expected 4 features, got %d", n)
+	}
+	l.SetRangePolicy(RangeDropFeatures, 0)
+	if ids := featureIDs(l.render()); ids != "[0]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if v, f := l.OutOfRange(); v != 5 || f != 3 {
+		t.Fatalf("expected 5 vertices of 3 features, got %d %d", v, f)
+	}
+}
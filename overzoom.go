@@ -0,0 +1,106 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotChildTile is returned when overzooming to a tile that is not
+// inside of the tile.
+var ErrNotChildTile = errors.New("not a child tile")
+
+// Overzoom returns the child tile at z/x/y made from the features of the
+// tile, which must have its z/x/y set with SetTileID. The geometry is
+// scaled up to the child tile and clipped to its canvas, plus a small
+// buffer, and features with nothing left are dropped. This allows for
+// serving zooms beyond the highest zoom of a tileset. The layers of the
+// child keep the settings of the layers of the tile. For a tile of a tile
+// matrix, the x/y are the column and row of the child in the matrix of
+// the child zoom, see SetTileMatrix.
+func (t *Tile) Overzoom(childZ, childX, childY int) (*Tile, error) {
+	child := TileID{childZ, childX, childY}
+	if childZ < t.id.Z || child.ZoomTo(t.id.Z)[0] != t.id {
+		return nil, fmt.Errorf("%s in %s: %w", child, t.id, ErrNotChildTile)
+	}
+	n := int(1) << uint(childZ-t.id.Z)
+	scale := float64(n)
+	col, row := childX-t.id.X*n, childY-t.id.Y*n
+	var matrix *tileMatrix
+	if t.matrix != nil {
+		matrix = t.matrix.child(n)
+		if matrix.m.bottomLeft() {
+			// the rows count up from the bottom of the tile
+			row = n - 1 - row
+		}
+	}
+	offX := float64(col) * gTileSize / scale
+	offY := float64(row) * gTileSize / scale
+	// the canvas of the child on the canvas of the tile
+	r := clipRect{
+		minX: offX - clipBuffer/scale,
+		minY: offY - clipBuffer/scale,
+		maxX: offX + (gTileSize+clipBuffer)/scale,
+		maxY: offY + (gTileSize+clipBuffer)/scale,
+	}
+	ct := &Tile{strict: t.strict, dropEmpty: t.dropEmpty,
+		maxSize: t.maxSize, canonical: t.canonical, scheme: t.scheme,
+		matrix: matrix, id: child, other: t.other}
+	for _, l := range t.layers {
+		cl := ct.AddLayer(l.name)
+		cl.copySettings(l)
+		for _, f := range l.Features() {
+			g := f.clip(r)
+			if len(g.ops) == 0 {
+				continue
+			}
+			for i := 0; i < len(g.coords); i += 2 {
+				g.coords[i] = (g.coords[i] - offX) * scale
+				g.coords[i+1] = (g.coords[i+1] - offY) * scale
+			}
+			cl.features = append(cl.features, &Feature{
+				geomType: f.geomType, id: f.id, hasID: f.hasID,
+				tags: f.Tags(), geom: g, layer: cl,
+			})
+		}
+	}
+	return ct, nil
+}
+
+// copySettings sets the options of the layer to those of another layer.
+func (l *Layer) copySettings(from *Layer) {
+	l.extent, l.hasExtent = from.extent, from.hasExtent
+	l.autoClose = from.autoClose
+	l.sortTags = from.sortTags
+	l.nestedJSON = from.nestedJSON
+	l.flatten = from.flatten
+	l.version = from.version
+	l.timeFormat = from.timeFormat
+	l.include, l.exclude = from.include, from.exclude
+	l.mapper = from.mapper
+	l.idProperty = from.idProperty
+	l.autoID, l.nextID = from.autoID, from.nextID
+	l.concurrent = from.concurrent
+	l.dropPolicy = from.dropPolicy
+	l.cluster = from.cluster
+	l.mergeLines = from.mergeLines
+	l.dissolve = from.dissolve
+	l.densify = from.densify
+	l.labels = from.labels
+	l.centroids = from.centroids
+	l.bboxes = from.bboxes
+	l.grid = from.grid
+	l.elevation = from.elevation
+	l.filter = from.filter
+	l.metrics = from.metrics
+	l.minSize = from.minSize
+	l.repair = from.repair
+	l.orient = from.orient
+	l.outPolicy, l.outBuffer = from.outPolicy, from.outBuffer
+	l.idPolicy = from.idPolicy
+	l.less = from.less
+	l.other = from.other
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOverzoom(t *testing.T) {
+	var tile Tile
+	tile.SetTileID(TileID{Z: 14, X: 100, Y: 200})
+	l := tile.AddLayer("things")
+	l.SetExtent(1024)
+	l.SetAutoID(0)
+	l.AddFeature(Point).MoveTo(300, 100) // 0, in the child
+	l.AddFeature(Point).MoveTo(100, 100) // 1, not in the child
+	line := l.AddFeature(LineString)     // 2, crosses the child
+	line.MoveTo(200, 128)
+	line.LineTo(400, 128)
+	square := l.AddFeature(Polygon) // 3, covers the child
+	square.MoveTo(0, 0)
+	square.LineTo(512, 0)
+	square.LineTo(512, 512)
+	square.LineTo(0, 512)
+	square.ClosePath()
+	square.AddTag("name", "square")
+
+	child, err := tile.Overzoom(15, 201, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.TileID() != (TileID{15, 201, 400}) {
+		t.Fatalf("unexpected id %s", child.TileID())
+	}
+	cl := child.GetLayer("things")
+	if cl == nil || cl.Extent() != 1024 {
+		t.Fatal("expected the layer with its extent")
+	}
+	features := cl.Features()
+	if ids := featureIDs(features); ids != "[0 2 3]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	for i, expect := range []string{
+		"[88 200]",
+		"[-8 256 288 256]",
+		"[-8 520 -8 0 512 0 512 520]",
+	} {
+		if s := fmt.Sprint(features[i].geom.coords); s != expect {
+			t.Fatalf("feature %d: expected %s, got %s", i, expect, s)
+		}
+	}
+	if v, _ := features[2].Tag("name"); v != "square" {
+		t.Fatal("expected the tags to be kept")
+	}
+	if err := features[2].Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tile.Overzoom(15, 10, 10); !errors.Is(err, ErrNotChildTile) {
+		t.Fatalf("expected ErrNotChildTile, got %v", err)
+	}
+	if _, err := tile.Overzoom(13, 50, 100); !errors.Is(err, ErrNotChildTile) {
+		t.Fatalf("expected ErrNotChildTile, got %v", err)
+	}
+}
+
+func TestOverzoomClosedPoints(t *testing.T) {
+	// a ClosePath has no coordinates, which the points after it follow
+	var tile Tile
+	l := tile.AddLayer("points")
+	f := l.AddFeature(Point)
+	f.MoveTo(100, 100)
+	f.ClosePath()
+	f.MoveTo(50, 100)
+	f.MoveTo(10, 10)
+	f.ClosePath()
+	ct, err := tile.Overzoom(2, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pts []float64
+	ct.Layers()[0].Features()[0].ForEachPoint(CanvasSpace,
+		func(x, y float64) bool {
+			pts = append(pts, x, y)
+			return true
+		})
+	if s := fmt.Sprint(pts); s != "[400 400 200 400 40 40]" {
+		t.Fatalf("unexpected points %s", s)
+	}
+}
+
+func TestClipLine(t *testing.T) {
+	var g geometry
+	points := []command{
+		{moveTo, -10, 5}, {lineTo, 5, 5}, {lineTo, 5, 20},
+		{lineTo, 8, 20}, {lineTo, 8, 8},
+	}
+	clipLine(&g, points, clipRect{0, 0, 10, 10})
+	if s := fmt.Sprint(g.ops, g.coords); s != "[1 2 2 1 2] [0 5 5 5 5 10 8 10 8 8]" {
+		t.Fatalf("unexpected geometry %s", s)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// errMalformedPBF is the error of a pbfReader for a message that is cut
+// short or has an unknown wire type.
+var errMalformedPBF = errors.New("malformed protobuf")
+
+// Protobuf wire types
+const (
+	pbfVarint  = 0
+	pbfFixed64 = 1
+	pbfBytes   = 2
+	pbfFixed32 = 5
+)
+
+// pbfReader reads the fields of a protobuf message. Reads after an error
+// return zero values, and the error is kept in err.
+type pbfReader struct {
+	data []byte
+	err  error
+}
+
+// next reads the key of the next field, returning false at the end of
+// the message or after an error.
+func (r *pbfReader) next() (field, wire int, ok bool) {
+	if r.err != nil || len(r.data) == 0 {
+		return 0, 0, false
+	}
+	key := r.uvarint()
+	if r.err != nil {
+		return 0, 0, false
+	}
+	return int(key >> 3), int(key & 7), true
+}
+
+// uvarint reads a varint
+func (r *pbfReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, sz := binary.Uvarint(r.data)
+	if sz <= 0 {
+		r.err = errMalformedPBF
+		return 0
+	}
+	r.data = r.data[sz:]
+	return n
+}
+
+// varint reads a zigzag encoded varint
+func (r *pbfReader) varint() int64 {
+	return unzigzag(r.uvarint())
+}
+
+// unzigzag decodes a zigzag encoded integer
+func unzigzag(n uint64) int64 {
+	return int64(n>>1) ^ -int64(n&1)
+}
+
+// fixed64 reads eight bytes
+func (r *pbfReader) fixed64() uint64 {
+	if r.err != nil || len(r.data) < 8 {
+		r.err = errMalformedPBF
+		return 0
+	}
+	n := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return n
+}
+
+// fixed32 reads four bytes
+func (r *pbfReader) fixed32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = errMalformedPBF
+		return 0
+	}
+	n := binary.LittleEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return n
+}
+
+// double reads a double
+func (r *pbfReader) double() float64 {
+	return math.Float64frombits(r.fixed64())
+}
+
+// bytes reads a length-delimited field, which is not copied
+func (r *pbfReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil || n > uint64(len(r.data)) {
+		r.err = errMalformedPBF
+		return nil
+	}
+	b := r.data[:n:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// packed reads a field of varints, which is packed or a single value
+func (r *pbfReader) packed(wire int) []uint64 {
+	return r.appendPacked(nil, wire)
+}
+
+// appendPacked is packed, but appends the values to vals
+func (r *pbfReader) appendPacked(vals []uint64, wire int) []uint64 {
+	if wire == pbfVarint {
+		return append(vals, r.uvarint())
+	}
+	if wire != pbfBytes {
+		r.err = errMalformedPBF
+		return vals
+	}
+	pr := pbfReader{data: r.bytes()}
+	for r.err == nil && pr.err == nil && len(pr.data) > 0 {
+		vals = append(vals, pr.uvarint())
+	}
+	if pr.err != nil {
+		r.err = pr.err
+	}
+	return vals
+}
+
+// skip skips the value of a field of the wire type
+func (r *pbfReader) skip(wire int) {
+	switch wire {
+	case pbfVarint:
+		r.uvarint()
+	case pbfFixed64:
+		r.fixed64()
+	case pbfBytes:
+		r.bytes()
+	case pbfFixed32:
+		r.fixed32()
+	default:
+		r.err = errMalformedPBF
+	}
+}
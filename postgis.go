@@ -0,0 +1,141 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PostGISLayer is a layer of a tile whose features are queried from
+// PostGIS, or any database that returns (E)WKB geometry.
+type PostGISLayer struct {
+	// Name is the name of the layer
+	Name string
+	// Query selects the features of the tile. The placeholders {minx},
+	// {miny}, {maxx}, and {maxy} are the bounds of the tile, plus a small
+	// buffer, in Web Mercator (EPSG:3857) meters, and {zoom} is the zoom of
+	// the tile. They are passed as query parameters, such as in
+	// "WHERE geom && ST_MakeEnvelope({minx}, {miny}, {maxx}, {maxy}, 3857)".
+	Query string
+	// GeomColumn is the column of the geometry, which is WKB or EWKB, raw
+	// or as hex. EWKB may have an SRID of 4326 for lon/lat, and otherwise
+	// the geometry is taken to be Web Mercator meters. Default is "geom".
+	GeomColumn string
+	// IDColumn, when set, is the column of the feature ids. The other
+	// columns are tags.
+	IDColumn string
+}
+
+// QueryPostGISTile returns the map tile with the layers, whose features
+// are queried from the database for the bounds of the tile. This allows for
+// encoding tiles in the application rather than with ST_AsMVT.
+func QueryPostGISTile(ctx context.Context, db *sql.DB, id TileID,
+	layers ...PostGISLayer,
+) (*Tile, error) {
+	tile := &Tile{}
+	tile.SetTileID(id)
+	minx, miny, maxx, maxy := id.BoundsMercator()
+	buffer := (maxx - minx) * clipBuffer / gTileSize
+	args := []interface{}{minx - buffer, miny - buffer, maxx + buffer,
+		maxy + buffer, id.Z}
+	for _, pl := range layers {
+		query := strings.NewReplacer(
+			"{minx}", "$1", "{miny}", "$2", "{maxx}", "$3", "{maxy}", "$4",
+			"{zoom}", "$5",
+		).Replace(pl.Query)
+		// only pass the parameters that the query uses
+		n := 0
+		for i := 1; i <= len(args); i++ {
+			if strings.Contains(query, fmt.Sprintf("$%d", i)) {
+				n = i
+			}
+		}
+		rows, err := db.QueryContext(ctx, query, args[:n]...)
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", pl.Name, err)
+		}
+		err = addSQLRows(tile.AddLayer(pl.Name), rows, pl)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("layer %q: %w", pl.Name, err)
+		}
+	}
+	return tile, nil
+}
+
+// addSQLRows adds the features of the rows to the layer.
+func addSQLRows(l *Layer, rows *sql.Rows, pl PostGISLayer) error {
+	geomCol := pl.GeomColumn
+	if geomCol == "" {
+		geomCol = "geom"
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		gf := GeoFeature{Tags: make(map[string]interface{})}
+		for i, col := range cols {
+			switch {
+			case col == geomCol:
+				if gf.Geometry, err = parseSQLGeometry(values[i]); err != nil {
+					return err
+				}
+			case col == pl.IDColumn:
+				gf.ID = values[i]
+			case values[i] != nil:
+				gf.Tags[col] = values[i]
+			}
+		}
+		l.AddGeoFeature(gf)
+	}
+	return rows.Err()
+}
+
+// parseSQLGeometry parses a WKB or EWKB column value into lat/lon
+// geometry.
+func parseSQLGeometry(value interface{}) (Geometry, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return Geometry{}, nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return Geometry{}, fmt.Errorf("%w: %T geometry", ErrInvalidWKB, value)
+	}
+	if len(data) > 0 && data[0] == '0' {
+		// hex, as drivers return the geometry type
+		raw, err := hex.DecodeString(string(data))
+		if err != nil {
+			return Geometry{}, fmt.Errorf("%w: %v", ErrInvalidWKB, err)
+		}
+		data = raw
+	}
+	g, srid, err := parseWKB(data)
+	if err != nil || srid == 4326 {
+		return g, err
+	}
+	for _, path := range g.Paths {
+		for i, p := range path {
+			path[i][0], path[i][1] = mercatorLonLat(p[0], p[1])
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQueryPostGISTile(t *testing.T) {
+	tx, ty, _, _ := LatLonToTile(33.4, -111.9, 10)
+	id := TileID{Z: 10, X: tx, Y: ty}
+	mx, my := lonLatMercator(-111.9, 33.4)
+	results := []fakeResult{
+		{"FROM places", []string{"gid", "geom", "name", "pop"},
+			[][]driver.Value{
+				// hex EWKB in lon/lat, as drivers return geometry
+				{int64(7), strings.ToUpper(hex.EncodeToString(
+					wkb(0x20000001, 4326, -111.9, 33.4))), "tempe", int64(180000)},
+				// raw WKB in Web Mercator meters
+				{int64(8), wkb(1, mx, my), "also tempe", nil},
+				// outside of the tile
+				{int64(9), wkb(1, 0.0, 0.0), "null island", nil},
+			}},
+		{"FROM roads", []string{"geom"}, nil},
+	}
+	db := openFakeDB(t, results)
+	defer db.Close()
+	tile, err := QueryPostGISTile(context.Background(), db, id,
+		PostGISLayer{
+			Name:     "places",
+			Query:    "SELECT * FROM places WHERE geom && ST_MakeEnvelope({minx}, {miny}, {maxx}, {maxy}, 3857) AND {zoom} > 8",
+			IDColumn: "gid",
+		},
+		PostGISLayer{
+			Name:  "roads",
+			Query: "SELECT geom FROM roads WHERE geom && ST_MakeEnvelope({minx}, {miny}, {maxx}, {maxy}, 3857)",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tile.Layers()) != 2 {
+		t.Fatalf("expected 2 layers, got %d", len(tile.Layers()))
+	}
+	features := tile.Layers()[0].Features()
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+	if id, ok := features[0].ID(); !ok || id != 7 {
+		t.Fatalf("expected id 7, got %v", id)
+	}
+	var tags []string
+	for _, f := range features {
+		for _, tag := range f.Tags() {
+			tags = append(tags, tag.Key)
+		}
+	}
+	if s := strings.Join(tags, ","); s != "name,pop,name" {
+		t.Fatalf("unexpected tags %s", s)
+	}
+	minx, miny, maxx, maxy := id.BoundsMercator()
+	buffer := (maxx - minx) * clipBuffer / gTileSize
+	queries := testDriver.queries
+	if !strings.Contains(queries[0], "ST_MakeEnvelope($1, $2, $3, $4, 3857) AND $5 > 8") ||
+		!strings.HasSuffix(queries[0], "10]") {
+		t.Fatalf("unexpected query %s", queries[0])
+	}
+	// only the parameters that are used are passed
+	args := fmt.Sprint([]driver.Value{minx - buffer, miny - buffer,
+		maxx + buffer, maxy + buffer})
+	if !strings.HasSuffix(queries[1], args) {
+		t.Fatalf("expected args %s, got %s", args, queries[1])
+	}
+
+	_, err = QueryPostGISTile(context.Background(), db, id,
+		PostGISLayer{Name: "bad", Query: "SELECT * FROM missing"})
+	if err == nil || !strings.Contains(err.Error(), `layer "bad"`) {
+		t.Fatalf("expected a layer error, got %v", err)
+	}
+	if _, err := parseSQLGeometry("0zz"); !errors.Is(err, ErrInvalidWKB) {
+		t.Fatalf("expected ErrInvalidWKB, got %v", err)
+	}
+}
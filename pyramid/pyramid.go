@@ -0,0 +1,374 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package pyramid builds all of the tiles of a range of zooms from a set of
+// lat/lon features.
+package pyramid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tidwall/mvt"
+)
+
+// Options are the options of Build
+type Options struct {
+	// MinZoom and MaxZoom are the range of zooms to build, inclusive
+	MinZoom, MaxZoom int
+	// Layer is the name of the layer of the features. Default is
+	// "features".
+	Layer string
+	// Simplify is the Douglas-Peucker tolerance, in pixels, that is used
+	// for the zooms below MaxZoom. Default is zero, which does not
+	// simplify.
+	Simplify float64
+	// Workers is the number of tiles that are rendered at once. Default is
+	// the number of CPUs.
+	Workers int
+	// Progress, when set, is called after each tile with the number of
+	// tiles done so far and the total number of tiles.
+	Progress func(done, total int)
+	// TempDir is the directory of the temporary file that the features
+	// are written to while the tiles are built. Default is the directory
+	// of os.TempDir.
+	TempDir string
+}
+
+// ErrInvalidZoom is returned for a zoom range that is empty or not within
+// 0 to 30.
+var ErrInvalidZoom = errors.New("invalid zoom range")
+
+func init() {
+	// the tag values, other than the basic types, that the features are
+	// written with
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register(time.Time{})
+}
+
+// Build reads the features from the source until it returns io.EOF, assigns
+// them to the tiles that cover their bounds at each zoom, including those
+// whose buffers they reach into past the edges, and calls emit
+// with each of the rendered tiles. Tiles that end up with no features are
+// not emitted. Emit is called from one goroutine at a time, such that it
+// may write to an MBTiles database or a directory of tiles as it goes,
+// and an error returned from it stops the build.
+//
+// The features are written to a temporary file as they are read, rather
+// than kept in memory, and are read back for each tile that they are in,
+// such that only the tiles of one zoom are assigned at once and only a
+// few tiles are held waiting to be emitted. Tag values of types other
+// than basic types, []interface{}, map[string]interface{}, and time.Time
+// must be registered with gob.Register.
+func Build(src mvt.FeatureSource, opts Options,
+	emit func(id mvt.TileID, data []byte) error,
+) error {
+	if opts.MinZoom < 0 || opts.MaxZoom > 30 || opts.MinZoom > opts.MaxZoom {
+		return fmt.Errorf("%d-%d: %w", opts.MinZoom, opts.MaxZoom,
+			ErrInvalidZoom)
+	}
+	if opts.Layer == "" {
+		opts.Layer = "features"
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	sp, err := spillFeatures(src, opts.TempDir)
+	if err != nil {
+		return err
+	}
+	defer sp.close()
+	var total int
+	for z := opts.MinZoom; z <= opts.MaxZoom; z++ {
+		tiles := make(map[mvt.TileID]bool)
+		err := sp.scan(func(b [4]float64, _ int64) {
+			for _, id := range cover(b, z) {
+				tiles[id] = true
+			}
+		})
+		if err != nil {
+			return err
+		}
+		total += len(tiles)
+	}
+	b := builder{sp: sp, opts: opts, emit: emit, total: total}
+	b.render = b.renderTile
+	for z := opts.MinZoom; z <= opts.MaxZoom; z++ {
+		jobs, err := sp.jobs(z)
+		if err != nil {
+			return err
+		}
+		if err := b.run(jobs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tileBuffer is the pixels past the edges of a tile that its features are
+// clipped to, see mvt.Layer.AddGeometry.
+const tileBuffer = 8
+
+// cover returns the tiles at the zoom of the lat/lon bounds, as min lat,
+// min lon, max lat, and max lon, grown by the buffer of a tile, such that
+// a feature is in the tiles whose buffers it reaches into.
+func cover(b [4]float64, z int) []mvt.TileID {
+	x1, y1 := mvt.LatLonToPixel(b[2], b[1], z)
+	x2, y2 := mvt.LatLonToPixel(b[0], b[3], z)
+	maxLat, minLon := mvt.PixelToLatLon(x1-tileBuffer, y1-tileBuffer, z)
+	minLat, maxLon := mvt.PixelToLatLon(x2+tileBuffer, y2+tileBuffer, z)
+	if b[1] > b[3] && minLon <= maxLon {
+		// across the antimeridian, with the gap between closed by the
+		// buffer
+		minLon, maxLon = -180, 180
+	}
+	return mvt.TilesCoveringBounds(minLat, minLon, maxLat, maxLon, z)
+}
+
+// job is a tile to render, with the offsets of its features in the spill
+type job struct {
+	id      mvt.TileID
+	offsets []int64
+}
+
+// builder renders the jobs and emits the tiles in order
+type builder struct {
+	sp     *spill
+	opts   Options
+	render func(j job) ([]byte, error)
+	emit   func(id mvt.TileID, data []byte) error
+	done   int
+	total  int
+}
+
+// result is a rendered tile
+type result struct {
+	id   mvt.TileID
+	data []byte
+	err  error
+}
+
+// run renders the jobs with a pool of workers, emitting them in order.
+// At most twice as many tiles as there are workers are rendered ahead of
+// the tile that is next to be emitted.
+func (b *builder) run(jobs []job) error {
+	type work struct {
+		job
+		out chan result
+	}
+	pending := make(chan chan result, b.opts.Workers*2)
+	queue := make(chan work)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		defer close(queue)
+		for _, j := range jobs {
+			out := make(chan result, 1)
+			select {
+			case pending <- out:
+			case <-stop:
+				return
+			}
+			select {
+			case queue <- work{j, out}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	for w := 0; w < b.opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range queue {
+				data, err := b.render(w.job)
+				w.out <- result{w.id, data, err}
+			}
+		}()
+	}
+	var err error
+	for out := range pending {
+		r := <-out
+		err = r.err
+		if err == nil && r.data != nil {
+			err = b.emit(r.id, r.data)
+		}
+		if err != nil {
+			break
+		}
+		b.done++
+		if b.opts.Progress != nil {
+			b.opts.Progress(b.done, b.total)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	return err
+}
+
+// renderTile returns the encoded tile of the features, or nil when none of
+// them are in the tile.
+func (b *builder) renderTile(j job) ([]byte, error) {
+	var tile mvt.Tile
+	tile.SetTileID(j.id)
+	l := tile.AddLayer(b.opts.Layer)
+	for _, offset := range j.offsets {
+		gf, err := b.sp.read(offset)
+		if err != nil {
+			return nil, err
+		}
+		f := l.AddGeoFeature(gf)
+		if f != nil && j.id.Z < b.opts.MaxZoom {
+			f.Simplify(b.opts.Simplify)
+		}
+	}
+	if len(l.Features()) == 0 {
+		return nil, nil
+	}
+	return tile.Encode()
+}
+
+// spill is a temporary file of features. Each is a record of its lat/lon
+// bounds, as min lat, min lon, max lat, and max lon, the size of the
+// feature, and the gob of the feature.
+type spill struct {
+	file *os.File
+	size int64
+}
+
+// spillHeader is the size of the bounds and size of a record
+const spillHeader = 36
+
+// spillFeatures writes the features of the source to a spill, leaving out
+// those without any points.
+func spillFeatures(src mvt.FeatureSource, dir string) (*spill, error) {
+	file, err := os.CreateTemp(dir, "pyramid-*.features")
+	if err != nil {
+		return nil, err
+	}
+	sp := &spill{file: file}
+	w := bufio.NewWriter(file)
+	var buf bytes.Buffer
+	var n int
+	for ; ; n++ {
+		geom, tags, id, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			sp.close()
+			return nil, err
+		}
+		minLat, minLon, maxLat, maxLon := geom.Bounds()
+		if minLat > maxLat {
+			continue
+		}
+		buf.Reset()
+		err = gob.NewEncoder(&buf).Encode(mvt.GeoFeature{
+			Geometry: geom, Tags: tags, ID: id})
+		if err != nil {
+			sp.close()
+			return nil, fmt.Errorf("feature %d: %w", n, err)
+		}
+		var head [spillHeader]byte
+		for i, v := range []float64{minLat, minLon, maxLat, maxLon} {
+			binary.LittleEndian.PutUint64(head[i*8:], math.Float64bits(v))
+		}
+		binary.LittleEndian.PutUint32(head[32:], uint32(buf.Len()))
+		w.Write(head[:])
+		w.Write(buf.Bytes())
+		sp.size += spillHeader + int64(buf.Len())
+	}
+	if err := w.Flush(); err != nil {
+		sp.close()
+		return nil, err
+	}
+	return sp, nil
+}
+
+// scan calls fn with the bounds and offset of each feature, in order
+func (sp *spill) scan(fn func(bounds [4]float64, offset int64)) error {
+	r := bufio.NewReader(io.NewSectionReader(sp.file, 0, sp.size))
+	var head [spillHeader]byte
+	for offset := int64(0); offset < sp.size; {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return err
+		}
+		var b [4]float64
+		for i := range b {
+			b[i] = math.Float64frombits(
+				binary.LittleEndian.Uint64(head[i*8:]))
+		}
+		fn(b, offset)
+		n := binary.LittleEndian.Uint32(head[32:])
+		if _, err := r.Discard(int(n)); err != nil {
+			return err
+		}
+		offset += spillHeader + int64(n)
+	}
+	return nil
+}
+
+// jobs returns the tiles of the zoom that the features are in, with the
+// offsets of their features, row by row from the northwest.
+func (sp *spill) jobs(z int) ([]job, error) {
+	tiles := make(map[mvt.TileID][]int64)
+	err := sp.scan(func(b [4]float64, offset int64) {
+		for _, id := range cover(b, z) {
+			tiles[id] = append(tiles[id], offset)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]job, 0, len(tiles))
+	for id, offsets := range tiles {
+		jobs = append(jobs, job{id, offsets})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i].id, jobs[j].id
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	})
+	return jobs, nil
+}
+
+// read returns the feature at the offset. It is safe to call from many
+// goroutines at once.
+func (sp *spill) read(offset int64) (mvt.GeoFeature, error) {
+	var head [spillHeader]byte
+	if _, err := sp.file.ReadAt(head[:], offset); err != nil {
+		return mvt.GeoFeature{}, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(head[32:]))
+	if _, err := sp.file.ReadAt(data, offset+spillHeader); err != nil {
+		return mvt.GeoFeature{}, err
+	}
+	var gf mvt.GeoFeature
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&gf)
+	return gf, err
+}
+
+// close closes and removes the file
+func (sp *spill) close() {
+	sp.file.Close()
+	os.Remove(sp.file.Name())
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pyramid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tidwall/mvt"
+)
+
+func TestBuild(t *testing.T) {
+	features := []mvt.GeoFeature{{
+		Geometry: mvt.Geometry{Type: mvt.Point,
+			Paths: [][][2]float64{{{-111.93, 33.41}}}},
+		Tags: map[string]interface{}{"name": "tempe"},
+		ID:   1,
+	}, {
+		// crosses the prime meridian in the north
+		Geometry: mvt.Geometry{Type: mvt.LineString,
+			Paths: [][][2]float64{{{-10, 50}, {10, 50}}}},
+	}}
+	var tiles []string
+	var progress []int
+	err := Build(mvt.SliceSource(features), Options{
+		MinZoom: 0, MaxZoom: 2, Workers: 3, Simplify: 1,
+		Progress: func(done, total int) {
+			progress = append(progress, done, total)
+		},
+	}, func(id mvt.TileID, data []byte) error {
+		if len(data) == 0 {
+			t.Fatalf("%s: expected data", id)
+		}
+		tiles = append(tiles, id.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "[0/0/0 1/0/0 1/1/0 2/0/1 2/1/1 2/2/1]"
+	if s := fmt.Sprint(tiles); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+	if s := fmt.Sprint(progress[len(progress)-2:]); s != "[6 6]" {
+		t.Fatalf("unexpected progress %s", s)
+	}
+
+	stop := errors.New("stop")
+	var n int
+	err = Build(mvt.SliceSource(features), Options{MaxZoom: 2},
+		func(id mvt.TileID, data []byte) error {
+			n++
+			return stop
+		})
+	if err != stop || n != 1 {
+		t.Fatalf("expected to stop after one tile, got %v after %d", err, n)
+	}
+	err = Build(mvt.SliceSource(nil), Options{MinZoom: 3, MaxZoom: 2}, nil)
+	if !errors.Is(err, ErrInvalidZoom) {
+		t.Fatalf("expected ErrInvalidZoom, got %v", err)
+	}
+}
+
+func TestBuildBuffer(t *testing.T) {
+	// west of the prime meridian, within the buffer of the tile to the
+	// east of it at zoom 1 and 2, but not at zoom 3
+	features := []mvt.GeoFeature{{
+		Geometry: mvt.Geometry{Type: mvt.Point,
+			Paths: [][][2]float64{{{-1, 40}}}},
+	}}
+	var tiles []string
+	err := Build(mvt.SliceSource(features), Options{MaxZoom: 3},
+		func(id mvt.TileID, data []byte) error {
+			tiles = append(tiles, id.String())
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "[0/0/0 1/0/0 1/1/0 2/1/1 2/2/1 3/3/3]"
+	if s := fmt.Sprint(tiles); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+}
+
+func TestBuildSpill(t *testing.T) {
+	dir := t.TempDir()
+	features := []mvt.GeoFeature{{
+		Geometry: mvt.Geometry{Type: mvt.Point,
+			Paths: [][][2]float64{{{-111.93, 33.41}}}},
+		Tags: map[string]interface{}{"name": "tempe",
+			"list": []interface{}{1, "a"}},
+		ID: 7,
+	}, {
+		// no points, which is in no tiles
+		Geometry: mvt.Geometry{Type: mvt.Point},
+	}}
+	var n int
+	err := Build(mvt.SliceSource(features), Options{TempDir: dir},
+		func(id mvt.TileID, data []byte) error {
+			n++
+			tile, err := mvt.Decode(data)
+			if err != nil {
+				return err
+			}
+			f := tile.Layers()[0].Features()[0]
+			if id, ok := f.ID(); !ok || id != 7 {
+				t.Fatalf("expected id 7, got %d %t", id, ok)
+			}
+			name, _ := f.Tag("name")
+			list, _ := f.Tag("list")
+			if s := fmt.Sprint(name, list); s != "tempe[1 a]" {
+				t.Fatalf("unexpected tags %s", s)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 tile, got %d", n)
+	}
+	if ents, _ := os.ReadDir(dir); len(ents) != 0 {
+		t.Fatalf("expected the spill to be removed, got %d files", len(ents))
+	}
+}
+
+func TestBuildCleanup(t *testing.T) {
+	point := mvt.Geometry{Type: mvt.Point,
+		Paths: [][][2]float64{{{-111.93, 33.41}}}}
+	bad := errors.New("bad")
+	for _, c := range []struct {
+		name string
+		src  mvt.FeatureSource
+		emit error
+	}{
+		{"source", mvt.SourceFunc(func() (mvt.Geometry,
+			map[string]interface{}, interface{}, error) {
+			return mvt.Geometry{}, nil, nil, bad
+		}), nil},
+		{"encode", mvt.SliceSource([]mvt.GeoFeature{{Geometry: point,
+			Tags: map[string]interface{}{"v": struct{ A int }{1}}}}), nil},
+		{"emit", mvt.SliceSource([]mvt.GeoFeature{{Geometry: point}}), bad},
+	} {
+		dir := t.TempDir()
+		err := Build(c.src, Options{MaxZoom: 2, TempDir: dir},
+			func(id mvt.TileID, data []byte) error {
+				return c.emit
+			})
+		if err == nil {
+			t.Fatalf("%s: expected an error", c.name)
+		}
+		if c.name != "encode" && err != bad {
+			t.Fatalf("%s: expected %v, got %v", c.name, bad, err)
+		}
+		if ents, _ := os.ReadDir(dir); len(ents) != 0 {
+			t.Fatalf("%s: expected the spill to be removed, got %d files",
+				c.name, len(ents))
+		}
+	}
+}
+
+func TestBuildWindow(t *testing.T) {
+	const workers = 2
+	jobs := make([]job, 50)
+	for i := range jobs {
+		jobs[i].id = mvt.TileID{Z: 6, X: i}
+	}
+	var rendered atomic.Int32
+	var most int32
+	b := builder{opts: Options{Workers: workers}}
+	b.render = func(j job) ([]byte, error) {
+		rendered.Add(1)
+		return []byte{1}, nil
+	}
+	var emitted int32
+	b.emit = func(id mvt.TileID, data []byte) error {
+		if emitted == 0 {
+			// let the workers run ahead as far as they can
+			time.Sleep(50 * time.Millisecond)
+		}
+		emitted++
+		most = max(most, rendered.Load()-emitted)
+		return nil
+	}
+	if err := b.run(jobs); err != nil {
+		t.Fatal(err)
+	}
+	// the window is twice the workers
+	if most > workers*2 || emitted != 50 {
+		t.Fatalf("expected at most %d tiles ahead, got %d of %d", workers*2,
+			most, emitted)
+	}
+	stop := errors.New("stop")
+	b.emit = func(id mvt.TileID, data []byte) error {
+		return stop
+	}
+	rendered.Store(0)
+	if err := b.run(jobs); err != stop {
+		t.Fatalf("expected %v, got %v", stop, err)
+	}
+	// the one that was emitted, and those in the window
+	if n := rendered.Load(); n > workers*2+1 {
+		t.Fatalf("expected at most %d tiles rendered, got %d", workers*2+1, n)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"io"
+)
+
+// QueryPoint returns the features of the encoded tile whose geometries are
+// within the radius of the point, such as for finding what is under a
+// cursor, in the order of the tile. Points and radius are on the 512x512
+// canvas, whatever the extent of each layer. A polygon is hit by a point
+// that is inside of it.
+func QueryPoint(tile []byte, px, py, radius float64) ([]FeatureRef, error) {
+	return queryTile(tile, func(geomType GeometryType,
+		parts [][][2]float64,
+	) bool {
+		return hitPoint(geomType, parts, px, py, radius)
+	})
+}
+
+// QueryBox returns the features of the encoded tile whose geometries
+// intersect the box on the 512x512 canvas, in the order of the tile. A
+// polygon is hit by a box that is inside of it.
+func QueryBox(tile []byte, minX, minY, maxX, maxY float64,
+) ([]FeatureRef, error) {
+	r := clipRect{minX, minY, maxX, maxY}
+	return queryTile(tile, func(geomType GeometryType,
+		parts [][][2]float64,
+	) bool {
+		return hitBox(geomType, parts, r)
+	})
+}
+
+// queryTile returns the features of the encoded tile whose geometry parts,
+// scaled to the canvas, are hit
+func queryTile(tile []byte, hit func(geomType GeometryType,
+	parts [][][2]float64) bool,
+) ([]FeatureRef, error) {
+	var refs []FeatureRef
+	var parts [][][2]float64
+	r := NewTileReader(tile)
+	for {
+		l, err := r.NextLayer()
+		if err == io.EOF {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.Extent() == 0 {
+			return nil, fmt.Errorf("layer %q: %w: extent 0", l.Name(),
+				ErrInvalidTile)
+		}
+		scale := gTileSize / float64(l.Extent())
+		var index int
+		for {
+			f, err := l.NextFeature()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if parts, err = readParts(parts, f.Geometry(), scale); err != nil {
+				return nil, fmt.Errorf("layer %q: %w", l.Name(), err)
+			}
+			ref := FeatureRef{Layer: l.Name()}
+			if id, ok := f.ID(); ok {
+				ref.ID, ref.HasID = id, true
+			} else {
+				ref.Index = index
+				index++
+			}
+			if hit(f.GeomType(), parts) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+}
+
+// readParts reads the parts of the geometry, scaled, into the memory of
+// parts. Each part begins with a MoveTo.
+func readParts(parts [][][2]float64, g *GeometryReader, scale float64,
+) ([][][2]float64, error) {
+	parts = parts[:0]
+	for {
+		op, x, y, err := g.Next()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if op == OpClosePath {
+			continue
+		}
+		if op == OpMoveTo || len(parts) == 0 {
+			if n := len(parts); n < cap(parts) {
+				parts = append(parts, parts[:n+1][n][:0])
+			} else {
+				parts = append(parts, nil)
+			}
+		}
+		n := len(parts) - 1
+		parts[n] = append(parts[n],
+			[2]float64{float64(x) * scale, float64(y) * scale})
+	}
+}
+
+// hitPoint returns true when the geometry parts are within the radius of
+// the point. The parts of a polygon are its rings.
+func hitPoint(geomType GeometryType, parts [][][2]float64,
+	px, py, radius float64,
+) bool {
+	if geomType == Polygon {
+		return polygonDist(px, py, parts) >= -radius
+	}
+	p := command{x: px, y: py}
+	for _, part := range parts {
+		for i := range part {
+			a := command{x: part[i][0], y: part[i][1]}
+			b := a
+			if geomType == LineString && i > 0 {
+				b = command{x: part[i-1][0], y: part[i-1][1]}
+			}
+			if segmentDist(p, a, b) <= radius {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hitBox returns true when the geometry parts intersect the box. The parts
+// of a polygon are its rings.
+func hitBox(geomType GeometryType, parts [][][2]float64, r clipRect) bool {
+	for _, part := range parts {
+		for i := range part {
+			a, b := part[i], part[i]
+			switch {
+			case geomType == LineString && i > 0:
+				b = part[i-1]
+			case geomType == Polygon:
+				b = part[(i+1)%len(part)]
+			}
+			if _, _, _, _, ok := clipSegment(a[0], a[1], b[0], b[1],
+				r); ok {
+				return true
+			}
+		}
+	}
+	return geomType == Polygon && len(parts) > 0 &&
+		polygonDist((r.minX+r.maxX)/2, (r.minY+r.maxY)/2, parts) > 0
+}
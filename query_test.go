@@ -0,0 +1,108 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func encodeQueryTestTile(t *testing.T) []byte {
+	t.Helper()
+	var tile Tile
+	points := tile.AddLayer("points")
+	p := points.AddFeature(Point)
+	p.SetID(1)
+	p.MoveTo(100, 100)
+	points.AddFeature(Point).MoveTo(400, 400)
+	roads := tile.AddLayer("roads")
+	roads.SetExtent(512)
+	ls := roads.AddFeature(LineString)
+	ls.SetID(2)
+	ls.MoveTo(0, 200)
+	ls.LineTo(512, 200)
+	areas := tile.AddLayer("areas")
+	pg := areas.AddFeature(Polygon)
+	pg.SetID(3)
+	pg.MoveTo(50, 50)
+	pg.LineTo(250, 50)
+	pg.LineTo(250, 250)
+	pg.LineTo(50, 250)
+	pg.ClosePath()
+	pg.MoveTo(120, 120)
+	pg.LineTo(120, 180)
+	pg.LineTo(180, 180)
+	pg.LineTo(180, 120)
+	pg.ClosePath()
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb
+}
+
+func queryString(refs []FeatureRef) string {
+	var s string
+	for _, ref := range refs {
+		s += fmt.Sprintf("%s:%s ", ref.Layer, ref)
+	}
+	return s
+}
+
+func TestQueryPoint(t *testing.T) {
+	pb := encodeQueryTestTile(t)
+	tests := []struct {
+		x, y, radius float64
+		exp          string
+	}{
+		{102, 101, 3, "points:1 areas:3 "},
+		{102, 101, 1, "areas:3 "},
+		{398, 401, 3, "points:#0 "},
+		{300, 202, 2, "roads:2 "},
+		{300, 202, 1, ""},
+		{150, 150, 0, ""},
+		{150, 150, 31, "areas:3 "},
+		{260, 100, 10, "areas:3 "},
+		{260, 100, 9, ""},
+	}
+	for _, tt := range tests {
+		refs, err := QueryPoint(pb, tt.x, tt.y, tt.radius)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := queryString(refs); got != tt.exp {
+			t.Fatalf("%v,%v %v: expected %q, got %q", tt.x, tt.y, tt.radius,
+				tt.exp, got)
+		}
+	}
+	if _, err := QueryPoint(pb[:len(pb)-1], 0, 0, 1); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestQueryBox(t *testing.T) {
+	pb := encodeQueryTestTile(t)
+	tests := []struct {
+		minX, minY, maxX, maxY float64
+		exp                    string
+	}{
+		{0, 0, 512, 512, "points:1 points:#0 roads:2 areas:3 "},
+		{90, 90, 110, 110, "points:1 areas:3 "},
+		{300, 190, 310, 210, "roads:2 "},
+		{130, 130, 170, 170, ""},
+		{60, 60, 70, 70, "areas:3 "},
+		{300, 300, 310, 310, ""},
+	}
+	for _, tt := range tests {
+		refs, err := QueryBox(pb, tt.minX, tt.minY, tt.maxX, tt.maxY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := queryString(refs); got != tt.exp {
+			t.Fatalf("%v,%v,%v,%v: expected %q, got %q", tt.minX, tt.minY,
+				tt.maxX, tt.maxY, tt.exp, got)
+		}
+	}
+}
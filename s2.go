@@ -0,0 +1,59 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build s2
+
+package mvt
+
+import "github.com/golang/geo/s2"
+
+// AddS2Cell adds a polygon feature of the S2 cell, with the id and tags,
+// see AddGeoFeature. It returns nil when none of the cell is in the tile,
+// or when the cell is not valid.
+//
+// This requires the s2 build tag.
+func (l *Layer) AddS2Cell(id uint64, cell s2.CellID,
+	tags map[string]interface{}) *Feature {
+	if !cell.IsValid() {
+		return nil
+	}
+	return l.AddGeoFeature(GeoFeature{s2CellPolygon(cell), tags, id})
+}
+
+// AddS2CellUnion adds a feature of the polygons of the cells of the union,
+// such as a region coverage, with the id and tags, see AddGeoFeature. It
+// returns nil when none of the cells are in the tile.
+//
+// This requires the s2 build tag.
+func (l *Layer) AddS2CellUnion(id uint64, cells s2.CellUnion,
+	tags map[string]interface{}) *Feature {
+	g := Geometry{Type: Polygon}
+	for _, cell := range cells {
+		if cell.IsValid() {
+			g.Paths = append(g.Paths, s2CellPolygon(cell).Paths...)
+			g.Rings = append(g.Rings, 1)
+		}
+	}
+	return l.AddGeoFeature(GeoFeature{g, tags, id})
+}
+
+// s2CellPolygon returns the polygon of the cell. The edges of a cell are
+// geodesics, so those of large cells are divided to follow their curve.
+func s2CellPolygon(id s2.CellID) Geometry {
+	cell := s2.CellFromCellID(id)
+	n := 1
+	if level := cell.Level(); level < 8 {
+		n = 1 << uint(8-level)
+	}
+	vertices := make([][2]float64, 0, 4*n)
+	for k := 0; k < 4; k++ {
+		a, b := cell.Vertex(k), cell.Vertex((k+1)%4)
+		for i := 0; i < n; i++ {
+			ll := s2.LatLngFromPoint(s2.Interpolate(float64(i)/float64(n), a, b))
+			vertices = append(vertices, [2]float64{ll.Lng.Degrees(),
+				ll.Lat.Degrees()})
+		}
+	}
+	return cellPolygon(vertices)
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// curveTolerance is how far, in pixels, a flattened curve may stray from
+// the true curve. This is one unit of the default extent of 4096.
+const curveTolerance = 512.0 / 4096
+
+// arcSegments returns the number of segments that an arc of the radius
+// and angle, in radians, is flattened to.
+func arcSegments(radius, angle float64) int {
+	angle = math.Abs(angle)
+	// at most an eighth of a turn per segment
+	n := math.Ceil(angle / (math.Pi / 4))
+	if radius > curveTolerance {
+		// the angle of a segment whose middle is within the tolerance
+		step := 2 * math.Acos(1-curveTolerance/radius)
+		n = math.Max(n, math.Ceil(angle/step))
+	}
+	return int(math.Min(math.Max(n, 1), 4096))
+}
+
+// drawRing draws the points as a closed ring that is a new part of the
+// feature. Polygon rings end with a ClosePath, and the rings of other
+// features repeat their first point.
+func (f *Feature) drawRing(points [][2]float64) {
+	if len(points) == 0 {
+		return
+	}
+	if len(f.geom.ops) > 0 {
+		f.NewPath()
+	}
+	f.MoveTo(points[0][0], points[0][1])
+	for _, p := range points[1:] {
+		f.LineTo(p[0], p[1])
+	}
+	if f.geomType == Polygon {
+		f.ClosePath()
+	} else {
+		f.LineTo(points[0][0], points[0][1])
+	}
+}
+
+// arcPoints returns the points of an arc of a circle, from the start to
+// the end angle in radians, including both ends.
+func arcPoints(cx, cy, radius, start, end float64) [][2]float64 {
+	n := arcSegments(radius, end-start)
+	points := make([][2]float64, n+1)
+	for i := range points {
+		a := start + (end-start)*float64(i)/float64(n)
+		points[i] = [2]float64{cx + radius*math.Cos(a),
+			cy + radius*math.Sin(a)}
+	}
+	return points
+}
+
+// Circle draws a circle as a new part of the feature, with enough
+// vertices for its radius. The ring is an exterior ring of a Polygon.
+func (f *Feature) Circle(cx, cy, radius float64) {
+	n := arcSegments(radius, 2*math.Pi)
+	points := make([][2]float64, n)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		points[i] = [2]float64{cx + radius*math.Cos(a),
+			cy + radius*math.Sin(a)}
+	}
+	f.drawRing(points)
+}
+
+// ArcTo draws an arc of the circle at the center, from the start to the
+// end angle in radians, which turn clockwise from the x axis. Angles past
+// a full turn wrap around the circle again. A line is drawn from the
+// current point to the start of the arc, unless there is no current point,
+// which is the case for a new feature or following a ClosePath.
+func (f *Feature) ArcTo(cx, cy, radius, startAngle, endAngle float64) {
+	points := arcPoints(cx, cy, radius, startAngle, endAngle)
+	if len(f.geom.ops) == 0 || f.geom.lastOp() == closePath {
+		f.MoveTo(points[0][0], points[0][1])
+		points = points[1:]
+	}
+	for _, p := range points {
+		f.LineTo(p[0], p[1])
+	}
+}
+
+// SplineThrough draws a smooth curve that passes through each of the x/y
+// points, which is a cardinal spline that is flattened with CubicTo. A
+// tension of zero is a Catmull-Rom spline, and a tension of one draws
+// straight lines between the points. A line is drawn from the current
+// point to the first point, unless there is no current point.
+func (f *Feature) SplineThrough(points [][2]float64, tension float64) {
+	if len(points) == 0 {
+		return
+	}
+	if len(f.geom.ops) == 0 || f.geom.lastOp() == closePath {
+		f.MoveTo(points[0][0], points[0][1])
+	} else {
+		f.LineTo(points[0][0], points[0][1])
+	}
+	// the tangents at the ends use the end points themselves as neighbors
+	s := (1 - tension) / 6
+	for i := 0; i+1 < len(points); i++ {
+		p0, p1, p2, p3 := points[max(i-1, 0)], points[i], points[i+1],
+			points[min(i+2, len(points)-1)]
+		f.CubicTo(
+			p1[0]+s*(p2[0]-p0[0]), p1[1]+s*(p2[1]-p0[1]),
+			p2[0]-s*(p3[0]-p1[0]), p2[1]-s*(p3[1]-p1[1]),
+			p2[0], p2[1],
+		)
+	}
+}
+
+// Rect draws a rectangle as a new part of the feature, from the x/y of a
+// corner and its width and height. The ring is an exterior ring of a
+// Polygon.
+func (f *Feature) Rect(x, y, width, height float64) {
+	x, width = math.Min(x, x+width), math.Abs(width)
+	y, height = math.Min(y, y+height), math.Abs(height)
+	f.drawRing([][2]float64{
+		{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height},
+	})
+}
+
+// RoundedRect draws a rectangle with corners that are rounded by the
+// radius, which is at most half of the width or height, as a new part of
+// the feature. The ring is an exterior ring of a Polygon.
+func (f *Feature) RoundedRect(x, y, width, height, radius float64) {
+	x, width = math.Min(x, x+width), math.Abs(width)
+	y, height = math.Min(y, y+height), math.Abs(height)
+	radius = clamp(radius, 0, math.Min(width, height)/2)
+	if radius == 0 {
+		f.Rect(x, y, width, height)
+		return
+	}
+	var points [][2]float64
+	for _, corner := range [][3]float64{
+		{x + radius, y + radius, math.Pi},
+		{x + width - radius, y + radius, math.Pi * 3 / 2},
+		{x + width - radius, y + height - radius, 0},
+		{x + radius, y + height - radius, math.Pi / 2},
+	} {
+		points = append(points, arcPoints(corner[0], corner[1], radius,
+			corner[2], corner[2]+math.Pi/2)...)
+	}
+	f.drawRing(points)
+}
+
+// RegularPolygon draws a polygon of equal sides, whose vertices are the
+// radius from the center, as a new part of the feature. The first vertex
+// is straight up from the center, turned clockwise by the rotation in
+// radians. The ring is an exterior ring of a Polygon. Nothing is drawn for
+// fewer than three sides.
+func (f *Feature) RegularPolygon(cx, cy, radius float64, sides int,
+	rotation float64) {
+	if sides < 3 {
+		return
+	}
+	points := make([][2]float64, sides)
+	for i := range points {
+		a := rotation - math.Pi/2 + 2*math.Pi*float64(i)/float64(sides)
+		points[i] = [2]float64{cx + radius*math.Cos(a),
+			cy + radius*math.Sin(a)}
+	}
+	f.drawRing(points)
+}
+
+// AddCircleLatLon adds a Polygon feature of the points that are the
+// distance in meters from the lat/lon, on a spherical earth, with enough
+// vertices for the size of the circle at the zoom of the tile. It returns
+// nil when none of the circle is in the tile.
+func (l *Layer) AddCircleLatLon(lat, lon, radius float64) *Feature {
+	n := arcSegments(radius/l.metersPerPixel(lat), 2*math.Pi)
+	lat1, lon1 := lat*math.Pi/180, lon*math.Pi/180
+	d := radius / earthRadius
+	ring := make([][2]float64, n+1)
+	for i := 0; i < n; i++ {
+		bearing := 2 * math.Pi * float64(i) / float64(n)
+		lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) +
+			math.Cos(lat1)*math.Sin(d)*math.Cos(bearing))
+		lon2 := lon1 + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat1),
+			math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+		ring[i] = [2]float64{lon2 * 180 / math.Pi, lat2 * 180 / math.Pi}
+	}
+	ring[n] = ring[0]
+	return l.AddGeometry(Geometry{Type: Polygon, Paths: [][][2]float64{ring}})
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCircle(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	f := l.AddFeature(Polygon)
+	f.Circle(256, 256, 100)
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	n := len(f.geom.coords) / 2
+	if n != arcSegments(100, 2*math.Pi) || n < 32 {
+		t.Fatalf("unexpected vertex count %d", n)
+	}
+	for i := 0; i < len(f.geom.coords); i += 2 {
+		x, y := f.geom.coords[i], f.geom.coords[i+1]
+		if d := math.Hypot(x-256, y-256); math.Abs(d-100) > 1e-9 {
+			t.Fatalf("vertex %v,%v is %v from the center", x, y, d)
+		}
+	}
+	// the midpoint of a segment is within the tolerance
+	x0, y0, x1, y1 := f.geom.coords[0], f.geom.coords[1], f.geom.coords[2],
+		f.geom.coords[3]
+	if d := 100 - math.Hypot((x0+x1)/2-256, (y0+y1)/2-256); d > curveTolerance {
+		t.Fatalf("segment strays %v from the circle", d)
+	}
+	f.Circle(256, 256, 1)
+	if len(f.paths()) != 2 || arcSegments(1, 2*math.Pi) > n {
+		t.Fatal("expected a second, smaller ring")
+	}
+
+	line := l.AddFeature(LineString)
+	line.Circle(0, 0, 10)
+	c := line.geom.coords
+	if c[0] != c[len(c)-2] || c[1] != c[len(c)-1] {
+		t.Fatal("expected the line to end at its start")
+	}
+
+	tile.SetTileID(TileID{Z: 10, X: 190, Y: 408})
+	lat, lon := PixelToLatLon(190*512+256, 408*512+256, 10)
+	circle := l.AddCircleLatLon(lat, lon, 1000)
+	if circle == nil {
+		t.Fatal("expected a feature")
+	}
+	if err := circle.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	radius := 1000 / MetersPerPixel(10, lat)
+	for i := 0; i < len(circle.geom.coords); i += 2 {
+		x, y := circle.geom.coords[i], circle.geom.coords[i+1]
+		if d := math.Hypot(x-256, y-256); math.Abs(d-radius) > 0.5 {
+			t.Fatalf("vertex %v,%v is %v from the center, not %v", x, y, d,
+				radius)
+		}
+	}
+	if l.AddCircleLatLon(0, 0, 1000) != nil {
+		t.Fatal("expected no feature")
+	}
+}
+
+func TestShapes(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	rect := l.AddFeature(Polygon)
+	rect.Rect(10, 20, 30, 40)
+	rect.Rect(100, 100, -10, -10)
+	if err := rect.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(rect.geom.coords); s !=
+		"[10 20 40 20 40 60 10 60 90 90 100 90 100 100 90 100]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+	if a := rect.area(); a != 30*40+10*10 {
+		t.Fatalf("unexpected area %v", a)
+	}
+
+	rounded := l.AddFeature(Polygon)
+	rounded.RoundedRect(0, 0, 100, 50, 10)
+	if err := rounded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	expect := 100*50 - (4-math.Pi)*10*10
+	// flattened arcs are inside of the true arcs by up to the tolerance
+	if a := rounded.area(); math.Abs(a-expect) > 2*math.Pi*10*curveTolerance {
+		t.Fatalf("expected an area of about %v, got %v", expect, a)
+	}
+	// the radius is at most half of the height, and zero is a rectangle
+	rounded = l.AddFeature(Polygon)
+	rounded.RoundedRect(0, 0, 100, 50, 100)
+	rounded.RoundedRect(0, 0, 10, 10, 0)
+	expect = 50*50 + math.Pi*25*25 + 100
+	if a := rounded.area(); math.Abs(a-expect) > 2*math.Pi*25*curveTolerance {
+		t.Fatalf("expected an area of about %v, got %v", expect, a)
+	}
+
+	hex := l.AddFeature(Polygon)
+	hex.RegularPolygon(100, 100, 10, 6, 0)
+	if err := hex.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hex.geom.coords) / 2; n != 6 {
+		t.Fatalf("expected 6 vertices, got %d", n)
+	}
+	if x, y := hex.geom.coords[0], hex.geom.coords[1]; math.Abs(x-100) > 1e-9 ||
+		y != 90 {
+		t.Fatalf("expected the first vertex to be up, got %v,%v", x, y)
+	}
+	hex.RegularPolygon(100, 100, 10, 2, 0)
+	if len(hex.paths()) != 1 {
+		t.Fatal("expected nothing drawn for two sides")
+	}
+}
+
+func TestArcTo(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	f := l.AddFeature(LineString)
+	f.ArcTo(100, 100, 50, 0, math.Pi/2)
+	if f.geom.ops[0] != moveTo {
+		t.Fatal("expected the arc to start the feature")
+	}
+	c := f.geom.coords
+	if c[0] != 150 || c[1] != 100 || math.Abs(c[len(c)-2]-100) > 1e-9 ||
+		c[len(c)-1] != 150 {
+		t.Fatalf("unexpected ends %v,%v %v,%v", c[0], c[1], c[len(c)-2],
+			c[len(c)-1])
+	}
+	for i := 0; i < len(c); i += 2 {
+		if d := math.Hypot(c[i]-100, c[i+1]-100); math.Abs(d-50) > 1e-9 {
+			t.Fatalf("vertex %v,%v is %v from the center", c[i], c[i+1], d)
+		}
+	}
+	n := len(c) / 2
+	if n != arcSegments(50, math.Pi/2)+1 {
+		t.Fatalf("unexpected vertex count %d", n)
+	}
+
+	// continuing from the current point, and counterclockwise
+	f.ArcTo(100, 100, 50, math.Pi, 0)
+	if len(f.geom.coords)/2 != n+arcSegments(50, math.Pi)+1 ||
+		f.geom.ops[n] != lineTo {
+		t.Fatal("expected a line to the start of the second arc")
+	}
+	// through the angle of pi/2, which is below the center
+	if y := f.geom.coords[2*(n+arcSegments(50, math.Pi)/2)+1]; y < 149 {
+		t.Fatalf("expected the arc to go under the center, got y %v", y)
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplineThrough(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	points := [][2]float64{{0, 0}, {100, 50}, {200, 0}, {300, 50}}
+	f := l.AddFeature(LineString)
+	f.SplineThrough(points, 0)
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the curve passes through each point
+	c := f.geom.coords
+	for _, p := range points {
+		var found bool
+		for i := 0; i < len(c); i += 2 {
+			if math.Abs(c[i]-p[0]) < 1e-9 && math.Abs(c[i+1]-p[1]) < 1e-9 {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected the curve to pass through %v", p)
+		}
+	}
+	// and is smooth, bending away from the straight line between the
+	// first two points
+	var bend float64
+	for i := 0; i < len(c) && c[i] < 100; i += 2 {
+		bend = math.Max(bend, c[i+1]-c[i]/2)
+	}
+	if bend < 3 {
+		t.Fatalf("expected the curve to bend, got %v", bend)
+	}
+
+	// a tension of one is straight lines
+	f = l.AddFeature(LineString)
+	f.SplineThrough(points, 1)
+	c = f.geom.coords
+	for i := 0; i < len(c); i += 2 {
+		if c[i+1] < 0 || c[i+1] > 50 {
+			t.Fatalf("unexpected point %v,%v", c[i], c[i+1])
+		}
+	}
+	f.SplineThrough(nil, 0)
+	f.SplineThrough([][2]float64{{400, 400}}, 0)
+	c = f.geom.coords
+	if n := len(c); c[n-2] != 400 || c[n-1] != 400 {
+		t.Fatal("expected a line to the single point")
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidShapefile is returned for a shapefile that is malformed or of
+// a shape type that is not supported.
+var ErrInvalidShapefile = errors.New("invalid shapefile")
+
+// TextEncoding is the character encoding of text fields
+type TextEncoding int
+
+const (
+	// UTF8 text, which is the default
+	UTF8 TextEncoding = iota
+	// Latin1 is ISO-8859-1 text, as written by many older tools
+	Latin1
+)
+
+// dbfField is a field of a dBASE table
+type dbfField struct {
+	name     string
+	typ      byte
+	size     int
+	decimals int
+}
+
+// ShapefileReader is a FeatureSource that reads the shapes of a .shp file
+// along with their records from the .dbf file, whose fields become tags.
+// Coordinates must be lon/lat. The .prj file is not read.
+type ShapefileReader struct {
+	shp      *bufio.Reader
+	dbf      *bufio.Reader
+	fields   []dbfField
+	record   []byte
+	encoding TextEncoding
+}
+
+// NewShapefileReader returns a reader of the .shp and .dbf files, having
+// read their headers. The dbf may be nil, for shapes without tags.
+func NewShapefileReader(shp, dbf io.Reader) (*ShapefileReader, error) {
+	r := &ShapefileReader{shp: bufio.NewReader(shp)}
+	var head [100]byte
+	if _, err := io.ReadFull(r.shp, head[:]); err != nil {
+		return nil, shpError(err)
+	}
+	if binary.BigEndian.Uint32(head[:]) != 9994 {
+		return nil, fmt.Errorf("%w: bad file code", ErrInvalidShapefile)
+	}
+	if dbf != nil {
+		r.dbf = bufio.NewReader(dbf)
+		if err := r.readDBFHeader(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// SetEncoding sets the encoding of the text fields of the .dbf file, which
+// is often given by a .cpg file. Default is UTF8.
+func (r *ShapefileReader) SetEncoding(encoding TextEncoding) {
+	r.encoding = encoding
+}
+
+// shpError converts a read error to an invalid file error
+func shpError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: unexpected end of file", ErrInvalidShapefile)
+	}
+	return err
+}
+
+// readDBFHeader reads the header and field descriptors of the .dbf file.
+func (r *ShapefileReader) readDBFHeader() error {
+	var head [32]byte
+	if _, err := io.ReadFull(r.dbf, head[:]); err != nil {
+		return shpError(err)
+	}
+	headerSize := int(binary.LittleEndian.Uint16(head[8:]))
+	recordSize := int(binary.LittleEndian.Uint16(head[10:]))
+	if headerSize < 33 || recordSize < 1 {
+		return fmt.Errorf("%w: bad dbf header", ErrInvalidShapefile)
+	}
+	fields := make([]byte, headerSize-32)
+	if _, err := io.ReadFull(r.dbf, fields); err != nil {
+		return shpError(err)
+	}
+	size := 1 // the deletion flag
+	for len(fields) >= 32 && fields[0] != 0x0d {
+		name, _, _ := bytes.Cut(fields[:11], []byte{0})
+		f := dbfField{
+			name:     string(name),
+			typ:      fields[11],
+			size:     int(fields[16]),
+			decimals: int(fields[17]),
+		}
+		if f.typ == 'C' {
+			// character fields use the decimals as the high byte
+			f.size |= f.decimals << 8
+		}
+		r.fields = append(r.fields, f)
+		size += f.size
+		fields = fields[32:]
+	}
+	if size > recordSize {
+		return fmt.Errorf("%w: fields larger than the dbf record",
+			ErrInvalidShapefile)
+	}
+	r.record = make([]byte, recordSize)
+	return nil
+}
+
+// Next returns the next feature, or io.EOF when there are no more
+func (r *ShapefileReader) Next() (geom Geometry,
+	tags map[string]interface{}, id interface{}, err error) {
+	for {
+		var head [8]byte
+		if _, err := io.ReadFull(r.shp, head[:]); err != nil {
+			if err == io.EOF {
+				return Geometry{}, nil, nil, io.EOF
+			}
+			return Geometry{}, nil, nil, shpError(err)
+		}
+		size := int(binary.BigEndian.Uint32(head[4:])) * 2
+		if size < 4 || size > 1<<30 {
+			return Geometry{}, nil, nil, fmt.Errorf("%w: bad record size",
+				ErrInvalidShapefile)
+		}
+		content := make([]byte, size)
+		if _, err := io.ReadFull(r.shp, content); err != nil {
+			return Geometry{}, nil, nil, shpError(err)
+		}
+		geom, err = parseShape(content)
+		if err != nil {
+			return Geometry{}, nil, nil, err
+		}
+		deleted := false
+		if r.dbf != nil {
+			if _, err := io.ReadFull(r.dbf, r.record); err != nil {
+				return Geometry{}, nil, nil, shpError(err)
+			}
+			deleted = r.record[0] == '*'
+			tags = r.readRecord()
+		}
+		if !deleted {
+			return geom, tags, nil, nil
+		}
+	}
+}
+
+// readRecord returns the fields of the current .dbf record. Blank fields
+// are left out.
+func (r *ShapefileReader) readRecord() map[string]interface{} {
+	tags := make(map[string]interface{}, len(r.fields))
+	data := r.record[1:]
+	for _, f := range r.fields {
+		raw := data[:f.size]
+		data = data[f.size:]
+		if f.typ == 'I' && len(raw) == 4 {
+			tags[f.name] = int64(int32(binary.LittleEndian.Uint32(raw)))
+			continue
+		}
+		text := strings.TrimSpace(r.decode(raw))
+		if text == "" {
+			continue
+		}
+		switch f.typ {
+		case 'N', 'F':
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				tags[f.name] = n
+			} else if n, err := strconv.ParseFloat(text, 64); err == nil {
+				tags[f.name] = n
+			}
+		case 'L':
+			switch text[0] {
+			case 'T', 't', 'Y', 'y':
+				tags[f.name] = true
+			case 'F', 'f', 'N', 'n':
+				tags[f.name] = false
+			}
+		default:
+			tags[f.name] = text
+		}
+	}
+	return tags
+}
+
+// decode converts text of the encoding to a string
+func (r *ShapefileReader) decode(raw []byte) string {
+	if r.encoding != Latin1 {
+		return strings.ToValidUTF8(string(raw), string(utf8.RuneError))
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// parseShape parses the content of a shape record, keeping only the x/y
+// of each point.
+func parseShape(content []byte) (Geometry, error) {
+	le := binary.LittleEndian
+	bad := fmt.Errorf("%w: bad shape", ErrInvalidShapefile)
+	point := func(b []byte) [2]float64 {
+		return [2]float64{math.Float64frombits(le.Uint64(b)),
+			math.Float64frombits(le.Uint64(b[8:]))}
+	}
+	typ := le.Uint32(content)
+	switch typ {
+	case 0:
+		return Geometry{}, nil
+	case 1, 11, 21:
+		if len(content) < 20 {
+			return Geometry{}, bad
+		}
+		return Geometry{Type: Point,
+			Paths: [][][2]float64{{point(content[4:])}}}, nil
+	case 8, 18, 28:
+		if len(content) < 40 {
+			return Geometry{}, bad
+		}
+		n := int(le.Uint32(content[36:]))
+		if n > (len(content)-40)/16 {
+			return Geometry{}, bad
+		}
+		g := Geometry{Type: Point}
+		for i := 0; i < n; i++ {
+			g.Paths = append(g.Paths, [][2]float64{point(content[40+i*16:])})
+		}
+		return g, nil
+	case 3, 13, 23, 5, 15, 25:
+		if len(content) < 44 {
+			return Geometry{}, bad
+		}
+		nparts := int(le.Uint32(content[36:]))
+		npoints := int(le.Uint32(content[40:]))
+		if nparts > (len(content)-44)/4 ||
+			npoints > (len(content)-44-nparts*4)/16 {
+			return Geometry{}, bad
+		}
+		points := content[44+nparts*4:]
+		g := Geometry{Type: LineString}
+		if typ%10 == 5 {
+			g.Type = Polygon
+		}
+		for i := 0; i < nparts; i++ {
+			start := int(le.Uint32(content[44+i*4:]))
+			end := npoints
+			if i < nparts-1 {
+				end = int(le.Uint32(content[48+i*4:]))
+			}
+			if start > end || end > npoints {
+				return Geometry{}, bad
+			}
+			path := make([][2]float64, 0, end-start)
+			for j := start; j < end; j++ {
+				path = append(path, point(points[j*16:]))
+			}
+			g.Paths = append(g.Paths, path)
+		}
+		if g.Type == Polygon {
+			g.Paths, g.Rings = shpPolygons(g.Paths)
+		}
+		return g, nil
+	}
+	return Geometry{}, fmt.Errorf("%w: unsupported shape type %d",
+		ErrInvalidShapefile, typ)
+}
+
+// shpPolygons groups the rings of a polygon shape into polygons, and
+// returns them in order along with their number of rings. Shapefiles
+// wind shells clockwise and holes counterclockwise, in any order, so each
+// hole goes to the smallest shell that it is in. Holes that are in no
+// shell are taken as shells.
+func shpPolygons(rings [][][2]float64) ([][][2]float64, []int) {
+	var shells []int
+	for i, ring := range rings {
+		if pathArea(ring) <= 0 {
+			shells = append(shells, i)
+		}
+	}
+	holes := make(map[int][]int)
+	for i, ring := range rings {
+		if pathArea(ring) <= 0 || len(ring) == 0 {
+			continue
+		}
+		best, bestArea := -1, math.Inf(1)
+		for _, j := range shells {
+			area := math.Abs(pathArea(rings[j]))
+			if area < bestArea &&
+				polygonDist(ring[0][0], ring[0][1], rings[j:j+1]) > 0 {
+				best, bestArea = j, area
+			}
+		}
+		if best == -1 {
+			shells = append(shells, i)
+			continue
+		}
+		holes[best] = append(holes[best], i)
+	}
+	sort.Ints(shells)
+	paths := make([][][2]float64, 0, len(rings))
+	counts := make([]int, 0, len(shells))
+	for _, i := range shells {
+		paths = append(paths, rings[i])
+		for _, j := range holes[i] {
+			paths = append(paths, rings[j])
+		}
+		counts = append(counts, 1+len(holes[i]))
+	}
+	return paths, counts
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// testShapefile returns .shp and .dbf files of a point, a line, a polygon
+// with a hole, and a deleted null shape.
+func testShapefile() (shp, dbf []byte) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	coords := func(b []byte, values ...float64) []byte {
+		for _, v := range values {
+			b = le.AppendUint64(b, math.Float64bits(v))
+		}
+		return b
+	}
+	poly := func(typ uint32, parts []uint32, values ...float64) []byte {
+		b := le.AppendUint32(nil, typ)
+		b = coords(b, 0, 0, 0, 0)
+		b = le.AppendUint32(b, uint32(len(parts)))
+		b = le.AppendUint32(b, uint32(len(values)/2))
+		for _, p := range parts {
+			b = le.AppendUint32(b, p)
+		}
+		return coords(b, values...)
+	}
+	shapes := [][]byte{
+		coords(le.AppendUint32(nil, 1), 8.54, 47.37),
+		poly(3, []uint32{0}, 0, 0, 1, 1, 2, 0),
+		poly(5, []uint32{0, 5}, 0, 0, 0, 10, 10, 10, 10, 0, 0, 0,
+			2, 2, 8, 2, 8, 8, 2, 8, 2, 2),
+		le.AppendUint32(nil, 0),
+	}
+	shp = make([]byte, 100)
+	be.PutUint32(shp, 9994)
+	le.PutUint32(shp[28:], 1000)
+	for i, s := range shapes {
+		shp = be.AppendUint32(shp, uint32(i+1))
+		shp = be.AppendUint32(shp, uint32(len(s)/2))
+		shp = append(shp, s...)
+	}
+	be.PutUint32(shp[24:], uint32(len(shp)/2))
+
+	fields := []struct {
+		name     string
+		typ      byte
+		size     int
+		decimals int
+	}{{"NAME", 'C', 10, 0}, {"POP", 'N', 8, 0}, {"AREA", 'N', 6, 2},
+		{"OK", 'L', 1, 0}}
+	records := [][]string{
+		{" ", "Z\xfcrich", "  421878", "  87.9", "T"},
+		{" ", "line", "", "", "?"},
+		{" ", "square", "       0", "  0.64", "F"},
+		{"*", "gone", "", "", ""},
+	}
+	dbf = make([]byte, 32)
+	dbf[0] = 3
+	le.PutUint32(dbf[4:], uint32(len(records)))
+	le.PutUint16(dbf[8:], uint16(32+32*len(fields)+1))
+	size := 1
+	for _, f := range fields {
+		desc := make([]byte, 32)
+		copy(desc, f.name)
+		desc[11] = f.typ
+		desc[16], desc[17] = byte(f.size), byte(f.decimals)
+		dbf = append(dbf, desc...)
+		size += f.size
+	}
+	le.PutUint16(dbf[10:], uint16(size))
+	dbf = append(dbf, 0x0d)
+	for _, rec := range records {
+		dbf = append(dbf, rec[0]...)
+		for i, f := range fields {
+			dbf = append(dbf, fmt.Sprintf("%-*s", f.size, rec[i+1])...)
+		}
+	}
+	return shp, append(dbf, 0x1a)
+}
+
+func TestShapefileReader(t *testing.T) {
+	shp, dbf := testShapefile()
+	r, err := NewShapefileReader(bytes.NewReader(shp), bytes.NewReader(dbf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetEncoding(Latin1)
+	features := readAllGeo(t, r)
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	for i, expect := range []string{
+		"{1 [[[8.54 47.37]]] []} map[AREA:87.9 NAME:Zürich OK:true POP:421878]",
+		"{2 [[[0 0] [1 1] [2 0]]] []} map[NAME:line]",
+		"{3 [[[0 0] [0 10] [10 10] [10 0] [0 0]] " +
+			"[[2 2] [8 2] [8 8] [2 8] [2 2]]] [2]} " +
+			"map[AREA:0.64 NAME:square OK:false POP:0]",
+	} {
+		s := fmt.Sprint(features[i].Geometry, " ", features[i].Tags)
+		if s != expect {
+			t.Fatalf("feature %d: expected %s, got %s", i, expect, s)
+		}
+	}
+	if v := features[0].Tags["POP"]; v != int64(421878) {
+		t.Fatalf("expected an int64 POP, got %T", v)
+	}
+
+	// the polygon keeps its hole when drawn
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	f := l.AddGeoFeature(features[2])
+	if err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if paths := f.paths(); len(paths) != 2 || ringArea(pathPoints(paths[1])) >= 0 {
+		t.Fatal("expected a hole")
+	}
+
+	r, err = NewShapefileReader(bytes.NewReader(shp), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if features := readAllGeo(t, r); len(features) != 4 {
+		t.Fatalf("expected 4 features, got %d", len(features))
+	}
+	if _, err := NewShapefileReader(bytes.NewReader(shp[4:]), nil); !errors.Is(err,
+		ErrInvalidShapefile) {
+		t.Fatalf("expected ErrInvalidShapefile, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// Simplify removes the points of lines and polygon rings that are within
+// the tolerance, in pixels on the 512x512 canvas, of the line through
+// their neighbors, using the Douglas-Peucker algorithm. A polygon ring
+// that would have fewer than three points is left as it is. Points are
+// not changed.
+func (f *Feature) Simplify(tolerance float64) {
+	if f.geomType == Point || tolerance <= 0 {
+		return
+	}
+	var g geometry
+	for _, path := range f.paths() {
+		points := pathPoints(path)
+		closed := path[len(path)-1].which == closePath
+		if closed {
+			points = append(points, points[0])
+		}
+		keep := make([]bool, len(points))
+		keep[0], keep[len(points)-1] = true, true
+		douglasPeucker(points, keep, tolerance)
+		var kept []command
+		for i, p := range points {
+			if keep[i] {
+				kept = append(kept, p)
+			}
+		}
+		if closed {
+			kept = kept[:len(kept)-1]
+			if len(kept) < 3 {
+				kept = points[:len(points)-1]
+			}
+		}
+		for i, p := range kept {
+			which := lineTo
+			if i == 0 {
+				which = moveTo
+			}
+			g.push(which, p.x, p.y)
+		}
+		if closed {
+			g.push(closePath, 0, 0)
+		}
+	}
+	f.geom = g
+}
+
+// douglasPeucker marks the points to keep between the first and last
+func douglasPeucker(points []command, keep []bool, tolerance float64) {
+	if len(points) < 3 {
+		return
+	}
+	a, b := points[0], points[len(points)-1]
+	best, bestDist := 0, tolerance
+	for i := 1; i < len(points)-1; i++ {
+		if d := segmentDist(points[i], a, b); d > bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best == 0 {
+		return
+	}
+	keep[best] = true
+	douglasPeucker(points[:best+1], keep[:best+1], tolerance)
+	douglasPeucker(points[best:], keep[best:], tolerance)
+}
+
+// segmentDist returns the distance from p to the segment a-b
+func segmentDist(p, a, b command) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.x-a.x, p.y-a.y)
+	}
+	t := ((p.x-a.x)*dx + (p.y-a.y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+// Smooth rounds the corners of lines and polygon rings by Chaikin's corner
+// cutting, which replaces each segment with points at a quarter and three
+// quarters of its length, for each iteration. The ends of lines stay where
+// they are. Each iteration about doubles the number of points, so a few
+// are usually enough, and Simplify may be used after to remove the points
+// that are not needed.
+func (f *Feature) Smooth(iterations int) {
+	if f.geomType == Point || iterations <= 0 {
+		return
+	}
+	var g geometry
+	for _, path := range f.paths() {
+		points := pathPoints(path)
+		closed := path[len(path)-1].which == closePath
+		for i := 0; i < iterations && len(points) > 2; i++ {
+			points = chaikin(points, closed)
+		}
+		for i, p := range points {
+			which := lineTo
+			if i == 0 {
+				which = moveTo
+			}
+			g.push(which, p.x, p.y)
+		}
+		if closed {
+			g.push(closePath, 0, 0)
+		}
+	}
+	f.geom = g
+}
+
+// chaikin returns the points with their corners cut once
+func chaikin(points []command, closed bool) []command {
+	n := len(points)
+	segs := n - 1
+	if closed {
+		segs = n
+	}
+	cut := make([]command, 0, 2*segs+2)
+	if !closed {
+		cut = append(cut, points[0])
+	}
+	for i := 0; i < segs; i++ {
+		a, b := points[i], points[(i+1)%n]
+		cut = append(cut,
+			command{which: lineTo, x: 0.75*a.x + 0.25*b.x,
+				y: 0.75*a.y + 0.25*b.y},
+			command{which: lineTo, x: 0.25*a.x + 0.75*b.x,
+				y: 0.25*a.y + 0.75*b.y},
+		)
+	}
+	if !closed {
+		cut = append(cut, points[n-1])
+	}
+	return cut
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSmooth(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("lines")
+	f := l.AddFeature(LineString)
+	f.MoveTo(0, 0)
+	f.LineTo(8, 0)
+	f.LineTo(8, 8)
+	f.Smooth(1)
+	if s := fmt.Sprint(f.geom.coords); s != "[0 0 2 0 6 0 8 2 8 6 8 8]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+	f.Smooth(2)
+	if n := len(f.geom.coords) / 2; n != 24 {
+		t.Fatalf("expected 24 points, got %d", n)
+	}
+	c := f.geom.coords
+	if c[0] != 0 || c[1] != 0 || c[len(c)-2] != 8 || c[len(c)-1] != 8 {
+		t.Fatal("expected the ends to stay")
+	}
+
+	p := l.AddFeature(Polygon)
+	p.Rect(0, 0, 8, 8)
+	p.Smooth(1)
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(p.geom.coords); s !=
+		"[2 0 6 0 8 2 8 6 6 8 2 8 0 6 0 2]" {
+		t.Fatalf("unexpected coords %s", s)
+	}
+	if a := p.area(); a != 64-4*2 {
+		t.Fatalf("unexpected area %v", a)
+	}
+
+	pt := l.AddFeature(Point)
+	pt.MoveTo(1, 1)
+	pt.Smooth(3)
+	if len(pt.geom.coords) != 2 {
+		t.Fatal("expected the point to be left as it is")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "io"
+
+// FeatureSource streams lat/lon features, such as from a file or a
+// database cursor, so that they may be drawn without having all of them
+// in memory at once.
+type FeatureSource interface {
+	// Next returns the next feature, or io.EOF when there are no more. The
+	// id is nil for a feature without one, and otherwise is a number or a
+	// string of digits.
+	Next() (geom Geometry, tags map[string]interface{}, id interface{},
+		err error)
+}
+
+// SourceFunc is a function that is a FeatureSource
+type SourceFunc func() (geom Geometry, tags map[string]interface{},
+	id interface{}, err error)
+
+// Next calls the function
+func (fn SourceFunc) Next() (Geometry, map[string]interface{}, interface{},
+	error) {
+	return fn()
+}
+
+// GeoFeature is a lat/lon feature of a FeatureSource
+type GeoFeature struct {
+	Geometry Geometry
+	Tags     map[string]interface{}
+	ID       interface{}
+}
+
+// SliceSource returns a FeatureSource of the features in memory
+func SliceSource(features []GeoFeature) FeatureSource {
+	return SourceFunc(func() (Geometry, map[string]interface{}, interface{},
+		error) {
+		if len(features) == 0 {
+			return Geometry{}, nil, nil, io.EOF
+		}
+		f := features[0]
+		features = features[1:]
+		return f.Geometry, f.Tags, f.ID, nil
+	})
+}
+
+// AddFrom adds the features of the source that are in the tile, see
+// AddGeometry, until the source has no more features, returning the
+// first error of the source other than io.EOF.
+func (l *Layer) AddFrom(src FeatureSource) error {
+	for {
+		geom, tags, id, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l.AddGeoFeature(GeoFeature{geom, tags, id})
+	}
+}
+
+// AddGeoFeature adds a feature drawn from the lat/lon feature, with its
+// tags and id, see AddGeometry. It returns nil when none of the feature is
+// in the tile.
+func (l *Layer) AddGeoFeature(gf GeoFeature) *Feature {
+	f := l.AddGeometry(gf.Geometry)
+	if f == nil {
+		return nil
+	}
+	if id, ok := parseID(gf.ID); ok {
+		f.SetID(id)
+	}
+	f.AddTags(gf.Tags)
+	if l.labels && gf.Geometry.Type == Polygon {
+		l.addLabelPoint(gf)
+	}
+	if l.centroids != nil {
+		l.addCentroid(gf)
+	}
+	return f
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddFrom(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("places")
+	src := SliceSource([]GeoFeature{{
+		Geometry: Geometry{Type: Point, Paths: [][][2]float64{{{-111.9, 33.4}}}},
+		Tags:     map[string]interface{}{"name": "tempe"},
+		ID:       "7",
+	}, {
+		Geometry: Geometry{Type: Point, Paths: [][][2]float64{{{2.35, 48.85}}}},
+	}})
+	if err := l.AddFrom(src); err != nil {
+		t.Fatal(err)
+	}
+	features := l.Features()
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+	if id, ok := features[0].ID(); !ok || id != 7 {
+		t.Fatalf("expected id 7, got %v", id)
+	}
+	if v, _ := features[0].Tag("name"); v != "tempe" {
+		t.Fatal("expected the tags")
+	}
+	if _, ok := features[1].ID(); ok {
+		t.Fatal("expected no id")
+	}
+
+	bad := errors.New("bad")
+	src = SourceFunc(func() (Geometry, map[string]interface{}, interface{},
+		error) {
+		return Geometry{}, nil, nil, bad
+	})
+	if err := l.AddFrom(src); err != bad {
+		t.Fatalf("expected bad, got %v", err)
+	}
+}
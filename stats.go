@@ -0,0 +1,123 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"sort"
+)
+
+// statsLargest is the number of the largest features of each layer that
+// are in its stats
+const statsLargest = 10
+
+// TileStats are the sizes and counts of the contents of an encoded tile,
+// see Stats.
+type TileStats struct {
+	// Size is the size of the tile in bytes
+	Size int
+	// Layers are the stats of the layers in the order of the tile
+	Layers []LayerStats
+}
+
+// LayerStats are the sizes and counts of the contents of a layer of an
+// encoded tile
+type LayerStats struct {
+	Name    string
+	Version int
+	// Size is the size of the layer in bytes
+	Size int
+	// Features is the number of features, and Points, LineStrings,
+	// Polygons, and Unknowns are the numbers of those of each type
+	Features, Points, LineStrings, Polygons, Unknowns int
+	// Vertices is the number of the MoveTo and LineTo vertices of the
+	// features
+	Vertices int
+	// Keys and Values are the numbers of entries in the key and value
+	// tables, and KeysSize and ValuesSize are their sizes in bytes
+	Keys, Values, KeysSize, ValuesSize int
+	// Largest are the largest features, up to ten, from the largest down
+	Largest []FeatureStats
+}
+
+// FeatureStats are the size and number of vertices of a feature
+type FeatureStats struct {
+	FeatureRef
+	Size     int
+	Vertices int
+}
+
+// Stats returns the sizes and counts of the contents of the encoded tile,
+// which are for finding what makes it large.
+func Stats(tile []byte) (TileStats, error) {
+	stats := TileStats{Size: len(tile)}
+	layers, _, err := splitTile(tile)
+	if err != nil {
+		return TileStats{}, err
+	}
+	for _, tl := range layers {
+		ls, err := layerStats(tl.msg)
+		if err != nil {
+			return TileStats{}, fmt.Errorf("layer %q: %w", tl.name, err)
+		}
+		stats.Layers = append(stats.Layers, ls)
+	}
+	return stats, nil
+}
+
+// layerStats returns the stats of a layer message
+func layerStats(msg []byte) (LayerStats, error) {
+	layer, err := parseLayer(msg)
+	if err != nil {
+		return LayerStats{}, err
+	}
+	ls := LayerStats{Name: layer.name, Version: int(layer.version),
+		Size: len(msg), Features: len(layer.features),
+		Keys: len(layer.keys), Values: len(layer.values)}
+	for _, key := range layer.keys {
+		ls.KeysSize += len(encodeKey(key))
+	}
+	for _, val := range layer.values {
+		ls.ValuesSize += len(appendUvarint([]byte{34}, uint64(len(val)))) +
+			len(val)
+	}
+	var index int
+	for _, fmsg := range layer.features {
+		f, err := parseFeature(fmsg)
+		if err != nil {
+			return LayerStats{}, err
+		}
+		switch f.geomType {
+		case Point:
+			ls.Points++
+		case LineString:
+			ls.LineStrings++
+		case Polygon:
+			ls.Polygons++
+		default:
+			ls.Unknowns++
+		}
+		vertices, err := countVertices(f.geometry)
+		if err != nil {
+			return LayerStats{}, err
+		}
+		ls.Vertices += vertices
+		fs := FeatureStats{Size: len(fmsg), Vertices: vertices}
+		if f.hasID {
+			fs.FeatureRef = FeatureRef{ID: f.id, HasID: true}
+		} else {
+			fs.FeatureRef = FeatureRef{Index: index}
+			index++
+		}
+		ls.Largest = append(ls.Largest, fs)
+	}
+	sort.SliceStable(ls.Largest, func(i, j int) bool {
+		return ls.Largest[i].Size > ls.Largest[j].Size
+	})
+	if len(ls.Largest) > statsLargest {
+		ls.Largest = ls.Largest[:statsLargest:statsLargest]
+	}
+	return ls, nil
+}
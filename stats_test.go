@@ -0,0 +1,53 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("places")
+	for i := 0; i < 12; i++ {
+		f := l.AddFeature(Point)
+		f.MoveTo(float64(i), 1)
+		f.AddTag("kind", "city")
+	}
+	f := l.AddFeature(Polygon)
+	f.SetID(99)
+	f.Rect(0, 0, 100, 100)
+	f.AddTag("kind", "park")
+	tile.AddLayer("empty")
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := Stats(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Size != len(pb) || len(stats.Layers) != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	ls := stats.Layers[0]
+	if ls.Name != "places" || ls.Version != 2 || ls.Features != 13 ||
+		ls.Points != 12 || ls.Polygons != 1 || ls.LineStrings != 0 ||
+		ls.Vertices != 16 || ls.Keys != 1 || ls.Values != 2 ||
+		ls.KeysSize != 6 || ls.ValuesSize != 16 {
+		t.Fatalf("unexpected layer stats %+v", ls)
+	}
+	if len(ls.Largest) != 10 || ls.Largest[0].String() != "99" ||
+		ls.Largest[0].Vertices != 4 || ls.Largest[1].String() != "#8" {
+		t.Fatalf("unexpected largest features %+v", ls.Largest)
+	}
+	if ls := stats.Layers[1]; ls.Name != "empty" || ls.Features != 0 {
+		t.Fatalf("unexpected layer stats %+v", ls)
+	}
+	if _, err := Stats(pb[:len(pb)-1]); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
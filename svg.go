@@ -0,0 +1,160 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SVGOptions are the options of RenderSVG. The zero value uses the
+// defaults.
+type SVGOptions struct {
+	// Scale is the size of a canvas pixel in the SVG. Default is 1, for a
+	// 512x512 tile.
+	Scale float64
+	// Buffer is the pixels around the tile that are drawn, which are
+	// outlined with a dashed line. Default is the 8 pixels that
+	// geometries are clipped to.
+	Buffer float64
+	// Colors are the colors of the layers, in order, which repeat when
+	// there are more layers than colors. Default is a palette of ten.
+	Colors []string
+	// PointRadius is the radius of the points in canvas pixels. Default
+	// is 3.
+	PointRadius float64
+}
+
+// svgColors are the default colors of the layers.
+var svgColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// RenderSVG draws the tile as an SVG image, for visually debugging the
+// geometries of its layers without a map client. The features are those
+// that the tile would encode, after the render time options of each
+// layer, drawn in a distinct color per layer over the outlines of the
+// tile and its buffer. Polygons are filled with the nonzero rule, such
+// that holes that are wound the wrong way are filled, and the outlines
+// of their interior rings are dashed. Each feature has a title with its
+// layer and id.
+func (t *Tile) RenderSVG(opts SVGOptions) []byte {
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = clipBuffer
+	}
+	colors := opts.Colors
+	if len(colors) == 0 {
+		colors = svgColors
+	}
+	radius := opts.PointRadius
+	if radius <= 0 {
+		radius = 3
+	}
+	size := gTileSize + buffer*2
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" `+
+		`width="%s" height="%s" viewBox="%s %s %s %s">`+"\n",
+		svgNum(size*scale), svgNum(size*scale), svgNum(-buffer),
+		svgNum(-buffer), svgNum(size), svgNum(size))
+	fmt.Fprintf(&sb, `<rect x="%s" y="%s" width="%s" height="%s" `+
+		`fill="#fff" stroke="#999" stroke-dasharray="4" `+
+		`vector-effect="non-scaling-stroke"/>`+"\n",
+		svgNum(-buffer), svgNum(-buffer), svgNum(size), svgNum(size))
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="none" `+
+		`stroke="#000" vector-effect="non-scaling-stroke"/>`+"\n",
+		gTileSize, gTileSize)
+	for i, layer := range t.layers {
+		fmt.Fprintf(&sb, `<g id="%s" fill="%s" stroke="%s">`+"\n",
+			html.EscapeString(layer.name), colors[i%len(colors)],
+			colors[i%len(colors)])
+		for _, f := range layer.render() {
+			layer.appendSVG(&sb, f, radius)
+		}
+		sb.WriteString("</g>\n")
+	}
+	sb.WriteString("</svg>\n")
+	return []byte(sb.String())
+}
+
+// appendSVG writes the feature as it will be encoded.
+func (l *Layer) appendSVG(sb *strings.Builder, f *Feature, radius float64) {
+	title := html.EscapeString(l.name)
+	if f.hasID {
+		title += " " + strconv.FormatUint(f.id, 10)
+	}
+	fmt.Fprintf(sb, "<g><title>%s</title>\n", title)
+	g := l.geometry(f)
+	paths := splitPaths(g.commands())
+	switch f.geomType {
+	case Point:
+		for _, path := range paths {
+			for _, p := range pathPoints(path) {
+				fmt.Fprintf(sb, `<circle cx="%s" cy="%s" r="%s" `+
+					`fill-opacity="0.5"/>`+"\n",
+					svgNum(p.x), svgNum(p.y), svgNum(radius))
+			}
+		}
+	case Polygon:
+		var d strings.Builder
+		for _, path := range paths {
+			appendSVGPath(&d, path)
+		}
+		fmt.Fprintf(sb, `<path d="%s" fill-opacity="0.25" `+
+			`fill-rule="nonzero" stroke="none"/>`+"\n", d.String())
+		for _, path := range paths {
+			d.Reset()
+			appendSVGPath(&d, path)
+			dash := ""
+			if ringArea(pathPoints(path)) < 0 {
+				dash = ` stroke-dasharray="3"`
+			}
+			fmt.Fprintf(sb, `<path d="%s" fill="none"%s/>`+"\n",
+				d.String(), dash)
+		}
+	default:
+		var d strings.Builder
+		for _, path := range paths {
+			appendSVGPath(&d, path)
+		}
+		fmt.Fprintf(sb, `<path d="%s" fill="none"/>`+"\n", d.String())
+	}
+	sb.WriteString("</g>\n")
+}
+
+// appendSVGPath writes the path data of a path, which is closed when the
+// path ends with a ClosePath.
+func appendSVGPath(d *strings.Builder, path []command) {
+	for i, p := range pathPoints(path) {
+		if d.Len() > 0 {
+			d.WriteByte(' ')
+		}
+		if i == 0 {
+			d.WriteByte('M')
+		} else {
+			d.WriteByte('L')
+		}
+		d.WriteString(svgNum(p.x))
+		d.WriteByte(' ')
+		d.WriteString(svgNum(p.y))
+	}
+	if len(path) > 0 && path[len(path)-1].which == closePath {
+		d.WriteString(" Z")
+	}
+}
+
+// svgNum formats a number of the SVG with at most two decimals, and
+// without the sign of a negative zero.
+func svgNum(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100+0, 'f', -1, 64)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRenderSVG(t *testing.T) {
+	var tile Tile
+	roads := tile.AddLayer("roads & paths")
+	f := roads.AddFeature(LineString)
+	f.SetID(7)
+	f.MoveTo(-4, 10)
+	f.LineTo(100.125, 10)
+	areas := tile.AddLayer("areas")
+	f = areas.AddFeature(Polygon)
+	f.MoveTo(10, 10)
+	f.LineTo(50, 10)
+	f.LineTo(50, 50)
+	f.LineTo(10, 50)
+	f.ClosePath()
+	f.MoveTo(20, 20)
+	f.LineTo(20, 40)
+	f.LineTo(40, 40)
+	f.LineTo(40, 20)
+	f.ClosePath()
+	areas.AddFeature(Point).MoveTo(256, 256)
+
+	svg := string(tile.RenderSVG(SVGOptions{Scale: 2}))
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`width="1056" height="1056" viewBox="-8 -8 528 528"`,
+		`<g id="roads &amp; paths" fill="#1f77b4"`,
+		`<title>roads &amp; paths 7</title>`,
+		`<path d="M-4 10 L100.13 10" fill="none"/>`,
+		`<g id="areas" fill="#ff7f0e"`,
+		`<path d="M10 10 L50 10 L50 50 L10 50 Z" fill="none"/>`,
+		`<path d="M20 20 L20 40 L40 40 L40 20 Z" fill="none" ` +
+			`stroke-dasharray="3"/>`,
+		`<circle cx="256" cy="256" r="3"`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Fatalf("expected %s in\n%s", want, svg)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "math"
+
+// DropEveryNth returns a policy that thins points, such as of dense GPS or
+// sensor data, by keeping one in every n of them, starting with the first.
+// Other features are kept.
+func DropEveryNth(n int) DropPolicy {
+	return func(features []*Feature, zoom int) []*Feature {
+		if n <= 1 {
+			return features
+		}
+		kept := features[:0]
+		var i int
+		for _, f := range features {
+			if f.geomType == Point {
+				i++
+				if (i-1)%n != 0 {
+					continue
+				}
+			}
+			kept = append(kept, f)
+		}
+		return kept
+	}
+}
+
+// DropByGrid returns a policy that thins points by keeping the first one
+// in each cell of a grid over the canvas, with cells of the size in
+// pixels. A point is in the cell of its first position. Other features
+// are kept.
+func DropByGrid(cellSize float64) DropPolicy {
+	return thinGrid(cellSize, func(f *Feature) float64 {
+		return 0
+	})
+}
+
+// DropByGridRank is DropByGrid, but keeps the point with the highest
+// numeric value for the tag key in each cell, and the first of those with
+// the same value. Points without a numeric value for the key are kept only
+// in cells that have no others.
+func DropByGridRank(key string, cellSize float64) DropPolicy {
+	return thinGrid(cellSize, func(f *Feature) float64 {
+		if v, ok := f.Tag(key); ok {
+			if rank, ok := toFloat(v); ok {
+				return rank
+			}
+		}
+		return math.Inf(-1)
+	})
+}
+
+// thinGrid returns a policy that keeps the point with the highest score
+// in each cell of the grid, in their original order
+func thinGrid(cellSize float64, score func(f *Feature) float64,
+) DropPolicy {
+	return func(features []*Feature, zoom int) []*Feature {
+		if !(cellSize > 0) {
+			return features
+		}
+		type best struct {
+			f     *Feature
+			score float64
+		}
+		cells := make(map[[2]int]best)
+		for _, f := range features {
+			if f.geomType != Point || len(f.geom.coords) == 0 {
+				continue
+			}
+			cell := [2]int{int(math.Floor(f.geom.coords[0] / cellSize)),
+				int(math.Floor(f.geom.coords[1] / cellSize))}
+			s := score(f)
+			if b, ok := cells[cell]; !ok || s > b.score {
+				cells[cell] = best{f, s}
+			}
+		}
+		keep := make(map[*Feature]bool, len(cells))
+		for _, b := range cells {
+			keep[b.f] = true
+		}
+		kept := features[:0]
+		for _, f := range features {
+			if keep[f] || f.geomType != Point || len(f.geom.coords) == 0 {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import "testing"
+
+func TestDropEveryNth(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	l.SetAutoID(0)
+	for i := 0; i < 7; i++ {
+		if i == 3 {
+			f := l.AddFeature(LineString)
+			f.MoveTo(0, 0)
+			f.LineTo(1, 1)
+			continue
+		}
+		l.AddFeature(Point).MoveTo(float64(i), 0)
+	}
+	l.SetDropPolicy(DropEveryNth(2))
+	if ids := featureIDs(l.render()); ids != "[0 2 3 5]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetDropPolicy(DropEveryNth(1))
+	if n := len(l.render()); n != 7 {
+		t.Fatalf("expected 7 features, got %d", n)
+	}
+}
+
+func TestDropByGrid(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	l.SetAutoID(0)
+	add := func(x, y float64, rank interface{}) {
+		f := l.AddFeature(Point)
+		f.MoveTo(x, y)
+		if rank != nil {
+			f.AddTag("rank", rank)
+		}
+	}
+	add(1, 1, nil)
+	add(5, 5, 3)
+	add(9, 9, 7)
+	add(12, 1, 2)
+	add(15, 5, nil)
+	add(-1, 1, 1)
+	f := l.AddFeature(Polygon)
+	f.MoveTo(1, 1)
+	f.LineTo(5, 1)
+	f.LineTo(5, 5)
+	f.ClosePath()
+	l.SetDropPolicy(DropByGrid(10))
+	if ids := featureIDs(l.render()); ids != "[0 3 5 6]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetDropPolicy(DropByGridRank("rank", 10))
+	if ids := featureIDs(l.render()); ids != "[2 3 5 6]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetDropPolicy(DropByGridRank("rank", 100))
+	if ids := featureIDs(l.render()); ids != "[2 5 6]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if len(l.Features()) != 7 {
+		t.Fatal("expected the layer to keep all features")
+	}
+}
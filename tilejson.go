@@ -0,0 +1,172 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// TileJSON is a TileJSON 3.0 document, which describes a tileset to map
+// clients, see TileJSONBuilder.
+type TileJSON struct {
+	TileJSON     string        `json:"tilejson"`
+	Name         string        `json:"name,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Version      string        `json:"version,omitempty"`
+	Attribution  string        `json:"attribution,omitempty"`
+	Scheme       string        `json:"scheme,omitempty"`
+	Tiles        []string      `json:"tiles"`
+	MinZoom      int           `json:"minzoom"`
+	MaxZoom      int           `json:"maxzoom"`
+	Bounds       [4]float64    `json:"bounds"`
+	Center       [3]float64    `json:"center"`
+	VectorLayers []VectorLayer `json:"vector_layers"`
+}
+
+// VectorLayer describes a layer of a tileset in a TileJSON document. The
+// fields are the tag keys of the layer, with the type of their values,
+// which is "String", "Number", "Boolean", or "Mixed".
+type VectorLayer struct {
+	ID          string            `json:"id"`
+	Description string            `json:"description,omitempty"`
+	MinZoom     int               `json:"minzoom"`
+	MaxZoom     int               `json:"maxzoom"`
+	Fields      map[string]string `json:"fields"`
+}
+
+// TileJSONBuilder collects the layers, tag keys and types, zooms, and
+// bounds of the tiles of a tileset as they are generated, such as from the
+// emit func of pyramid.Build, for its TileJSON document. It is not safe
+// for concurrent use.
+type TileJSONBuilder struct {
+	layers map[string]*VectorLayer
+	tiles  int
+	minZ   int
+	maxZ   int
+	bounds [4]float64
+}
+
+// NewTileJSONBuilder returns a builder without any tiles
+func NewTileJSONBuilder() *TileJSONBuilder {
+	return &TileJSONBuilder{layers: make(map[string]*VectorLayer)}
+}
+
+// AddTile adds an encoded tile of the tileset
+func (b *TileJSONBuilder) AddTile(id TileID, tile []byte) error {
+	layers, _, err := splitTile(tile)
+	if err != nil {
+		return err
+	}
+	for _, tl := range layers {
+		if err := b.addLayer(id.Z, tl.msg); err != nil {
+			return fmt.Errorf("layer %q: %w", tl.name, err)
+		}
+	}
+	offX, offY := float64(id.X*gTileSize), float64(id.Y*gTileSize)
+	maxLat, minLon := PixelToLatLon(offX, offY, id.Z)
+	minLat, maxLon := PixelToLatLon(offX+gTileSize, offY+gTileSize, id.Z)
+	if b.tiles == 0 {
+		b.minZ, b.maxZ = id.Z, id.Z
+		b.bounds = [4]float64{minLon, minLat, maxLon, maxLat}
+	} else {
+		b.minZ, b.maxZ = min(b.minZ, id.Z), max(b.maxZ, id.Z)
+		b.bounds = [4]float64{math.Min(b.bounds[0], minLon),
+			math.Min(b.bounds[1], minLat), math.Max(b.bounds[2], maxLon),
+			math.Max(b.bounds[3], maxLat)}
+	}
+	b.tiles++
+	return nil
+}
+
+// addLayer adds a layer message of a tile at the zoom
+func (b *TileJSONBuilder) addLayer(z int, msg []byte) error {
+	layer, err := parseLayer(msg)
+	if err != nil {
+		return err
+	}
+	vl := b.layers[layer.name]
+	if vl == nil {
+		vl = &VectorLayer{ID: layer.name, MinZoom: z, MaxZoom: z,
+			Fields: make(map[string]string)}
+		b.layers[layer.name] = vl
+	}
+	vl.MinZoom, vl.MaxZoom = min(vl.MinZoom, z), max(vl.MaxZoom, z)
+	if layer.version > 2 {
+		// the tags are attributes, which are not described
+		return nil
+	}
+	types := make([]string, len(layer.values))
+	for i, val := range layer.values {
+		v, err := decodeValue(val)
+		if err != nil {
+			return err
+		}
+		switch v.(type) {
+		case string:
+			types[i] = "String"
+		case bool:
+			types[i] = "Boolean"
+		default:
+			types[i] = "Number"
+		}
+	}
+	for _, fmsg := range layer.features {
+		f, err := parseFeature(fmsg)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(f.tags); i += 2 {
+			if f.tags[i] >= uint64(len(layer.keys)) ||
+				f.tags[i+1] >= uint64(len(types)) {
+				return fmt.Errorf("%w: tag index out of range",
+					ErrInvalidTile)
+			}
+			key, typ := layer.keys[f.tags[i]], types[f.tags[i+1]]
+			if prev, ok := vl.Fields[key]; ok && prev != typ {
+				typ = "Mixed"
+			}
+			vl.Fields[key] = typ
+		}
+	}
+	return nil
+}
+
+// TileJSON returns the TileJSON document of the tiles that were added,
+// with the URLs of the tiles, such as "https://example.com/{z}/{x}/{y}.mvt".
+// The center is that of the bounds at the lowest zoom. Other fields, such
+// as the name, can be set on the document before it is encoded as JSON.
+func (b *TileJSONBuilder) TileJSON(tiles ...string) *TileJSON {
+	tj := &TileJSON{TileJSON: "3.0.0", Tiles: append([]string{}, tiles...),
+		MinZoom: b.minZ, MaxZoom: b.maxZ, Bounds: b.bounds,
+		Center: [3]float64{(b.bounds[0] + b.bounds[2]) / 2,
+			(b.bounds[1] + b.bounds[3]) / 2, float64(b.minZ)},
+		VectorLayers: b.vectorLayers(),
+	}
+	if b.tiles == 0 {
+		tj.Bounds = [4]float64{-180, gMinLat, 180, gMaxLat}
+		tj.Center = [3]float64{}
+	}
+	return tj
+}
+
+// vectorLayers returns copies of the layers, sorted by name
+func (b *TileJSONBuilder) vectorLayers() []VectorLayer {
+	layers := make([]VectorLayer, 0, len(b.layers))
+	for _, vl := range b.layers {
+		fields := make(map[string]string, len(vl.Fields))
+		for k, v := range vl.Fields {
+			fields[k] = v
+		}
+		layer := *vl
+		layer.Fields = fields
+		layers = append(layers, layer)
+	}
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].ID < layers[j].ID
+	})
+	return layers
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestTileJSON(t *testing.T) {
+	b := NewTileJSONBuilder()
+	empty := b.TileJSON()
+	if empty.Bounds[0] != -180 || len(empty.VectorLayers) != 0 {
+		t.Fatalf("unexpected empty document %+v", empty)
+	}
+	for _, id := range []TileID{{Z: 1, X: 1, Y: 0}, {Z: 2, X: 3, Y: 1}} {
+		var tile Tile
+		roads := tile.AddLayer("roads")
+		f := roads.AddFeature(Point)
+		f.MoveTo(1, 1)
+		f.AddTag("name", "Main St")
+		f.AddTag("toll", id.Z == 1)
+		if id.Z == 1 {
+			f.AddTag("ref", 1)
+		} else {
+			f.AddTag("ref", "A1")
+			tile.AddLayer("water").AddFeature(Point).MoveTo(1, 1)
+		}
+		pb, err := tile.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.AddTile(id, pb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tj := b.TileJSON("https://example.com/{z}/{x}/{y}.mvt")
+	tj.Name = "test"
+	data, err := json.Marshal(tj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"tilejson":"3.0.0","name":"test",` +
+		`"tiles":["https://example.com/{z}/{x}/{y}.mvt"],` +
+		`"minzoom":1,"maxzoom":2,"bounds":[0,0,180,85.05112877980659],` +
+		`"center":[90,42.525564389903295,1],"vector_layers":[` +
+		`{"id":"roads","minzoom":1,"maxzoom":2,"fields":{"name":"String",` +
+		`"ref":"Mixed","toll":"Boolean"}},` +
+		`{"id":"water","minzoom":2,"maxzoom":2,"fields":{}}]}`
+	if string(data) != expect {
+		t.Fatalf("expected %s, got %s", expect, data)
+	}
+	err = b.AddTile(TileID{}, []byte{26, 1})
+	if !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
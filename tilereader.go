@@ -0,0 +1,277 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"fmt"
+	"io"
+)
+
+// Op is a command of an encoded geometry
+type Op int
+
+const (
+	// OpMoveTo starts a new part at a point
+	OpMoveTo Op = moveTo
+	// OpLineTo draws a line to a point
+	OpLineTo Op = lineTo
+	// OpClosePath closes the ring of a polygon
+	OpClosePath Op = closePath
+)
+
+// TileReader reads the layers of an encoded tile as they are needed,
+// without decoding the whole tile, such as for scanning large tiles for
+// stats or filtering them. The layer, feature, and geometry readers that
+// it returns are reused by the following calls, and the tile must not be
+// changed while it is read.
+type TileReader struct {
+	pr    pbfReader
+	layer LayerReader
+}
+
+// NewTileReader returns a reader of the encoded tile
+func NewTileReader(tile []byte) *TileReader {
+	return &TileReader{pr: pbfReader{data: tile}}
+}
+
+// NextLayer returns the next layer, or io.EOF when there are no more.
+// ErrInvalidTile is returned for a malformed tile.
+func (r *TileReader) NextLayer() (*LayerReader, error) {
+	for {
+		field, wire, ok := r.pr.next()
+		if !ok {
+			break
+		}
+		if field != 3 || wire != pbfBytes {
+			r.pr.skip(wire)
+			continue
+		}
+		msg := r.pr.bytes()
+		if r.pr.err != nil {
+			break
+		}
+		if err := r.layer.reset(msg); err != nil {
+			return nil, err
+		}
+		return &r.layer, nil
+	}
+	if r.pr.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTile, r.pr.err)
+	}
+	return nil, io.EOF
+}
+
+// LayerReader reads the features of a layer of an encoded tile
+type LayerReader struct {
+	name    string
+	version uint32
+	extent  uint32
+	keys    [][]byte
+	values  [][]byte
+	pr      pbfReader
+	feature FeatureReader
+}
+
+// reset starts reading a layer message, with the fields other than the
+// features read first, as they may follow the features.
+func (l *LayerReader) reset(msg []byte) error {
+	l.name, l.version, l.extent = "", 1, 4096
+	l.keys, l.values = l.keys[:0], l.values[:0]
+	l.pr = pbfReader{data: msg}
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfBytes:
+			l.name = string(pr.bytes())
+		case field == 3 && wire == pbfBytes:
+			l.keys = append(l.keys, pr.bytes())
+		case field == 4 && wire == pbfBytes:
+			l.values = append(l.values, pr.bytes())
+		case field == 5 && wire == pbfVarint:
+			l.extent = uint32(pr.uvarint())
+		case field == 15 && wire == pbfVarint:
+			l.version = uint32(pr.uvarint())
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	return nil
+}
+
+// Name returns the name of the layer
+func (l *LayerReader) Name() string {
+	return l.name
+}
+
+// Version returns the spec version of the layer, which is 1 when it is
+// not set
+func (l *LayerReader) Version() uint32 {
+	return l.version
+}
+
+// Extent returns the extent of the layer, which is 4096 when it is not
+// set
+func (l *LayerReader) Extent() uint32 {
+	return l.extent
+}
+
+// NextFeature returns the next feature of the layer, or io.EOF when there
+// are no more. ErrInvalidTile is returned for a malformed feature.
+func (l *LayerReader) NextFeature() (*FeatureReader, error) {
+	for {
+		field, wire, ok := l.pr.next()
+		if !ok {
+			break
+		}
+		if field != 2 || wire != pbfBytes {
+			l.pr.skip(wire)
+			continue
+		}
+		msg := l.pr.bytes()
+		if l.pr.err != nil {
+			break
+		}
+		if err := l.feature.reset(l, msg); err != nil {
+			return nil, fmt.Errorf("layer %q: %w", l.name, err)
+		}
+		return &l.feature, nil
+	}
+	if l.pr.err != nil {
+		return nil, fmt.Errorf("layer %q: %w: %v", l.name, ErrInvalidTile,
+			l.pr.err)
+	}
+	return nil, io.EOF
+}
+
+// FeatureReader reads a feature of a layer of an encoded tile
+type FeatureReader struct {
+	layer    *LayerReader
+	id       uint64
+	hasID    bool
+	geomType GeometryType
+	tags     []uint64
+	cmds     []uint64
+	geometry GeometryReader
+}
+
+// reset reads a feature message, keeping the memory of the last one
+func (f *FeatureReader) reset(l *LayerReader, msg []byte) error {
+	f.layer, f.id, f.hasID, f.geomType = l, 0, false, Unknown
+	f.tags, f.cmds = f.tags[:0], f.cmds[:0]
+	pr := pbfReader{data: msg}
+	for {
+		field, wire, ok := pr.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == pbfVarint:
+			f.id, f.hasID = pr.uvarint(), true
+		case field == 2:
+			f.tags = pr.appendPacked(f.tags, wire)
+		case field == 3 && wire == pbfVarint:
+			f.geomType = GeometryType(pr.uvarint())
+		case field == 4:
+			f.cmds = pr.appendPacked(f.cmds, wire)
+		default:
+			pr.skip(wire)
+		}
+	}
+	if pr.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTile, pr.err)
+	}
+	if len(f.tags)%2 != 0 {
+		return fmt.Errorf("%w: odd number of tag indexes", ErrInvalidTile)
+	}
+	return nil
+}
+
+// ID returns the id of the feature, and false when it has none
+func (f *FeatureReader) ID() (id uint64, ok bool) {
+	return f.id, f.hasID
+}
+
+// GeomType returns the geometry type of the feature
+func (f *FeatureReader) GeomType() GeometryType {
+	return f.geomType
+}
+
+// NumTags returns the number of tags of the feature
+func (f *FeatureReader) NumTags() int {
+	return len(f.tags) / 2
+}
+
+// Tag returns the key and value of the i'th tag of the feature.
+// ErrInvalidTile is returned for a tag that is out of the range of the
+// key or value table, or a value that is malformed.
+func (f *FeatureReader) Tag(i int) (key string, value interface{},
+	err error,
+) {
+	k, v := f.tags[i*2], f.tags[i*2+1]
+	if k >= uint64(len(f.layer.keys)) || v >= uint64(len(f.layer.values)) {
+		return "", nil, fmt.Errorf("%w: tag index out of range",
+			ErrInvalidTile)
+	}
+	if value, err = decodeValue(f.layer.values[v]); err != nil {
+		return "", nil, err
+	}
+	return string(f.layer.keys[k]), value, nil
+}
+
+// Geometry returns a reader of the geometry commands of the feature
+func (f *FeatureReader) Geometry() *GeometryReader {
+	f.geometry = GeometryReader{cmds: f.cmds}
+	return &f.geometry
+}
+
+// GeometryReader reads the commands of an encoded geometry, with the
+// points in the coordinates of the layer extent
+type GeometryReader struct {
+	cmds  []uint64
+	op    Op
+	count int
+	x, y  int64
+}
+
+// Next returns the next command and its point, or io.EOF when there are no
+// more. A MoveTo or LineTo of more than one point is returned as one
+// command per point, and a ClosePath returns the point that it ends on,
+// which is the last point of the ring. ErrInvalidTile is returned for an
+// unknown command or one that is cut short.
+func (g *GeometryReader) Next() (op Op, x, y int64, err error) {
+	for g.count == 0 {
+		if len(g.cmds) == 0 {
+			return 0, 0, 0, io.EOF
+		}
+		g.op, g.count = Op(g.cmds[0]&7), int(g.cmds[0]>>3)
+		g.cmds = g.cmds[1:]
+		switch g.op {
+		case OpClosePath:
+			g.count = 1
+		case OpMoveTo, OpLineTo:
+			if g.count > len(g.cmds)/2 {
+				return 0, 0, 0, fmt.Errorf("%w: command is cut short",
+					ErrInvalidTile)
+			}
+		default:
+			return 0, 0, 0, fmt.Errorf("%w: unknown command %d",
+				ErrInvalidTile, g.op)
+		}
+	}
+	g.count--
+	if g.op != OpClosePath {
+		g.x += unzigzag(g.cmds[0])
+		g.y += unzigzag(g.cmds[1])
+		g.cmds = g.cmds[2:]
+	}
+	return g.op, g.x, g.y, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTileReader(t *testing.T) {
+	r := NewTileReader(encodeDecodeTestTile(t))
+	var out []string
+	for {
+		l, err := r.NextLayer()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, fmt.Sprintf("%s v%d %d", l.Name(), l.Version(),
+			l.Extent()))
+		for {
+			f, err := l.NextFeature()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, _ := f.ID()
+			s := fmt.Sprintf("  %d %d", f.GeomType(), id)
+			for i := 0; i < f.NumTags(); i++ {
+				key, value, err := f.Tag(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s += fmt.Sprintf(" %s=%v", key, value)
+			}
+			g := f.Geometry()
+			for {
+				op, x, y, err := g.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				s += fmt.Sprintf(" %d:%d,%d", op, x, y)
+			}
+			out = append(out, s)
+		}
+	}
+	exp := strings.Join([]string{
+		"shapes v2 1000",
+		"  1 7 name=points rank=-2 1:20,40 1:-5,1005",
+		"  2 0 name=line width=1.5 1:19,19 2:390,39 2:585,216",
+		"  3 0 open=true size=2.5 count=3 1:19,19 2:195,19 2:195,195 " +
+			"7:195,195",
+		"legacy v1 4096",
+		"  1 0 1:8,8",
+	}, "\n")
+	if got := strings.Join(out, "\n"); got != exp {
+		t.Fatalf("expected\n%s\ngot\n%s", exp, got)
+	}
+}
+
+func TestTileReaderInvalid(t *testing.T) {
+	r := NewTileReader(encodeTestLayer(10, 1, 'a',
+		18, 9, 18, 2, 0, 0, 34, 3, 17, 2, 2, 120, 2))
+	l, err := r.NextLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := l.NextFeature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.Tag(0); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+	if _, _, _, err := f.Geometry().Next(); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+	if _, err := l.NextFeature(); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	r = NewTileReader([]byte{26, 5, 10})
+	if _, err := r.NextLayer(); !errors.Is(err, ErrInvalidTile) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTile, err)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidQuadKey is returned for a quadkey with digits other than
+	// 0-3.
+	ErrInvalidQuadKey = errors.New("invalid quadkey")
+	// ErrInvalidTilePath is returned for a path that does not end with a
+	// z/x/y tile that exists.
+	ErrInvalidTilePath = errors.New("invalid tile path")
+)
+
+// FlipY converts a tile Y coordinate between the XYZ scheme, where y=0 is
+// the northernmost row, and the TMS scheme, where y=0 is the southernmost
+// row. The conversion is its own inverse.
+func FlipY(tileY, tileZ int) int {
+	return (1 << uint(tileZ)) - 1 - tileY
+}
+
+// LatLonXYTMS is LatLonXY for a map tile addressed in the TMS scheme.
+func LatLonXYTMS(lat, lon float64, tileX, tileY, tileZ int) (x, y float64) {
+	return LatLonXY(lat, lon, tileX, FlipY(tileY, tileZ), tileZ)
+}
+
+// TileBoundsTMS is TileBounds for a map tile addressed in the TMS scheme.
+func TileBoundsTMS(tileX, tileY, tileZ int,
+) (minLat, minLon, maxLat, maxLon float64) {
+	return TileBounds(tileX, FlipY(tileY, tileZ), tileZ)
+}
+
+// QuadKey returns the Bing Maps quadkey for a map tile.
+func QuadKey(tileX, tileY, tileZ int) string {
+	qk := make([]byte, tileZ)
+	for i := tileZ; i > 0; i-- {
+		digit := byte('0')
+		mask := 1 << uint(i-1)
+		if tileX&mask != 0 {
+			digit++
+		}
+		if tileY&mask != 0 {
+			digit += 2
+		}
+		qk[tileZ-i] = digit
+	}
+	return string(qk)
+}
+
+// QuadKeyTile returns the map tile for a Bing Maps quadkey.
+func QuadKeyTile(qk string) (tileX, tileY, tileZ int, err error) {
+	tileZ = len(qk)
+	for i := tileZ; i > 0; i-- {
+		mask := 1 << uint(i-1)
+		switch qk[tileZ-i] {
+		case '0':
+		case '1':
+			tileX |= mask
+		case '2':
+			tileY |= mask
+		case '3':
+			tileX |= mask
+			tileY |= mask
+		default:
+			return 0, 0, 0, fmt.Errorf("%w %q", ErrInvalidQuadKey, qk)
+		}
+	}
+	return tileX, tileY, tileZ, nil
+}
+
+// ParseTilePath returns the map tile at the end of a z/x/y path, such as
+// "/tiles/14/2620/6331.mvt". Any extension on the y coordinate is ignored.
+func ParseTilePath(tilePath string) (tileZ, tileX, tileY int, err error) {
+	parts := strings.Split(strings.Trim(tilePath, "/"), "/")
+	if len(parts) < 3 {
+		return 0, 0, 0, fmt.Errorf("%w %q", ErrInvalidTilePath, tilePath)
+	}
+	parts = parts[len(parts)-3:]
+	parts[2] = strings.TrimSuffix(parts[2], path.Ext(parts[2]))
+	var zxy [3]int
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 8)
+		if i > 0 {
+			n, err = strconv.ParseUint(part, 10, 32)
+		}
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("%w %q", ErrInvalidTilePath, tilePath)
+		}
+		zxy[i] = int(n)
+	}
+	tileZ, tileX, tileY = zxy[0], zxy[1], zxy[2]
+	if tileZ > 30 || tileX >= 1<<uint(tileZ) || tileY >= 1<<uint(tileZ) {
+		return 0, 0, 0, fmt.Errorf("%w %q", ErrInvalidTilePath, tilePath)
+	}
+	return tileZ, tileX, tileY, nil
+}
+
+// FormatTilePath returns the z/x/y path for a map tile, followed by the
+// extension, such as ".mvt", if it is not empty.
+func FormatTilePath(tileZ, tileX, tileY int, ext string) string {
+	return fmt.Sprintf("%d/%d/%d%s", tileZ, tileX, tileY, ext)
+}
+
+// LatLonToTile returns the map tile at the zoom that contains a lat/lon,
+// along with the point x/y of the lat/lon within that tile.
+// The tile is 512x512.
+func LatLonToTile(lat, lon float64, tileZ int,
+) (tileX, tileY int, x, y float64) {
+	px, py := LatLonToPixel(lat, lon, tileZ)
+	n := 1<<uint(tileZ) - 1
+	tileX = int(clamp(math.Floor(px/gTileSize), 0, float64(n)))
+	tileY = int(clamp(math.Floor(py/gTileSize), 0, float64(n)))
+	return tileX, tileY, px - float64(tileX*gTileSize),
+		py - float64(tileY*gTileSize)
+}
+
+// LatLonToPixel converts a lat/lon to a pixel on the whole map at the
+// zoom, which is 512<<zoom pixels wide.
+func LatLonToPixel(lat, lon float64, levelOfDetail int,
+) (pixelX, pixelY float64) {
+	lat = clamp(lat, gMinLat, gMaxLat)
+	lon = clamp(lon, gMinLon, gMaxLon)
+	lx := (lon + 180) / 360
+	sinLat := math.Sin(lat * math.Pi / 180)
+	ly := 0.5 - math.Log((1+sinLat)/(1-sinLat))/(4*math.Pi)
+	mapSize := float64(gMapSize(levelOfDetail))
+	return clamp(lx*mapSize, 0, mapSize), clamp(ly*mapSize, 0, mapSize)
+}
+
+// PixelToLatLon converts a pixel on the whole map at the zoom, which is
+// 512<<zoom pixels wide, to a lat/lon. It is the inverse of LatLonToPixel.
+func PixelToLatLon(pixelX, pixelY float64, levelOfDetail int,
+) (lat, lon float64) {
+	mapSize := float64(gMapSize(levelOfDetail))
+	x := clamp(pixelX, 0, mapSize)/mapSize - 0.5
+	y := 0.5 - clamp(pixelY, 0, mapSize)/mapSize
+	lat = 90 - 360*math.Atan(math.Exp(-y*2*math.Pi))/math.Pi
+	lon = 360 * x
+	return lat, lon
+}
+
+// TileID is the z/x/y address of a map tile in the XYZ scheme
+type TileID struct {
+	Z, X, Y int
+}
+
+// TilesCoveringBounds returns the map tiles at the zoom that cover the
+// lat/lon bounds, row by row from the northwest. Bounds with a min lon past
+// their max lon cross the antimeridian, and cover the tiles east of the
+// min lon and west of the max lon. Bounds with a min lat past their max
+// lat cover no tiles.
+func TilesCoveringBounds(minLat, minLon, maxLat, maxLon float64, tileZ int,
+) []TileID {
+	if minLat > maxLat {
+		return nil
+	}
+	minX, minY, _, _ := LatLonToTile(maxLat, minLon, tileZ)
+	maxX, maxY, x, y := LatLonToTile(minLat, maxLon, tileZ)
+	// a bound on a tile edge does not cover the next tile over
+	if y == 0 && maxY > minY {
+		maxY--
+	}
+	var cols []int
+	if minLon > maxLon {
+		// across the antimeridian
+		if x == 0 {
+			maxX--
+		}
+		for tx := minX; tx < 1<<uint(tileZ); tx++ {
+			cols = append(cols, tx)
+		}
+		for tx := 0; tx <= maxX && tx < minX; tx++ {
+			cols = append(cols, tx)
+		}
+	} else {
+		if x == 0 && maxX > minX {
+			maxX--
+		}
+		for tx := minX; tx <= maxX; tx++ {
+			cols = append(cols, tx)
+		}
+	}
+	tiles := make([]TileID, 0, len(cols)*(maxY-minY+1))
+	for ty := minY; ty <= maxY; ty++ {
+		for _, tx := range cols {
+			tiles = append(tiles, TileID{tileZ, tx, ty})
+		}
+	}
+	return tiles
+}
+
+// TilesCovering returns the map tiles at the zoom that the lat/lon geometry
+// is in, row by row from the northwest, such as for the tiles of a
+// pyramid. These are the tiles of its bounds, see TilesCoveringBounds,
+// that it reaches into rather than only passes by, such as those along a
+// diagonal line or inside of a polygon.
+func TilesCovering(g Geometry, tileZ int) []TileID {
+	if len(g.Paths) == 0 {
+		return nil
+	}
+	toPixel := func(lat, lon float64) (x, y float64) {
+		return LatLonToPixel(lat, lon, tileZ)
+	}
+	f := &Feature{geomType: g.Type, geom: g.pixels(toPixel, 0, 0)}
+	minLat, minLon, maxLat, maxLon := g.Bounds()
+	var tiles []TileID
+	for _, id := range TilesCoveringBounds(minLat, minLon, maxLat, maxLon,
+		tileZ) {
+		minX, minY := float64(id.X*gTileSize), float64(id.Y*gTileSize)
+		r := clipRect{minX, minY, minX + gTileSize, minY + gTileSize}
+		if len(f.clip(r).ops) > 0 {
+			tiles = append(tiles, id)
+		}
+	}
+	return tiles
+}
+
+// String returns the z/x/y path of the tile
+func (id TileID) String() string {
+	return FormatTilePath(id.Z, id.X, id.Y, "")
+}
+
+// Parent returns the tile one zoom level up that contains the tile. The
+// parent of a zoom 0 tile is itself.
+func (id TileID) Parent() TileID {
+	if id.Z == 0 {
+		return id
+	}
+	return TileID{id.Z - 1, id.X >> 1, id.Y >> 1}
+}
+
+// Children returns the four tiles one zoom level down that the tile
+// contains, in northwest, northeast, southwest, southeast order.
+func (id TileID) Children() [4]TileID {
+	z, x, y := id.Z+1, id.X<<1, id.Y<<1
+	return [4]TileID{{z, x, y}, {z, x + 1, y}, {z, x, y + 1}, {z, x + 1, y + 1}}
+}
+
+// ZoomTo returns the tiles at the zoom that cover the same area as the
+// tile. That is the single containing tile when zooming out, and all of
+// the descendant tiles, row by row, when zooming in.
+func (id TileID) ZoomTo(tileZ int) []TileID {
+	if tileZ <= id.Z {
+		shift := uint(id.Z - tileZ)
+		return []TileID{{tileZ, id.X >> shift, id.Y >> shift}}
+	}
+	shift := uint(tileZ - id.Z)
+	n := 1 << shift
+	tiles := make([]TileID, 0, n*n)
+	for y := id.Y << shift; y < (id.Y+1)<<shift; y++ {
+		for x := id.X << shift; x < (id.X+1)<<shift; x++ {
+			tiles = append(tiles, TileID{tileZ, x, y})
+		}
+	}
+	return tiles
+}
+
+// earthRadius is the WGS84 semi-major axis, in meters, used by the Web
+// Mercator (EPSG:3857) projection.
+const earthRadius = 6378137.0
+
+// originShift is the extent of the Web Mercator projection, in meters, from
+// the origin to each edge of the map.
+const originShift = math.Pi * earthRadius
+
+// BoundsMercator returns the bounds of the tile in Web Mercator
+// (EPSG:3857) meters.
+func (id TileID) BoundsMercator() (minX, minY, maxX, maxY float64) {
+	size := 2 * originShift / float64(uint64(1)<<uint(id.Z))
+	minX = float64(id.X)*size - originShift
+	maxY = originShift - float64(id.Y)*size
+	return minX, maxY - size, minX + size, maxY
+}
+
+// MetersPerPixel returns the ground resolution of a tile pixel at the zoom
+// and latitude. The tile is 512x512.
+func MetersPerPixel(tileZ int, lat float64) float64 {
+	mapSize := float64(gMapSize(tileZ))
+	return math.Cos(lat*math.Pi/180) * 2 * originShift / mapSize
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestTMS(t *testing.T) {
+	if FlipY(0, 0) != 0 || FlipY(0, 2) != 3 || FlipY(FlipY(5, 4), 4) != 5 {
+		t.Fatal("bad flip")
+	}
+	// TMS 2/3/2 is XYZ 2/3/1
+	minLat, minLon, maxLat, maxLon := TileBoundsTMS(3, 2, 2)
+	s := fmt.Sprintf("%.4f %.4f %.4f %.4f", minLat, minLon, maxLat, maxLon)
+	if s != "0.0000 90.0000 66.5133 180.0000" {
+		t.Fatalf("unexpected bounds %s", s)
+	}
+	// TMS 1/0/0 is XYZ 1/0/1
+	minLat, minLon, maxLat, maxLon = TileBoundsTMS(0, 0, 1)
+	s = fmt.Sprintf("%.4f %.4f %.4f %.4f", minLat, minLon, maxLat, maxLon)
+	if s != "-85.0511 -180.0000 0.0000 0.0000" {
+		t.Fatalf("unexpected bounds %s", s)
+	}
+	minX, minY, maxX, maxY := TileID{2, 3, 1}.BoundsMercator()
+	x1, y1 := lonLatMercator(90, 0)
+	x2, y2 := lonLatMercator(180, 66.51326044311186)
+	if math.Abs(minX-x1) > 1e-6 || math.Abs(minY-y1) > 1e-6 ||
+		math.Abs(maxX-x2) > 1e-6 || math.Abs(maxY-y2) > 1e-6 {
+		t.Fatal("expected TMS bounds to match the mercator bounds")
+	}
+	for _, p := range [][2]float64{{33.41, -111.93}, {-45, 45}, {60, 170}} {
+		tx, ty, x1, y1 := LatLonToTile(p[0], p[1], 5)
+		x2, y2 := LatLonXYTMS(p[0], p[1], tx, FlipY(ty, 5), 5)
+		if math.Abs(x1-x2) > 1e-9 || math.Abs(y1-y2) > 1e-9 {
+			t.Fatalf("expected %f %f, got %f %f", x1, y1, x2, y2)
+		}
+	}
+	x, y := LatLonXYTMS(0, 0, 1, 0, 1)
+	if x != 0 || y != 0 {
+		t.Fatalf("unexpected point %f %f", x, y)
+	}
+	// the TMS functions are the XYZ functions of the flipped tile
+	for _, id := range []TileID{{0, 0, 0}, {2, 3, 1}, {15, 6195, 13154}} {
+		ty := FlipY(id.Y, id.Z)
+		a1, a2, a3, a4 := TileBounds(id.X, id.Y, id.Z)
+		b1, b2, b3, b4 := TileBoundsTMS(id.X, ty, id.Z)
+		if a1 != b1 || a2 != b2 || a3 != b3 || a4 != b4 {
+			t.Fatalf("%s: expected TMS bounds to match XYZ bounds", id)
+		}
+		lat, lon := (a1+a3)/2, (a2+a4)/2
+		x1, y1 := LatLonXY(lat, lon, id.X, id.Y, id.Z)
+		x2, y2 := LatLonXYTMS(lat, lon, id.X, ty, id.Z)
+		tx, tyy, x3, y3 := LatLonToTile(lat, lon, id.Z)
+		if x1 != x2 || y1 != y2 || tx != id.X || tyy != id.Y ||
+			math.Abs(x1-x3) > 1e-6 || math.Abs(y1-y3) > 1e-6 {
+			t.Fatalf("%s: expected %f %f, got %f %f and %f %f", id, x3, y3,
+				x1, y1, x2, y2)
+		}
+	}
+}
+
+func TestQuadKey(t *testing.T) {
+	if qk := QuadKey(3, 5, 3); qk != "213" {
+		t.Fatalf("expected 213, got %s", qk)
+	}
+	if QuadKey(0, 0, 0) != "" {
+		t.Fatal("expected empty quadkey at zoom 0")
+	}
+	x, y, z, err := QuadKeyTile("213")
+	if err != nil || x != 3 || y != 5 || z != 3 {
+		t.Fatalf("unexpected tile %d/%d/%d %v", z, x, y, err)
+	}
+	if _, _, _, err := QuadKeyTile("124"); !errors.Is(err, ErrInvalidQuadKey) {
+		t.Fatalf("expected %v, got %v", ErrInvalidQuadKey, err)
+	}
+}
+
+func TestTilePath(t *testing.T) {
+	for _, path := range []string{
+		"14/2620/6331.mvt", "/tiles/14/2620/6331", "14/2620/6331.pbf",
+	} {
+		z, x, y, err := ParseTilePath(path)
+		if err != nil || z != 14 || x != 2620 || y != 6331 {
+			t.Fatalf("%s: unexpected tile %d/%d/%d %v", path, z, x, y, err)
+		}
+	}
+	for _, path := range []string{
+		"", "2620/6331", "a/1/1", "1/2/0", "1/-1/0", "31/0/0",
+	} {
+		if _, _, _, err := ParseTilePath(path); !errors.Is(err, ErrInvalidTilePath) {
+			t.Fatalf("%s: expected %v, got %v", path, ErrInvalidTilePath, err)
+		}
+	}
+	if p := FormatTilePath(14, 2620, 6331, ".mvt"); p != "14/2620/6331.mvt" {
+		t.Fatalf("unexpected path %s", p)
+	}
+}
+
+func TestLatLonToTile(t *testing.T) {
+	tx, ty, x, y := LatLonToTile(0, 0, 1)
+	if tx != 1 || ty != 1 || x != 0 || y != 0 {
+		t.Fatalf("unexpected tile %d/%d %f %f", tx, ty, x, y)
+	}
+	tx, ty, x, y = LatLonToTile(90, 180, 3)
+	if tx != 7 || ty != 0 || x != 512 || y != 0 {
+		t.Fatalf("unexpected tile %d/%d %f %f", tx, ty, x, y)
+	}
+	tx, ty, x, y = LatLonToTile(33.4131, -111.9396, 15)
+	if tx != 6195 || ty != 13154 || x < 0 || x >= 512 || y < 0 || y >= 512 {
+		t.Fatalf("unexpected tile %d/%d %f %f", tx, ty, x, y)
+	}
+}
+
+func TestTilesCoveringBounds(t *testing.T) {
+	tiles := TilesCoveringBounds(-90, -180, 90, 180, 1)
+	if fmt.Sprint(tiles) != "[1/0/0 1/1/0 1/0/1 1/1/1]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	tiles = TilesCoveringBounds(0, -180, 85, 0, 1)
+	if fmt.Sprint(tiles) != "[1/0/0]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	tiles = TilesCoveringBounds(33.4080, -111.9350, 33.4090, -111.9340, 15)
+	if fmt.Sprint(tiles) != "[15/6195/13154]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	// across the antimeridian
+	tiles = TilesCoveringBounds(-10, 170, 10, -170, 2)
+	if fmt.Sprint(tiles) != "[2/3/1 2/0/1 2/3/2 2/0/2]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	tiles = TilesCoveringBounds(0, 90, 10, -180, 2)
+	if fmt.Sprint(tiles) != "[2/3/1]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	if tiles = TilesCoveringBounds(10, 0, 0, 10, 2); len(tiles) != 0 {
+		t.Fatalf("expected no tiles, got %v", tiles)
+	}
+}
+
+func TestTilesCovering(t *testing.T) {
+	// the triangle leaves out the tiles of its bounds past its diagonal
+	tri := Geometry{Type: Polygon, Paths: [][][2]float64{
+		{{-170, 80}, {170, 80}, {-170, -80}, {-170, 80}},
+	}}
+	tiles := TilesCovering(tri, 2)
+	if fmt.Sprint(tiles) != "[2/0/0 2/1/0 2/2/0 2/3/0 2/0/1 2/1/1 2/2/1 "+
+		"2/3/1 2/0/2 2/1/2 2/0/3]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	points := Geometry{Type: Point,
+		Paths: [][][2]float64{{{-100, 40}}, {{100, -40}}}}
+	if tiles = TilesCovering(points, 2); fmt.Sprint(tiles) !=
+		"[2/0/1 2/3/2]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	if tiles = TilesCovering(Geometry{}, 2); len(tiles) != 0 {
+		t.Fatalf("expected no tiles, got %v", tiles)
+	}
+}
+
+func TestTileIDNavigation(t *testing.T) {
+	id := TileID{3, 5, 2}
+	if id.String() != "3/5/2" {
+		t.Fatalf("unexpected string %s", id)
+	}
+	if p := id.Parent(); p != (TileID{2, 2, 1}) {
+		t.Fatalf("unexpected parent %v", p)
+	}
+	if p := (TileID{}).Parent(); p != (TileID{}) {
+		t.Fatalf("unexpected parent %v", p)
+	}
+	for _, child := range id.Children() {
+		if child.Parent() != id {
+			t.Fatalf("child %v is not in %v", child, id)
+		}
+	}
+	if tiles := id.ZoomTo(1); fmt.Sprint(tiles) != "[1/1/0]" {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	if tiles := id.ZoomTo(3); len(tiles) != 1 || tiles[0] != id {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+	tiles := id.ZoomTo(5)
+	if len(tiles) != 16 || tiles[0] != (TileID{5, 20, 8}) ||
+		tiles[15] != (TileID{5, 23, 11}) {
+		t.Fatalf("unexpected tiles %v", tiles)
+	}
+}
+
+func TestMercator(t *testing.T) {
+	minX, minY, maxX, maxY := TileID{0, 0, 0}.BoundsMercator()
+	if minX != -originShift || minY != -originShift ||
+		maxX != originShift || maxY != originShift {
+		t.Fatalf("unexpected bounds %f %f %f %f", minX, minY, maxX, maxY)
+	}
+	minX, minY, maxX, maxY = TileID{1, 1, 0}.BoundsMercator()
+	if minX != 0 || minY != 0 || maxX != originShift || maxY != originShift {
+		t.Fatalf("unexpected bounds %f %f %f %f", minX, minY, maxX, maxY)
+	}
+	if r := MetersPerPixel(0, 0); math.Abs(r-78271.517) > 0.001 {
+		t.Fatalf("unexpected resolution %f", r)
+	}
+	if r := MetersPerPixel(1, 60); math.Abs(r-19567.879) > 0.001 {
+		t.Fatalf("unexpected resolution %f", r)
+	}
+}
+
+func TestPixelLatLon(t *testing.T) {
+	for _, c := range [][2]float64{{0, 0}, {33.4131, -111.9396}, {-60, 170}} {
+		px, py := LatLonToPixel(c[0], c[1], 12)
+		lat, lon := PixelToLatLon(px, py, 12)
+		if math.Abs(lat-c[0]) > 1e-9 || math.Abs(lon-c[1]) > 1e-9 {
+			t.Fatalf("expected %v, got %f %f", c, lat, lon)
+		}
+	}
+	if px, py := LatLonToPixel(0, 0, 1); px != 512 || py != 512 {
+		t.Fatalf("unexpected pixel %f %f", px, py)
+	}
+	lat, lon := PixelToLatLon(0, 0, 0)
+	if math.Abs(lat-gMaxLat) > 1e-6 || lon != gMinLon {
+		t.Fatalf("unexpected lat/lon %f %f", lat, lon)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrInvalidTileMatrixSet is returned for a tile matrix set definition that
+// cannot be used.
+var ErrInvalidTileMatrixSet = errors.New("invalid tile matrix set")
+
+// TileMatrixSet is a tile grid from an OGC Two Dimensional Tile Matrix Set
+// (version 2.0) JSON definition.
+type TileMatrixSet struct {
+	ID           string       `json:"id"`
+	Title        string       `json:"title,omitempty"`
+	CRS          interface{}  `json:"crs"`
+	OrderedAxes  []string     `json:"orderedAxes,omitempty"`
+	TileMatrices []TileMatrix `json:"tileMatrices"`
+}
+
+// TileMatrix is a single zoom level of a TileMatrixSet. Coordinates are in
+// the units of the CRS, with x as the easting and y as the northing.
+type TileMatrix struct {
+	ID               string     `json:"id"`
+	ScaleDenominator float64    `json:"scaleDenominator"`
+	CellSize         float64    `json:"cellSize"`
+	CornerOfOrigin   string     `json:"cornerOfOrigin,omitempty"`
+	PointOfOrigin    [2]float64 `json:"pointOfOrigin"`
+	TileWidth        int        `json:"tileWidth"`
+	TileHeight       int        `json:"tileHeight"`
+	MatrixWidth      int        `json:"matrixWidth"`
+	MatrixHeight     int        `json:"matrixHeight"`
+
+	// northFirst is set when the CRS axis order puts the northing first
+	northFirst bool
+}
+
+// ParseTileMatrixSet parses an OGC Two Dimensional Tile Matrix Set JSON
+// definition.
+func ParseTileMatrixSet(data []byte) (*TileMatrixSet, error) {
+	var tms TileMatrixSet
+	if err := json.Unmarshal(data, &tms); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTileMatrixSet, err)
+	}
+	if len(tms.TileMatrices) == 0 {
+		return nil, fmt.Errorf("%w: no tile matrices", ErrInvalidTileMatrixSet)
+	}
+	northFirst := len(tms.OrderedAxes) > 0 && isNorthAxis(tms.OrderedAxes[0])
+	for i := range tms.TileMatrices {
+		m := &tms.TileMatrices[i]
+		if m.CellSize <= 0 || m.TileWidth <= 0 || m.TileHeight <= 0 {
+			return nil, fmt.Errorf("%w: tile matrix %q", ErrInvalidTileMatrixSet,
+				m.ID)
+		}
+		m.northFirst = northFirst
+	}
+	return &tms, nil
+}
+
+// isNorthAxis returns true for an axis abbreviation that is a northing or
+// latitude.
+func isNorthAxis(axis string) bool {
+	switch strings.ToLower(axis) {
+	case "n", "y", "lat", "latitude", "north", "northing":
+		return true
+	}
+	return false
+}
+
+// TileMatrix returns the tile matrix with the id.
+func (tms *TileMatrixSet) TileMatrix(id string) (TileMatrix, bool) {
+	for _, m := range tms.TileMatrices {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return TileMatrix{}, false
+}
+
+// origin returns the point of origin as an x/y.
+func (m TileMatrix) origin() (x, y float64) {
+	if m.northFirst {
+		return m.PointOfOrigin[1], m.PointOfOrigin[0]
+	}
+	return m.PointOfOrigin[0], m.PointOfOrigin[1]
+}
+
+// bottomLeft returns true when rows count up from the bottom of the grid.
+func (m TileMatrix) bottomLeft() bool {
+	return strings.EqualFold(m.CornerOfOrigin, "bottomLeft")
+}
+
+// tileSize returns the width and height of a tile in CRS units.
+func (m TileMatrix) tileSize() (width, height float64) {
+	return m.CellSize * float64(m.TileWidth), m.CellSize * float64(m.TileHeight)
+}
+
+// TileBounds returns the bounds of the tile at the column and row.
+func (m TileMatrix) TileBounds(col, row int) (minX, minY, maxX, maxY float64) {
+	ox, oy := m.origin()
+	w, h := m.tileSize()
+	minX = ox + float64(col)*w
+	if m.bottomLeft() {
+		minY = oy + float64(row)*h
+	} else {
+		minY = oy - float64(row+1)*h
+	}
+	return minX, minY, minX + w, minY + h
+}
+
+// TileAt returns the column and row of the tile that contains the x/y.
+func (m TileMatrix) TileAt(x, y float64) (col, row int) {
+	ox, oy := m.origin()
+	w, h := m.tileSize()
+	col = int(math.Floor((x - ox) / w))
+	if m.bottomLeft() {
+		row = int(math.Floor((y - oy) / h))
+	} else {
+		row = int(math.Floor((oy - y) / h))
+	}
+	return col, row
+}
+
+// XY converts an x/y in the CRS to a point x/y for the tile at the column
+// and row. The tile is 512x512.
+func (m TileMatrix) XY(x, y float64, col, row int) (px, py float64) {
+	minX, minY, maxX, maxY := m.TileBounds(col, row)
+	px = (x - minX) / (maxX - minX) * gTileSize
+	py = (maxY - y) / (maxY - minY) * gTileSize
+	return px, py
+}
+
+// Projection converts between lat/lons and the x/ys of the CRS of a tile
+// matrix set, such as with a binding to PROJ.
+type Projection interface {
+	// Project converts a lat/lon to an x/y of the CRS
+	Project(lat, lon float64) (x, y float64)
+	// Unproject converts an x/y of the CRS to a lat/lon
+	Unproject(x, y float64) (lat, lon float64)
+}
+
+// tileMatrix is the tile matrix of a tile, see Tile.SetTileMatrix
+type tileMatrix struct {
+	m    TileMatrix
+	proj Projection
+}
+
+// SetTileMatrix sets the tile to be the tile of the tile matrix at the
+// column and row of the X and Y of its id, see SetTileID, in place of its
+// tiling scheme. The lat/lon geometries that are added with AddGeometry,
+// AddGeoFeature, AddFrom, and AddCircleLatLon, along with their labels and
+// centroids, are placed in the tile with the projection, which also gives
+// the points of ForEachPoint and ForEachRing in LonLatSpace. The units of
+// the CRS are taken to be meters for the vertices of circles. Overzoom
+// gives its child tiles the matrix with the cell size divided by the scale,
+// as in a quad tree. A nil projection clears the matrix.
+func (t *Tile) SetTileMatrix(m TileMatrix, proj Projection) {
+	if proj == nil {
+		t.matrix = nil
+		return
+	}
+	t.matrix = &tileMatrix{m: m, proj: proj}
+}
+
+// toPixel converts a lat/lon to a point x/y of the tile. The tile is
+// 512x512.
+func (tm *tileMatrix) toPixel(lat, lon float64, id TileID) (x, y float64) {
+	x, y = tm.proj.Project(lat, lon)
+	return tm.m.XY(x, y, id.X, id.Y)
+}
+
+// fromPixel converts a point x/y of the tile to a lat/lon
+func (tm *tileMatrix) fromPixel(x, y float64, id TileID) (lat, lon float64) {
+	minX, minY, maxX, maxY := tm.m.TileBounds(id.X, id.Y)
+	return tm.proj.Unproject(minX+x/gTileSize*(maxX-minX),
+		maxY-y/gTileSize*(maxY-minY))
+}
+
+// child returns the tile matrix of the tiles that are the scale times
+// smaller.
+func (tm *tileMatrix) child(scale int) *tileMatrix {
+	m := tm.m
+	m.ID = ""
+	m.CellSize /= float64(scale)
+	m.ScaleDenominator /= float64(scale)
+	m.MatrixWidth *= scale
+	m.MatrixHeight *= scale
+	return &tileMatrix{m: m, proj: tm.proj}
+}
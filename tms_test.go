@@ -0,0 +1,132 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+const laeaQuad = `{
+	"id": "EuropeanETRS89_LAEAQuad",
+	"crs": "http://www.opengis.net/def/crs/EPSG/0/3035",
+	"orderedAxes": ["Y", "X"],
+	"tileMatrices": [{
+		"id": "0",
+		"scaleDenominator": 62779017.857142866,
+		"cellSize": 17578.125,
+		"cornerOfOrigin": "topLeft",
+		"pointOfOrigin": [5500000.0, 2000000.0],
+		"tileWidth": 256,
+		"tileHeight": 256,
+		"matrixWidth": 1,
+		"matrixHeight": 1
+	}, {
+		"id": "1",
+		"scaleDenominator": 31389508.928571433,
+		"cellSize": 8789.0625,
+		"cornerOfOrigin": "topLeft",
+		"pointOfOrigin": [5500000.0, 2000000.0],
+		"tileWidth": 256,
+		"tileHeight": 256,
+		"matrixWidth": 2,
+		"matrixHeight": 2
+	}]
+}`
+
+func TestTileMatrixSet(t *testing.T) {
+	tms, err := ParseTileMatrixSet([]byte(laeaQuad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := tms.TileMatrix("1")
+	if !ok {
+		t.Fatal("expected tile matrix 1")
+	}
+	minX, minY, maxX, maxY := m.TileBounds(1, 1)
+	if minX != 4250000 || minY != 1000000 || maxX != 6500000 || maxY != 3250000 {
+		t.Fatalf("unexpected bounds %f %f %f %f", minX, minY, maxX, maxY)
+	}
+	// Berlin, roughly
+	x, y := 4550000.0, 3270000.0
+	col, row := m.TileAt(x, y)
+	if col != 1 || row != 0 {
+		t.Fatalf("unexpected tile %d %d", col, row)
+	}
+	px, py := m.XY(x, y, col, row)
+	if math.Abs(px-68.2666) > 1e-3 || math.Abs(py-507.4488) > 1e-3 {
+		t.Fatalf("unexpected point %f %f", px, py)
+	}
+	if _, err := ParseTileMatrixSet([]byte(`{"tileMatrices":[]}`)); !errors.Is(err, ErrInvalidTileMatrixSet) {
+		t.Fatalf("expected %v, got %v", ErrInvalidTileMatrixSet, err)
+	}
+}
+
+// plateCarree is a projection of lat/lons as x/ys in degrees
+type plateCarree struct{}
+
+func (plateCarree) Project(lat, lon float64) (x, y float64) {
+	return lon, lat
+}
+
+func (plateCarree) Unproject(x, y float64) (lat, lon float64) {
+	return y, x
+}
+
+func TestTileMatrixTile(t *testing.T) {
+	// two by two tiles of 256 degrees, with rows from the bottom
+	m := TileMatrix{ID: "0", CellSize: 1, CornerOfOrigin: "bottomLeft",
+		TileWidth: 256, TileHeight: 256, MatrixWidth: 2, MatrixHeight: 2}
+	var tile Tile
+	tile.SetTileID(TileID{1, 1, 0})
+	tile.SetTileMatrix(m, plateCarree{})
+	l := tile.AddLayer("grid")
+	f := l.AddGeometry(Geometry{Type: Point,
+		Paths: [][][2]float64{{{300, 64}}}})
+	if f == nil {
+		t.Fatal("expected a feature")
+	}
+	if l.AddGeometry(Geometry{Type: Point,
+		Paths: [][][2]float64{{{100, 64}}}}) != nil {
+		t.Fatal("expected no feature for the tile at column 0")
+	}
+	points := func(f *Feature) string {
+		var pts []float64
+		f.ForEachPoint(CanvasSpace, func(x, y float64) bool {
+			pts = append(pts, x, y)
+			return true
+		})
+		f.ForEachPoint(LonLatSpace, func(lon, lat float64) bool {
+			pts = append(pts, lon, lat)
+			return true
+		})
+		return fmt.Sprint(pts)
+	}
+	if s := points(f); s != "[88 384 300 64]" {
+		t.Fatalf("unexpected points %s", s)
+	}
+	// the lower left child, at the row from the bottom
+	ct, err := tile.Overzoom(2, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	features := ct.Layers()[0].Features()
+	if len(features) != 1 {
+		t.Fatalf("expected 1 feature, got %d", len(features))
+	}
+	if s := points(features[0]); s != "[176 256 300 64]" {
+		t.Fatalf("unexpected points %s", s)
+	}
+	circle := l.AddCircleLatLon(64, 300, 10)
+	if circle == nil || len(circle.geom.ops) < 8 {
+		t.Fatal("expected a circle")
+	}
+	tile.SetTileMatrix(m, nil)
+	if tile.matrix != nil {
+		t.Fatal("expected the matrix to be cleared")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidTopoJSON is returned for a TopoJSON topology that is malformed
+// or of a type that is not supported.
+var ErrInvalidTopoJSON = errors.New("invalid topojson")
+
+// Topology is a decoded TopoJSON topology, whose objects are collections
+// of lat/lon features. The arcs of the topology are decoded once and
+// shared by the features that refer to them, so that shared boundaries
+// are drawn with the same points on both sides.
+type Topology struct {
+	objects map[string][]GeoFeature
+}
+
+// topoObject is a TopoJSON object or geometry
+type topoObject struct {
+	Type        string                 `json:"type"`
+	ID          interface{}            `json:"id"`
+	Properties  map[string]interface{} `json:"properties"`
+	Arcs        json.RawMessage        `json:"arcs"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Geometries  []*topoObject          `json:"geometries"`
+}
+
+// topoDecoder resolves the geometries of a topology
+type topoDecoder struct {
+	arcs      [][][2]float64
+	scale     [2]float64
+	translate [2]float64
+	quantized bool
+}
+
+// ParseTopoJSON decodes a TopoJSON topology, which may be quantized.
+func ParseTopoJSON(data []byte) (*Topology, error) {
+	var topo struct {
+		Type      string `json:"type"`
+		Transform *struct {
+			Scale     [2]float64 `json:"scale"`
+			Translate [2]float64 `json:"translate"`
+		} `json:"transform"`
+		Arcs    [][][]float64          `json:"arcs"`
+		Objects map[string]*topoObject `json:"objects"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&topo); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTopoJSON, err)
+	}
+	if topo.Type != "Topology" {
+		return nil, fmt.Errorf("%w: type %q is not Topology",
+			ErrInvalidTopoJSON, topo.Type)
+	}
+	d := &topoDecoder{scale: [2]float64{1, 1}}
+	if topo.Transform != nil {
+		d.quantized = true
+		d.scale = topo.Transform.Scale
+		d.translate = topo.Transform.Translate
+	}
+	// decode the arcs, whose quantized positions are deltas
+	d.arcs = make([][][2]float64, len(topo.Arcs))
+	for i, arc := range topo.Arcs {
+		var x, y float64
+		d.arcs[i] = make([][2]float64, len(arc))
+		for j, p := range arc {
+			if len(p) < 2 {
+				return nil, fmt.Errorf("%w: arc %d: position without a "+
+					"lon/lat", ErrInvalidTopoJSON, i)
+			}
+			if d.quantized {
+				x, y = x+p[0], y+p[1]
+				d.arcs[i][j] = d.position(x, y)
+			} else {
+				d.arcs[i][j] = [2]float64{p[0], p[1]}
+			}
+		}
+	}
+	t := &Topology{objects: make(map[string][]GeoFeature)}
+	for name, obj := range topo.Objects {
+		if obj == nil {
+			continue
+		}
+		var features []GeoFeature
+		if err := d.features(obj, &features); err != nil {
+			return nil, fmt.Errorf("%w: object %q: %v", ErrInvalidTopoJSON,
+				name, err)
+		}
+		t.objects[name] = features
+	}
+	return t, nil
+}
+
+// position returns the lon/lat of a quantized position
+func (d *topoDecoder) position(x, y float64) [2]float64 {
+	return [2]float64{x*d.scale[0] + d.translate[0],
+		y*d.scale[1] + d.translate[1]}
+}
+
+// features appends the features of the object. The geometries of a
+// collection are each a feature.
+func (d *topoDecoder) features(obj *topoObject, features *[]GeoFeature,
+) error {
+	if obj.Type == "GeometryCollection" {
+		for _, child := range obj.Geometries {
+			if child != nil {
+				if err := d.features(child, features); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	geom, err := d.geometry(obj)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]interface{}, len(obj.Properties))
+	for key, value := range obj.Properties {
+		tags[key] = jsonValue(value)
+	}
+	*features = append(*features, GeoFeature{geom, tags, jsonValue(obj.ID)})
+	return nil
+}
+
+// geometry resolves the arcs or positions of a geometry
+func (d *topoDecoder) geometry(obj *topoObject) (Geometry, error) {
+	var g Geometry
+	var err error
+	switch obj.Type {
+	case "", "null":
+		return g, nil
+	case "Point", "MultiPoint":
+		var points [][]float64
+		if obj.Type == "Point" {
+			points = make([][]float64, 1)
+			err = json.Unmarshal(obj.Coordinates, &points[0])
+		} else {
+			err = json.Unmarshal(obj.Coordinates, &points)
+		}
+		g.Type = Point
+		for i := 0; err == nil && i < len(points); i++ {
+			p := points[i]
+			if len(p) < 2 {
+				err = errors.New("position without a lon/lat")
+			} else if d.quantized {
+				g.Paths = append(g.Paths, [][2]float64{d.position(p[0], p[1])})
+			} else {
+				g.Paths = append(g.Paths, [][2]float64{{p[0], p[1]}})
+			}
+		}
+	case "LineString":
+		var arcs []int
+		if err = json.Unmarshal(obj.Arcs, &arcs); err == nil {
+			g.Type = LineString
+			var path [][2]float64
+			if path, err = d.path(arcs); err == nil {
+				g.Paths = append(g.Paths, path)
+			}
+		}
+	case "MultiLineString", "Polygon":
+		var lines [][]int
+		if err = json.Unmarshal(obj.Arcs, &lines); err == nil {
+			g.Type = LineString
+			if obj.Type == "Polygon" {
+				g.Type = Polygon
+			}
+			g.Paths, err = d.paths(lines...)
+		}
+	case "MultiPolygon":
+		var polys [][][]int
+		if err = json.Unmarshal(obj.Arcs, &polys); err == nil {
+			g.Type = Polygon
+			for _, rings := range polys {
+				var paths [][][2]float64
+				if paths, err = d.paths(rings...); err != nil {
+					break
+				}
+				g.Paths = append(g.Paths, paths...)
+				g.Rings = append(g.Rings, len(paths))
+			}
+		}
+	default:
+		return Geometry{}, fmt.Errorf("unsupported type %q", obj.Type)
+	}
+	if err != nil {
+		return Geometry{}, fmt.Errorf("%s: %v", obj.Type, err)
+	}
+	return g, nil
+}
+
+// paths resolves lists of arc indexes to paths
+func (d *topoDecoder) paths(lists ...[]int) ([][][2]float64, error) {
+	paths := make([][][2]float64, len(lists))
+	for i, arcs := range lists {
+		var err error
+		if paths[i], err = d.path(arcs); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// path joins arcs into a path. A negative index, ~i, is arc i reversed.
+// Each arc after the first starts where the previous one ends, so its
+// first point is left out.
+func (d *topoDecoder) path(arcs []int) ([][2]float64, error) {
+	var path [][2]float64
+	for _, i := range arcs {
+		reverse := i < 0
+		if reverse {
+			i = ^i
+		}
+		if i >= len(d.arcs) {
+			return nil, fmt.Errorf("arc %d does not exist", i)
+		}
+		arc := d.arcs[i]
+		if len(path) > 0 && len(arc) > 0 {
+			path = path[:len(path)-1]
+		}
+		if !reverse {
+			path = append(path, arc...)
+			continue
+		}
+		for j := len(arc) - 1; j >= 0; j-- {
+			path = append(path, arc[j])
+		}
+	}
+	return path, nil
+}
+
+// Objects returns the names of the objects of the topology, in order
+func (t *Topology) Objects() []string {
+	names := make([]string, 0, len(t.objects))
+	for name := range t.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns a FeatureSource of the features of the named object,
+// which has no features when the topology does not have it.
+func (t *Topology) Source(name string) FeatureSource {
+	return SliceSource(t.objects[name])
+}
+
+// AddLayers adds a layer to the tile for each object of the topology, in
+// order, with the features of the object that are in the tile.
+func (t *Topology) AddLayers(tile *Tile) {
+	for _, name := range t.Objects() {
+		tile.AddLayer(name).AddFrom(t.Source(name))
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// two squares that share an edge, a line, and a point, quantized to
+// units of 0.5 degrees
+const testTopoJSON = `{
+	"type": "Topology",
+	"transform": {"scale": [0.5, 0.5], "translate": [-1, -1]},
+	"objects": {
+		"areas": {"type": "GeometryCollection", "geometries": [
+			{"type": "Polygon", "id": 1, "arcs": [[0, 1]],
+				"properties": {"name": "west"}},
+			{"type": "Polygon", "id": 2, "arcs": [[2, -1]],
+				"properties": {"name": "east"}}
+		]},
+		"other": {"type": "GeometryCollection", "geometries": [
+			{"type": "LineString", "arcs": [-3]},
+			{"type": "Point", "coordinates": [2, 4]},
+			{"type": null}
+		]}
+	},
+	"arcs": [
+		[[2, 0], [0, 2]],
+		[[2, 2], [-2, 0], [0, -2], [2, 0]],
+		[[2, 0], [2, 0], [0, 2], [-2, 0]]
+	]
+}`
+
+func TestTopoJSON(t *testing.T) {
+	topo, err := ParseTopoJSON([]byte(testTopoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(topo.Objects()); s != "[areas other]" {
+		t.Fatalf("unexpected objects %s", s)
+	}
+	features := readAllGeo(t, topo.Source("areas"))
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, got %d", len(features))
+	}
+	for i, expect := range []string{
+		"{3 [[[0 -1] [0 0] [-1 0] [-1 -1] [0 -1]]] []} map[name:west] 1",
+		"{3 [[[0 -1] [1 -1] [1 0] [0 0] [0 -1]]] []} map[name:east] 2",
+	} {
+		f := features[i]
+		if s := fmt.Sprint(f.Geometry, " ", f.Tags, " ", f.ID); s != expect {
+			t.Fatalf("feature %d: expected %s, got %s", i, expect, s)
+		}
+	}
+	features = readAllGeo(t, topo.Source("other"))
+	if len(features) != 3 {
+		t.Fatalf("expected 3 features, got %d", len(features))
+	}
+	if s := fmt.Sprint(features[0].Geometry); s !=
+		"{2 [[[0 0] [1 0] [1 -1] [0 -1]]] []}" {
+		t.Fatalf("unexpected line %s", s)
+	}
+	if s := fmt.Sprint(features[1].Geometry); s != "{1 [[[0 1]]] []}" {
+		t.Fatalf("unexpected point %s", s)
+	}
+	if len(readAllGeo(t, topo.Source("missing"))) != 0 {
+		t.Fatal("expected no features")
+	}
+
+	var tile Tile
+	tile.SetTileID(TileID{Z: 1, X: 0, Y: 0})
+	topo.AddLayers(&tile)
+	if len(tile.Layers()) != 2 || tile.Layers()[0].Name() != "areas" ||
+		len(tile.Layers()[0].Features()) != 2 {
+		t.Fatalf("unexpected layers %v", tile.Layers())
+	}
+
+	for _, data := range []string{
+		`{"type": "FeatureCollection"}`,
+		`{"type": "Topology", "objects": {"a": {"type": "LineString", "arcs": [3]}}}`,
+		`{"type": "Topology", "objects": {"a": {"type": "Sphere"}}}`,
+	} {
+		if _, err := ParseTopoJSON([]byte(data)); !errors.Is(err,
+			ErrInvalidTopoJSON) {
+			t.Fatalf("expected ErrInvalidTopoJSON for %s, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The errors of ValidateTopology
+var (
+	// ErrSelfIntersection is returned for a ring that crosses or touches
+	// itself
+	ErrSelfIntersection = errors.New("ring intersects itself")
+	// ErrRingsIntersect is returned for rings that cross each other, or
+	// that share part of an edge
+	ErrRingsIntersect = errors.New("rings intersect")
+	// ErrHoleOutside is returned for a hole that is not inside of the
+	// exterior ring before it
+	ErrHoleOutside = errors.New("hole is outside of its exterior ring")
+)
+
+// ValidateTopology checks that the rings of a Polygon feature do not
+// intersect themselves or each other, other than rings touching at a
+// point, and that each hole is inside of the exterior ring before it. Such
+// polygons are drawn with artifacts by some renderers. Rings with fewer
+// than three points are skipped, see Validate. Other features are valid.
+func (f *Feature) ValidateTopology() error {
+	if f.geomType != Polygon {
+		return nil
+	}
+	rings := polygonRings(f)
+	segs := ringSegments(rings)
+	var err error
+	eachSegmentPair(segs, func(i, j int) bool {
+		s, t := segs[i], segs[j]
+		if s.ring != t.ring {
+			if segmentsCross(s, t) || segmentsOverlap(s, t) {
+				err = fmt.Errorf("rings %d and %d: %w", s.ring, t.ring,
+					ErrRingsIntersect)
+			}
+			return err == nil
+		}
+		n := len(rings[s.ring])
+		if (s.index+1)%n == t.index || (t.index+1)%n == s.index {
+			// neighbors share a point, and only intersect when the ring
+			// turns back on itself
+			if segmentsOverlap(s, t) {
+				err = fmt.Errorf("ring %d: %w", s.ring, ErrSelfIntersection)
+			}
+		} else if segmentsTouch(s, t) {
+			err = fmt.Errorf("ring %d: %w", s.ring, ErrSelfIntersection)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	exterior := -1
+	for i, ring := range rings {
+		if ring == nil {
+			continue
+		}
+		if ringArea(ring) > 0 {
+			exterior = i
+			continue
+		}
+		if exterior == -1 || !ringInRing(ring, rings[exterior]) {
+			return fmt.Errorf("ring %d: %w", i, ErrHoleOutside)
+		}
+	}
+	return nil
+}
+
+// SetRepairPolygons sets whether the polygons that fail ValidateTopology
+// are rebuilt when the layer is rendered. A repaired polygon covers the
+// area that is inside of an odd number of its rings, like the linework
+// method of GEOS MakeValid, with its rings wound as the spec says.
+// Polygons with nothing left are dropped. Default is false.
+func (l *Layer) SetRepairPolygons(repair bool) {
+	l.repair = repair
+}
+
+// repairPolygons returns the features with copies of the invalid polygons
+// that are repaired
+func (l *Layer) repairPolygons(features []*Feature) []*Feature {
+	kept := make([]*Feature, 0, len(features))
+	for _, f := range features {
+		if f.geomType == Polygon && f.ValidateTopology() != nil {
+			g := repairRings(polygonRings(f))
+			if len(g.ops) == 0 {
+				continue
+			}
+			repaired := *f
+			repaired.geom = g
+			f = &repaired
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// polygonRings returns the points of the rings of the polygon, without a
+// last point that repeats the first, or points that repeat the one before
+// them. Rings with fewer than three points are nil.
+func polygonRings(f *Feature) [][]command {
+	paths := f.paths()
+	rings := make([][]command, len(paths))
+	for i, path := range paths {
+		var ring []command
+		for _, p := range pathPoints(path) {
+			if n := len(ring); n > 0 && ring[n-1].x == p.x &&
+				ring[n-1].y == p.y {
+				continue
+			}
+			ring = append(ring, p)
+		}
+		if n := len(ring); n > 1 && ring[0].x == ring[n-1].x &&
+			ring[0].y == ring[n-1].y {
+			ring = ring[:n-1]
+		}
+		if len(ring) >= 3 {
+			rings[i] = ring
+		}
+	}
+	return rings
+}
+
+// ringSegment is an edge of a ring, from the point of its index to the
+// next point
+type ringSegment struct {
+	a, b        [2]float64
+	ring, index int
+}
+
+// ringSegments returns the edges of the rings
+func ringSegments(rings [][]command) []ringSegment {
+	var segs []ringSegment
+	for i, ring := range rings {
+		for j := range ring {
+			p, q := ring[j], ring[(j+1)%len(ring)]
+			segs = append(segs, ringSegment{[2]float64{p.x, p.y},
+				[2]float64{q.x, q.y}, i, j})
+		}
+	}
+	return segs
+}
+
+// eachSegmentPair calls fn with the indexes of each pair of segments whose
+// bounds overlap, until it returns false
+func eachSegmentPair(segs []ringSegment, fn func(i, j int) bool) {
+	order := make([]int, len(segs))
+	for i := range order {
+		order[i] = i
+	}
+	minX := func(s ringSegment) float64 { return min(s.a[0], s.b[0]) }
+	sort.Slice(order, func(i, j int) bool {
+		return minX(segs[order[i]]) < minX(segs[order[j]])
+	})
+	for i, si := range order {
+		s := segs[si]
+		maxX := max(s.a[0], s.b[0])
+		for _, tj := range order[i+1:] {
+			t := segs[tj]
+			if minX(t) > maxX {
+				break
+			}
+			if max(t.a[1], t.b[1]) < min(s.a[1], s.b[1]) ||
+				min(t.a[1], t.b[1]) > max(s.a[1], s.b[1]) {
+				continue
+			}
+			if !fn(si, tj) {
+				return
+			}
+		}
+	}
+}
+
+// orientation returns the cross product of b-a and c-a, which is zero
+// when the points are on a line
+func orientation(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment returns true when p is on the segment a-b
+func onSegment(p, a, b [2]float64) bool {
+	return orientation(a, b, p) == 0 &&
+		p[0] >= min(a[0], b[0]) && p[0] <= max(a[0], b[0]) &&
+		p[1] >= min(a[1], b[1]) && p[1] <= max(a[1], b[1])
+}
+
+// segmentsCross returns true when the segments cross at a point that is
+// inside of both of them
+func segmentsCross(s, t ringSegment) bool {
+	o1, o2 := orientation(s.a, s.b, t.a), orientation(s.a, s.b, t.b)
+	o3, o4 := orientation(t.a, t.b, s.a), orientation(t.a, t.b, s.b)
+	return (o1 > 0 && o2 < 0 || o1 < 0 && o2 > 0) &&
+		(o3 > 0 && o4 < 0 || o3 < 0 && o4 > 0)
+}
+
+// segmentsTouch returns true when the segments have any point in common
+func segmentsTouch(s, t ringSegment) bool {
+	return segmentsCross(s, t) || onSegment(t.a, s.a, s.b) ||
+		onSegment(t.b, s.a, s.b) || onSegment(s.a, t.a, t.b) ||
+		onSegment(s.b, t.a, t.b)
+}
+
+// segmentsOverlap returns true when the segments are on the same line and
+// share more than a point
+func segmentsOverlap(s, t ringSegment) bool {
+	if orientation(s.a, s.b, t.a) != 0 || orientation(s.a, s.b, t.b) != 0 {
+		return false
+	}
+	// project onto the axis that the segment is longest along
+	axis := 0
+	if math.Abs(s.b[1]-s.a[1]) > math.Abs(s.b[0]-s.a[0]) {
+		axis = 1
+	}
+	lo := max(min(s.a[axis], s.b[axis]), min(t.a[axis], t.b[axis]))
+	hi := min(max(s.a[axis], s.b[axis]), max(t.a[axis], t.b[axis]))
+	return hi > lo
+}
+
+// ringInRing returns true when none of the points of the inner ring are
+// outside of the outer ring
+func ringInRing(inner, outer []command) bool {
+	for _, p := range inner {
+		if !pointInRing(p.x, p.y, outer) {
+			return false
+		}
+	}
+	return true
+}
+
+// repairRings returns the polygon of the area that is inside of an odd
+// number of the rings. The edges of the rings are split where they meet,
+// and those that are shared by an even number of rings are dropped, which
+// leaves the boundary of the area. The rest are turned to have the area on
+// their left, and are walked back into rings.
+func repairRings(rings [][]command) geometry {
+	segs := ringSegments(rings)
+	type split struct {
+		t float64
+		p [2]float64
+	}
+	splits := make([][]split, len(segs))
+	addSplit := func(i int, p [2]float64) {
+		s := segs[i]
+		d := [2]float64{s.b[0] - s.a[0], s.b[1] - s.a[1]}
+		t := ((p[0]-s.a[0])*d[0] + (p[1]-s.a[1])*d[1]) /
+			(d[0]*d[0] + d[1]*d[1])
+		splits[i] = append(splits[i], split{t, p})
+	}
+	eachSegmentPair(segs, func(i, j int) bool {
+		s, t := segs[i], segs[j]
+		for _, p := range [2][2]float64{t.a, t.b} {
+			if onSegment(p, s.a, s.b) {
+				addSplit(i, p)
+			}
+		}
+		for _, p := range [2][2]float64{s.a, s.b} {
+			if onSegment(p, t.a, t.b) {
+				addSplit(j, p)
+			}
+		}
+		if segmentsCross(s, t) {
+			if p, ok := segmentIntersection(s.a, s.b, t.a, t.b); ok {
+				addSplit(i, p)
+				addSplit(j, p)
+			}
+		}
+		return true
+	})
+	// count the pieces of the edges, in either direction
+	counts := make(map[ringEdge]int)
+	var pieces []ringEdge
+	for i, s := range segs {
+		if s.a == s.b {
+			continue
+		}
+		points := splits[i]
+		sort.Slice(points, func(a, b int) bool {
+			return points[a].t < points[b].t
+		})
+		prev := s.a
+		for _, sp := range append(points, split{1, s.b}) {
+			if sp.t <= 0 || sp.p == prev {
+				continue
+			}
+			e := ringEdge{prev[0], prev[1], sp.p[0], sp.p[1]}
+			if e.bx < e.ax || (e.bx == e.ax && e.by < e.ay) {
+				e = ringEdge{e.bx, e.by, e.ax, e.ay}
+			}
+			if counts[e] == 0 {
+				pieces = append(pieces, e)
+			}
+			counts[e]++
+			prev = sp.p
+			if sp.t >= 1 {
+				break
+			}
+		}
+	}
+	var edges []ringEdge
+	for _, e := range pieces {
+		if counts[e]%2 == 0 {
+			continue
+		}
+		// a point just to the left of the middle of the edge
+		dx, dy := e.bx-e.ax, e.by-e.ay
+		eps := 1e-7 / math.Hypot(dx, dy)
+		x, y := (e.ax+e.bx)/2-dy*eps, (e.ay+e.by)/2+dx*eps
+		var inside bool
+		for _, ring := range rings {
+			if ring != nil && pointInRing(x, y, ring) {
+				inside = !inside
+			}
+		}
+		if !inside {
+			e = ringEdge{e.bx, e.by, e.ax, e.ay}
+		}
+		edges = append(edges, e)
+	}
+	return traceRings(edges)
+}
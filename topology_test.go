@@ -0,0 +1,110 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"testing"
+)
+
+// addRings adds a polygon of the rings, which are drawn as they are
+func addRings(l *Layer, rings ...[][2]float64) *Feature {
+	f := l.AddFeature(Polygon)
+	for _, ring := range rings {
+		for i, p := range ring {
+			if i == 0 {
+				f.MoveTo(p[0], p[1])
+			} else {
+				f.LineTo(p[0], p[1])
+			}
+		}
+		f.ClosePath()
+	}
+	return f
+}
+
+var (
+	topoSquare = [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	topoHole   = [][2]float64{{2, 2}, {2, 8}, {8, 8}, {8, 2}}
+)
+
+func TestValidateTopology(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	tests := []struct {
+		name  string
+		rings [][][2]float64
+		err   error
+	}{
+		{"valid", [][][2]float64{topoSquare, topoHole}, nil},
+		{"hole touching", [][][2]float64{topoSquare,
+			{{0, 5}, {5, 8}, {5, 2}}}, nil},
+		{"bowtie", [][][2]float64{{{0, 0}, {10, 10}, {10, 0}, {0, 10}}},
+			ErrSelfIntersection},
+		{"touching itself", [][][2]float64{{{0, 0}, {10, 0}, {10, 10},
+			{5, 0}, {0, 10}}}, ErrSelfIntersection},
+		{"spike", [][][2]float64{{{0, 0}, {10, 0}, {15, 0}, {12, 0},
+			{10, 10}}}, ErrSelfIntersection},
+		{"crossing hole", [][][2]float64{topoSquare,
+			{{5, 2}, {5, 8}, {15, 8}, {15, 2}}}, ErrRingsIntersect},
+		{"hole outside", [][][2]float64{topoSquare,
+			{{20, 2}, {20, 8}, {28, 8}, {28, 2}}}, ErrHoleOutside},
+		{"hole first", [][][2]float64{topoHole}, ErrHoleOutside},
+	}
+	for _, tt := range tests {
+		f := addRings(l, tt.rings...)
+		if err := f.ValidateTopology(); !errors.Is(err, tt.err) ||
+			(err == nil) != (tt.err == nil) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+	line := l.AddFeature(LineString)
+	line.MoveTo(0, 0)
+	line.LineTo(10, 10)
+	line.LineTo(10, 0)
+	line.LineTo(0, 10)
+	if err := line.ValidateTopology(); err != nil {
+		t.Fatalf("expected lines to be valid, got %v", err)
+	}
+}
+
+func TestRepairPolygons(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("shapes")
+	l.SetAutoID(0)
+	valid := addRings(l, topoSquare, topoHole)
+	addRings(l, [][2]float64{{0, 0}, {10, 10}, {10, 0}, {0, 10}})
+	addRings(l, topoSquare, [][2]float64{{20, 2}, {20, 8}, {28, 8},
+		{28, 2}})
+	addRings(l, topoSquare, [][2]float64{{5, 2}, {5, 8}, {15, 8}, {15, 2}})
+	addRings(l, [][2]float64{{0, 0}, {10, 0}, {0, 0}, {10, 0}})
+	l.AddFeature(Point).MoveTo(1, 1)
+	l.SetRepairPolygons(true)
+	features := l.render()
+	if ids := featureIDs(features); ids != "[0 1 2 3 5]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if features[0] != valid {
+		t.Fatal("expected a valid polygon to be kept as it is")
+	}
+	areas := []float64{64, 50, 148, 100}
+	parts := []int{2, 2, 2, 2}
+	for i, f := range features[:4] {
+		if err := f.ValidateTopology(); err != nil {
+			t.Fatalf("feature %d: %v", i, err)
+		}
+		if err := f.Validate(); err != nil {
+			t.Fatalf("feature %d: %v", i, err)
+		}
+		if f.Area() != areas[i] || len(f.paths()) != parts[i] {
+			t.Fatalf("feature %d: expected an area of %v in %d rings, "+
+				"got %v in %d", i, areas[i], parts[i], f.Area(),
+				len(f.paths()))
+		}
+	}
+	if err := l.Features()[1].ValidateTopology(); err == nil {
+		t.Fatal("expected the layer to keep the invalid polygon")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateID is returned when a feature has the same id as an earlier
+// feature in the layer, with the IDError policy.
+var ErrDuplicateID = errors.New("duplicate feature id")
+
+// IDPolicy is what is done with the features of a layer that have the same
+// id as an earlier feature when it is rendered, as clients that key state
+// by feature id, such as MapLibre feature-state, break when ids collide
+type IDPolicy int
+
+const (
+	// IDAllow encodes the features with the ids as they are
+	IDAllow IDPolicy = iota
+	// IDError has Encode return ErrDuplicateID
+	IDError
+	// IDRenumber gives the features new ids, counting up from the largest
+	// id of the layer
+	IDRenumber
+	// IDDrop drops the features
+	IDDrop
+)
+
+// SetIDPolicy sets what is done with features that have the id of an
+// earlier feature in the layer when it is rendered, see DuplicateIDs. The
+// ids are checked after the other render time options, such as a drop
+// policy. Features without ids are left as they are. Default is IDAllow.
+func (l *Layer) SetIDPolicy(policy IDPolicy) {
+	l.idPolicy = policy
+}
+
+// DuplicateIDs returns the number of features that had the id of an
+// earlier feature the last time that the layer was rendered with an id
+// policy, see SetIDPolicy.
+func (l *Layer) DuplicateIDs() int {
+	return l.duplicates
+}
+
+// uniqueIDs returns the features with the duplicate ids renumbered or
+// dropped by the id policy, and counts them. Renumbered features are
+// copies.
+func (l *Layer) uniqueIDs(features []*Feature) []*Feature {
+	l.duplicates = 0
+	var next uint64
+	for _, f := range features {
+		if f.hasID {
+			next = max(next, f.id+1)
+		}
+	}
+	seen := make(map[uint64]bool)
+	var kept []*Feature
+	for i, f := range features {
+		if !f.hasID || !seen[f.id] {
+			if f.hasID {
+				seen[f.id] = true
+			}
+			if kept != nil {
+				kept = append(kept, f)
+			}
+			continue
+		}
+		l.duplicates++
+		if kept == nil {
+			kept = append(make([]*Feature, 0, len(features)),
+				features[:i]...)
+		}
+		switch l.idPolicy {
+		case IDRenumber:
+			renumbered := *f
+			renumbered.id = next
+			next++
+			kept = append(kept, &renumbered)
+		case IDError:
+			kept = append(kept, f)
+		}
+	}
+	if kept == nil {
+		return features
+	}
+	return kept
+}
+
+// checkIDs returns ErrDuplicateID for the first feature that has the id
+// of an earlier feature.
+func (l *Layer) checkIDs(features []*Feature) error {
+	seen := make(map[uint64]bool)
+	for i, f := range features {
+		if !f.hasID {
+			continue
+		}
+		if seen[f.id] {
+			return fmt.Errorf("layer %q: feature %d: %w %d", l.name, i,
+				ErrDuplicateID, f.id)
+		}
+		seen[f.id] = true
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIDPolicy(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("points")
+	for i, id := range []uint64{4, 7, 4, 2, 7, 4} {
+		f := l.AddFeature(Point)
+		f.MoveTo(float64(i), 0)
+		f.SetID(id)
+	}
+	l.AddFeature(Point).MoveTo(10, 0)
+
+	if ids := featureIDs(l.render()); ids != "[4 7 4 2 7 4 0]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetIDPolicy(IDRenumber)
+	if ids := featureIDs(l.render()); ids != "[4 7 8 2 9 10 0]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	if n := l.DuplicateIDs(); n != 3 {
+		t.Fatalf("expected 3 duplicates, got %d", n)
+	}
+	if l.features[2].id != 4 {
+		t.Fatal("expected the layer features to be unchanged")
+	}
+	l.SetIDPolicy(IDDrop)
+	if ids := featureIDs(l.render()); ids != "[4 7 2 0]" {
+		t.Fatalf("unexpected ids %s", ids)
+	}
+	l.SetIDPolicy(IDError)
+	if _, err := tile.Encode(); !errors.Is(err, ErrDuplicateID) {
+		t.Fatalf("expected %v, got %v", ErrDuplicateID, err)
+	}
+	if n := l.DuplicateIDs(); n != 3 {
+		t.Fatalf("expected 3 duplicates, got %d", n)
+	}
+	l.SetDropPolicy(func(features []*Feature, zoom int) []*Feature {
+		return features[:2]
+	})
+	if _, err := tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Complex value types from the 3.0 draft of the vector tile spec. Each
+// attribute value is a uint64 with the type in the low four bits and a
+// parameter in the remaining bits.
+const (
+	attrString     = 0 // index into string_values
+	attrFloat      = 1 // index into float_values
+	attrDouble     = 2 // index into double_values
+	attrUint       = 3 // index into int_values
+	attrSint       = 4 // index into int_values
+	attrInlineUint = 5 // the value itself
+	attrInlineSint = 6 // the zigzag encoded value itself
+	attrBoolNull   = 7 // 0 is false, 1 is true, 2 is null
+	attrList       = 8 // count of the values that follow
+	attrMap        = 9 // count of the key/value pairs that follow
+)
+
+// maxInline is the largest parameter that fits in a complex value.
+const maxInline = 1<<60 - 1
+
+// attrTables collects the layer tables for version 3 attributes.
+type attrTables struct {
+	keys      []string
+	keyidx    map[string]int
+	strs      []string
+	stridx    map[string]int
+	floats    []float32
+	floatidx  map[uint32]int
+	doubles   []float64
+	doubleidx map[uint64]int
+	ints      []int64
+	intidx    map[int64]int
+}
+
+func newAttrTables() *attrTables {
+	return &attrTables{
+		keyidx:    make(map[string]int),
+		stridx:    make(map[string]int),
+		floatidx:  make(map[uint32]int),
+		doubleidx: make(map[uint64]int),
+		intidx:    make(map[int64]int),
+	}
+}
+
+func (t *attrTables) key(key string) uint64 {
+	idx, ok := t.keyidx[key]
+	if !ok {
+		idx = len(t.keys)
+		t.keyidx[key] = idx
+		t.keys = append(t.keys, key)
+	}
+	return uint64(idx)
+}
+
+func (t *attrTables) str(s string) uint64 {
+	idx, ok := t.stridx[s]
+	if !ok {
+		idx = len(t.strs)
+		t.stridx[s] = idx
+		t.strs = append(t.strs, s)
+	}
+	return uint64(idx)
+}
+
+func (t *attrTables) int(n int64) uint64 {
+	idx, ok := t.intidx[n]
+	if !ok {
+		idx = len(t.ints)
+		t.intidx[n] = idx
+		t.ints = append(t.ints, n)
+	}
+	return uint64(idx)
+}
+
+func complexValue(typ int, param uint64) uint64 {
+	return param<<4 | uint64(typ)
+}
+
+// appendValue appends the complex value encoding of v.
+func (t *attrTables) appendValue(attrs []uint64, v interface{}) []uint64 {
+	switch v := v.(type) {
+	case nil:
+		return append(attrs, complexValue(attrBoolNull, 2))
+	case bool:
+		if v {
+			return append(attrs, complexValue(attrBoolNull, 1))
+		}
+		return append(attrs, complexValue(attrBoolNull, 0))
+	case string:
+		return append(attrs, complexValue(attrString, t.str(v)))
+	case []byte:
+		return append(attrs, complexValue(attrString, t.str(string(v))))
+	case float32:
+		bits := math.Float32bits(v)
+		idx, ok := t.floatidx[bits]
+		if !ok {
+			idx = len(t.floats)
+			t.floatidx[bits] = idx
+			t.floats = append(t.floats, v)
+		}
+		return append(attrs, complexValue(attrFloat, uint64(idx)))
+	case float64:
+		bits := math.Float64bits(v)
+		idx, ok := t.doubleidx[bits]
+		if !ok {
+			idx = len(t.doubles)
+			t.doubleidx[bits] = idx
+			t.doubles = append(t.doubles, v)
+		}
+		return append(attrs, complexValue(attrDouble, uint64(idx)))
+	case uint64:
+		if v <= maxInline {
+			return append(attrs, complexValue(attrInlineUint, v))
+		}
+		return append(attrs, complexValue(attrUint, t.int(int64(v))))
+	case int64:
+		zz := uint64(v<<1) ^ uint64(v>>63)
+		if zz <= maxInline {
+			return append(attrs, complexValue(attrInlineSint, zz))
+		}
+		return append(attrs, complexValue(attrSint, t.int(v)))
+	case uint8:
+		return t.appendValue(attrs, uint64(v))
+	case uint16:
+		return t.appendValue(attrs, uint64(v))
+	case uint32:
+		return t.appendValue(attrs, uint64(v))
+	case int8:
+		return t.appendValue(attrs, int64(v))
+	case int16:
+		return t.appendValue(attrs, int64(v))
+	case int32:
+		return t.appendValue(attrs, int64(v))
+	case int:
+		return t.appendValue(attrs, int64(v))
+	case uint:
+		return t.appendValue(attrs, uint64(v))
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		attrs = append(attrs, complexValue(attrList, uint64(rv.Len())))
+		for i := 0; i < rv.Len(); i++ {
+			attrs = t.appendValue(attrs, rv.Index(i).Interface())
+		}
+		return attrs
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			keys := rv.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			attrs = append(attrs, complexValue(attrMap, uint64(len(keys))))
+			for _, k := range keys {
+				attrs = append(attrs, t.key(k.String()))
+				attrs = t.appendValue(attrs, rv.MapIndex(k).Interface())
+			}
+			return attrs
+		}
+	}
+	return t.appendValue(attrs, fmt.Sprintf("%v", v))
+}
+
+// isSupportedAttribute returns true when appendValue has an encoding for
+// v rather than falling back to its string form.
+func isSupportedAttribute(v interface{}) bool {
+	if v == nil || isSupportedValue(v) {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if !isSupportedAttribute(rv.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		for _, k := range rv.MapKeys() {
+			if !isSupportedAttribute(rv.MapIndex(k).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// appendV3 appends the features with their attributes, followed by the
+// key table and the typed value tables of the 3.0 draft.
+func (l *Layer) appendV3(ctx context.Context, used int, pb []byte,
+	features []*Feature,
+) ([]byte, error) {
+	t := newAttrTables()
+	var attrs []uint64
+	for i, feature := range features {
+		if i%checkInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		attrs = attrs[:0]
+		for _, tag := range feature.tags {
+			attrs = append(attrs, t.key(tag.Key))
+			attrs = t.appendValue(attrs, l.normalizeValue(tag.Value))
+		}
+		var err error
+		pb, err = l.appendFeature(pb, used, i, feature,
+			appendPacked(nil, 42, attrs))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range t.keys {
+		pb = append(pb, encodeKey(key)...)
+	}
+	for _, s := range t.strs {
+		pb = append(pb, 50)
+		pb = appendString(pb, s)
+	}
+	if len(t.floats) > 0 {
+		pb = append(pb, 58)
+		pb = appendUvarint(pb, uint64(len(t.floats)*4))
+		for _, v := range t.floats {
+			pb = binary.LittleEndian.AppendUint32(pb, math.Float32bits(v))
+		}
+	}
+	if len(t.doubles) > 0 {
+		pb = append(pb, 66)
+		pb = appendUvarint(pb, uint64(len(t.doubles)*8))
+		for _, v := range t.doubles {
+			pb = binary.LittleEndian.AppendUint64(pb, math.Float64bits(v))
+		}
+	}
+	if len(t.ints) > 0 {
+		pb = append(pb, 74)
+		pb = appendUvarint(pb, uint64(len(t.ints)*8))
+		for _, v := range t.ints {
+			pb = binary.LittleEndian.AppendUint64(pb, uint64(v))
+		}
+	}
+	return pb, nil
+}
+
+// appendElevations appends the elevations of the vertices of a feature as
+// the packed, delta encoded field of the 3.0 draft, without scaling.
+func appendElevations(pb []byte, zs []float64) []byte {
+	var epb []byte
+	var last int64
+	for _, z := range zs {
+		n := int64(math.Round(z))
+		epb = appendVarint(epb, n-last)
+		last = n
+	}
+	pb = append(pb, 50)
+	pb = appendUvarint(pb, uint64(len(epb)))
+	return append(pb, epb...)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestV3Attributes(t *testing.T) {
+	tables := newAttrTables()
+	attrs := tables.appendValue(nil, map[string]interface{}{
+		"list": []interface{}{int64(-1), "x", nil},
+		"ok":   true,
+		"pi":   3.14,
+	})
+	expect := []uint64{
+		complexValue(attrMap, 3),
+		0, complexValue(attrList, 3),
+		complexValue(attrInlineSint, 1),
+		complexValue(attrString, 0),
+		complexValue(attrBoolNull, 2),
+		1, complexValue(attrBoolNull, 1),
+		2, complexValue(attrDouble, 0),
+	}
+	if fmt.Sprint(attrs) != fmt.Sprint(expect) {
+		t.Fatalf("expected %v, got %v", expect, attrs)
+	}
+	if fmt.Sprint(tables.keys) != "[list ok pi]" {
+		t.Fatalf("unexpected keys %v", tables.keys)
+	}
+	if !isSupportedAttribute(map[string]interface{}{"a": []int64{1}}) ||
+		isSupportedAttribute(struct{}{}) {
+		t.Fatal("unexpected attribute support")
+	}
+}
+
+func TestV3Render(t *testing.T) {
+	var tile Tile
+	tile.SetStrict(true)
+	l := tile.AddLayer("v3")
+	l.SetVersion(3)
+	f := l.AddFeature(Point)
+	f.MoveTo(1, 1)
+	f.AddTag("names", []string{"a", "b"})
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(pb, []byte{120, 3}) {
+		t.Fatal("expected version 3")
+	}
+	// attributes: key 0, list of 2, string 0, string 1
+	if !bytes.Contains(pb, []byte{42, 4, 0, 40, 0, 16}) {
+		t.Fatalf("expected attributes field in %v", pb)
+	}
+	if !bytes.Contains(pb, []byte{50, 1, 'a', 50, 1, 'b'}) {
+		t.Fatalf("expected string values in %v", pb)
+	}
+}
+
+func TestV3Elevations(t *testing.T) {
+	var tile Tile
+	l := tile.AddLayer("v3")
+	f := l.AddFeature(LineString)
+	f.MoveTo(0, 0)
+	f.LineTo3(1, 1, 100)
+	f.LineTo3(2, 2, 98.6)
+	f.LineTo(3, 3)
+	if fmt.Sprint(f.geom.elevations()) != "[0 100 98.6 0]" {
+		t.Fatalf("unexpected elevations %v", f.geom.elevations())
+	}
+	// deltas of 0, 100, -1, and -99, zigzag encoded
+	expect := []byte{50, 6, 0, 200, 1, 1, 197, 1}
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(pb, expect) {
+		t.Fatal("expected the elevations to be dropped for version 2")
+	}
+	l.SetVersion(3)
+	if pb, err = tile.Encode(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pb, expect) {
+		t.Fatalf("expected elevations field in %v", pb)
+	}
+	f.geom.coords = f.geom.coords[:4]
+	if f.geom.elevations() != nil {
+		t.Fatal("expected no elevations for a rebuilt geometry")
+	}
+}
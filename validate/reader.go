@@ -0,0 +1,98 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import "encoding/binary"
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// reader reads the fields of a protobuf message. Reads after an error
+// return zero values, and the error is kept in err.
+type reader struct {
+	data []byte
+	err  error
+}
+
+// next reads the key of the next field, returning false at the end of
+// the message or after an error.
+func (r *reader) next() (field, wire int, ok bool) {
+	if r.err != nil || len(r.data) == 0 {
+		return 0, 0, false
+	}
+	key := r.uvarint()
+	if r.err != nil {
+		return 0, 0, false
+	}
+	return int(key >> 3), int(key & 7), true
+}
+
+// uvarint reads a varint
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	n, sz := binary.Uvarint(r.data)
+	if sz <= 0 {
+		r.err = ErrMalformed
+		return 0
+	}
+	r.data = r.data[sz:]
+	return n
+}
+
+// bytes reads a length-delimited field, which is not copied
+func (r *reader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil || n > uint64(len(r.data)) {
+		r.err = ErrMalformed
+		return nil
+	}
+	b := r.data[:n:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// packed reads a field of varints, which is packed or a single value
+func (r *reader) packed(wire int) []uint64 {
+	if wire == wireVarint {
+		return []uint64{r.uvarint()}
+	}
+	if wire != wireBytes {
+		r.err = ErrMalformed
+		return nil
+	}
+	pr := reader{data: r.bytes()}
+	var vals []uint64
+	for r.err == nil && pr.err == nil && len(pr.data) > 0 {
+		vals = append(vals, pr.uvarint())
+	}
+	if pr.err != nil {
+		r.err = pr.err
+	}
+	return vals
+}
+
+// skip skips the value of a field of the wire type
+func (r *reader) skip(wire int) {
+	n := map[int]int{wireFixed64: 8, wireFixed32: 4}[wire]
+	switch {
+	case wire == wireVarint:
+		r.uvarint()
+	case wire == wireBytes:
+		r.bytes()
+	case n == 0:
+		r.err = ErrMalformed
+	case r.err == nil && len(r.data) < n:
+		r.err = ErrMalformed
+	case r.err == nil:
+		r.data = r.data[n:]
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+// Package validate checks encoded vector tiles against version 2.1 of the
+// Mapbox Vector Tile spec, such as the tiles of other tools, with the
+// checks of the mvt-fixtures test suite.
+package validate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors of the issues of a tile
+var (
+	// ErrMalformed is a protobuf message that is cut short or has an
+	// unknown wire type
+	ErrMalformed = errors.New("malformed protobuf")
+	// ErrUnknownField is a field that the spec does not define, other than
+	// those in the range of extensions
+	ErrUnknownField = errors.New("unknown field")
+	// ErrUnsupportedVersion is a layer version other than 1 or 2, whose
+	// layer is not checked further
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrMissingName is a layer without a name
+	ErrMissingName = errors.New("missing layer name")
+	// ErrDuplicateLayer is a layer of version 2 with the name of an earlier
+	// layer
+	ErrDuplicateLayer = errors.New("duplicate layer name")
+	// ErrInvalidExtent is a layer extent of zero
+	ErrInvalidExtent = errors.New("invalid extent")
+	// ErrInvalidValue is a value that does not have exactly one of its
+	// types set
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrInvalidTags is a feature with an odd number of tag indexes or an
+	// index that is out of the range of the key or value table
+	ErrInvalidTags = errors.New("invalid tags")
+	// ErrInvalidType is a feature of an unknown geometry type
+	ErrInvalidType = errors.New("invalid geometry type")
+	// ErrInvalidGeometry is a feature geometry with an unknown command, a
+	// command count of zero, a command that is cut short, or a sequence of
+	// commands that is not allowed for the geometry type
+	ErrInvalidGeometry = errors.New("invalid geometry")
+	// ErrInvalidWinding is a polygon ring with no area, or a polygon whose
+	// first ring is not an exterior ring
+	ErrInvalidWinding = errors.New("invalid winding order")
+)
+
+// Issue is a violation of the spec by a tile
+type Issue struct {
+	// Layer is the index of the layer in the tile, or -1 for the tile
+	Layer int
+	// Feature is the index of the feature in the layer, or -1 for the
+	// layer
+	Feature int
+	// Err is the violation, which is one of the errors of the package
+	Err error
+}
+
+// Error returns the location and the violation of the issue
+func (issue Issue) Error() string {
+	switch {
+	case issue.Layer < 0:
+		return issue.Err.Error()
+	case issue.Feature < 0:
+		return fmt.Sprintf("layer %d: %v", issue.Layer, issue.Err)
+	}
+	return fmt.Sprintf("layer %d: feature %d: %v", issue.Layer,
+		issue.Feature, issue.Err)
+}
+
+// Unwrap returns the violation of the issue
+func (issue Issue) Unwrap() error {
+	return issue.Err
+}
+
+// Geometry types and commands of the spec
+const (
+	typeUnknown    = 0
+	typePolygon    = 3
+	cmdMoveTo      = 1
+	cmdLineTo      = 2
+	cmdClosePath   = 7
+	extensionsFrom = 16
+)
+
+// Tile returns the issues of the encoded tile, in the order of the tile,
+// or nil when it follows the spec. A malformed message stops the checks of
+// the layer or feature that it is in.
+func Tile(tile []byte) []Issue {
+	var issues []Issue
+	names := make(map[string]bool)
+	var layer int
+	r := reader{data: tile}
+	for {
+		field, wire, ok := r.next()
+		if !ok {
+			break
+		}
+		if field != 3 || wire != wireBytes {
+			r.skip(wire)
+			if field < extensionsFrom {
+				issues = append(issues, Issue{-1, -1,
+					fmt.Errorf("%w %d of tile", ErrUnknownField, field)})
+			}
+			continue
+		}
+		msg := r.bytes()
+		if r.err != nil {
+			break
+		}
+		issues = checkLayer(issues, layer, msg, names)
+		layer++
+	}
+	if r.err != nil {
+		issues = append(issues, Issue{-1, -1, r.err})
+	}
+	return issues
+}
+
+// layer is the fields of a layer message
+type layer struct {
+	name     string
+	hasName  bool
+	version  uint64
+	extent   uint64
+	features [][]byte
+	keys     int
+	values   int
+}
+
+// checkLayer appends the issues of a layer message
+func checkLayer(issues []Issue, index int, msg []byte,
+	names map[string]bool,
+) []Issue {
+	l := layer{version: 1, extent: 4096}
+	add := func(err error) {
+		issues = append(issues, Issue{index, -1, err})
+	}
+	r := reader{data: msg}
+	for {
+		field, wire, ok := r.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == wireBytes:
+			l.name, l.hasName = string(r.bytes()), true
+		case field == 2 && wire == wireBytes:
+			l.features = append(l.features, r.bytes())
+		case field == 3 && wire == wireBytes:
+			r.bytes()
+			l.keys++
+		case field == 4 && wire == wireBytes:
+			if err := checkValue(r.bytes()); err != nil {
+				add(fmt.Errorf("value %d: %w", l.values, err))
+			}
+			l.values++
+		case field == 5 && wire == wireVarint:
+			l.extent = r.uvarint()
+		case field == 15 && wire == wireVarint:
+			l.version = r.uvarint()
+		default:
+			r.skip(wire)
+			if field < extensionsFrom {
+				add(fmt.Errorf("%w %d of layer", ErrUnknownField, field))
+			}
+		}
+	}
+	if r.err != nil {
+		add(r.err)
+		return issues
+	}
+	if l.version != 1 && l.version != 2 {
+		add(fmt.Errorf("%w %d", ErrUnsupportedVersion, l.version))
+		return issues
+	}
+	if !l.hasName || l.name == "" {
+		add(ErrMissingName)
+	} else if names[l.name] && l.version == 2 {
+		add(fmt.Errorf("%w %q", ErrDuplicateLayer, l.name))
+	}
+	names[l.name] = true
+	if l.extent == 0 {
+		add(ErrInvalidExtent)
+	}
+	for i, f := range l.features {
+		if err := checkFeature(f, l.keys, l.values); err != nil {
+			issues = append(issues, Issue{index, i, err})
+		}
+	}
+	return issues
+}
+
+// checkValue returns the issue of a value message
+func checkValue(msg []byte) error {
+	var types int
+	r := reader{data: msg}
+	for {
+		field, wire, ok := r.next()
+		if !ok {
+			break
+		}
+		want := [...]int{1: wireBytes, 2: wireFixed32, 3: wireFixed64,
+			4: wireVarint, 5: wireVarint, 6: wireVarint, 7: wireVarint}
+		r.skip(wire)
+		switch {
+		case field >= 1 && field <= 7 && wire == want[field]:
+			types++
+		case field < extensionsFrom:
+			return fmt.Errorf("%w %d of value", ErrUnknownField, field)
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if types != 1 {
+		return fmt.Errorf("%w: %d types", ErrInvalidValue, types)
+	}
+	return nil
+}
+
+// checkFeature returns the first issue of a feature message
+func checkFeature(msg []byte, keys, values int) error {
+	var tags, geometry []uint64
+	var geomType uint64
+	r := reader{data: msg}
+	for {
+		field, wire, ok := r.next()
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wire == wireVarint:
+			r.uvarint()
+		case field == 2:
+			tags = append(tags, r.packed(wire)...)
+		case field == 3 && wire == wireVarint:
+			geomType = r.uvarint()
+		case field == 4:
+			geometry = append(geometry, r.packed(wire)...)
+		default:
+			r.skip(wire)
+			if field < extensionsFrom {
+				return fmt.Errorf("%w %d of feature", ErrUnknownField, field)
+			}
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(tags)%2 != 0 {
+		return fmt.Errorf("%w: odd number of indexes", ErrInvalidTags)
+	}
+	for i := 0; i < len(tags); i += 2 {
+		if tags[i] >= uint64(keys) || tags[i+1] >= uint64(values) {
+			return fmt.Errorf("%w: index out of range", ErrInvalidTags)
+		}
+	}
+	if geomType > typePolygon {
+		return fmt.Errorf("%w %d", ErrInvalidType, geomType)
+	}
+	if geomType == typeUnknown {
+		return nil
+	}
+	return checkGeometry(int(geomType), geometry)
+}
+
+// command is a decoded geometry command
+type command struct {
+	id     int
+	count  int
+	params []int64
+}
+
+// decodeCommands decodes the commands of a geometry
+func decodeCommands(geometry []uint64) ([]command, error) {
+	var cmds []command
+	for i := 0; i < len(geometry); {
+		cmd := command{id: int(geometry[i] & 7), count: int(geometry[i] >> 3)}
+		i++
+		var n int
+		switch cmd.id {
+		case cmdMoveTo, cmdLineTo:
+			n = cmd.count * 2
+		case cmdClosePath:
+		default:
+			return nil, fmt.Errorf("%w: unknown command %d",
+				ErrInvalidGeometry, cmd.id)
+		}
+		if cmd.count == 0 {
+			return nil, fmt.Errorf("%w: command %d with a count of zero",
+				ErrInvalidGeometry, cmd.id)
+		}
+		if n > len(geometry)-i {
+			return nil, fmt.Errorf("%w: command %d is cut short",
+				ErrInvalidGeometry, cmd.id)
+		}
+		for _, p := range geometry[i : i+n] {
+			cmd.params = append(cmd.params, int64(p>>1)^-int64(p&1))
+		}
+		i += n
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// checkGeometry returns the issue of the geometry of a feature of the
+// type
+func checkGeometry(geomType int, geometry []uint64) error {
+	cmds, err := decodeCommands(geometry)
+	if err != nil {
+		return err
+	}
+	if len(cmds) == 0 {
+		return fmt.Errorf("%w: no commands", ErrInvalidGeometry)
+	}
+	switch geomType {
+	case 1:
+		if len(cmds) != 1 || cmds[0].id != cmdMoveTo {
+			return fmt.Errorf("%w: point is not a single MoveTo",
+				ErrInvalidGeometry)
+		}
+		return nil
+	case 2:
+		for i := 0; i < len(cmds); i += 2 {
+			if cmds[i].id != cmdMoveTo || cmds[i].count != 1 ||
+				i+1 >= len(cmds) || cmds[i+1].id != cmdLineTo {
+				return fmt.Errorf("%w: line %d is not a MoveTo of one "+
+					"point and a LineTo", ErrInvalidGeometry, i/2)
+			}
+		}
+		return nil
+	}
+	// the cursor is kept across rings, as the spec encodes the points
+	// relative to the last point of the previous ring
+	var x, y int64
+	for i := 0; i < len(cmds); i += 3 {
+		if i+2 >= len(cmds) || cmds[i].id != cmdMoveTo ||
+			cmds[i].count != 1 || cmds[i+1].id != cmdLineTo ||
+			cmds[i+1].count < 2 || cmds[i+2].id != cmdClosePath ||
+			cmds[i+2].count != 1 {
+			return fmt.Errorf("%w: ring %d is not a MoveTo of one point, "+
+				"a LineTo of two or more, and a ClosePath",
+				ErrInvalidGeometry, i/3)
+		}
+		var ring [][2]int64
+		for _, cmd := range cmds[i : i+2] {
+			for j := 0; j < len(cmd.params); j += 2 {
+				x, y = x+cmd.params[j], y+cmd.params[j+1]
+				ring = append(ring, [2]int64{x, y})
+			}
+		}
+		var area int64
+		for j := range ring {
+			a, b := ring[j], ring[(j+1)%len(ring)]
+			area += a[0]*b[1] - b[0]*a[1]
+		}
+		if area == 0 || (i == 0 && area < 0) {
+			return fmt.Errorf("%w: ring %d", ErrInvalidWinding, i/3)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/tidwall/mvt"
+)
+
+// field encodes a length-delimited field
+func field(num int, data ...[]byte) []byte {
+	var b []byte
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	out := binary.AppendUvarint(nil, uint64(num<<3|wireBytes))
+	out = binary.AppendUvarint(out, uint64(len(b)))
+	return append(out, b...)
+}
+
+// varint encodes a varint field
+func varint(num int, v uint64) []byte {
+	out := binary.AppendUvarint(nil, uint64(num<<3|wireVarint))
+	return binary.AppendUvarint(out, v)
+}
+
+// packed encodes a packed field of varints
+func packed(num int, vals ...uint64) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = binary.AppendUvarint(b, v)
+	}
+	return field(num, b)
+}
+
+// testLayer encodes a layer of version 2 with the key "a", the value "b",
+// and the features
+func testLayer(name string, features ...[]byte) []byte {
+	return field(3, field(1, []byte(name)), field(2, features...),
+		field(3, []byte("a")), field(4, field(1, []byte("b"))),
+		varint(15, 2))
+}
+
+func TestValid(t *testing.T) {
+	var tile mvt.Tile
+	l := tile.AddLayer("shapes")
+	p := l.AddFeature(mvt.Point)
+	p.MoveTo(10, 10)
+	p.MoveTo(20, 20)
+	p.AddTag("name", "points")
+	ls := l.AddFeature(mvt.LineString)
+	ls.MoveTo(10, 10)
+	ls.LineTo(20, 20)
+	ls.LineTo(30, 10)
+	pg := l.AddFeature(mvt.Polygon)
+	pg.MoveTo(10, 10)
+	pg.LineTo(100, 10)
+	pg.LineTo(100, 100)
+	pg.LineTo(10, 100)
+	pg.ClosePath()
+	pg.MoveTo(20, 20)
+	pg.LineTo(20, 90)
+	pg.LineTo(90, 90)
+	pg.LineTo(90, 20)
+	pg.ClosePath()
+	pg.AddTag("area", 1.5)
+	tile.AddLayer("empty")
+	pb, err := tile.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := Tile(pb); issues != nil {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+	// extensions are allowed
+	pb = append(pb, varint(16, 1)...)
+	if issues := Tile(pb); issues != nil {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestIssues(t *testing.T) {
+	point := field(2, varint(3, 1), packed(4, 9, 2, 2))
+	square := packed(4, 9, 0, 0, 26, 20, 0, 0, 20, 19, 0, 15)
+	tests := []struct {
+		name    string
+		tile    []byte
+		layer   int
+		feature int
+		err     error
+	}{
+		{"unknown tile field", varint(2, 1), -1, -1, ErrUnknownField},
+		{"cut short", field(3, point)[:5], -1, -1, ErrMalformed},
+		{"unknown layer field",
+			field(3, field(1, []byte("a")), varint(6, 1), varint(15, 2)),
+			0, -1, ErrUnknownField},
+		{"version", field(3, field(1, []byte("a")), varint(15, 3)),
+			0, -1, ErrUnsupportedVersion},
+		{"missing name", field(3, point, varint(15, 2)),
+			0, -1, ErrMissingName},
+		{"duplicate", append(testLayer("a"), testLayer("a")...),
+			1, -1, ErrDuplicateLayer},
+		{"extent", field(3, field(1, []byte("a")), varint(5, 0),
+			varint(15, 2)), 0, -1, ErrInvalidExtent},
+		{"value", field(3, field(1, []byte("a")), field(4),
+			varint(15, 2)), 0, -1, ErrInvalidValue},
+		{"two values", field(3, field(1, []byte("a")),
+			field(4, varint(4, 1), varint(7, 1)), varint(15, 2)),
+			0, -1, ErrInvalidValue},
+		{"odd tags", testLayer("a", packed(2, 0), point[2:]),
+			0, 0, ErrInvalidTags},
+		{"key index", testLayer("a", packed(2, 1, 0), point[2:]),
+			0, 0, ErrInvalidTags},
+		{"value index", testLayer("a", packed(2, 0, 1), point[2:]),
+			0, 0, ErrInvalidTags},
+		{"type", testLayer("a", varint(3, 4)), 0, 0, ErrInvalidType},
+		{"unknown feature field", testLayer("a", varint(5, 1)),
+			0, 0, ErrUnknownField},
+		{"command", testLayer("a", varint(3, 1), packed(4, 11, 2, 2)),
+			0, 0, ErrInvalidGeometry},
+		{"zero count", testLayer("a", varint(3, 1), packed(4, 1)),
+			0, 0, ErrInvalidGeometry},
+		{"missing params", testLayer("a", varint(3, 1), packed(4, 17, 2)),
+			0, 0, ErrInvalidGeometry},
+		{"point LineTo", testLayer("a", varint(3, 1),
+			packed(4, 9, 2, 2, 10, 2, 2)), 0, 0, ErrInvalidGeometry},
+		{"line without LineTo", testLayer("a", varint(3, 2),
+			packed(4, 9, 2, 2)), 0, 0, ErrInvalidGeometry},
+		{"line MoveTo of two", testLayer("a", varint(3, 2),
+			packed(4, 17, 2, 2, 4, 4, 10, 2, 2)), 0, 0, ErrInvalidGeometry},
+		{"polygon without ClosePath", testLayer("a", varint(3, 3),
+			packed(4, 9, 0, 0, 26, 20, 0, 0, 20, 19, 0)),
+			0, 0, ErrInvalidGeometry},
+		{"polygon clockwise", testLayer("a", varint(3, 3),
+			packed(4, 9, 0, 0, 26, 0, 20, 20, 0, 0, 19, 15)),
+			0, 0, ErrInvalidWinding},
+		{"polygon zero area", testLayer("a", varint(3, 3),
+			packed(4, 9, 0, 0, 18, 2, 2, 2, 2, 15)),
+			0, 0, ErrInvalidWinding},
+		{"valid after invalid", append(testLayer("a", varint(3, 4)),
+			testLayer("b", varint(3, 3), square)...), 0, 0, ErrInvalidType},
+	}
+	for _, tt := range tests {
+		issues := Tile(tt.tile)
+		if len(issues) != 1 {
+			t.Fatalf("%s: expected one issue, got %v", tt.name, issues)
+		}
+		issue := issues[0]
+		if issue.Layer != tt.layer || issue.Feature != tt.feature ||
+			!errors.Is(issue, tt.err) {
+			t.Fatalf("%s: expected %v at %d/%d, got %v at %d/%d", tt.name,
+				tt.err, tt.layer, tt.feature, issue.Err, issue.Layer,
+				issue.Feature)
+		}
+	}
+	issue := Tile(testLayer("a", varint(3, 4)))[0]
+	if issue.Error() != "layer 0: feature 0: invalid geometry type 4" {
+		t.Fatalf("unexpected error %q", issue.Error())
+	}
+}
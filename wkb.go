@@ -0,0 +1,146 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidWKB is returned for well-known binary geometry that is
+// malformed or of a type that is not supported.
+var ErrInvalidWKB = errors.New("invalid wkb")
+
+// wkbReader reads well-known binary geometry
+type wkbReader struct {
+	data []byte
+	err  error
+}
+
+func (r *wkbReader) fail(msg string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s", ErrInvalidWKB, msg)
+	}
+}
+
+func (r *wkbReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data) < n {
+		r.fail("unexpected end of data")
+		return make([]byte, n&0xffff)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *wkbReader) uint32(order binary.ByteOrder) uint32 {
+	return order.Uint32(r.next(4))
+}
+
+// parseWKB parses a WKB or EWKB geometry into lat/lon paths, keeping only
+// the x/y of each position. It also returns the SRID of an EWKB geometry,
+// which is zero when it has none.
+func parseWKB(data []byte) (Geometry, int, error) {
+	r := &wkbReader{data: data}
+	g, srid := r.geometry(0)
+	if r.err != nil {
+		return Geometry{}, 0, r.err
+	}
+	return g, srid, nil
+}
+
+// geometry reads a geometry, whose type must be of the kind for the parts
+// of multi geometries, or zero for any.
+func (r *wkbReader) geometry(kind uint32) (Geometry, int) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if r.next(1)[0] == 0 {
+		order = binary.BigEndian
+	}
+	typ := r.uint32(order)
+	var srid int
+	// EWKB flags
+	dims := 2
+	if typ&0x80000000 != 0 {
+		dims++
+	}
+	if typ&0x40000000 != 0 {
+		dims++
+	}
+	if typ&0x20000000 != 0 {
+		srid = int(r.uint32(order))
+	}
+	typ &= 0x0fffffff
+	// ISO dimensions
+	switch typ / 1000 {
+	case 1, 2:
+		dims = 3
+	case 3:
+		dims = 4
+	}
+	typ %= 1000
+	if kind != 0 && typ != kind {
+		r.fail(fmt.Sprintf("type %d in a multi geometry of %d", typ, kind))
+		return Geometry{}, 0
+	}
+	point := func() [2]float64 {
+		b := r.next(8 * dims)
+		return [2]float64{
+			math.Float64frombits(order.Uint64(b)),
+			math.Float64frombits(order.Uint64(b[8:])),
+		}
+	}
+	points := func() [][2]float64 {
+		n := int(r.uint32(order))
+		if n > len(r.data)/(8*dims) {
+			r.fail("too many points")
+			return nil
+		}
+		path := make([][2]float64, n)
+		for i := range path {
+			path[i] = point()
+		}
+		return path
+	}
+	count := func() int {
+		n := int(r.uint32(order))
+		// each part is at least a byte order and a type
+		if n > len(r.data)/5 {
+			r.fail("too many parts")
+			return 0
+		}
+		return n
+	}
+	var g Geometry
+	switch typ {
+	case 1:
+		g.Type = Point
+		if p := point(); !math.IsNaN(p[0]) {
+			// POINT EMPTY is NaN coordinates
+			g.Paths = [][][2]float64{{p}}
+		}
+	case 2:
+		g.Type = LineString
+		g.Paths = [][][2]float64{points()}
+	case 3:
+		g.Type = Polygon
+		for n := count(); n > 0 && r.err == nil; n-- {
+			g.Paths = append(g.Paths, points())
+		}
+	case 4, 5, 6:
+		g.Type = []GeometryType{Point, LineString, Polygon}[typ-4]
+		for n := count(); n > 0 && r.err == nil; n-- {
+			part, _ := r.geometry(typ - 3)
+			g.Paths = append(g.Paths, part.Paths...)
+			if typ == 6 {
+				g.Rings = append(g.Rings, len(part.Paths))
+			}
+		}
+	default:
+		r.fail(fmt.Sprintf("unsupported type %d", typ))
+	}
+	return g, srid
+}
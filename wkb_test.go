@@ -0,0 +1,66 @@
+// Copyright (c) 2018, Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// wkb returns little endian WKB of the type followed by the values, where
+// uint32 values are counts and float64 values are coordinates.
+func wkb(typ uint32, values ...interface{}) []byte {
+	le := binary.LittleEndian
+	b := le.AppendUint32([]byte{1}, typ)
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			b = le.AppendUint32(b, uint32(v))
+		case float64:
+			b = le.AppendUint64(b, math.Float64bits(v))
+		case []byte:
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+func TestParseWKB(t *testing.T) {
+	for _, tc := range []struct {
+		data   []byte
+		expect string
+		srid   int
+	}{
+		{wkb(1, 1.0, 2.0), "{1 [[[1 2]]] []}", 0},
+		{wkb(2, 2, 1.0, 2.0, 3.0, 4.0), "{2 [[[1 2] [3 4]]] []}", 0},
+		{wkb(3, 1, 3, 0.0, 0.0, 1.0, 0.0, 0.0, 0.0),
+			"{3 [[[0 0] [1 0] [0 0]]] []}", 0},
+		{wkb(4, 2, wkb(1, 1.0, 2.0), wkb(1, 3.0, 4.0)),
+			"{1 [[[1 2]] [[3 4]]] []}", 0},
+		{wkb(6, 2, wkb(3, 1, 1, 5.0, 5.0), wkb(3, 1, 1, 6.0, 6.0)),
+			"{3 [[[5 5]] [[6 6]]] [1 1]}", 0},
+		// ISO Z and EWKB Z with an SRID
+		{wkb(1001, 1.0, 2.0, 3.0), "{1 [[[1 2]]] []}", 0},
+		{wkb(0xa0000001, 4326, 1.0, 2.0, 3.0), "{1 [[[1 2]]] []}", 4326},
+	} {
+		g, srid, err := parseWKB(tc.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := fmt.Sprint(g); s != tc.expect || srid != tc.srid {
+			t.Fatalf("expected %s %d, got %s %d", tc.expect, tc.srid, s, srid)
+		}
+	}
+	for _, data := range [][]byte{
+		wkb(7, 0), wkb(2, 5, 1.0), wkb(4, 1, wkb(2, 0)), {1},
+	} {
+		if _, _, err := parseWKB(data); !errors.Is(err, ErrInvalidWKB) {
+			t.Fatalf("expected ErrInvalidWKB, got %v", err)
+		}
+	}
+}